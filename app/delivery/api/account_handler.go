@@ -0,0 +1,156 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/RedLucky/potongin/app/delivery/api/response"
+	"github.com/RedLucky/potongin/domain"
+	"github.com/labstack/echo/v4"
+)
+
+// AccountHandler represent the httphandler for the account of the logged in user
+type AccountHandler struct {
+	AccountUsecase domain.AccountUsecase
+	Response       *response.JsonResponse
+}
+
+type TokenParam struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// NewAccountHandler will initialize the me/ resources endpoint, the email
+// change is confirmed from the link sent by email so it is registered on public
+func NewAccountHandler(routes *Routes, uc domain.AccountUsecase, response *response.JsonResponse) {
+	handler := &AccountHandler{
+		AccountUsecase: uc,
+		Response:       response,
+	}
+	e := routes.Protected
+	e.PATCH("/me", handler.UpdateProfile)
+	e.POST("/me/email", handler.ChangeEmail)
+	e.PUT("/me/password", handler.ChangePassword)
+	e.DELETE("/me", handler.Delete)
+	routes.Public.POST("/me/email/confirm", handler.ConfirmEmail)
+
+	legacy := routes.LegacyProtected
+	legacy.PATCH("/me", handler.UpdateProfile, routes.Deprecated("/v1/me"))
+	legacy.POST("/me/email", handler.ChangeEmail, routes.Deprecated("/v1/me/email"))
+	legacy.PUT("/me/password", handler.ChangePassword, routes.Deprecated("/v1/me/password"))
+	legacy.DELETE("/me", handler.Delete, routes.Deprecated("/v1/me"))
+	routes.LegacyPublic.POST("/me/email/confirm", handler.ConfirmEmail, routes.Deprecated("/v1/me/email/confirm"))
+}
+
+// UpdateProfile will change the name and the username of the current user
+func (handler *AccountHandler) UpdateProfile(c echo.Context) (err error) {
+	var profile domain.UpdateProfile
+	if err = c.Bind(&profile); err != nil {
+		return handler.Response.Error(c, err)
+	}
+	var ok bool
+	if ok, err = validateAccountParam(&profile); !ok {
+		return handler.Response.Error(c, err)
+	}
+
+	userId := c.Get("user_id").(int64)
+	ctx := c.Request().Context()
+	user, err := handler.AccountUsecase.UpdateProfile(ctx, userId, profile)
+	if err != nil {
+		return handler.Response.Error(c, err)
+	}
+	userResponse := map[string]interface{}{
+		"id":         user.ID,
+		"username":   user.Username,
+		"email":      user.Email,
+		"name":       user.Name,
+		"created_at": user.CreatedAt,
+		"updated_at": user.UpdatedAt,
+	}
+	return handler.Response.Success(c, "success", http.StatusOK, map[string]interface{}{"user": userResponse})
+}
+
+// ChangeEmail will send a verification link to the new email, the email only change once verified
+func (handler *AccountHandler) ChangeEmail(c echo.Context) (err error) {
+	var param domain.ChangeEmail
+	if err = c.Bind(&param); err != nil {
+		return handler.Response.Error(c, err)
+	}
+	var ok bool
+	if ok, err = validateAccountParam(&param); !ok {
+		return handler.Response.Error(c, err)
+	}
+
+	userId := c.Get("user_id").(int64)
+	ctx := c.Request().Context()
+	_, err = handler.AccountUsecase.RequestEmailChange(ctx, userId, param.Email, param.Password)
+	if err != nil {
+		return handler.Response.Error(c, err)
+	}
+	return handler.Response.Success(c, "verification sent to the new email", http.StatusAccepted, map[string]interface{}{})
+}
+
+// ConfirmEmail will switch to the new email of the verified token
+func (handler *AccountHandler) ConfirmEmail(c echo.Context) (err error) {
+	var param TokenParam
+	if err = c.Bind(&param); err != nil {
+		return handler.Response.Error(c, err)
+	}
+	var ok bool
+	if ok, err = validateAccountParam(&param); !ok {
+		return handler.Response.Error(c, err)
+	}
+
+	err = handler.AccountUsecase.ConfirmEmailChange(c.Request().Context(), param.Token)
+	if err != nil {
+		return handler.Response.Error(c, err)
+	}
+	return handler.Response.Success(c, "success", http.StatusOK, map[string]interface{}{})
+}
+
+// ChangePassword will replace the password and log out the other sessions
+func (handler *AccountHandler) ChangePassword(c echo.Context) (err error) {
+	var param domain.ChangePassword
+	if err = c.Bind(&param); err != nil {
+		return handler.Response.Error(c, err)
+	}
+	var ok bool
+	if ok, err = validateAccountParam(&param); !ok {
+		return handler.Response.Error(c, err)
+	}
+
+	userId := c.Get("user_id").(int64)
+	sessionId, _ := c.Get("session_id").(string)
+	ctx := c.Request().Context()
+	err = handler.AccountUsecase.ChangePassword(ctx, userId, sessionId, param.CurrentPassword, param.NewPassword)
+	if err != nil {
+		return handler.Response.Error(c, err)
+	}
+	return handler.Response.Success(c, "success", http.StatusOK, map[string]interface{}{})
+}
+
+// Delete will delete the current user, its links are transferred or deleted
+func (handler *AccountHandler) Delete(c echo.Context) (err error) {
+	var param domain.DeleteAccount
+	if err = c.Bind(&param); err != nil {
+		return handler.Response.Error(c, err)
+	}
+	var ok bool
+	if ok, err = validateAccountParam(&param); !ok {
+		return handler.Response.Error(c, err)
+	}
+
+	userId := c.Get("user_id").(int64)
+	ctx := c.Request().Context()
+	err = handler.AccountUsecase.DeleteAccount(ctx, userId, param.Password, param.TransferTo)
+	if err != nil {
+		return handler.Response.Error(c, err)
+	}
+	return handler.Response.Success(c, "success", http.StatusOK, map[string]interface{}{})
+}
+
+func validateAccountParam(m interface{}) (bool, error) {
+	err := validate.Struct(m)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
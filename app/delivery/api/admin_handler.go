@@ -0,0 +1,260 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/RedLucky/potongin/app/delivery/api/response"
+	"github.com/RedLucky/potongin/domain"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+)
+
+// AdminHandler represent the httphandler for the admin console
+type AdminHandler struct {
+	AdminUsecase domain.AdminUsecase
+	AuditUsecase domain.AuditUsecase
+	Response     *response.JsonResponse
+}
+
+// NewAdminHandler will initialize the admin/ resources endpoint, reserved to admins
+func NewAdminHandler(routes *Routes, uc domain.AdminUsecase, auditUc domain.AuditUsecase, response *response.JsonResponse) {
+	handler := &AdminHandler{
+		AdminUsecase: uc,
+		AuditUsecase: auditUc,
+		Response:     response,
+	}
+	admin := routes.Protected.Group("/admin", handler.RequireAdmin)
+	admin.GET("/users", handler.SearchUsers)
+	admin.GET("/users/:id", handler.GetUser)
+	admin.POST("/users/:id/suspension", handler.Suspend)
+	admin.DELETE("/users/:id/suspension", handler.Unsuspend)
+	admin.POST("/users/:id/email-verification", handler.VerifyEmail)
+	admin.POST("/users/:id/password-reset", handler.ForcePasswordReset)
+	admin.DELETE("/users/:id/lockout", handler.UnlockAccount)
+	admin.POST("/links/:id/takedown", handler.TakeDownLink)
+	admin.DELETE("/links/:id/takedown", handler.RestoreLink)
+	admin.GET("/audit-logs", handler.FetchAudit)
+	admin.GET("/audit-logs/export", handler.ExportAudit)
+
+	legacy := routes.LegacyProtected.Group("/admin", handler.RequireAdmin)
+	legacy.GET("/users", handler.SearchUsers, routes.Deprecated("/v1/admin/users"))
+	legacy.GET("/users/:id", handler.GetUser, routes.Deprecated("/v1/admin/users/:id"))
+	legacy.POST("/users/:id/suspend", handler.Suspend, routes.Deprecated("/v1/admin/users/:id/suspension"))
+	legacy.POST("/users/:id/unsuspend", handler.Unsuspend, routes.Deprecated("/v1/admin/users/:id/suspension"))
+	legacy.POST("/users/:id/verifyEmail", handler.VerifyEmail, routes.Deprecated("/v1/admin/users/:id/email-verification"))
+	legacy.POST("/users/:id/resetPassword", handler.ForcePasswordReset, routes.Deprecated("/v1/admin/users/:id/password-reset"))
+	legacy.POST("/links/:id/takedown", handler.TakeDownLink, routes.Deprecated("/v1/admin/links/:id/takedown"))
+	legacy.POST("/links/:id/restore", handler.RestoreLink, routes.Deprecated("/v1/admin/links/:id/takedown"))
+	legacy.GET("/audit", handler.FetchAudit, routes.Deprecated("/v1/admin/audit-logs"))
+	legacy.GET("/audit/export", handler.ExportAudit, routes.Deprecated("/v1/admin/audit-logs/export"))
+}
+
+// RequireAdmin refuse the request unless the current user is an admin
+func (handler *AdminHandler) RequireAdmin(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		userId, ok := c.Get("user_id").(int64)
+		if !ok {
+			return handler.Response.Error(c, domain.ErrorAuthorization)
+		}
+		isAdmin, err := handler.AdminUsecase.IsAdmin(c.Request().Context(), userId)
+		if err != nil {
+			return handler.Response.Error(c, err)
+		}
+		if !isAdmin {
+			return handler.Response.Error(c, domain.ErrForbidden)
+		}
+		return next(c)
+	}
+}
+
+// SearchUsers will list the users matching ?q= with their links totals
+func (handler *AdminHandler) SearchUsers(c echo.Context) error {
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	offset, _ := strconv.Atoi(c.QueryParam("offset"))
+	ctx := c.Request().Context()
+
+	users, err := handler.AdminUsecase.SearchUsers(ctx, c.QueryParam("q"), limit, offset)
+	if err != nil {
+		return handler.Response.Error(c, err)
+	}
+	return handler.Response.Paginated(c, "success", http.StatusOK, map[string]interface{}{"users": users},
+		response.Meta{Limit: limit, Offset: offset, Count: len(users)})
+}
+
+// GetUser will get the user by given id with its links
+func (handler *AdminHandler) GetUser(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return handler.Response.Error(c, domain.ErrBadParamInput)
+	}
+	ctx := c.Request().Context()
+
+	user, links, err := handler.AdminUsecase.GetUser(ctx, id)
+	if err != nil {
+		return handler.Response.Error(c, err)
+	}
+	return handler.Response.Success(c, "success", http.StatusOK, map[string]interface{}{"user": user, "links": links})
+}
+
+func (handler *AdminHandler) Suspend(c echo.Context) (err error) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return handler.Response.Error(c, domain.ErrBadParamInput)
+	}
+	var param domain.ModerationReason
+	if err = c.Bind(&param); err != nil {
+		return handler.Response.Error(c, err)
+	}
+	var ok bool
+	if ok, err = validateAdminParam(&param); !ok {
+		return handler.Response.Error(c, err)
+	}
+
+	if err = handler.AdminUsecase.Suspend(c.Request().Context(), id, param.Reason); err != nil {
+		return handler.Response.Error(c, err)
+	}
+	return handler.Response.Success(c, "success", http.StatusOK, map[string]interface{}{})
+}
+
+func (handler *AdminHandler) Unsuspend(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return handler.Response.Error(c, domain.ErrBadParamInput)
+	}
+	if err = handler.AdminUsecase.Unsuspend(c.Request().Context(), id); err != nil {
+		return handler.Response.Error(c, err)
+	}
+	return handler.Response.Success(c, "success", http.StatusOK, map[string]interface{}{})
+}
+
+func (handler *AdminHandler) VerifyEmail(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return handler.Response.Error(c, domain.ErrBadParamInput)
+	}
+	if err = handler.AdminUsecase.VerifyEmail(c.Request().Context(), id); err != nil {
+		return handler.Response.Error(c, err)
+	}
+	return handler.Response.Success(c, "success", http.StatusOK, map[string]interface{}{})
+}
+
+// ForcePasswordReset will log the user out and refuse its password until reset
+func (handler *AdminHandler) ForcePasswordReset(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return handler.Response.Error(c, domain.ErrBadParamInput)
+	}
+	if _, err = handler.AdminUsecase.ForcePasswordReset(c.Request().Context(), id); err != nil {
+		return handler.Response.Error(c, err)
+	}
+	return handler.Response.Success(c, "success", http.StatusOK, map[string]interface{}{})
+}
+
+// UnlockAccount will lift the lockout of the user after too many failed logins
+func (handler *AdminHandler) UnlockAccount(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return handler.Response.Error(c, domain.ErrBadParamInput)
+	}
+	if err = handler.AdminUsecase.UnlockAccount(c.Request().Context(), id); err != nil {
+		return handler.Response.Error(c, err)
+	}
+	return handler.Response.Success(c, "success", http.StatusOK, map[string]interface{}{})
+}
+
+func (handler *AdminHandler) TakeDownLink(c echo.Context) (err error) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return handler.Response.Error(c, domain.ErrBadParamInput)
+	}
+	var param domain.ModerationReason
+	if err = c.Bind(&param); err != nil {
+		return handler.Response.Error(c, err)
+	}
+	var ok bool
+	if ok, err = validateAdminParam(&param); !ok {
+		return handler.Response.Error(c, err)
+	}
+
+	if err = handler.AdminUsecase.TakeDownLink(c.Request().Context(), id, param.Reason); err != nil {
+		return handler.Response.Error(c, err)
+	}
+	return handler.Response.Success(c, "success", http.StatusOK, map[string]interface{}{})
+}
+
+func (handler *AdminHandler) RestoreLink(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return handler.Response.Error(c, domain.ErrBadParamInput)
+	}
+	if err = handler.AdminUsecase.RestoreLink(c.Request().Context(), id); err != nil {
+		return handler.Response.Error(c, err)
+	}
+	return handler.Response.Success(c, "success", http.StatusOK, map[string]interface{}{})
+}
+
+// FetchAudit will list the audit logs, newest first, matching the query filters
+func (handler *AdminHandler) FetchAudit(c echo.Context) error {
+	filter, err := auditFilterFromQuery(c)
+	if err != nil {
+		return handler.Response.Error(c, domain.ErrBadParamInput)
+	}
+	logs, err := handler.AuditUsecase.Fetch(c.Request().Context(), filter)
+	if err != nil {
+		return handler.Response.Error(c, err)
+	}
+	return handler.Response.Paginated(c, "success", http.StatusOK, map[string]interface{}{"logs": logs},
+		response.Meta{Limit: filter.Limit, Offset: filter.Offset, Count: len(logs)})
+}
+
+// ExportAudit will stream every audit log matching the query filters as JSON lines
+func (handler *AdminHandler) ExportAudit(c echo.Context) error {
+	filter, err := auditFilterFromQuery(c)
+	if err != nil {
+		return handler.Response.Error(c, domain.ErrBadParamInput)
+	}
+	c.Response().Header().Set(echo.HeaderContentType, "application/x-ndjson")
+	c.Response().Header().Set(echo.HeaderContentDisposition, `attachment; filename="audit.jsonl"`)
+	c.Response().WriteHeader(http.StatusOK)
+	if err = handler.AuditUsecase.Export(c.Request().Context(), filter, c.Response()); err != nil {
+		// the status is already sent, the export is cut short
+		logrus.Error("audit export failed: ", err)
+	}
+	return nil
+}
+
+// auditFilterFromQuery read ?actor_id=&action=&target_type=&target_id=&from=&to=&limit=&offset=,
+// from and to are RFC 3339 times
+func auditFilterFromQuery(c echo.Context) (filter domain.AuditFilter, err error) {
+	if param := c.QueryParam("actor_id"); param != "" {
+		if filter.ActorId, err = strconv.ParseInt(param, 10, 64); err != nil {
+			return
+		}
+	}
+	if param := c.QueryParam("from"); param != "" {
+		if filter.From, err = time.Parse(time.RFC3339, param); err != nil {
+			return
+		}
+	}
+	if param := c.QueryParam("to"); param != "" {
+		if filter.To, err = time.Parse(time.RFC3339, param); err != nil {
+			return
+		}
+	}
+	filter.Action = c.QueryParam("action")
+	filter.TargetType = c.QueryParam("target_type")
+	filter.TargetId = c.QueryParam("target_id")
+	filter.Limit, _ = strconv.Atoi(c.QueryParam("limit"))
+	filter.Offset, _ = strconv.Atoi(c.QueryParam("offset"))
+	return
+}
+
+func validateAdminParam(m interface{}) (bool, error) {
+	err := validate.Struct(m)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
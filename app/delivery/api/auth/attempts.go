@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// login failures are counted per account and per ip within a sliding window,
+// a "wait" key enforce the backoff between two attempts
+
+func accountFailKey(email string) string {
+	return "login_fail:account:" + strings.ToLower(email)
+}
+
+func ipFailKey(ip string) string {
+	return "login_fail:ip:" + ip
+}
+
+func waitKey(counterKey string) string {
+	return counterKey + ":wait"
+}
+
+func lockKey(email string) string {
+	return "login_lock:" + strings.ToLower(email)
+}
+
+func unlockTokenKey(token string) string {
+	return "login_unlock:" + token
+}
+
+// RecordLoginFailure count a failed login and return the failures made so far
+func RecordLoginFailure(redisConn redis.Conn, email, ip string, window time.Duration) (accountFailures, ipFailures int, err error) {
+	redisConn.Send("MULTI")
+	redisConn.Send("INCR", accountFailKey(email))
+	redisConn.Send("EXPIRE", accountFailKey(email), int(window.Seconds()))
+	redisConn.Send("INCR", ipFailKey(ip))
+	redisConn.Send("EXPIRE", ipFailKey(ip), int(window.Seconds()))
+	values, err := redis.Values(redisConn.Do("EXEC"))
+	if err != nil {
+		return 0, 0, err
+	}
+	accountFailures, _ = redis.Int(values[0], nil)
+	ipFailures, _ = redis.Int(values[2], nil)
+	return
+}
+
+// SetLoginBackoff forbid the next attempt of the account and the ip for the given durations
+func SetLoginBackoff(redisConn redis.Conn, email, ip string, accountWait, ipWait time.Duration) error {
+	redisConn.Send("MULTI")
+	if accountWait > 0 {
+		redisConn.Send("SET", waitKey(accountFailKey(email)), 1, "PX", accountWait.Milliseconds())
+	}
+	if ipWait > 0 {
+		redisConn.Send("SET", waitKey(ipFailKey(ip)), 1, "PX", ipWait.Milliseconds())
+	}
+	_, err := redisConn.Do("EXEC")
+	return err
+}
+
+// LoginBackoff return how long the account or the ip still has to wait before the next attempt
+func LoginBackoff(redisConn redis.Conn, email, ip string) (time.Duration, error) {
+	redisConn.Send("MULTI")
+	redisConn.Send("PTTL", waitKey(accountFailKey(email)))
+	redisConn.Send("PTTL", waitKey(ipFailKey(ip)))
+	values, err := redis.Int64s(redisConn.Do("EXEC"))
+	if err != nil {
+		return 0, err
+	}
+
+	var wait int64
+	for _, ttl := range values {
+		if ttl > wait {
+			wait = ttl
+		}
+	}
+	return time.Duration(wait) * time.Millisecond, nil
+}
+
+// ResetLoginFailures clear the account counter after a successful login, the
+// ip counter is kept so one good account can't be used to reset it
+func ResetLoginFailures(redisConn redis.Conn, email string) error {
+	_, err := redisConn.Do("DEL", accountFailKey(email), waitKey(accountFailKey(email)))
+	return err
+}
+
+func LockAccount(redisConn redis.Conn, email string, duration time.Duration) error {
+	_, err := redisConn.Do("SET", lockKey(email), 1, "EX", int(duration.Seconds()))
+	return err
+}
+
+func IsAccountLocked(redisConn redis.Conn, email string) (bool, error) {
+	return redis.Bool(redisConn.Do("EXISTS", lockKey(email)))
+}
+
+// UnlockAccount lift the lock and forget the failures of the account
+func UnlockAccount(redisConn redis.Conn, email string) error {
+	_, err := redisConn.Do("DEL", lockKey(email), accountFailKey(email), waitKey(accountFailKey(email)))
+	return err
+}
+
+func SaveUnlockToken(redisConn redis.Conn, token, email string, duration time.Duration) error {
+	_, err := redisConn.Do("SET", unlockTokenKey(token), strings.ToLower(email), "EX", int(duration.Seconds()))
+	return err
+}
+
+// ConsumeUnlockToken return the email of the token, it return redis.ErrNil when the token is unknown or used
+func ConsumeUnlockToken(redisConn redis.Conn, token string) (email string, err error) {
+	redisConn.Send("MULTI")
+	redisConn.Send("GET", unlockTokenKey(token))
+	redisConn.Send("DEL", unlockTokenKey(token))
+	values, err := redis.Values(redisConn.Do("EXEC"))
+	if err != nil {
+		return "", err
+	}
+	return redis.String(values[0], nil)
+}
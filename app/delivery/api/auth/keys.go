@@ -0,0 +1,466 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
+	"github.com/mitchellh/mapstructure"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+var (
+	// Keys hold the asymmetric keys used to sign access tokens. When it is nil
+	// access tokens are signed with the legacy HS256 secret.
+	Keys *KeySet
+
+	ErrUnknownKey          = errors.New("unknown signing key")
+	ErrNoSigningKey        = errors.New("no active signing key")
+	ErrUnsupportedKeyAlgo  = errors.New("unsupported signing algorithm")
+	ErrKeyAlgorithmInvalid = errors.New("token algorithm does not match its key")
+)
+
+// SigningKey is a key pair used to sign access tokens, identified by its kid
+type SigningKey struct {
+	Kid        string
+	Method     jwt.SigningMethod
+	PrivateKey crypto.PrivateKey
+	PublicKey  crypto.PublicKey
+	// ActiveFrom is when the key starts signing new tokens
+	ActiveFrom time.Time
+	// RetireAt is when the key stops signing, zero means never. A retired key
+	// is still used for verification during the grace period.
+	RetireAt time.Time
+}
+
+// the generated keys are reloaded from the store this often, and at most
+// every keyRefreshInterval when a token is signed by an unknown key
+const (
+	keySyncInterval     = 30 * time.Second
+	keyRefreshInterval  = time.Second
+	keyStoreCallTimeout = 2 * time.Second
+)
+
+// KeySet is the list of signing keys, safe for concurrent use
+type KeySet struct {
+	mu    sync.RWMutex
+	keys  []*SigningKey
+	grace time.Duration
+	// AcceptHS256 keep accepting access tokens signed with the shared secret
+	// while clients migrate to the asymmetric keys
+	AcceptHS256 bool
+
+	// store hold the generated keys, it is nil when the keys come from the
+	// config and are never rotated
+	store KeyStore
+	// refreshedAt is when a missing key last reloaded the store
+	refreshedAt time.Time
+}
+
+type keyConfig struct {
+	Kid            string    `mapstructure:"kid"`
+	Algorithm      string    `mapstructure:"algorithm"`
+	PrivateKeyFile string    `mapstructure:"private_key_file"`
+	ActiveFrom     time.Time `mapstructure:"active_from"`
+	RetireAt       time.Time `mapstructure:"retire_at"`
+}
+
+// NewKeySet create an empty key set, retired keys keep verifying tokens for the grace duration
+func NewKeySet(grace time.Duration) *KeySet {
+	return &KeySet{grace: grace, AcceptHS256: true}
+}
+
+// LoadKeys build the key set from the `authentication` config. It return nil
+// when the service still signs with HS256 only. Without configured keys, the
+// keys are generated and shared through the store.
+func LoadKeys(store KeyStore) (*KeySet, error) {
+	algorithm := viper.GetString(`authentication.signing_algorithm`)
+	if algorithm == "" || algorithm == jwt.SigningMethodHS256.Alg() {
+		return nil, nil
+	}
+
+	// a retired key verifies the access tokens it signed until they expire
+	grace := accessDuration()
+	if viper.IsSet(`authentication.key_grace_period`) {
+		configured := time.Duration(viper.GetInt(`authentication.key_grace_period`)) * time.Minute
+		if configured < grace {
+			return nil, fmt.Errorf("authentication.key_grace_period (%v) is shorter than authentication.duration_access (%v)", configured, grace)
+		}
+		grace = configured
+	}
+	ks := NewKeySet(grace)
+	if viper.IsSet(`authentication.accept_hs256`) {
+		ks.AcceptHS256 = viper.GetBool(`authentication.accept_hs256`)
+	}
+
+	var configs []keyConfig
+	decodeTime := viper.DecodeHook(mapstructure.StringToTimeHookFunc(time.RFC3339))
+	if err := viper.UnmarshalKey(`authentication.signing_keys`, &configs, decodeTime); err != nil {
+		return nil, err
+	}
+	for _, config := range configs {
+		key, err := loadKey(config)
+		if err != nil {
+			return nil, fmt.Errorf("signing key %s: %w", config.Kid, err)
+		}
+		ks.Add(key)
+	}
+
+	if len(configs) == 0 {
+		ks.store = store
+		ctx, cancel := context.WithTimeout(context.Background(), keyStoreCallTimeout)
+		defer cancel()
+		// a store down at start is retried by the rotation and on each signature
+		if err := ks.rotateIfDue(ctx, algorithm, 0, time.Now()); err != nil {
+			logrus.WithError(err).Error("failed to load the signing keys")
+		}
+	}
+	return ks, nil
+}
+
+func loadKey(config keyConfig) (*SigningKey, error) {
+	method := jwt.GetSigningMethod(config.Algorithm)
+	if method == nil {
+		return nil, ErrUnsupportedKeyAlgo
+	}
+	pem, err := ioutil.ReadFile(config.PrivateKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &SigningKey{
+		Kid:        config.Kid,
+		Method:     method,
+		ActiveFrom: config.ActiveFrom,
+		RetireAt:   config.RetireAt,
+	}
+	switch method.(type) {
+	case *jwt.SigningMethodRSA:
+		private, err := jwt.ParseRSAPrivateKeyFromPEM(pem)
+		if err != nil {
+			return nil, err
+		}
+		key.PrivateKey, key.PublicKey = private, &private.PublicKey
+	case *jwt.SigningMethodECDSA:
+		private, err := jwt.ParseECPrivateKeyFromPEM(pem)
+		if err != nil {
+			return nil, err
+		}
+		key.PrivateKey, key.PublicKey = private, &private.PublicKey
+	case *jwt.SigningMethodEd25519:
+		private, err := jwt.ParseEdPrivateKeyFromPEM(pem)
+		if err != nil {
+			return nil, err
+		}
+		key.PrivateKey, key.PublicKey = private, private.(ed25519.PrivateKey).Public()
+	default:
+		return nil, ErrUnsupportedKeyAlgo
+	}
+	return key, nil
+}
+
+// GenerateKey create a new random key for the given algorithm
+func GenerateKey(algorithm string) (*SigningKey, error) {
+	key := &SigningKey{Kid: uuid.New().String()}
+	switch algorithm {
+	case jwt.SigningMethodRS256.Alg():
+		private, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, err
+		}
+		key.Method, key.PrivateKey, key.PublicKey = jwt.SigningMethodRS256, private, &private.PublicKey
+	case jwt.SigningMethodES256.Alg():
+		private, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		key.Method, key.PrivateKey, key.PublicKey = jwt.SigningMethodES256, private, &private.PublicKey
+	case jwt.SigningMethodEdDSA.Alg():
+		public, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		key.Method, key.PrivateKey, key.PublicKey = jwt.SigningMethodEdDSA, private, public
+	default:
+		return nil, ErrUnsupportedKeyAlgo
+	}
+	return key, nil
+}
+
+func (ks *KeySet) Add(key *SigningKey) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.keys = append(ks.keys, key)
+	sort.Slice(ks.keys, func(i, j int) bool {
+		return ks.keys[i].ActiveFrom.Before(ks.keys[j].ActiveFrom)
+	})
+}
+
+// Signing return the newest key allowed to sign at the given time
+func (ks *KeySet) Signing(now time.Time) (*SigningKey, error) {
+	key, err := ks.signing(now)
+	if err == ErrNoSigningKey && ks.refresh() {
+		return ks.signing(now)
+	}
+	return key, err
+}
+
+func (ks *KeySet) signing(now time.Time) (*SigningKey, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	for i := len(ks.keys) - 1; i >= 0; i-- {
+		key := ks.keys[i]
+		if key.ActiveFrom.After(now) {
+			continue
+		}
+		if !key.RetireAt.IsZero() && !now.Before(key.RetireAt) {
+			continue
+		}
+		return key, nil
+	}
+	return nil, ErrNoSigningKey
+}
+
+// Verification return the key with the given kid if it is still trusted at
+// the given time. An unknown kid may come from a key just generated by
+// another instance, the keys are reloaded once.
+func (ks *KeySet) Verification(kid string, now time.Time) (*SigningKey, error) {
+	key, err := ks.verification(kid, now)
+	if err == ErrUnknownKey && ks.refresh() {
+		return ks.verification(kid, now)
+	}
+	return key, err
+}
+
+func (ks *KeySet) verification(kid string, now time.Time) (*SigningKey, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	for _, key := range ks.keys {
+		if key.Kid == kid && ks.trusted(key, now) {
+			return key, nil
+		}
+	}
+	return nil, ErrUnknownKey
+}
+
+// upcoming keys are trusted too, so they can be published before they start signing
+func (ks *KeySet) trusted(key *SigningKey, now time.Time) bool {
+	return key.RetireAt.IsZero() || now.Before(key.RetireAt.Add(ks.grace))
+}
+
+// Rotate generate a new key signing from now and retire the current one
+func (ks *KeySet) Rotate(algorithm string, now time.Time) (*SigningKey, error) {
+	key, err := GenerateKey(algorithm)
+	if err != nil {
+		return nil, err
+	}
+	key.ActiveFrom = now
+
+	if current, err := ks.signing(now); err == nil {
+		ks.mu.Lock()
+		current.RetireAt = now
+		ks.mu.Unlock()
+	}
+	ks.Add(key)
+	ks.Prune(now)
+	return key, nil
+}
+
+// Prune drop the keys whose grace period is over
+func (ks *KeySet) Prune(now time.Time) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	keys := ks.keys[:0]
+	for _, key := range ks.keys {
+		if ks.trusted(key, now) {
+			keys = append(keys, key)
+		}
+	}
+	ks.keys = keys
+}
+
+// StartRotation keep the generated keys in sync with the store and rotate
+// them every interval, never when 0, until stop is closed. The keys of the
+// config are not rotated.
+func (ks *KeySet) StartRotation(algorithm string, interval time.Duration, stop <-chan struct{}) {
+	if ks.store == nil {
+		return
+	}
+	ticker := time.NewTicker(keySyncInterval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), keyStoreCallTimeout)
+				if err := ks.rotateIfDue(ctx, algorithm, interval, now); err != nil {
+					logrus.WithError(err).Error("failed to rotate the signing keys")
+				}
+				cancel()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// rotateIfDue load the stored keys, then a single instance generates a new
+// one when there is no signing key or the current one signed for interval
+func (ks *KeySet) rotateIfDue(ctx context.Context, algorithm string, interval time.Duration, now time.Time) error {
+	if err := ks.Sync(ctx); err != nil {
+		return err
+	}
+	current, err := ks.signing(now)
+	if err == nil && (interval <= 0 || now.Before(current.ActiveFrom.Add(interval))) {
+		return nil
+	}
+
+	locked, err := ks.store.LockRotation(ctx, keySyncInterval)
+	if err != nil || !locked {
+		return err
+	}
+	key, err := ks.Rotate(algorithm, now)
+	if err != nil {
+		return err
+	}
+	if err = ks.store.SaveKeys(ctx, ks.list()); err != nil {
+		return err
+	}
+	logrus.WithField("kid", key.Kid).Info("signing key rotated")
+	return nil
+}
+
+// Sync add the keys of the store unknown to the set, and the retirement of the known ones
+func (ks *KeySet) Sync(ctx context.Context) error {
+	stored, err := ks.store.LoadKeys(ctx)
+	if err != nil {
+		return err
+	}
+
+	ks.mu.Lock()
+	for _, key := range stored {
+		known := false
+		for _, existing := range ks.keys {
+			if existing.Kid == key.Kid {
+				existing.RetireAt, known = key.RetireAt, true
+			}
+		}
+		if !known {
+			ks.keys = append(ks.keys, key)
+		}
+	}
+	sort.Slice(ks.keys, func(i, j int) bool {
+		return ks.keys[i].ActiveFrom.Before(ks.keys[j].ActiveFrom)
+	})
+	ks.mu.Unlock()
+	ks.Prune(time.Now())
+	return nil
+}
+
+// refresh reload the stored keys unless it was just done, and tell if it did
+func (ks *KeySet) refresh() bool {
+	if ks.store == nil {
+		return false
+	}
+	ks.mu.Lock()
+	recent := time.Since(ks.refreshedAt) < keyRefreshInterval
+	if !recent {
+		ks.refreshedAt = time.Now()
+	}
+	ks.mu.Unlock()
+	if recent {
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), keyStoreCallTimeout)
+	defer cancel()
+	if err := ks.Sync(ctx); err != nil {
+		logrus.WithError(err).Error("failed to load the signing keys")
+		return false
+	}
+	return true
+}
+
+func (ks *KeySet) list() []*SigningKey {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return append([]*SigningKey(nil), ks.keys...)
+}
+
+// JSONWebKey is the public part of a signing key as described by RFC 7517
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// JWKS return the public keys trusted at the given time
+func (ks *KeySet) JWKS(now time.Time) JSONWebKeySet {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	set := JSONWebKeySet{Keys: []JSONWebKey{}}
+	for _, key := range ks.keys {
+		if !ks.trusted(key, now) {
+			continue
+		}
+		jwk := JSONWebKey{Kid: key.Kid, Use: "sig", Alg: key.Method.Alg()}
+		switch public := key.PublicKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = encodeSegment(public.N.Bytes())
+			jwk.E = encodeSegment(big.NewInt(int64(public.E)).Bytes())
+		case *ecdsa.PublicKey:
+			size := (public.Curve.Params().BitSize + 7) / 8
+			jwk.Kty = "EC"
+			jwk.Crv = public.Curve.Params().Name
+			jwk.X = encodeSegment(padBytes(public.X.Bytes(), size))
+			jwk.Y = encodeSegment(padBytes(public.Y.Bytes(), size))
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = encodeSegment(public)
+		default:
+			continue
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+func encodeSegment(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func padBytes(b []byte, size int) []byte {
+	if len(b) >= size {
+		return b
+	}
+	padded := make([]byte, size)
+	copy(padded[size-len(b):], b)
+	return padded
+}
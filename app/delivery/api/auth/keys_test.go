@@ -0,0 +1,235 @@
+package auth_test
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/RedLucky/potongin/app/delivery/api/auth"
+	"github.com/RedLucky/potongin/config/cache"
+	"github.com/RedLucky/potongin/domain"
+	"github.com/alicebob/miniredis/v2"
+	jwt "github.com/golang-jwt/jwt"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupTokenConfig() {
+	viper.Set(`authentication.duration_access`, 15)
+	viper.Set(`authentication.duration_refresh`, 18)
+	viper.Set(`authentication.jwt_signature_access_key`, "access-secret")
+	viper.Set(`authentication.jwt_signature_refresh_key`, "refresh-secret")
+}
+
+func TestKeySet_Rotate(t *testing.T) {
+	ks := auth.NewKeySet(time.Hour)
+	start := time.Now()
+
+	first, err := ks.Rotate("RS256", start)
+	require.NoError(t, err)
+	second, err := ks.Rotate("ES256", start.Add(24*time.Hour))
+	require.NoError(t, err)
+
+	t.Run("newest-key-signs", func(t *testing.T) {
+		key, err := ks.Signing(start.Add(24 * time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, second.Kid, key.Kid)
+	})
+
+	t.Run("old-key-verifies-during-grace", func(t *testing.T) {
+		key, err := ks.Verification(first.Kid, start.Add(24*time.Hour+30*time.Minute))
+		require.NoError(t, err)
+		assert.Equal(t, first.Kid, key.Kid)
+		assert.Len(t, ks.JWKS(start.Add(24*time.Hour)).Keys, 2)
+	})
+
+	t.Run("old-key-rejected-after-grace", func(t *testing.T) {
+		_, err := ks.Verification(first.Kid, start.Add(25*time.Hour))
+		assert.Equal(t, auth.ErrUnknownKey, err)
+		assert.Len(t, ks.JWKS(start.Add(25*time.Hour)).Keys, 1)
+	})
+}
+
+func TestTokenValid_Asymmetric(t *testing.T) {
+	setupTokenConfig()
+	defer func() { auth.Keys = nil }()
+
+	for _, algorithm := range []string{"RS256", "ES256", "EdDSA"} {
+		t.Run(algorithm, func(t *testing.T) {
+			auth.Keys = auth.NewKeySet(time.Hour)
+			key, err := auth.Keys.Rotate(algorithm, time.Now())
+			require.NoError(t, err)
+
+			token, err := auth.CreateToken(&domain.User{ID: 1}, "session")
+			require.NoError(t, err)
+
+			parsed, _ := jwt.Parse(token.AccessToken, nil)
+			assert.Equal(t, key.Kid, parsed.Header["kid"])
+			assert.Equal(t, algorithm, parsed.Header["alg"])
+
+			claims, err := auth.TokenValid(token.AccessToken, auth.AccessToken)
+			require.NoError(t, err)
+			assert.Equal(t, token.AccessUUID, claims["access_uuid"])
+
+			jwks := auth.Keys.JWKS(time.Now())
+			require.Len(t, jwks.Keys, 1)
+			assert.Equal(t, key.Kid, jwks.Keys[0].Kid)
+		})
+	}
+}
+
+func TestTokenValid_LegacyHS256(t *testing.T) {
+	setupTokenConfig()
+	defer func() { auth.Keys = nil }()
+
+	legacy, err := auth.CreateToken(&domain.User{ID: 1}, "session")
+	require.NoError(t, err)
+
+	auth.Keys = auth.NewKeySet(time.Hour)
+	_, err = auth.Keys.Rotate("RS256", time.Now())
+	require.NoError(t, err)
+
+	t.Run("accepted-during-migration", func(t *testing.T) {
+		_, err := auth.TokenValid(legacy.AccessToken, auth.AccessToken)
+		assert.NoError(t, err)
+	})
+
+	t.Run("rejected-after-migration", func(t *testing.T) {
+		auth.Keys.AcceptHS256 = false
+		defer func() { auth.Keys.AcceptHS256 = true }()
+		_, err := auth.TokenValid(legacy.AccessToken, auth.AccessToken)
+		assert.Error(t, err)
+	})
+
+	t.Run("hmac-with-kid-rejected", func(t *testing.T) {
+		key, _ := auth.Keys.Signing(time.Now())
+		forged := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"access_uuid": "x"})
+		forged.Header["kid"] = key.Kid
+		tokenString, err := forged.SignedString([]byte("access-secret"))
+		require.NoError(t, err)
+
+		_, err = auth.TokenValid(tokenString, auth.AccessToken)
+		assert.Error(t, err)
+	})
+}
+
+func TestLoadKeys_Shared(t *testing.T) {
+	viper.Set(`authentication.signing_algorithm`, "ES256")
+	defer viper.Set(`authentication.signing_algorithm`, nil)
+	redis := cache.Open(cache.Options{Address: miniredis.RunT(t).Addr()})
+	defer redis.Close()
+	store := auth.NewKeyStore(redis.Pool)
+
+	first, err := auth.LoadKeys(store)
+	require.NoError(t, err)
+	signing, err := first.Signing(time.Now())
+	require.NoError(t, err)
+
+	t.Run("other-instance-signs-with-the-same-key", func(t *testing.T) {
+		second, err := auth.LoadKeys(store)
+		require.NoError(t, err)
+
+		key, err := second.Signing(time.Now())
+		require.NoError(t, err)
+		assert.Equal(t, signing.Kid, key.Kid)
+	})
+
+	t.Run("key-rotated-elsewhere-is-loaded", func(t *testing.T) {
+		second, err := auth.LoadKeys(store)
+		require.NoError(t, err)
+		rotated, err := first.Rotate("ES256", time.Now())
+		require.NoError(t, err)
+		stored, err := store.LoadKeys(context.TODO())
+		require.NoError(t, err)
+		require.NoError(t, store.SaveKeys(context.TODO(), append(stored, rotated)))
+
+		key, err := second.Verification(rotated.Kid, time.Now())
+		require.NoError(t, err)
+		assert.Equal(t, rotated.Kid, key.Kid)
+	})
+
+	t.Run("restart-keeps-the-keys", func(t *testing.T) {
+		restarted, err := auth.LoadKeys(store)
+		require.NoError(t, err)
+
+		_, err = restarted.Verification(signing.Kid, time.Now())
+		assert.NoError(t, err)
+		assert.Len(t, restarted.JWKS(time.Now()).Keys, 2)
+	})
+}
+
+func TestLoadKeys_GracePeriod(t *testing.T) {
+	setupTokenConfig()
+	retireAt := time.Now().Truncate(time.Second)
+	viper.Set(`authentication.signing_algorithm`, "RS256")
+	viper.Set(`authentication.signing_keys`, []map[string]interface{}{
+		{"kid": "old", "algorithm": "RS256", "private_key_file": keyFile(t), "active_from": "2021-09-01T00:00:00Z", "retire_at": retireAt.Format(time.RFC3339)},
+		{"kid": "new", "algorithm": "RS256", "private_key_file": keyFile(t), "active_from": retireAt.Format(time.RFC3339)},
+	})
+	defer viper.Set(`authentication.signing_algorithm`, nil)
+	defer viper.Set(`authentication.signing_keys`, nil)
+
+	t.Run("defaults-to-the-access-duration", func(t *testing.T) {
+		ks, err := auth.LoadKeys(nil)
+		require.NoError(t, err)
+
+		// an access token signed just before the rotation stays valid until it expires
+		_, err = ks.Verification("old", retireAt.Add(14*time.Minute))
+		assert.NoError(t, err)
+		_, err = ks.Verification("old", retireAt.Add(16*time.Minute))
+		assert.Equal(t, auth.ErrUnknownKey, err)
+	})
+
+	t.Run("shorter-than-the-access-duration", func(t *testing.T) {
+		viper.Set(`authentication.key_grace_period`, 5)
+		defer viper.Set(`authentication.key_grace_period`, nil)
+
+		_, err := auth.LoadKeys(nil)
+
+		assert.Error(t, err)
+	})
+}
+
+// keyFile write a new RS256 private key to a PEM file
+func keyFile(t *testing.T) string {
+	key, err := auth.GenerateKey("RS256")
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key.PrivateKey)
+	require.NoError(t, err)
+	file := filepath.Join(t.TempDir(), key.Kid+".pem")
+	require.NoError(t, ioutil.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600))
+	return file
+}
+
+func TestLoadKeys_Configured(t *testing.T) {
+	file := keyFile(t)
+
+	viper.Set(`authentication.signing_algorithm`, "RS256")
+	viper.Set(`authentication.signing_keys`, []map[string]interface{}{
+		{"kid": "2021-09", "algorithm": "RS256", "private_key_file": file, "active_from": "2021-09-01T00:00:00Z"},
+	})
+	defer viper.Set(`authentication.signing_algorithm`, nil)
+	defer viper.Set(`authentication.signing_keys`, nil)
+	redis := cache.Open(cache.Options{Address: miniredis.RunT(t).Addr()})
+	defer redis.Close()
+	store := auth.NewKeyStore(redis.Pool)
+
+	ks, err := auth.LoadKeys(store)
+	require.NoError(t, err)
+	stop := make(chan struct{})
+	defer close(stop)
+	ks.StartRotation("RS256", time.Nanosecond, stop)
+
+	signing, err := ks.Signing(time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, "2021-09", signing.Kid)
+	// the configured keys are neither generated nor shared
+	stored, err := store.LoadKeys(context.TODO())
+	require.NoError(t, err)
+	assert.Empty(t, stored)
+}
@@ -0,0 +1,199 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"sync"
+	"time"
+
+	jwt "github.com/golang-jwt/jwt"
+	"github.com/gomodule/redigo/redis"
+)
+
+// KeyStore share the generated signing keys between the instances and keep
+// them across restarts
+type KeyStore interface {
+	LoadKeys(ctx context.Context) ([]*SigningKey, error)
+	// SaveKeys replace the stored keys
+	SaveKeys(ctx context.Context, keys []*SigningKey) error
+	// LockRotation return true for the single instance allowed to rotate
+	// the keys for ttl
+	LockRotation(ctx context.Context, ttl time.Duration) (bool, error)
+}
+
+const (
+	signingKeysKey   = "signing_keys"
+	rotationLockKey  = "signing_keys:rotation"
+	pemPrivateKeyTag = "PRIVATE KEY"
+)
+
+type storedKey struct {
+	Kid        string    `json:"kid"`
+	Algorithm  string    `json:"algorithm"`
+	PrivateKey string    `json:"private_key"`
+	ActiveFrom time.Time `json:"active_from"`
+	RetireAt   time.Time `json:"retire_at"`
+}
+
+// redisKeyStore keep the keys in a hash of redis, by kid
+type redisKeyStore struct {
+	pool *redis.Pool
+}
+
+// NewKeyStore return the key store kept in the redis of the pool
+func NewKeyStore(pool *redis.Pool) KeyStore {
+	return &redisKeyStore{pool: pool}
+}
+
+func (s *redisKeyStore) LoadKeys(ctx context.Context) ([]*SigningKey, error) {
+	conn, err := s.pool.GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	values, err := redis.StringMap(conn.Do("HGETALL", signingKeysKey))
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]*SigningKey, 0, len(values))
+	for _, value := range values {
+		key, err := decodeKey([]byte(value))
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func (s *redisKeyStore) SaveKeys(ctx context.Context, keys []*SigningKey) error {
+	args := redis.Args{signingKeysKey}
+	for _, key := range keys {
+		value, err := encodeKey(key)
+		if err != nil {
+			return err
+		}
+		args = args.Add(key.Kid, value)
+	}
+
+	conn, err := s.pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.Send("MULTI")
+	conn.Send("DEL", signingKeysKey)
+	if len(keys) > 0 {
+		conn.Send("HSET", args...)
+	}
+	_, err = conn.Do("EXEC")
+	return err
+}
+
+func (s *redisKeyStore) LockRotation(ctx context.Context, ttl time.Duration) (bool, error) {
+	conn, err := s.pool.GetContext(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	_, err = redis.String(conn.Do("SET", rotationLockKey, 1, "NX", "PX", ttl.Milliseconds()))
+	if err == redis.ErrNil {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// memoryKeyStore keep the keys in the process, for a single instance without
+// redis: the generated keys are lost on restart
+type memoryKeyStore struct {
+	mu          sync.Mutex
+	keys        []*SigningKey
+	lockedUntil time.Time
+}
+
+// NewMemoryKeyStore return the key store kept in the memory of the process
+func NewMemoryKeyStore() KeyStore {
+	return &memoryKeyStore{}
+}
+
+func (s *memoryKeyStore) LoadKeys(ctx context.Context) ([]*SigningKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]*SigningKey(nil), s.keys...), nil
+}
+
+func (s *memoryKeyStore) SaveKeys(ctx context.Context, keys []*SigningKey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = append([]*SigningKey(nil), keys...)
+	return nil
+}
+
+func (s *memoryKeyStore) LockRotation(ctx context.Context, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if now.Before(s.lockedUntil) {
+		return false, nil
+	}
+	s.lockedUntil = now.Add(ttl)
+	return true, nil
+}
+
+// encodeKey serialize the key with its private part in PKCS #8
+func encodeKey(key *SigningKey) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(key.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(storedKey{
+		Kid:        key.Kid,
+		Algorithm:  key.Method.Alg(),
+		PrivateKey: string(pem.EncodeToMemory(&pem.Block{Type: pemPrivateKeyTag, Bytes: der})),
+		ActiveFrom: key.ActiveFrom,
+		RetireAt:   key.RetireAt,
+	})
+}
+
+func decodeKey(data []byte) (*SigningKey, error) {
+	var stored storedKey
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, err
+	}
+	method := jwt.GetSigningMethod(stored.Algorithm)
+	if method == nil {
+		return nil, ErrUnsupportedKeyAlgo
+	}
+	block, _ := pem.Decode([]byte(stored.PrivateKey))
+	if block == nil {
+		return nil, ErrUnsupportedKeyAlgo
+	}
+	private, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	key := &SigningKey{
+		Kid:        stored.Kid,
+		Method:     method,
+		PrivateKey: private,
+		ActiveFrom: stored.ActiveFrom,
+		RetireAt:   stored.RetireAt,
+	}
+	switch private := private.(type) {
+	case *rsa.PrivateKey:
+		key.PublicKey = &private.PublicKey
+	case *ecdsa.PrivateKey:
+		key.PublicKey = &private.PublicKey
+	case ed25519.PrivateKey:
+		key.PublicKey = private.Public()
+	default:
+		return nil, ErrUnsupportedKeyAlgo
+	}
+	return key, nil
+}
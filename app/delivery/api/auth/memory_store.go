@@ -0,0 +1,334 @@
+package auth
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RedLucky/potongin/domain"
+	"github.com/spf13/viper"
+)
+
+// the expired entries of the memory store are swept at most once per interval
+const memorySweepInterval = time.Minute
+
+// memoryTokenStore is the domain.TokenStore kept in the memory of the process,
+// for a single instance without redis. Its entries use the keys and the
+// lifetimes of the redis store and are lost on restart.
+type memoryTokenStore struct {
+	mu        sync.Mutex
+	entries   map[string]*memoryItem
+	nextSweep time.Time
+}
+
+type memoryItem struct {
+	value     interface{}
+	expiresAt time.Time
+}
+
+// tokenOwner is the entry of an access or a refresh token
+type tokenOwner struct {
+	userId    int64
+	sessionId string
+}
+
+// NewMemoryTokenStore return the token store kept in the memory of the process
+func NewMemoryTokenStore() domain.TokenStore {
+	return &memoryTokenStore{entries: make(map[string]*memoryItem)}
+}
+
+// get return the live entry of the key, the caller holds the lock
+func (s *memoryTokenStore) get(key string) (*memoryItem, bool) {
+	item, ok := s.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if !time.Now().Before(item.expiresAt) {
+		delete(s.entries, key)
+		return nil, false
+	}
+	return item, true
+}
+
+// set the entry of the key for ttl, the caller holds the lock
+func (s *memoryTokenStore) set(key string, value interface{}, ttl time.Duration) {
+	now := time.Now()
+	if now.After(s.nextSweep) {
+		for key, item := range s.entries {
+			if !now.Before(item.expiresAt) {
+				delete(s.entries, key)
+			}
+		}
+		s.nextSweep = now.Add(memorySweepInterval)
+	}
+	s.entries[key] = &memoryItem{value: value, expiresAt: now.Add(ttl)}
+}
+
+// incr count one more on the key and restart its ttl, the caller holds the lock
+func (s *memoryTokenStore) incr(key string, ttl time.Duration) int {
+	count := 1
+	if item, ok := s.get(key); ok {
+		count = item.value.(int) + 1
+	}
+	s.set(key, count, ttl)
+	return count
+}
+
+func (s *memoryTokenStore) del(keys ...string) {
+	for _, key := range keys {
+		delete(s.entries, key)
+	}
+}
+
+func accessDuration() time.Duration {
+	return time.Duration(viper.GetInt32(`authentication.duration_access`)) * time.Minute
+}
+
+func refreshDuration() time.Duration {
+	return time.Duration(viper.GetInt32(`authentication.duration_refresh`)) * time.Hour
+}
+
+func (s *memoryTokenStore) SaveTokens(ctx context.Context, user domain.User, jwt domain.JwtResults) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.set(jwt.AccessUUID, tokenOwner{userId: user.ID, sessionId: jwt.SessionID}, accessDuration())
+	s.set(jwt.RefreshUUID, tokenOwner{userId: user.ID, sessionId: jwt.SessionID}, refreshDuration())
+	return nil
+}
+
+func (s *memoryTokenStore) GetTokenSession(ctx context.Context, uuid string) (userId int64, sessionId string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	item, ok := s.get(uuid)
+	if !ok {
+		return 0, "", domain.ErrCacheMiss
+	}
+	owner := item.value.(tokenOwner)
+	return owner.userId, owner.sessionId, nil
+}
+
+func (s *memoryTokenStore) DeleteToken(ctx context.Context, uuid string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.del(uuid)
+	return nil
+}
+
+// ConsumeRefreshToken move the token to its "used" marker with the remaining
+// lifetime, as the redis store does
+func (s *memoryTokenStore) ConsumeRefreshToken(ctx context.Context, uuid string) (userId int64, sessionId string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if item, ok := s.get(uuid); ok {
+		s.del(uuid)
+		s.entries[usedRefreshKey(uuid)] = item
+		owner := item.value.(tokenOwner)
+		return owner.userId, owner.sessionId, nil
+	}
+	if item, ok := s.get(usedRefreshKey(uuid)); ok {
+		owner := item.value.(tokenOwner)
+		return owner.userId, owner.sessionId, domain.ErrRefreshTokenReused
+	}
+	return 0, "", domain.ErrCacheMiss
+}
+
+func (s *memoryTokenStore) SaveSession(ctx context.Context, session domain.Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.set(sessionKey(session.ID), session, refreshDuration())
+	ids := map[string]bool{}
+	if item, ok := s.get(userSessionsKey(session.UserId)); ok {
+		ids = item.value.(map[string]bool)
+	}
+	ids[session.ID] = true
+	s.set(userSessionsKey(session.UserId), ids, refreshDuration())
+	return nil
+}
+
+func (s *memoryTokenStore) GetSession(ctx context.Context, sessionId string) (domain.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	item, ok := s.get(sessionKey(sessionId))
+	if !ok {
+		return domain.Session{}, domain.ErrCacheMiss
+	}
+	return item.value.(domain.Session), nil
+}
+
+// get every live session of the user, dropping index entries whose session already expired
+func (s *memoryTokenStore) GetSessionsByUser(ctx context.Context, userId int64) ([]domain.Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sessions := make([]domain.Session, 0)
+	item, ok := s.get(userSessionsKey(userId))
+	if !ok {
+		return sessions, nil
+	}
+	ids := item.value.(map[string]bool)
+	for id := range ids {
+		session, ok := s.get(sessionKey(id))
+		if !ok {
+			delete(ids, id)
+			continue
+		}
+		sessions = append(sessions, session.value.(domain.Session))
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].CreatedAt.Before(sessions[j].CreatedAt) })
+	return sessions, nil
+}
+
+func (s *memoryTokenStore) TouchSession(ctx context.Context, sessionId string, lastSeen time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if item, ok := s.get(sessionKey(sessionId)); ok {
+		session := item.value.(domain.Session)
+		session.LastSeenAt = lastSeen
+		item.value = session
+	}
+	return nil
+}
+
+func (s *memoryTokenStore) DeleteSession(ctx context.Context, session domain.Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if session.AccessUUID != "" {
+		s.del(session.AccessUUID)
+	}
+	if session.RefreshUUID != "" {
+		s.del(session.RefreshUUID)
+	}
+	s.del(sessionKey(session.ID))
+	if item, ok := s.get(userSessionsKey(session.UserId)); ok {
+		delete(item.value.(map[string]bool), session.ID)
+	}
+	return nil
+}
+
+func (s *memoryTokenStore) SaveMfaChallenge(ctx context.Context, mfaUUID string, userId int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.set(mfaChallengeKey(mfaUUID), userId, mfaTokenDuration)
+	return nil
+}
+
+func (s *memoryTokenStore) ExistMfaChallenge(ctx context.Context, mfaUUID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.get(mfaChallengeKey(mfaUUID))
+	return ok, nil
+}
+
+func (s *memoryTokenStore) CountMfaAttempt(ctx context.Context, mfaUUID string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.incr(mfaChallengeKey(mfaUUID)+":attempts", mfaTokenDuration), nil
+}
+
+func (s *memoryTokenStore) ConsumeMfaChallenge(ctx context.Context, mfaUUID string) (userId int64, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	item, ok := s.get(mfaChallengeKey(mfaUUID))
+	s.del(mfaChallengeKey(mfaUUID), mfaChallengeKey(mfaUUID)+":attempts")
+	if !ok {
+		return 0, domain.ErrCacheMiss
+	}
+	return item.value.(int64), nil
+}
+
+func (s *memoryTokenStore) RecordLoginFailure(ctx context.Context, email, ip string, window time.Duration) (accountFailures, ipFailures int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.incr(accountFailKey(email), window), s.incr(ipFailKey(ip), window), nil
+}
+
+func (s *memoryTokenStore) SetLoginBackoff(ctx context.Context, email, ip string, accountWait, ipWait time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if accountWait > 0 {
+		s.set(waitKey(accountFailKey(email)), true, accountWait)
+	}
+	if ipWait > 0 {
+		s.set(waitKey(ipFailKey(ip)), true, ipWait)
+	}
+	return nil
+}
+
+func (s *memoryTokenStore) LoginBackoff(ctx context.Context, email, ip string) (time.Duration, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var wait time.Duration
+	for _, key := range []string{waitKey(accountFailKey(email)), waitKey(ipFailKey(ip))} {
+		if item, ok := s.get(key); ok {
+			if left := time.Until(item.expiresAt); left > wait {
+				wait = left
+			}
+		}
+	}
+	return wait, nil
+}
+
+func (s *memoryTokenStore) ResetLoginFailures(ctx context.Context, email string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.del(accountFailKey(email), waitKey(accountFailKey(email)))
+	return nil
+}
+
+func (s *memoryTokenStore) LockAccount(ctx context.Context, email string, duration time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.set(lockKey(email), true, duration)
+	return nil
+}
+
+func (s *memoryTokenStore) IsAccountLocked(ctx context.Context, email string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.get(lockKey(email))
+	return ok, nil
+}
+
+func (s *memoryTokenStore) UnlockAccount(ctx context.Context, email string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.del(lockKey(email), accountFailKey(email), waitKey(accountFailKey(email)))
+	return nil
+}
+
+func (s *memoryTokenStore) SaveUnlockToken(ctx context.Context, token, email string, duration time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.set(unlockTokenKey(token), strings.ToLower(email), duration)
+	return nil
+}
+
+func (s *memoryTokenStore) ConsumeUnlockToken(ctx context.Context, token string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	item, ok := s.get(unlockTokenKey(token))
+	if !ok {
+		return "", domain.ErrCacheMiss
+	}
+	s.del(unlockTokenKey(token))
+	return item.value.(string), nil
+}
+
+func (s *memoryTokenStore) SaveOidcState(ctx context.Context, state string, value domain.OidcState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.set(oidcStateKey(state), value, oidcStateDuration)
+	return nil
+}
+
+func (s *memoryTokenStore) ConsumeOidcState(ctx context.Context, state string) (domain.OidcState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	item, ok := s.get(oidcStateKey(state))
+	if !ok {
+		return domain.OidcState{}, domain.ErrCacheMiss
+	}
+	s.del(oidcStateKey(state))
+	return item.value.(domain.OidcState), nil
+}
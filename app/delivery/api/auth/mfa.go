@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/RedLucky/potongin/domain"
+	jwt "github.com/golang-jwt/jwt"
+	"github.com/gomodule/redigo/redis"
+	"github.com/google/uuid"
+	"github.com/spf13/viper"
+)
+
+const (
+	// mfa pending token lifetime, the user must type the code before it expires
+	mfaTokenDuration = 5 * time.Minute
+	// wrong codes allowed for one mfa pending token
+	MaxMfaAttempts = 5
+)
+
+// MfaClaims is the payload of the short-lived token returned by the first
+// login step when the user has two-factor authentication enabled
+type MfaClaims struct {
+	UserId     int64  `json:"user_id"`
+	MfaPending bool   `json:"mfa_pending"`
+	MfaUUID    string `json:"mfa_uuid"`
+	jwt.StandardClaims
+}
+
+func CreateMfaToken(user *domain.User) (token string, mfaUUID string, err error) {
+	mfaUUID = uuid.New().String()
+	claims := MfaClaims{
+		StandardClaims: jwt.StandardClaims{
+			Issuer:    viper.GetString(`server.application_name`),
+			ExpiresAt: time.Now().Add(mfaTokenDuration).Unix(),
+		},
+		UserId:     user.ID,
+		MfaPending: true,
+		MfaUUID:    mfaUUID,
+	}
+
+	token, err = jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(mfaKey())
+	return
+}
+
+func MfaTokenValid(tokenString string) (*MfaClaims, error) {
+	claims := &MfaClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return mfaKey(), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !claims.MfaPending || claims.MfaUUID == "" {
+		return nil, domain.ErrorAuthorization
+	}
+	return claims, nil
+}
+
+func mfaKey() []byte {
+	if key := viper.GetString(`authentication.jwt_signature_mfa_key`); key != "" {
+		return []byte(key)
+	}
+	return []byte(viper.GetString(`authentication.jwt_signature_refresh_key`))
+}
+
+// save the pending mfa challenge, it can only be exchanged once
+func SaveMfaChallenge(redisConn redis.Conn, mfaUUID string, userId int64) error {
+	_, err := redisConn.Do("SET", mfaChallengeKey(mfaUUID), userId, "EX", int(mfaTokenDuration.Seconds()))
+	return err
+}
+
+// count a wrong code on the challenge and return how many were made so far
+func CountMfaAttempt(redisConn redis.Conn, mfaUUID string) (attempts int, err error) {
+	redisConn.Send("MULTI")
+	redisConn.Send("INCR", mfaChallengeKey(mfaUUID)+":attempts")
+	redisConn.Send("EXPIRE", mfaChallengeKey(mfaUUID)+":attempts", int(mfaTokenDuration.Seconds()))
+	values, err := redis.Values(redisConn.Do("EXEC"))
+	if err != nil {
+		return 0, err
+	}
+	return redis.Int(values[0], nil)
+}
+
+// consume the challenge, it return redis.ErrNil when it was already used or expired
+func ConsumeMfaChallenge(redisConn redis.Conn, mfaUUID string) (userId int64, err error) {
+	redisConn.Send("MULTI")
+	redisConn.Send("GET", mfaChallengeKey(mfaUUID))
+	redisConn.Send("DEL", mfaChallengeKey(mfaUUID), mfaChallengeKey(mfaUUID)+":attempts")
+	values, err := redis.Values(redisConn.Do("EXEC"))
+	if err != nil {
+		return 0, err
+	}
+	return redis.Int64(values[0], nil)
+}
+
+func ExistMfaChallenge(redisConn redis.Conn, mfaUUID string) (bool, error) {
+	return redis.Bool(redisConn.Do("EXISTS", mfaChallengeKey(mfaUUID)))
+}
+
+func mfaChallengeKey(mfaUUID string) string {
+	return "mfa_pending:" + mfaUUID
+}
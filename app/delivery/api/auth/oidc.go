@@ -0,0 +1,406 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/RedLucky/potongin/domain"
+	jwt "github.com/golang-jwt/jwt"
+	"github.com/gomodule/redigo/redis"
+	"github.com/spf13/viper"
+)
+
+const (
+	// an authorization request must come back within this duration
+	oidcStateDuration = 10 * time.Minute
+	// minimum delay between two fetches of the provider keys
+	oidcJwksRefresh = time.Minute
+)
+
+var (
+	ErrOidcProviderNotFound = errors.New("identity provider not found")
+	ErrOidcInvalidIDToken   = errors.New("invalid id token")
+)
+
+// OidcProviderConfig is one entry of the `oidc.providers` config
+type OidcProviderConfig struct {
+	Issuer       string   `mapstructure:"issuer"`
+	ClientID     string   `mapstructure:"client_id"`
+	ClientSecret string   `mapstructure:"client_secret"`
+	RedirectURL  string   `mapstructure:"redirect_url"`
+	Scopes       []string `mapstructure:"scopes"`
+}
+
+// OidcProvider is an OpenID Connect identity provider we accept logins from,
+// using the authorization code flow with PKCE
+type OidcProvider struct {
+	Name   string
+	Config OidcProviderConfig
+	Client *http.Client
+
+	mu            sync.Mutex
+	discovery     *oidcDiscovery
+	keys          map[string]crypto.PublicKey
+	keysFetchedAt time.Time
+}
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksURI               string `json:"jwks_uri"`
+}
+
+// OidcIdentity is what we trust from a validated id token
+type OidcIdentity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+func NewOidcProvider(name string, config OidcProviderConfig) *OidcProvider {
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid", "email", "profile"}
+	}
+	return &OidcProvider{
+		Name:   name,
+		Config: config,
+		Client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// LoadOidcProviders build the providers listed in the `oidc.providers` config
+func LoadOidcProviders() (map[string]*OidcProvider, error) {
+	var configs map[string]OidcProviderConfig
+	if err := viper.UnmarshalKey(`oidc.providers`, &configs); err != nil {
+		return nil, err
+	}
+
+	providers := make(map[string]*OidcProvider, len(configs))
+	for name, config := range configs {
+		providers[name] = NewOidcProvider(name, config)
+	}
+	return providers, nil
+}
+
+// AuthCodeURL return the url of the provider login page for the given state,
+// nonce and PKCE verifier
+func (p *OidcProvider) AuthCodeURL(ctx context.Context, state, nonce, codeVerifier string) (string, error) {
+	discovery, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	values := url.Values{}
+	values.Set("response_type", "code")
+	values.Set("client_id", p.Config.ClientID)
+	values.Set("redirect_uri", p.Config.RedirectURL)
+	values.Set("scope", strings.Join(p.Config.Scopes, " "))
+	values.Set("state", state)
+	values.Set("nonce", nonce)
+	values.Set("code_challenge", CodeChallenge(codeVerifier))
+	values.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(discovery.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return discovery.AuthorizationEndpoint + separator + values.Encode(), nil
+}
+
+// Exchange trade the authorization code for the id token
+func (p *OidcProvider) Exchange(ctx context.Context, code, codeVerifier string) (idToken string, err error) {
+	discovery, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	values := url.Values{}
+	values.Set("grant_type", "authorization_code")
+	values.Set("code", code)
+	values.Set("redirect_uri", p.Config.RedirectURL)
+	values.Set("client_id", p.Config.ClientID)
+	values.Set("code_verifier", codeVerifier)
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(values.Encode()))
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+	if p.Config.ClientSecret != "" {
+		request.SetBasicAuth(url.QueryEscape(p.Config.ClientID), url.QueryEscape(p.Config.ClientSecret))
+	}
+
+	resp, err := p.Client.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK || body.Error != "" {
+		return "", fmt.Errorf("oidc token endpoint: %s %s", body.Error, body.ErrorDescription)
+	}
+	if body.IDToken == "" {
+		return "", ErrOidcInvalidIDToken
+	}
+	return body.IDToken, nil
+}
+
+// VerifyIDToken check the signature, issuer, audience, expiration and nonce of the id token
+func (p *OidcProvider) VerifyIDToken(ctx context.Context, idToken, nonce string) (identity OidcIdentity, err error) {
+	discovery, err := p.discover(ctx)
+	if err != nil {
+		return OidcIdentity{}, err
+	}
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.ParseWithClaims(idToken, claims, func(token *jwt.Token) (interface{}, error) {
+		switch token.Method.(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS, *jwt.SigningMethodECDSA, *jwt.SigningMethodEd25519:
+		default:
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		kid, _ := token.Header["kid"].(string)
+		return p.publicKey(ctx, discovery.JwksURI, kid)
+	})
+	if err != nil {
+		return OidcIdentity{}, err
+	}
+
+	if issuer, _ := claims["iss"].(string); issuer != discovery.Issuer {
+		return OidcIdentity{}, ErrOidcInvalidIDToken
+	}
+	if !claims.VerifyAudience(p.Config.ClientID, true) && !containsAudience(claims["aud"], p.Config.ClientID) {
+		return OidcIdentity{}, ErrOidcInvalidIDToken
+	}
+	if _, ok := claims["exp"]; !ok {
+		return OidcIdentity{}, ErrOidcInvalidIDToken
+	}
+	if tokenNonce, _ := claims["nonce"].(string); tokenNonce == "" || tokenNonce != nonce {
+		return OidcIdentity{}, ErrOidcInvalidIDToken
+	}
+
+	identity.Subject, _ = claims["sub"].(string)
+	identity.Email, _ = claims["email"].(string)
+	identity.Name, _ = claims["name"].(string)
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		identity.EmailVerified = verified
+	case string:
+		identity.EmailVerified = verified == "true"
+	}
+	if identity.Subject == "" {
+		return OidcIdentity{}, ErrOidcInvalidIDToken
+	}
+	return identity, nil
+}
+
+// aud may be a list of client ids
+func containsAudience(aud interface{}, clientID string) bool {
+	list, ok := aud.([]interface{})
+	if !ok {
+		return false
+	}
+	for _, value := range list {
+		if value == clientID {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *OidcProvider) discover(ctx context.Context) (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	var discovery oidcDiscovery
+	wellKnown := strings.TrimSuffix(p.Config.Issuer, "/") + "/.well-known/openid-configuration"
+	if err := p.getJSON(ctx, wellKnown, &discovery); err != nil {
+		return nil, err
+	}
+	if discovery.Issuer != p.Config.Issuer {
+		return nil, fmt.Errorf("oidc issuer mismatch: %s", discovery.Issuer)
+	}
+	p.discovery = &discovery
+	return p.discovery, nil
+}
+
+// publicKey return the provider key of the kid, refreshing the provider keys
+// when the kid is unknown since providers rotate them
+func (p *OidcProvider) publicKey(ctx context.Context, jwksURI, kid string) (crypto.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+	if time.Since(p.keysFetchedAt) < oidcJwksRefresh && p.keys != nil {
+		return nil, ErrUnknownKey
+	}
+
+	var set struct {
+		Keys []JSONWebKey `json:"keys"`
+	}
+	if err := p.getJSON(ctx, jwksURI, &set); err != nil {
+		return nil, err
+	}
+	p.keys = make(map[string]crypto.PublicKey, len(set.Keys))
+	p.keysFetchedAt = time.Now()
+	for _, jwk := range set.Keys {
+		key, err := jwk.PublicKey()
+		if err != nil {
+			continue
+		}
+		p.keys[jwk.Kid] = key
+	}
+
+	if key, ok := p.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, ErrUnknownKey
+}
+
+func (p *OidcProvider) getJSON(ctx context.Context, url string, v interface{}) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.Client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("oidc %s: unexpected status %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// PublicKey decode the public key of a JSON web key
+func (jwk JSONWebKey) PublicKey() (crypto.PublicKey, error) {
+	decode := base64.RawURLEncoding.DecodeString
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decode(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, ErrUnsupportedKeyAlgo
+		}
+		x, err := decode(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		if jwk.Crv != "Ed25519" {
+			return nil, ErrUnsupportedKeyAlgo
+		}
+		x, err := decode(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, ErrUnsupportedKeyAlgo
+	}
+}
+
+// RandomString return a random url safe string, for states, nonces and PKCE verifiers
+func RandomString() (string, error) {
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(random), nil
+}
+
+// CodeChallenge derive the S256 PKCE challenge of the verifier
+func CodeChallenge(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+func oidcStateKey(state string) string {
+	return "oidc_state:" + state
+}
+
+func SaveOidcState(redisConn redis.Conn, state string, value domain.OidcState) error {
+	payload, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	_, err = redisConn.Do("SET", oidcStateKey(state), payload, "EX", int(oidcStateDuration.Seconds()))
+	return err
+}
+
+// ConsumeOidcState return the saved state, it can only be used once
+func ConsumeOidcState(redisConn redis.Conn, state string) (value domain.OidcState, err error) {
+	redisConn.Send("MULTI")
+	redisConn.Send("GET", oidcStateKey(state))
+	redisConn.Send("DEL", oidcStateKey(state))
+	values, err := redis.Values(redisConn.Do("EXEC"))
+	if err != nil {
+		return domain.OidcState{}, err
+	}
+	payload, err := redis.Bytes(values[0], nil)
+	if err != nil {
+		return domain.OidcState{}, err
+	}
+	err = json.Unmarshal(payload, &value)
+	return
+}
+
+// ensure the identity is usable to link or create a local account
+func (identity OidcIdentity) Validate() error {
+	if identity.Email == "" || !identity.EmailVerified {
+		return domain.ErrorEmailNotVerified
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package auth_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/RedLucky/potongin/app/delivery/api/auth"
+	jwt "github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubProvider is a minimal OpenID Connect provider issuing id tokens for a
+// single authorization code
+type stubProvider struct {
+	server        *httptest.Server
+	key           *auth.SigningKey
+	clientID      string
+	code          string
+	codeChallenge string
+	claims        jwt.MapClaims
+}
+
+func newStubProvider(t *testing.T) *stubProvider {
+	key, err := auth.GenerateKey("RS256")
+	require.NoError(t, err)
+	stub := &stubProvider{key: key, clientID: "potongin", code: "the-code"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 stub.server.URL,
+			"authorization_endpoint": stub.server.URL + "/authorize",
+			"token_endpoint":         stub.server.URL + "/token",
+			"jwks_uri":               stub.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		ks := auth.NewKeySet(time.Hour)
+		ks.Add(stub.key)
+		json.NewEncoder(w).Encode(ks.JWKS(time.Now()))
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("code") != stub.code || auth.CodeChallenge(r.Form.Get("code_verifier")) != stub.codeChallenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		token := jwt.NewWithClaims(stub.key.Method, stub.claims)
+		token.Header["kid"] = stub.key.Kid
+		idToken, _ := token.SignedString(stub.key.PrivateKey)
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "opaque", "id_token": idToken})
+	})
+	stub.server = httptest.NewServer(mux)
+	return stub
+}
+
+func (stub *stubProvider) idClaims(nonce string) jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":            stub.server.URL,
+		"aud":            stub.clientID,
+		"sub":            "user-42",
+		"email":          "lucky@kryptopos.com",
+		"email_verified": true,
+		"name":           "Lucky Fernanda",
+		"nonce":          nonce,
+		"exp":            time.Now().Add(time.Minute).Unix(),
+		"iat":            time.Now().Unix(),
+	}
+}
+
+func TestOidcProvider_Login(t *testing.T) {
+	stub := newStubProvider(t)
+	defer stub.server.Close()
+	ctx := context.TODO()
+
+	provider := auth.NewOidcProvider("stub", auth.OidcProviderConfig{
+		Issuer:      stub.server.URL,
+		ClientID:    stub.clientID,
+		RedirectURL: "http://localhost/oauth/stub/callback",
+	})
+	verifier, _ := auth.RandomString()
+
+	t.Run("auth-code-url", func(t *testing.T) {
+		loginURL, err := provider.AuthCodeURL(ctx, "state", "nonce", verifier)
+		require.NoError(t, err)
+
+		parsed, err := url.Parse(loginURL)
+		require.NoError(t, err)
+		assert.Equal(t, "/authorize", parsed.Path)
+		assert.Equal(t, "state", parsed.Query().Get("state"))
+		assert.Equal(t, "S256", parsed.Query().Get("code_challenge_method"))
+		stub.codeChallenge = parsed.Query().Get("code_challenge")
+	})
+
+	t.Run("success", func(t *testing.T) {
+		stub.claims = stub.idClaims("nonce")
+		idToken, err := provider.Exchange(ctx, stub.code, verifier)
+		require.NoError(t, err)
+
+		identity, err := provider.VerifyIDToken(ctx, idToken, "nonce")
+		require.NoError(t, err)
+		assert.Equal(t, "user-42", identity.Subject)
+		assert.Equal(t, "lucky@kryptopos.com", identity.Email)
+		assert.True(t, identity.EmailVerified)
+	})
+
+	t.Run("wrong-code-verifier", func(t *testing.T) {
+		_, err := provider.Exchange(ctx, stub.code, "another-verifier")
+		assert.Error(t, err)
+	})
+
+	t.Run("wrong-nonce", func(t *testing.T) {
+		stub.claims = stub.idClaims("nonce")
+		idToken, err := provider.Exchange(ctx, stub.code, verifier)
+		require.NoError(t, err)
+
+		_, err = provider.VerifyIDToken(ctx, idToken, "replayed-nonce")
+		assert.Equal(t, auth.ErrOidcInvalidIDToken, err)
+	})
+
+	t.Run("wrong-audience", func(t *testing.T) {
+		stub.claims = stub.idClaims("nonce")
+		stub.claims["aud"] = "another-client"
+		idToken, err := provider.Exchange(ctx, stub.code, verifier)
+		require.NoError(t, err)
+
+		_, err = provider.VerifyIDToken(ctx, idToken, "nonce")
+		assert.Equal(t, auth.ErrOidcInvalidIDToken, err)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		stub.claims = stub.idClaims("nonce")
+		stub.claims["exp"] = time.Now().Add(-time.Minute).Unix()
+		idToken, err := provider.Exchange(ctx, stub.code, verifier)
+		require.NoError(t, err)
+
+		_, err = provider.VerifyIDToken(ctx, idToken, "nonce")
+		assert.Error(t, err)
+	})
+}
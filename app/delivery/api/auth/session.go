@@ -0,0 +1,157 @@
+package auth
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/RedLucky/potongin/domain"
+	"github.com/gomodule/redigo/redis"
+	"github.com/spf13/viper"
+)
+
+// touch last_seen_at only when the session still exists, otherwise HSET would
+// recreate an orphan hash without expiration
+var touchSessionScript = redis.NewScript(1, `
+if redis.call("EXISTS", KEYS[1]) == 1 then
+	return redis.call("HSET", KEYS[1], "last_seen_at", ARGV[1])
+end
+return 0`)
+
+// consume a refresh token exactly once: the token key is renamed into a
+// "used" marker that keeps the remaining TTL, so a replay can be told apart
+// from a token that never existed or already expired
+var consumeRefreshScript = redis.NewScript(2, `
+local owner = redis.call("HMGET", KEYS[1], "id", "session_id")
+if owner[1] then
+	redis.call("RENAME", KEYS[1], KEYS[2])
+	return {"ok", owner[1], owner[2] or ""}
+end
+local used = redis.call("HMGET", KEYS[2], "id", "session_id")
+if used[1] then
+	return {"reused", used[1], used[2] or ""}
+end
+return {"missing", "", ""}`)
+
+func sessionKey(sessionId string) string {
+	return "session:" + sessionId
+}
+
+func userSessionsKey(userId int64) string {
+	return "user_sessions:" + strconv.FormatInt(userId, 10)
+}
+
+// save session to redis and index it by its owner
+func SaveSession(redisConn redis.Conn, session domain.Session) error {
+	ttl := viper.GetInt32(`authentication.duration_refresh`) * 60 * 60 //hours
+	redisConn.Send("MULTI")
+	redisConn.Send("HSET", sessionKey(session.ID),
+		"id", session.ID,
+		"user_id", session.UserId,
+		"access_uuid", session.AccessUUID,
+		"refresh_uuid", session.RefreshUUID,
+		"device", session.Device,
+		"ip", session.IP,
+		"user_agent", session.UserAgent,
+		"generation", session.Generation,
+		"created_at", session.CreatedAt.Unix(),
+		"last_seen_at", session.LastSeenAt.Unix(),
+	)
+	redisConn.Send("EXPIRE", sessionKey(session.ID), ttl)
+	redisConn.Send("SADD", userSessionsKey(session.UserId), session.ID)
+	redisConn.Send("EXPIRE", userSessionsKey(session.UserId), ttl)
+	_, err := redisConn.Do("EXEC")
+	return err
+}
+
+func GetSession(redisConn redis.Conn, sessionId string) (session domain.Session, err error) {
+	values, err := redis.StringMap(redisConn.Do("HGETALL", sessionKey(sessionId)))
+	if err != nil {
+		return domain.Session{}, err
+	}
+	if len(values) == 0 {
+		return domain.Session{}, redis.ErrNil
+	}
+
+	session.ID = values["id"]
+	session.UserId, _ = strconv.ParseInt(values["user_id"], 10, 64)
+	session.AccessUUID = values["access_uuid"]
+	session.RefreshUUID = values["refresh_uuid"]
+	session.Device = values["device"]
+	session.IP = values["ip"]
+	session.UserAgent = values["user_agent"]
+	session.Generation, _ = strconv.ParseInt(values["generation"], 10, 64)
+	createdAt, _ := strconv.ParseInt(values["created_at"], 10, 64)
+	session.CreatedAt = time.Unix(createdAt, 0)
+	lastSeenAt, _ := strconv.ParseInt(values["last_seen_at"], 10, 64)
+	session.LastSeenAt = time.Unix(lastSeenAt, 0)
+	return
+}
+
+// get every live session of the user, dropping index entries whose session already expired
+func GetSessionsByUser(redisConn redis.Conn, userId int64) (sessions []domain.Session, err error) {
+	ids, err := redis.Strings(redisConn.Do("SMEMBERS", userSessionsKey(userId)))
+	if err != nil {
+		return nil, err
+	}
+
+	sessions = make([]domain.Session, 0, len(ids))
+	for _, id := range ids {
+		session, err := GetSession(redisConn, id)
+		if err == redis.ErrNil {
+			redisConn.Do("SREM", userSessionsKey(userId), id)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return
+}
+
+func TouchSession(redisConn redis.Conn, sessionId string, lastSeen time.Time) error {
+	_, err := touchSessionScript.Do(redisConn, sessionKey(sessionId), lastSeen.Unix())
+	return err
+}
+
+// delete the session together with the access and refresh token it holds
+func DeleteSession(redisConn redis.Conn, session domain.Session) error {
+	redisConn.Send("MULTI")
+	if session.AccessUUID != "" {
+		redisConn.Send("DEL", session.AccessUUID)
+	}
+	if session.RefreshUUID != "" {
+		redisConn.Send("DEL", session.RefreshUUID)
+	}
+	redisConn.Send("DEL", sessionKey(session.ID))
+	redisConn.Send("SREM", userSessionsKey(session.UserId), session.ID)
+	_, err := redisConn.Do("EXEC")
+	return err
+}
+
+// ConsumeRefreshToken mark the refresh token as used and return its owner.
+// It return domain.ErrRefreshTokenReused together with the owner when the token
+// was already consumed before, and redis.ErrNil when the token is unknown.
+func ConsumeRefreshToken(redisConn redis.Conn, uuid string) (userId int64, sessionId string, err error) {
+	values, err := redis.Strings(consumeRefreshScript.Do(redisConn, uuid, usedRefreshKey(uuid)))
+	if err != nil {
+		return 0, "", err
+	}
+	if values[0] == "missing" {
+		return 0, "", redis.ErrNil
+	}
+
+	userId, err = strconv.ParseInt(values[1], 10, 64)
+	if err != nil {
+		return 0, "", err
+	}
+	sessionId = values[2]
+	if values[0] == "reused" {
+		err = domain.ErrRefreshTokenReused
+	}
+	return
+}
+
+func usedRefreshKey(uuid string) string {
+	return "used_refresh:" + uuid
+}
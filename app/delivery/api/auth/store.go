@@ -0,0 +1,202 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/RedLucky/potongin/domain"
+	"github.com/gomodule/redigo/redis"
+)
+
+// redisTokenStore is the domain.TokenStore kept in redis, each call borrow a
+// connection of the pool
+type redisTokenStore struct {
+	pool *redis.Pool
+}
+
+// NewTokenStore return the token store kept in the redis of the pool
+func NewTokenStore(pool *redis.Pool) domain.TokenStore {
+	return &redisTokenStore{pool: pool}
+}
+
+// do run fn on a connection of the pool, an entry missing in redis is a
+// domain.ErrCacheMiss
+func (s *redisTokenStore) do(ctx context.Context, fn func(conn redis.Conn) error) error {
+	conn, err := s.pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if err = fn(conn); err == redis.ErrNil {
+		return domain.ErrCacheMiss
+	}
+	return err
+}
+
+func (s *redisTokenStore) SaveTokens(ctx context.Context, user domain.User, jwt domain.JwtResults) error {
+	return s.do(ctx, func(conn redis.Conn) error {
+		return SaveToken(conn, user, jwt)
+	})
+}
+
+func (s *redisTokenStore) GetTokenSession(ctx context.Context, uuid string) (userId int64, sessionId string, err error) {
+	err = s.do(ctx, func(conn redis.Conn) (err error) {
+		userId, sessionId, err = GetTokenSession(conn, uuid)
+		return
+	})
+	return
+}
+
+func (s *redisTokenStore) DeleteToken(ctx context.Context, uuid string) error {
+	return s.do(ctx, func(conn redis.Conn) error {
+		return DeleteTokenRedis(conn, uuid)
+	})
+}
+
+func (s *redisTokenStore) ConsumeRefreshToken(ctx context.Context, uuid string) (userId int64, sessionId string, err error) {
+	err = s.do(ctx, func(conn redis.Conn) (err error) {
+		userId, sessionId, err = ConsumeRefreshToken(conn, uuid)
+		return
+	})
+	return
+}
+
+func (s *redisTokenStore) SaveSession(ctx context.Context, session domain.Session) error {
+	return s.do(ctx, func(conn redis.Conn) error {
+		return SaveSession(conn, session)
+	})
+}
+
+func (s *redisTokenStore) GetSession(ctx context.Context, sessionId string) (session domain.Session, err error) {
+	err = s.do(ctx, func(conn redis.Conn) (err error) {
+		session, err = GetSession(conn, sessionId)
+		return
+	})
+	return
+}
+
+func (s *redisTokenStore) GetSessionsByUser(ctx context.Context, userId int64) (sessions []domain.Session, err error) {
+	err = s.do(ctx, func(conn redis.Conn) (err error) {
+		sessions, err = GetSessionsByUser(conn, userId)
+		return
+	})
+	return
+}
+
+func (s *redisTokenStore) TouchSession(ctx context.Context, sessionId string, lastSeen time.Time) error {
+	return s.do(ctx, func(conn redis.Conn) error {
+		return TouchSession(conn, sessionId, lastSeen)
+	})
+}
+
+func (s *redisTokenStore) DeleteSession(ctx context.Context, session domain.Session) error {
+	return s.do(ctx, func(conn redis.Conn) error {
+		return DeleteSession(conn, session)
+	})
+}
+
+func (s *redisTokenStore) SaveMfaChallenge(ctx context.Context, mfaUUID string, userId int64) error {
+	return s.do(ctx, func(conn redis.Conn) error {
+		return SaveMfaChallenge(conn, mfaUUID, userId)
+	})
+}
+
+func (s *redisTokenStore) ExistMfaChallenge(ctx context.Context, mfaUUID string) (exist bool, err error) {
+	err = s.do(ctx, func(conn redis.Conn) (err error) {
+		exist, err = ExistMfaChallenge(conn, mfaUUID)
+		return
+	})
+	return
+}
+
+func (s *redisTokenStore) CountMfaAttempt(ctx context.Context, mfaUUID string) (attempts int, err error) {
+	err = s.do(ctx, func(conn redis.Conn) (err error) {
+		attempts, err = CountMfaAttempt(conn, mfaUUID)
+		return
+	})
+	return
+}
+
+func (s *redisTokenStore) ConsumeMfaChallenge(ctx context.Context, mfaUUID string) (userId int64, err error) {
+	err = s.do(ctx, func(conn redis.Conn) (err error) {
+		userId, err = ConsumeMfaChallenge(conn, mfaUUID)
+		return
+	})
+	return
+}
+
+func (s *redisTokenStore) RecordLoginFailure(ctx context.Context, email, ip string, window time.Duration) (accountFailures, ipFailures int, err error) {
+	err = s.do(ctx, func(conn redis.Conn) (err error) {
+		accountFailures, ipFailures, err = RecordLoginFailure(conn, email, ip, window)
+		return
+	})
+	return
+}
+
+func (s *redisTokenStore) SetLoginBackoff(ctx context.Context, email, ip string, accountWait, ipWait time.Duration) error {
+	return s.do(ctx, func(conn redis.Conn) error {
+		return SetLoginBackoff(conn, email, ip, accountWait, ipWait)
+	})
+}
+
+func (s *redisTokenStore) LoginBackoff(ctx context.Context, email, ip string) (wait time.Duration, err error) {
+	err = s.do(ctx, func(conn redis.Conn) (err error) {
+		wait, err = LoginBackoff(conn, email, ip)
+		return
+	})
+	return
+}
+
+func (s *redisTokenStore) ResetLoginFailures(ctx context.Context, email string) error {
+	return s.do(ctx, func(conn redis.Conn) error {
+		return ResetLoginFailures(conn, email)
+	})
+}
+
+func (s *redisTokenStore) LockAccount(ctx context.Context, email string, duration time.Duration) error {
+	return s.do(ctx, func(conn redis.Conn) error {
+		return LockAccount(conn, email, duration)
+	})
+}
+
+func (s *redisTokenStore) IsAccountLocked(ctx context.Context, email string) (locked bool, err error) {
+	err = s.do(ctx, func(conn redis.Conn) (err error) {
+		locked, err = IsAccountLocked(conn, email)
+		return
+	})
+	return
+}
+
+func (s *redisTokenStore) UnlockAccount(ctx context.Context, email string) error {
+	return s.do(ctx, func(conn redis.Conn) error {
+		return UnlockAccount(conn, email)
+	})
+}
+
+func (s *redisTokenStore) SaveUnlockToken(ctx context.Context, token, email string, duration time.Duration) error {
+	return s.do(ctx, func(conn redis.Conn) error {
+		return SaveUnlockToken(conn, token, email, duration)
+	})
+}
+
+func (s *redisTokenStore) ConsumeUnlockToken(ctx context.Context, token string) (email string, err error) {
+	err = s.do(ctx, func(conn redis.Conn) (err error) {
+		email, err = ConsumeUnlockToken(conn, token)
+		return
+	})
+	return
+}
+
+func (s *redisTokenStore) SaveOidcState(ctx context.Context, state string, value domain.OidcState) error {
+	return s.do(ctx, func(conn redis.Conn) error {
+		return SaveOidcState(conn, state, value)
+	})
+}
+
+func (s *redisTokenStore) ConsumeOidcState(ctx context.Context, state string) (value domain.OidcState, err error) {
+	err = s.do(ctx, func(conn redis.Conn) (err error) {
+		value, err = ConsumeOidcState(conn, state)
+		return
+	})
+	return
+}
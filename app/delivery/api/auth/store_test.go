@@ -0,0 +1,119 @@
+package auth_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/RedLucky/potongin/app/delivery/api/auth"
+	"github.com/RedLucky/potongin/config/cache"
+	"github.com/RedLucky/potongin/domain"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenStore(t *testing.T) {
+	viper.Set(`authentication.duration_access`, 15)
+	viper.Set(`authentication.duration_refresh`, 18)
+
+	t.Run("redis", func(t *testing.T) {
+		redis := cache.Open(cache.Options{Address: miniredis.RunT(t).Addr()})
+		defer redis.Close()
+		testTokenStore(t, auth.NewTokenStore(redis.Pool))
+	})
+	t.Run("memory", func(t *testing.T) {
+		testTokenStore(t, auth.NewMemoryTokenStore())
+	})
+}
+
+// testTokenStore go through the tokens, the sessions and the oidc state
+func testTokenStore(t *testing.T, tokens domain.TokenStore) {
+	ctx := context.TODO()
+
+	jwt := domain.JwtResults{AccessUUID: "access", RefreshUUID: "refresh", SessionID: "session"}
+	require.NoError(t, tokens.SaveTokens(ctx, domain.User{ID: 7}, jwt))
+	session := domain.Session{ID: "session", UserId: 7, AccessUUID: "access", RefreshUUID: "refresh", CreatedAt: time.Now(), LastSeenAt: time.Now()}
+	require.NoError(t, tokens.SaveSession(ctx, session))
+
+	userId, sessionId, err := tokens.GetTokenSession(ctx, "access")
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), userId)
+	assert.Equal(t, "session", sessionId)
+
+	// the refresh token is consumed once, the replay is told apart from an unknown token
+	_, _, err = tokens.ConsumeRefreshToken(ctx, "refresh")
+	require.NoError(t, err)
+	userId, _, err = tokens.ConsumeRefreshToken(ctx, "refresh")
+	assert.Equal(t, domain.ErrRefreshTokenReused, err)
+	assert.Equal(t, int64(7), userId)
+	_, _, err = tokens.ConsumeRefreshToken(ctx, "unknown")
+	assert.Equal(t, domain.ErrCacheMiss, err)
+
+	require.NoError(t, tokens.DeleteSession(ctx, session))
+	_, err = tokens.GetSession(ctx, "session")
+	assert.Equal(t, domain.ErrCacheMiss, err)
+	_, _, err = tokens.GetTokenSession(ctx, "access")
+	assert.Equal(t, domain.ErrCacheMiss, err)
+
+	require.NoError(t, tokens.SaveOidcState(ctx, "state", domain.OidcState{Provider: "google", Nonce: "nonce"}))
+	state, err := tokens.ConsumeOidcState(ctx, "state")
+	require.NoError(t, err)
+	assert.Equal(t, "google", state.Provider)
+	_, err = tokens.ConsumeOidcState(ctx, "state")
+	assert.Equal(t, domain.ErrCacheMiss, err)
+}
+
+func TestMemoryTokenStore(t *testing.T) {
+	tokens := auth.NewMemoryTokenStore()
+	ctx := context.TODO()
+
+	t.Run("entries-expire", func(t *testing.T) {
+		require.NoError(t, tokens.SaveUnlockToken(ctx, "token", "Lucky@Kryptopos.com", 20*time.Millisecond))
+		require.NoError(t, tokens.LockAccount(ctx, "lucky@kryptopos.com", 20*time.Millisecond))
+		locked, err := tokens.IsAccountLocked(ctx, "lucky@kryptopos.com")
+		require.NoError(t, err)
+		assert.True(t, locked)
+
+		time.Sleep(30 * time.Millisecond)
+		locked, err = tokens.IsAccountLocked(ctx, "lucky@kryptopos.com")
+		require.NoError(t, err)
+		assert.False(t, locked)
+		_, err = tokens.ConsumeUnlockToken(ctx, "token")
+		assert.Equal(t, domain.ErrCacheMiss, err)
+	})
+
+	t.Run("login-failures", func(t *testing.T) {
+		for i := 1; i <= 3; i++ {
+			account, ip, err := tokens.RecordLoginFailure(ctx, "lucky@kryptopos.com", "192.0.2.1", time.Minute)
+			require.NoError(t, err)
+			assert.Equal(t, i, account)
+			assert.Equal(t, i, ip)
+		}
+		require.NoError(t, tokens.SetLoginBackoff(ctx, "lucky@kryptopos.com", "192.0.2.1", time.Second, time.Minute))
+		wait, err := tokens.LoginBackoff(ctx, "lucky@kryptopos.com", "192.0.2.1")
+		require.NoError(t, err)
+		assert.True(t, wait > time.Second && wait <= time.Minute)
+
+		// the ip counter outlives a successful login
+		require.NoError(t, tokens.ResetLoginFailures(ctx, "lucky@kryptopos.com"))
+		account, ip, err := tokens.RecordLoginFailure(ctx, "lucky@kryptopos.com", "192.0.2.1", time.Minute)
+		require.NoError(t, err)
+		assert.Equal(t, 1, account)
+		assert.Equal(t, 4, ip)
+	})
+
+	t.Run("mfa-challenge-consumed-once", func(t *testing.T) {
+		require.NoError(t, tokens.SaveMfaChallenge(ctx, "challenge", 7))
+		attempts, err := tokens.CountMfaAttempt(ctx, "challenge")
+		require.NoError(t, err)
+		assert.Equal(t, 1, attempts)
+
+		userId, err := tokens.ConsumeMfaChallenge(ctx, "challenge")
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), userId)
+		_, err = tokens.ConsumeMfaChallenge(ctx, "challenge")
+		assert.Equal(t, domain.ErrCacheMiss, err)
+	})
+}
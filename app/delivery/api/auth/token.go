@@ -1,6 +1,7 @@
 package auth
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"strings"
@@ -10,6 +11,7 @@ import (
 	jwt "github.com/golang-jwt/jwt"
 	"github.com/gomodule/redigo/redis"
 	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 )
 
@@ -18,7 +20,8 @@ var (
 	RefreshToken string = "refresh"
 )
 
-func CreateToken(user *domain.User) (jwtResults domain.JwtResults, err error) {
+func CreateToken(user *domain.User, sessionId string) (jwtResults domain.JwtResults, err error) {
+	jwtResults.SessionID = sessionId
 	// access_token
 	jwtResults.AccessUUID = uuid.New().String()
 	jwtResults.AccessExp = time.Now().Add(time.Duration(viper.GetInt32(`authentication.duration_access`)) * time.Minute).Unix()
@@ -28,10 +31,10 @@ func CreateToken(user *domain.User) (jwtResults domain.JwtResults, err error) {
 			ExpiresAt: jwtResults.AccessExp,
 		},
 		AccessUUID: jwtResults.AccessUUID,
+		SessionID:  sessionId,
 	}
 
-	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, Accessclaims)
-	jwtResults.AccessToken, err = accessToken.SignedString([]byte(viper.GetString(`authentication.jwt_signature_access_key`)))
+	jwtResults.AccessToken, err = signAccessToken(Accessclaims)
 	if err != nil {
 		return domain.JwtResults{}, err
 	}
@@ -45,6 +48,7 @@ func CreateToken(user *domain.User) (jwtResults domain.JwtResults, err error) {
 			ExpiresAt: jwtResults.RefreshExp,
 		},
 		RefreshUUID: jwtResults.RefreshUUID,
+		SessionID:   sessionId,
 	}
 
 	refreshToken := jwt.NewWithClaims(jwt.SigningMethodHS256, Refreshclaims)
@@ -56,11 +60,43 @@ func CreateToken(user *domain.User) (jwtResults domain.JwtResults, err error) {
 	return
 }
 
+// sign the access token with the current key of the key set, or with the
+// shared secret when no asymmetric key is configured
+func signAccessToken(claims jwt.Claims) (string, error) {
+	if Keys == nil {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+		return token.SignedString([]byte(viper.GetString(`authentication.jwt_signature_access_key`)))
+	}
+
+	key, err := Keys.Signing(time.Now())
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.Kid
+	return token.SignedString(key.PrivateKey)
+}
+
 func TokenValid(tokenString, tipe string) (jwt.MapClaims, error) {
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if kid, ok := token.Header["kid"].(string); ok && tipe == AccessToken && Keys != nil {
+			key, err := Keys.Verification(kid, time.Now())
+			if err != nil {
+				return nil, err
+			}
+			// never let the token pick another algorithm than its key
+			if token.Method.Alg() != key.Method.Alg() {
+				return nil, ErrKeyAlgorithmInvalid
+			}
+			return key.PublicKey, nil
+		}
+
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
+		if tipe == AccessToken && Keys != nil && !Keys.AcceptHS256 {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
 		var key string
 		if tipe == AccessToken {
 			key = viper.GetString(`authentication.jwt_signature_access_key`)
@@ -95,9 +131,11 @@ func SaveToken(redisConn redis.Conn, user domain.User, jwt domain.JwtResults) er
 	redisConn.Send("MULTI")
 	redisConn.Send("HSET", jwt.AccessUUID, "id", user.ID)
 	redisConn.Send("HSET", jwt.AccessUUID, "flag", "access_token")
+	redisConn.Send("HSET", jwt.AccessUUID, "session_id", jwt.SessionID)
 	redisConn.Send("EXPIRE", jwt.AccessUUID, viper.GetInt32(`authentication.duration_access`)*60) //minutes
 	redisConn.Send("HSET", jwt.RefreshUUID, "id", user.ID)
 	redisConn.Send("HSET", jwt.RefreshUUID, "flag", "refresh_token")
+	redisConn.Send("HSET", jwt.RefreshUUID, "session_id", jwt.SessionID)
 	redisConn.Send("EXPIRE", jwt.RefreshUUID, viper.GetInt32(`authentication.duration_refresh`)*60*60) //hours
 	_, err := redisConn.Do("EXEC")
 	if err != nil {
@@ -111,6 +149,46 @@ func GetTokenFromRedis(redisConn redis.Conn, uuid string) (userId int64, err err
 	return
 }
 
+// get the owner and the session of the token
+func GetTokenSession(redisConn redis.Conn, uuid string) (userId int64, sessionId string, err error) {
+	values, err := redis.Values(redisConn.Do("HMGET", uuid, "id", "session_id"))
+	if err != nil {
+		return 0, "", err
+	}
+	if values[0] == nil {
+		return 0, "", redis.ErrNil
+	}
+	if userId, err = redis.Int64(values[0], nil); err != nil {
+		return 0, "", err
+	}
+	sessionId, _ = redis.String(values[1], nil)
+	return
+}
+
+// Authenticate check the access token and find its owner and session, the
+// token must be valid and not revoked. The session is marked as seen now.
+func Authenticate(ctx context.Context, tokens domain.TokenStore, tokenString string) (userId int64, sessionId string, err error) {
+	claims, err := TokenValid(tokenString, AccessToken)
+	if err != nil {
+		return 0, "", err
+	}
+	accessUUID, ok := claims["access_uuid"].(string)
+	if !ok {
+		return 0, "", domain.ErrorAuthorization
+	}
+
+	// the token must not be revoked
+	if userId, sessionId, err = tokens.GetTokenSession(ctx, accessUUID); err != nil {
+		return 0, "", err
+	}
+	if sessionId != "" {
+		if errTouch := tokens.TouchSession(ctx, sessionId, time.Now()); errTouch != nil {
+			logrus.Error(errTouch)
+		}
+	}
+	return
+}
+
 func DeleteTokenRedis(redisConn redis.Conn, uuid string) (err error) {
 	_, err = redisConn.Do("DEL", uuid)
 	return
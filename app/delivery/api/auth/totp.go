@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP parameters (RFC 6238), the defaults every authenticator app supports
+const (
+	totpPeriod = 30
+	totpDigits = 6
+	// accept the previous and the next code to absorb clock drift
+	totpSkew = 1
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTotpSecret return a new random base32 encoded secret
+func GenerateTotpSecret() (string, error) {
+	secret := make([]byte, 20)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(secret), nil
+}
+
+// TotpURI build the otpauth:// uri understood by authenticator apps
+func TotpURI(issuer, account, secret string) string {
+	values := url.Values{}
+	values.Set("secret", secret)
+	values.Set("issuer", issuer)
+	values.Set("algorithm", "SHA1")
+	values.Set("digits", fmt.Sprint(totpDigits))
+	values.Set("period", fmt.Sprint(totpPeriod))
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+	return "otpauth://totp/" + label + "?" + values.Encode()
+}
+
+// TotpCode return the code of the secret at the given time
+func TotpCode(secret string, t time.Time) (string, error) {
+	return hotp(secret, t.Unix()/totpPeriod)
+}
+
+// ValidateTotp check the code against the secret around the given time. It
+// return the time step the code belongs to, so callers can refuse a code
+// whose step was already used.
+func ValidateTotp(secret, code string, t time.Time) (step int64, ok bool) {
+	current := t.Unix() / totpPeriod
+	for step = current - totpSkew; step <= current+totpSkew; step++ {
+		expected, err := hotp(secret, step)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// hotp compute the HOTP value (RFC 4226) of the counter
+func hotp(secret string, counter int64) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return "", err
+	}
+
+	var message [8]byte
+	binary.BigEndian.PutUint64(message[:], uint64(counter))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(message[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	modulo := uint32(1)
+	for i := 0; i < totpDigits; i++ {
+		modulo *= 10
+	}
+	return fmt.Sprintf("%0*d", totpDigits, value%modulo), nil
+}
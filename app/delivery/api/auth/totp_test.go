@@ -0,0 +1,54 @@
+package auth_test
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/RedLucky/potongin/app/delivery/api/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// test vectors of RFC 6238 appendix B (SHA1), truncated to 6 digits
+func TestTotpCode(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1111111111: "050471",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+
+	for unix, expected := range vectors {
+		code, err := auth.TotpCode(secret, time.Unix(unix, 0))
+		require.NoError(t, err)
+		assert.Equal(t, expected, code)
+	}
+}
+
+func TestValidateTotp(t *testing.T) {
+	secret, err := auth.GenerateTotpSecret()
+	require.NoError(t, err)
+	now := time.Now()
+
+	t.Run("current-code", func(t *testing.T) {
+		code, _ := auth.TotpCode(secret, now)
+		step, ok := auth.ValidateTotp(secret, code, now)
+		assert.True(t, ok)
+		assert.Equal(t, now.Unix()/30, step)
+	})
+
+	t.Run("previous-code-within-skew", func(t *testing.T) {
+		code, _ := auth.TotpCode(secret, now.Add(-30*time.Second))
+		_, ok := auth.ValidateTotp(secret, code, now)
+		assert.True(t, ok)
+	})
+
+	t.Run("expired-code", func(t *testing.T) {
+		code, _ := auth.TotpCode(secret, now.Add(-5*time.Minute))
+		_, ok := auth.ValidateTotp(secret, code, now)
+		assert.False(t, ok)
+	})
+}
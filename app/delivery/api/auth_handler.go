@@ -6,7 +6,6 @@ import (
 
 	"github.com/RedLucky/potongin/app/delivery/api/response"
 	"github.com/RedLucky/potongin/domain"
-	"github.com/go-playground/validator"
 	"github.com/labstack/echo/v4"
 )
 
@@ -15,17 +14,40 @@ type AuthHandler struct {
 	Response    *response.JsonResponse
 }
 
-func NewAuthHandler(e *echo.Echo, uc domain.AuthUsecase, response *response.JsonResponse) {
+func NewAuthHandler(routes *Routes, uc domain.AuthUsecase, response *response.JsonResponse) {
 	handler := &AuthHandler{
 		AuthUsecase: uc,
 		Response:    response,
 	}
-	e.POST("/login", handler.Login)
-	e.POST("/refreshToken", handler.refreshToken)
-	e.POST("/signup", handler.Signup)
-	e.POST("/createVerifyEmail", handler.createVerifyEmail)
-	e.POST("/verifyEmail", handler.verifyEmail)
-	e.POST("/logout", handler.Logout)
+	e := routes.Public
+	e.POST("/auth/tokens", handler.Login)
+	e.POST("/auth/tokens/mfa", handler.LoginMfa)
+	e.POST("/auth/tokens/refresh", handler.refreshToken)
+	e.DELETE("/auth/tokens", handler.Logout)
+	e.POST("/users", handler.Signup)
+	e.POST("/auth/email-verifications", handler.createVerifyEmail)
+	e.POST("/auth/email-verifications/confirm", handler.verifyEmail)
+	e.POST("/auth/password-resets", handler.createResetPassword)
+	e.POST("/auth/password-resets/verify", handler.verifyResetPassword)
+	e.POST("/auth/password-resets/confirm", handler.resetPassword)
+	e.POST("/auth/unlocks", handler.unlockAccount)
+	e.GET("/auth/oauth/:provider/login", handler.OidcLogin)
+	e.GET("/auth/oauth/:provider/callback", handler.OidcCallback)
+
+	legacy := routes.LegacyPublic
+	legacy.POST("/login", handler.Login, routes.Deprecated("/v1/auth/tokens"))
+	legacy.POST("/login/mfa", handler.LoginMfa, routes.Deprecated("/v1/auth/tokens/mfa"))
+	legacy.POST("/refreshToken", handler.refreshToken, routes.Deprecated("/v1/auth/tokens/refresh"))
+	legacy.POST("/signup", handler.Signup, routes.Deprecated("/v1/users"))
+	legacy.POST("/createVerifyEmail", handler.createVerifyEmail, routes.Deprecated("/v1/auth/email-verifications"))
+	legacy.POST("/verifyEmail", handler.verifyEmail, routes.Deprecated("/v1/auth/email-verifications/confirm"))
+	legacy.POST("/createResetPassword", handler.createResetPassword, routes.Deprecated("/v1/auth/password-resets"))
+	legacy.POST("/verifyResetPassword", handler.verifyResetPassword, routes.Deprecated("/v1/auth/password-resets/verify"))
+	legacy.POST("/resetPassword", handler.resetPassword, routes.Deprecated("/v1/auth/password-resets/confirm"))
+	legacy.POST("/logout", handler.Logout, routes.Deprecated("/v1/auth/tokens"))
+	legacy.POST("/unlockAccount", handler.unlockAccount, routes.Deprecated("/v1/auth/unlocks"))
+	legacy.GET("/oauth/:provider/login", handler.OidcLogin, routes.Deprecated("/v1/auth/oauth/:provider/login"))
+	legacy.GET("/oauth/:provider/callback", handler.OidcCallback, routes.Deprecated("/v1/auth/oauth/:provider/callback"))
 }
 
 func (handler *AuthHandler) Signup(c echo.Context) (err error) {
@@ -66,6 +88,12 @@ func (handler *AuthHandler) Login(c echo.Context) (err error) {
 	if err != nil {
 		return handler.Response.Error(c, err)
 	}
+	if jwtResults.MfaRequired {
+		return handler.Response.Success(c, "two-factor code required", http.StatusOK, map[string]interface{}{
+			"mfa_required": true,
+			"mfa_token":    jwtResults.MfaToken,
+		})
+	}
 	token := map[string]string{
 		"access_token":  jwtResults.AccessToken,
 		"refresh_token": jwtResults.RefreshToken,
@@ -75,6 +103,67 @@ func (handler *AuthHandler) Login(c echo.Context) (err error) {
 
 }
 
+// LoginMfa complete the login of a user with two-factor authentication enabled
+func (handler *AuthHandler) LoginMfa(c echo.Context) (err error) {
+	var mfa domain.MfaLogin
+	err = c.Bind(&mfa)
+	if err != nil {
+		return handler.Response.Error(c, err)
+	}
+
+	var ok bool
+	if ok, err = validateMfaLogin(&mfa); !ok {
+		return handler.Response.Error(c, err)
+	}
+
+	ctx := c.Request().Context()
+	jwtResults, err := handler.AuthUsecase.VerifyMfa(ctx, mfa.MfaToken, mfa.Code)
+	if err != nil {
+		return handler.Response.Error(c, err)
+	}
+	token := map[string]string{
+		"access_token":  jwtResults.AccessToken,
+		"refresh_token": jwtResults.RefreshToken,
+	}
+
+	return handler.Response.Success(c, "success", http.StatusOK, map[string]interface{}{"token": token})
+}
+
+// OidcLogin redirect to the login page of the identity provider
+func (handler *AuthHandler) OidcLogin(c echo.Context) (err error) {
+	ctx := c.Request().Context()
+	loginURL, err := handler.AuthUsecase.OidcLoginURL(ctx, c.Param("provider"))
+	if err != nil {
+		return handler.Response.Error(c, err)
+	}
+	return c.Redirect(http.StatusFound, loginURL)
+}
+
+// OidcCallback is where the identity provider send the user back with the authorization code
+func (handler *AuthHandler) OidcCallback(c echo.Context) (err error) {
+	if errorCode := c.QueryParam("error"); errorCode != "" {
+		return handler.Response.Error(c, domain.ErrorAuthorization)
+	}
+
+	ctx := c.Request().Context()
+	jwtResults, err := handler.AuthUsecase.OidcCallback(ctx, c.Param("provider"), c.QueryParam("code"), c.QueryParam("state"))
+	if err != nil {
+		return handler.Response.Error(c, err)
+	}
+	if jwtResults.MfaRequired {
+		return handler.Response.Success(c, "two-factor code required", http.StatusOK, map[string]interface{}{
+			"mfa_required": true,
+			"mfa_token":    jwtResults.MfaToken,
+		})
+	}
+	token := map[string]string{
+		"access_token":  jwtResults.AccessToken,
+		"refresh_token": jwtResults.RefreshToken,
+	}
+
+	return handler.Response.Success(c, "success", http.StatusOK, map[string]interface{}{"token": token})
+}
+
 func (handler *AuthHandler) refreshToken(c echo.Context) (err error) {
 	jwtResults, err := handler.AuthUsecase.GenerateNewAccessToken(c)
 	if err != nil {
@@ -92,7 +181,7 @@ func (handler *AuthHandler) createVerifyEmail(c echo.Context) (err error) {
 	payload := make(map[string]interface{})
 	err = json.NewDecoder(c.Request().Body).Decode(&payload)
 	if err != nil {
-		return err
+		return domain.ErrBadParamInput
 	}
 	email, ok := payload["email"].(string)
 	if !ok {
@@ -119,11 +208,82 @@ func (handler *AuthHandler) verifyEmail(c echo.Context) (err error) {
 	return handler.Response.Success(c, "success", http.StatusOK, map[string]interface{}{})
 }
 
+// createResetPassword always succeed, so it does not tell which emails are registered
+func (handler *AuthHandler) createResetPassword(c echo.Context) (err error) {
+	payload := make(map[string]interface{})
+	err = json.NewDecoder(c.Request().Body).Decode(&payload)
+	if err != nil {
+		return handler.Response.Error(c, domain.ErrBadParamInput)
+	}
+	email, ok := payload["email"].(string)
+	if !ok {
+		return handler.Response.Error(c, domain.ErrBadParamInput)
+	}
+	_, err = handler.AuthUsecase.CreateResetPassword(c.Request().Context(), email)
+	if err != nil {
+		return handler.Response.Error(c, err)
+	}
+	return handler.Response.Success(c, "success", http.StatusOK, map[string]interface{}{})
+}
+
+func (handler *AuthHandler) verifyResetPassword(c echo.Context) (err error) {
+	payload := make(map[string]interface{})
+	err = json.NewDecoder(c.Request().Body).Decode(&payload)
+	if err != nil {
+		return handler.Response.Error(c, domain.ErrBadParamInput)
+	}
+	token, ok := payload["token"].(string)
+	if !ok {
+		return handler.Response.Error(c, domain.ErrBadParamInput)
+	}
+	err = handler.AuthUsecase.VerifyResetPassword(c.Request().Context(), token)
+	if err != nil {
+		return handler.Response.Error(c, err)
+	}
+	return handler.Response.Success(c, "success", http.StatusOK, map[string]interface{}{})
+}
+
+func (handler *AuthHandler) resetPassword(c echo.Context) (err error) {
+	var param domain.ResetPasswordParam
+	err = c.Bind(&param)
+	if err != nil {
+		return handler.Response.Error(c, err)
+	}
+
+	var ok bool
+	if ok, err = validateResetPassword(&param); !ok {
+		return handler.Response.Error(c, err)
+	}
+
+	err = handler.AuthUsecase.ResetPassword(c.Request().Context(), param.Password, param.ConfirmPassword, param.Token)
+	if err != nil {
+		return handler.Response.Error(c, err)
+	}
+	return handler.Response.Success(c, "success", http.StatusOK, map[string]interface{}{})
+}
+
+func (handler *AuthHandler) unlockAccount(c echo.Context) (err error) {
+	payload := make(map[string]interface{})
+	err = json.NewDecoder(c.Request().Body).Decode(&payload)
+	if err != nil {
+		return handler.Response.Error(c, domain.ErrBadParamInput)
+	}
+	token, ok := payload["token"].(string)
+	if !ok {
+		return handler.Response.Error(c, domain.ErrBadParamInput)
+	}
+	err = handler.AuthUsecase.UnlockAccountByToken(c.Request().Context(), token)
+	if err != nil {
+		return handler.Response.Error(c, err)
+	}
+	return handler.Response.Success(c, "success", http.StatusOK, map[string]interface{}{})
+}
+
 func (handler *AuthHandler) Logout(c echo.Context) (err error) {
 	payload := make(map[string]interface{})
 	err = json.NewDecoder(c.Request().Body).Decode(&payload)
 	if err != nil {
-		return err
+		return domain.ErrBadParamInput
 	}
 	access_token, ok := payload["access_token"].(string)
 	if !ok {
@@ -135,7 +295,7 @@ func (handler *AuthHandler) Logout(c echo.Context) (err error) {
 		return domain.ErrBadParamInput
 	}
 
-	err = handler.AuthUsecase.Logout(access_token, refresh_token)
+	err = handler.AuthUsecase.Logout(c.Request().Context(), access_token, refresh_token)
 	if err != nil {
 		return handler.Response.Error(c, domain.ErrorAuthorization)
 	}
@@ -144,7 +304,22 @@ func (handler *AuthHandler) Logout(c echo.Context) (err error) {
 }
 
 func validateLogin(m *domain.Auth) (bool, error) {
-	validate := validator.New()
+	err := validate.Struct(m)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func validateMfaLogin(m *domain.MfaLogin) (bool, error) {
+	err := validate.Struct(m)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func validateResetPassword(m *domain.ResetPasswordParam) (bool, error) {
 	err := validate.Struct(m)
 	if err != nil {
 		return false, err
@@ -153,7 +328,6 @@ func validateLogin(m *domain.Auth) (bool, error) {
 }
 
 func isValidUser(m *domain.User) (bool, error) {
-	validate := validator.New()
 	err := validate.Struct(m)
 	if err != nil {
 		return false, err
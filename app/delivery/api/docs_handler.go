@@ -0,0 +1,284 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/RedLucky/potongin/app/delivery/api/auth"
+	"github.com/RedLucky/potongin/app/delivery/api/middleware"
+	"github.com/RedLucky/potongin/app/delivery/api/openapi"
+	"github.com/RedLucky/potongin/app/delivery/api/response"
+	"github.com/RedLucky/potongin/domain"
+	"github.com/labstack/echo/v4"
+)
+
+// APIVersion is the version of the API published in the OpenAPI document
+const APIVersion = "1.0.0"
+
+// DocsHandler serve the OpenAPI document of the API and its docs page
+type DocsHandler struct {
+	Document *openapi.Document
+}
+
+// NewDocsHandler will initialize the /openapi.json and /docs endpoints
+func NewDocsHandler(e *echo.Echo) {
+	handler := &DocsHandler{
+		Document: OpenAPI(),
+	}
+	e.GET("/openapi.json", handler.OpenAPI)
+	e.GET("/docs", handler.Docs)
+}
+
+// OpenAPI return the OpenAPI document as is, not wrapped in the json response
+func (handler *DocsHandler) OpenAPI(c echo.Context) error {
+	c.Response().Header().Set("Cache-Control", "public, max-age=300")
+	return c.JSON(http.StatusOK, handler.Document)
+}
+
+// Docs return the docs page, it loads the document from /openapi.json
+func (handler *DocsHandler) Docs(c echo.Context) error {
+	return c.HTMLBlob(http.StatusOK, openapi.DocsPage)
+}
+
+// the bodies decoded by hand, and the data answered as maps, described for the document
+type (
+	emailParam struct {
+		Email string `json:"email" validate:"required,email"`
+	}
+	logoutParam struct {
+		AccessToken  string `json:"access_token" validate:"required"`
+		RefreshToken string `json:"refresh_token" validate:"required"`
+	}
+	tokenPair struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	profile struct {
+		ID        int64     `json:"id"`
+		Username  string    `json:"username"`
+		Email     string    `json:"email"`
+		Name      string    `json:"name"`
+		CreatedAt time.Time `json:"created_at"`
+		UpdatedAt time.Time `json:"updated_at"`
+	}
+	graphqlResult struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []graphqlError  `json:"errors,omitempty"`
+	}
+	graphqlError struct {
+		Message string        `json:"message"`
+		Path    []interface{} `json:"path,omitempty"`
+		// Extensions carry the error_code, the HTTP status and the failed fields of the HTTP API
+		Extensions map[string]interface{} `json:"extensions,omitempty"`
+	}
+)
+
+// OpenAPI build the OpenAPI document of every route registered by the handlers
+// of this package. A route missing here fails the routes test of cmd.
+func OpenAPI() *openapi.Document {
+	spec := openapi.New("potongin API", APIVersion, "Shorten links, share them in organizations and follow their visits. "+
+		"Every JSON response is wrapped in the same envelope; the errors name themselves in `error_code`.")
+	spec.Envelope(response.Envelope{}, domain.Errors())
+
+	spec.Tag("auth", "signup, login, tokens and account recovery")
+	spec.Tag("account", "the profile, email, password and sessions of the logged in user")
+	spec.Tag("mfa", "two-factor authentication")
+	spec.Tag("links", "generated urls and their visits")
+	spec.Tag("organizations", "workspaces shared by their members")
+	spec.Tag("webhooks", "notifications of the events of the links")
+	spec.Tag("admin", "moderation and audit log, for the admins")
+	spec.Tag("graphql", "the dashboard queries of the links, their visits and the logged in user")
+	spec.Tag("meta", "keys, statistics and documentation")
+
+	limit := openapi.Query("limit", "integer", "page size")
+	offset := openapi.Query("offset", "integer", "items skipped")
+	auditFilters := []openapi.Parameter{
+		openapi.Query("actor_id", "integer", "user who acted"),
+		openapi.Query("action", "string", "e.g. user.login, link.update"),
+		openapi.Query("target_type", "string", "user, link or session"),
+		openapi.Query("target_id", "string", "id of the target"),
+		openapi.Query("from", "string", "RFC 3339 time, inclusive"),
+		openapi.Query("to", "string", "RFC 3339 time, exclusive"),
+	}
+	redirect := &openapi.Response{
+		Description: "redirect",
+		Headers:     map[string]openapi.Header{"Location": {Schema: &openapi.Schema{Type: "string", Format: "uri"}}},
+	}
+
+	routes := []openapi.Route{
+		// auth
+		{Method: http.MethodPost, Path: "/v1/users", Tag: "auth", Summary: "create an account", Body: domain.User{}, Status: http.StatusCreated,
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodPost, "/signup")}},
+		{Method: http.MethodPost, Path: "/v1/auth/tokens", Tag: "auth", Summary: "log in, or get an mfa_token when two-factor authentication is enabled", Body: domain.Auth{},
+			Data:    map[string]interface{}{"token": tokenPair{}, "mfa_required": true, "mfa_token": ""},
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodPost, "/login")}},
+		{Method: http.MethodPost, Path: "/v1/auth/tokens/mfa", Tag: "auth", Summary: "complete the login with a two-factor or recovery code", Body: domain.MfaLogin{},
+			Data:    map[string]interface{}{"token": tokenPair{}},
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodPost, "/login/mfa")}},
+		{Method: http.MethodPost, Path: "/v1/auth/tokens/refresh", Tag: "auth", Summary: "rotate the tokens, the refresh token is sent as the bearer token", Auth: true,
+			Data:    map[string]interface{}{"token": tokenPair{}},
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodPost, "/refreshToken")}},
+		{Method: http.MethodDelete, Path: "/v1/auth/tokens", Tag: "auth", Summary: "revoke the session of the tokens", Body: logoutParam{},
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodPost, "/logout")}},
+		{Method: http.MethodPost, Path: "/v1/auth/email-verifications", Tag: "auth", Summary: "send the email verification link again", Body: emailParam{},
+			Raw:     &openapi.Response{Description: "sent, with an empty body"},
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodPost, "/createVerifyEmail")}},
+		{Method: http.MethodPost, Path: "/v1/auth/email-verifications/confirm", Tag: "auth", Summary: "verify the email with the token of the link", Body: TokenParam{},
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodPost, "/verifyEmail")}},
+		{Method: http.MethodPost, Path: "/v1/auth/password-resets", Tag: "auth", Summary: "send a password reset link, it succeeds for unknown emails too", Body: emailParam{},
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodPost, "/createResetPassword")}},
+		{Method: http.MethodPost, Path: "/v1/auth/password-resets/verify", Tag: "auth", Summary: "check a password reset token", Body: TokenParam{},
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodPost, "/verifyResetPassword")}},
+		{Method: http.MethodPost, Path: "/v1/auth/password-resets/confirm", Tag: "auth", Summary: "set a new password with a password reset token", Body: domain.ResetPasswordParam{},
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodPost, "/resetPassword")}},
+		{Method: http.MethodPost, Path: "/v1/auth/unlocks", Tag: "auth", Summary: "unlock an account locked after failed logins", Body: TokenParam{},
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodPost, "/unlockAccount")}},
+		{Method: http.MethodGet, Path: "/v1/auth/oauth/:provider/login", Tag: "auth", Summary: "redirect to the identity provider", Status: http.StatusFound, Raw: redirect,
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodGet, "/oauth/:provider/login")}},
+		{Method: http.MethodGet, Path: "/v1/auth/oauth/:provider/callback", Tag: "auth", Summary: "log in with the authorization code of the identity provider",
+			Query:   []openapi.Parameter{openapi.Query("code", "string", "authorization code"), openapi.Query("state", "string", "state sent to the provider"), openapi.Query("error", "string", "error of the provider")},
+			Data:    map[string]interface{}{"token": tokenPair{}, "mfa_required": true, "mfa_token": ""},
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodGet, "/oauth/:provider/callback")}},
+
+		// account
+		{Method: http.MethodGet, Path: "/v1/me", Tag: "account", Summary: "the profile of the logged in user", Auth: true, Data: map[string]interface{}{"user": profile{}},
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodGet, "/me")}},
+		{Method: http.MethodPatch, Path: "/v1/me", Tag: "account", Summary: "update the profile", Auth: true, Body: domain.UpdateProfile{}, Data: map[string]interface{}{"user": profile{}},
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodPatch, "/me")}},
+		{Method: http.MethodDelete, Path: "/v1/me", Tag: "account", Summary: "delete the account, its links go to transfer_to, a member of one of its organizations, or are deleted", Auth: true, Body: domain.DeleteAccount{},
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodDelete, "/me")}},
+		{Method: http.MethodPost, Path: "/v1/me/email", Tag: "account", Summary: "send a confirmation link to the new email", Auth: true, Body: domain.ChangeEmail{}, Status: http.StatusAccepted,
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodPost, "/me/email")}},
+		{Method: http.MethodPost, Path: "/v1/me/email/confirm", Tag: "account", Summary: "confirm the new email with the token of the link", Body: TokenParam{},
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodPost, "/me/email/confirm")}},
+		{Method: http.MethodPut, Path: "/v1/me/password", Tag: "account", Summary: "change the password, the other sessions are revoked", Auth: true, Body: domain.ChangePassword{},
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodPut, "/me/password")}},
+		{Method: http.MethodGet, Path: "/v1/me/export", Tag: "account", Summary: "download every personal data", Auth: true,
+			Query: []openapi.Parameter{openapi.Query("format", "string", "json (default) or zip")},
+			Raw: &openapi.Response{Description: "the personal data, as is", Content: map[string]openapi.MediaType{
+				"application/json": {Schema: spec.Schema(domain.DataExport{})},
+				"application/zip":  {Schema: &openapi.Schema{Type: "string", Format: "binary"}},
+			}},
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodGet, "/me/export")}},
+		{Method: http.MethodGet, Path: "/v1/sessions", Tag: "account", Summary: "the active sessions", Auth: true, Data: map[string]interface{}{"sessions": []domain.Session{}},
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodGet, "/sessions")}},
+		{Method: http.MethodDelete, Path: "/v1/sessions", Tag: "account", Summary: "revoke every other session", Auth: true,
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodDelete, "/sessions")}},
+		{Method: http.MethodDelete, Path: "/v1/sessions/:id", Tag: "account", Summary: "revoke a session", Auth: true,
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodDelete, "/sessions/:id")}},
+		{Method: http.MethodGet, Path: "/v1/users", Tag: "account", Summary: "list the users", Auth: true, Data: map[string]interface{}{"users": []domain.User{}},
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodGet, "/users")}},
+		{Method: http.MethodPost, Path: "/user", Tag: "account", Summary: "create a user, without successor: users sign up on /v1/users", Auth: true, Deprecated: true, Body: domain.User{}, Status: http.StatusCreated, Data: map[string]interface{}{"user": domain.User{}}},
+		{Method: http.MethodGet, Path: "/v1/users/:id", Tag: "account", Summary: "get a user", Auth: true, Data: map[string]interface{}{"user": profile{}},
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodGet, "/user/:id")}},
+		{Method: http.MethodDelete, Path: "/v1/users/:id", Tag: "account", Summary: "delete a user", Auth: true, Status: http.StatusCreated,
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodDelete, "/user/:id")}},
+
+		// mfa
+		{Method: http.MethodPost, Path: "/v1/mfa", Tag: "mfa", Summary: "start the enrollment: a secret and its QR code", Auth: true, Data: map[string]interface{}{"mfa": domain.MfaEnrollment{}},
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodPost, "/mfa/enroll")}},
+		{Method: http.MethodPut, Path: "/v1/mfa", Tag: "mfa", Summary: "enable it with a first code, the recovery codes are shown once", Auth: true, Body: MfaCodeParam{},
+			Data:    map[string]interface{}{"recovery_codes": []string{}},
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodPost, "/mfa/confirm")}},
+		{Method: http.MethodDelete, Path: "/v1/mfa", Tag: "mfa", Summary: "disable it with a code", Auth: true, Body: MfaCodeParam{},
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodPost, "/mfa/disable")}},
+
+		// links
+		{Method: http.MethodPost, Path: "/v1/links", Tag: "links", Summary: "generate a link, in an organization with org_id", Auth: true, Body: domain.GeneratedUrl{}, Status: http.StatusCreated,
+			Data:    map[string]interface{}{"generated_url": domain.GeneratedUrl{}},
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodPost, "/createUrl")}},
+		{Method: http.MethodGet, Path: "/v1/links", Tag: "links", Summary: "the links of the personal workspace, or of an organization", Auth: true,
+			Query:   []openapi.Parameter{openapi.Query("org_id", "integer", "organization workspace")},
+			Data:    map[string]interface{}{"generated_url": []domain.GeneratedUrl{}},
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodGet, "/urls")}},
+		{Method: http.MethodGet, Path: "/v1/links/:id", Tag: "links", Summary: "get a link", Auth: true, Data: map[string]interface{}{"generated_url": domain.GeneratedUrl{}},
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodGet, "/url/:id")}},
+		{Method: http.MethodPost, Path: "/v1/visits", Tag: "links", Summary: "resolve a generated link and record the visit", Body: RequestParam{},
+			Data:    map[string]interface{}{"origin_url": ""},
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodPost, "/accessUrl")}},
+
+		// organizations
+		{Method: http.MethodPost, Path: "/v1/orgs", Tag: "organizations", Summary: "create an organization, owned by its creator", Auth: true, Body: domain.Organization{}, Status: http.StatusCreated,
+			Data:    map[string]interface{}{"organization": domain.Organization{}},
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodPost, "/orgs")}},
+		{Method: http.MethodGet, Path: "/v1/orgs", Tag: "organizations", Summary: "the organizations of the user", Auth: true, Data: map[string]interface{}{"organizations": []domain.Organization{}},
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodGet, "/orgs")}},
+		{Method: http.MethodGet, Path: "/v1/orgs/:org_id/members", Tag: "organizations", Summary: "the members", Auth: true, Data: map[string]interface{}{"members": []domain.OrganizationMember{}},
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodGet, "/orgs/:org_id/members")}},
+		{Method: http.MethodPut, Path: "/v1/orgs/:org_id/members/:user_id", Tag: "organizations", Summary: "change the role of a member", Auth: true, Body: domain.UpdateMemberRole{},
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodPut, "/orgs/:org_id/members/:user_id")}},
+		{Method: http.MethodDelete, Path: "/v1/orgs/:org_id/members/:user_id", Tag: "organizations", Summary: "remove a member", Auth: true,
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodDelete, "/orgs/:org_id/members/:user_id")}},
+		{Method: http.MethodPost, Path: "/v1/orgs/:org_id/invitations", Tag: "organizations", Summary: "invite by email", Auth: true, Body: domain.InviteMember{}, Status: http.StatusAccepted,
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodPost, "/orgs/:org_id/invitations")}},
+		{Method: http.MethodPost, Path: "/v1/invitations/accept", Tag: "organizations", Summary: "join with the token of the invitation", Auth: true, Body: TokenParam{},
+			Data:    map[string]interface{}{"member": domain.OrganizationMember{}},
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodPost, "/invitations/accept")}},
+
+		// webhooks
+		{Method: http.MethodPost, Path: "/v1/webhooks", Tag: "webhooks", Summary: "subscribe, the signing secret is shown once", Auth: true, Body: domain.CreateWebhook{}, Status: http.StatusCreated,
+			Data:    map[string]interface{}{"webhook": domain.Webhook{}, "secret": ""},
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodPost, "/webhooks")}},
+		{Method: http.MethodGet, Path: "/v1/webhooks", Tag: "webhooks", Summary: "the webhooks of the user", Auth: true, Data: map[string]interface{}{"webhooks": []domain.Webhook{}},
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodGet, "/webhooks")}},
+		{Method: http.MethodDelete, Path: "/v1/webhooks/:id", Tag: "webhooks", Summary: "unsubscribe", Auth: true,
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodDelete, "/webhooks/:id")}},
+		{Method: http.MethodGet, Path: "/v1/webhooks/:id/deliveries", Tag: "webhooks", Summary: "the latest deliveries", Auth: true, Query: []openapi.Parameter{limit},
+			Data: map[string]interface{}{"deliveries": []domain.WebhookDelivery{}}, Paginated: true,
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodGet, "/webhooks/:id/deliveries")}},
+		{Method: http.MethodPost, Path: "/v1/webhooks/:id/deliveries/:delivery_id/redeliveries", Tag: "webhooks", Summary: "send a delivery again", Auth: true, Status: http.StatusAccepted,
+			Data:    map[string]interface{}{"delivery": domain.WebhookDelivery{}},
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodPost, "/webhooks/:id/deliveries/:delivery_id/redeliver")}},
+
+		// admin
+		{Method: http.MethodGet, Path: "/v1/admin/users", Tag: "admin", Summary: "search the users", Auth: true,
+			Query: []openapi.Parameter{openapi.Query("q", "string", "username, email or name"), limit, offset},
+			Data:  map[string]interface{}{"users": []domain.AdminUser{}}, Paginated: true,
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodGet, "/admin/users")}},
+		{Method: http.MethodGet, Path: "/v1/admin/users/:id", Tag: "admin", Summary: "a user and its links", Auth: true,
+			Data:    map[string]interface{}{"user": domain.AdminUser{}, "links": []domain.GeneratedUrl{}},
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodGet, "/admin/users/:id")}},
+		{Method: http.MethodPost, Path: "/v1/admin/users/:id/suspension", Tag: "admin", Summary: "suspend a user, its sessions are revoked", Auth: true, Body: domain.ModerationReason{},
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodPost, "/admin/users/:id/suspend")}},
+		{Method: http.MethodDelete, Path: "/v1/admin/users/:id/suspension", Tag: "admin", Summary: "lift a suspension", Auth: true,
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodPost, "/admin/users/:id/unsuspend")}},
+		{Method: http.MethodPost, Path: "/v1/admin/users/:id/email-verification", Tag: "admin", Summary: "mark the email verified", Auth: true,
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodPost, "/admin/users/:id/verifyEmail")}},
+		{Method: http.MethodPost, Path: "/v1/admin/users/:id/password-reset", Tag: "admin", Summary: "force a password reset", Auth: true,
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodPost, "/admin/users/:id/resetPassword")}},
+		{Method: http.MethodDelete, Path: "/v1/admin/users/:id/lockout", Tag: "admin", Summary: "lift the lockout of an account after failed logins", Auth: true},
+		{Method: http.MethodPost, Path: "/v1/admin/links/:id/takedown", Tag: "admin", Summary: "take a link down, visitors get the reason", Auth: true, Body: domain.ModerationReason{},
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodPost, "/admin/links/:id/takedown")}},
+		{Method: http.MethodDelete, Path: "/v1/admin/links/:id/takedown", Tag: "admin", Summary: "restore a link taken down", Auth: true,
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodPost, "/admin/links/:id/restore")}},
+		{Method: http.MethodGet, Path: "/v1/admin/audit-logs", Tag: "admin", Summary: "the audit logs, newest first", Auth: true,
+			Query: append(append([]openapi.Parameter{}, auditFilters...), limit, offset),
+			Data:  map[string]interface{}{"logs": []domain.AuditLog{}}, Paginated: true,
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodGet, "/admin/audit")}},
+		{Method: http.MethodGet, Path: "/v1/admin/audit-logs/export", Tag: "admin", Summary: "download the audit logs as JSON lines", Auth: true, Query: auditFilters,
+			Raw: &openapi.Response{Description: "one audit log per line", Content: map[string]openapi.MediaType{
+				"application/x-ndjson": {Schema: spec.Schema(domain.AuditLog{})},
+			}},
+			Aliases: []openapi.Alias{openapi.Legacy(http.MethodGet, "/admin/audit/export")}},
+
+		// graphql
+		{Method: http.MethodPost, Path: "/graphql", Tag: "graphql", Auth: true, Body: graphqlParam{},
+			Summary: "run a GraphQL query or mutation of the dashboard, the schema is app/delivery/graph/schema.graphql",
+			Raw:     &openapi.Response{Description: "GraphQL response, the failed fields answer null with their errors", Content: openapi.JSON(spec.Schema(graphqlResult{}))}},
+
+		// meta
+		{Method: http.MethodGet, Path: "/.well-known/jwks.json", Tag: "meta", Summary: "the public keys verifying the access tokens",
+			Raw: &openapi.Response{Description: "JSON Web Key Set (RFC 7517)", Content: openapi.JSON(spec.Schema(auth.JSONWebKeySet{}))}},
+		{Method: http.MethodGet, Path: "/stats", Tag: "meta", Summary: "request statistics of the server",
+			Raw: &openapi.Response{Description: "statistics", Content: openapi.JSON(spec.Schema(middleware.CustomMiddleware{}))}},
+		{Method: http.MethodGet, Path: "/openapi.json", Tag: "meta", Summary: "this document",
+			Raw: &openapi.Response{Description: "OpenAPI 3 document", Content: openapi.JSON(&openapi.Schema{Type: "object"})}},
+		{Method: http.MethodGet, Path: "/docs", Tag: "meta", Summary: "the docs page of this document",
+			Raw: &openapi.Response{Description: "HTML page", Content: map[string]openapi.MediaType{"text/html": {Schema: &openapi.Schema{Type: "string"}}}}},
+	}
+	for _, route := range routes {
+		spec.Add(route)
+	}
+	return spec.Document()
+}
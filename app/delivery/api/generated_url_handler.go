@@ -2,11 +2,10 @@ package api
 
 import (
 	"net/http"
+	"strconv"
 
 	"github.com/RedLucky/potongin/app/delivery/api/response"
 	"github.com/RedLucky/potongin/domain"
-	"github.com/go-playground/validator"
-	"github.com/golang-jwt/jwt"
 	"github.com/labstack/echo/v4"
 )
 
@@ -15,16 +14,21 @@ type GeneratedUrlHandler struct {
 	Response            *response.JsonResponse
 }
 
-func NewGeneratedUrlHandler(e *echo.Group, guu domain.GeneratedUrlUsecase, response *response.JsonResponse) {
+func NewGeneratedUrlHandler(routes *Routes, guu domain.GeneratedUrlUsecase, response *response.JsonResponse) {
 	handlers := &GeneratedUrlHandler{
 		GeneratedUrlUsecase: guu,
 		Response:            response,
 	}
 
-	e.POST("/createUrl", handlers.CreateUrl)
-	e.GET("/urls", handlers.GetUrlByUserId)
-	e.GET("/url/:url_id", handlers.GetUrlById)
+	e := routes.Protected
+	e.POST("/links", handlers.CreateUrl)
+	e.GET("/links", handlers.GetUrlByWorkspace)
+	e.GET("/links/:id", handlers.GetUrlById)
 
+	legacy := routes.LegacyProtected
+	legacy.POST("/createUrl", handlers.CreateUrl, routes.Deprecated("/v1/links"))
+	legacy.GET("/urls", handlers.GetUrlByWorkspace, routes.Deprecated("/v1/links"))
+	legacy.GET("/url/:id", handlers.GetUrlById, routes.Deprecated("/v1/links/:id"))
 }
 
 func (handler *GeneratedUrlHandler) CreateUrl(c echo.Context) (err error) {
@@ -40,10 +44,7 @@ func (handler *GeneratedUrlHandler) CreateUrl(c echo.Context) (err error) {
 		return handler.Response.Error(c, err)
 	}
 
-	data := c.Get("user").(jwt.MapClaims)
-	idP := data["id"].(float64)
-	id := int64(idP)
-	generateUrl.UserId = id
+	generateUrl.UserId = c.Get("user_id").(int64)
 	ctx := c.Request().Context()
 	err = handler.GeneratedUrlUsecase.CreateUrl(ctx, &generateUrl)
 	if err != nil {
@@ -54,15 +55,22 @@ func (handler *GeneratedUrlHandler) CreateUrl(c echo.Context) (err error) {
 
 }
 
-func (handler *GeneratedUrlHandler) GetUrlByUserId(c echo.Context) (err error) {
+// GetUrlByWorkspace list the links of the personal workspace, or of the
+// organization given by ?org_id=
+func (handler *GeneratedUrlHandler) GetUrlByWorkspace(c echo.Context) (err error) {
 	var generateUrl []domain.GeneratedUrl
 
-	data := c.Get("user").(jwt.MapClaims)
-	idP := data["id"].(float64)
-	id := int64(idP)
+	var orgId int64
+	if param := c.QueryParam("org_id"); param != "" {
+		orgId, err = strconv.ParseInt(param, 10, 64)
+		if err != nil {
+			return handler.Response.Error(c, domain.ErrBadParamInput)
+		}
+	}
 
+	userId := c.Get("user_id").(int64)
 	ctx := c.Request().Context()
-	generateUrl, err = handler.GeneratedUrlUsecase.GetUrlByUserId(ctx, id)
+	generateUrl, err = handler.GeneratedUrlUsecase.GetUrlByWorkspace(ctx, userId, orgId)
 	if err != nil {
 		return handler.Response.Error(c, err)
 	}
@@ -73,9 +81,10 @@ func (handler *GeneratedUrlHandler) GetUrlByUserId(c echo.Context) (err error) {
 
 func (handler *GeneratedUrlHandler) GetUrlById(c echo.Context) (err error) {
 	var generateUrl domain.GeneratedUrl
-	id := c.Param("url_id")
+	id := c.Param("id")
+	userId := c.Get("user_id").(int64)
 	ctx := c.Request().Context()
-	generateUrl, err = handler.GeneratedUrlUsecase.GetUrlById(ctx, id)
+	generateUrl, err = handler.GeneratedUrlUsecase.GetUrlById(ctx, userId, id)
 	if err != nil {
 		return handler.Response.Error(c, err)
 	}
@@ -86,7 +95,6 @@ func (handler *GeneratedUrlHandler) GetUrlById(c echo.Context) (err error) {
 
 // private function
 func validateCreateUrl(m *domain.GeneratedUrl) (bool, error) {
-	validate := validator.New()
 	err := validate.Struct(m)
 	if err != nil {
 		return false, err
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/RedLucky/potongin/app/delivery/graph"
+	"github.com/RedLucky/potongin/domain"
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/labstack/echo/v4"
+)
+
+// GraphqlHandler answer the GraphQL queries of the dashboard
+type GraphqlHandler struct {
+	Schema *graphql.Schema
+}
+
+// graphqlParam is a GraphQL request
+type graphqlParam struct {
+	Query         string                 `json:"query" validate:"required"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// NewGraphqlHandler will initialize the /graphql endpoint, behind the authentication
+func NewGraphqlHandler(e *echo.Echo, authentication echo.MiddlewareFunc, guu domain.GeneratedUrlUsecase, uu domain.UserUsecase) {
+	handler := &GraphqlHandler{
+		Schema: graph.NewSchema(guu, uu),
+	}
+	e.POST("/graphql", handler.Query, authentication)
+}
+
+// Query execute the request and return the GraphQL response as is, not
+// wrapped in the json response: the errors of the resolvers are in its errors
+func (handler *GraphqlHandler) Query(c echo.Context) error {
+	var param graphqlParam
+	if err := c.Bind(&param); err != nil {
+		return err
+	}
+	if err := validate.Struct(&param); err != nil {
+		return err
+	}
+	result := handler.Schema.Exec(c.Request().Context(), param.Query, param.OperationName, param.Variables)
+	return c.JSON(http.StatusOK, result)
+}
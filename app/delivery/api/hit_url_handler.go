@@ -17,14 +17,14 @@ type RequestParam struct {
 	UrlGenerated string `json:"url_generated"`
 }
 
-func NewHitUrlHandler(e *echo.Echo, guu domain.GeneratedUrlUsecase, response *response.JsonResponse) {
+func NewHitUrlHandler(routes *Routes, guu domain.GeneratedUrlUsecase, response *response.JsonResponse) {
 	handlers := &GeneratedUrlHandler{
 		GeneratedUrlUsecase: guu,
 		Response:            response,
 	}
 
-	e.POST("/accessUrl", handlers.HitUrl)
-
+	routes.Public.POST("/visits", handlers.HitUrl)
+	routes.LegacyPublic.POST("/accessUrl", handlers.HitUrl, routes.Deprecated("/v1/visits"))
 }
 
 func (handler *GeneratedUrlHandler) HitUrl(c echo.Context) (err error) {
@@ -0,0 +1,31 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/RedLucky/potongin/app/delivery/api/auth"
+	"github.com/labstack/echo/v4"
+)
+
+// JwksHandler publish the public keys verifying our access tokens
+type JwksHandler struct {
+	Keys *auth.KeySet
+}
+
+// NewJwksHandler will initialize the /.well-known/jwks.json endpoint
+func NewJwksHandler(e *echo.Echo, keys *auth.KeySet) {
+	handler := &JwksHandler{
+		Keys: keys,
+	}
+	e.GET("/.well-known/jwks.json", handler.Jwks)
+}
+
+// Jwks return the key set as is (RFC 7517), not wrapped in the json response
+func (handler *JwksHandler) Jwks(c echo.Context) error {
+	c.Response().Header().Set("Cache-Control", "public, max-age=300")
+	if handler.Keys == nil {
+		return c.JSON(http.StatusOK, auth.JSONWebKeySet{Keys: []auth.JSONWebKey{}})
+	}
+	return c.JSON(http.StatusOK, handler.Keys.JWKS(time.Now()))
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/RedLucky/potongin/app/delivery/api/response"
+	"github.com/RedLucky/potongin/domain"
+	"github.com/labstack/echo/v4"
+)
+
+// MfaHandler represent the httphandler for two-factor authentication
+type MfaHandler struct {
+	MfaUsecase domain.MfaUsecase
+	Response   *response.JsonResponse
+}
+
+type MfaCodeParam struct {
+	Code string `json:"code" validate:"required"`
+}
+
+// NewMfaHandler will initialize the mfa/ resources endpoint
+func NewMfaHandler(routes *Routes, uc domain.MfaUsecase, response *response.JsonResponse) {
+	handler := &MfaHandler{
+		MfaUsecase: uc,
+		Response:   response,
+	}
+	e := routes.Protected
+	e.POST("/mfa", handler.Enroll)
+	e.PUT("/mfa", handler.Confirm)
+	e.DELETE("/mfa", handler.Disable)
+
+	legacy := routes.LegacyProtected
+	legacy.POST("/mfa/enroll", handler.Enroll, routes.Deprecated("/v1/mfa"))
+	legacy.POST("/mfa/confirm", handler.Confirm, routes.Deprecated("/v1/mfa"))
+	legacy.POST("/mfa/disable", handler.Disable, routes.Deprecated("/v1/mfa"))
+}
+
+// Enroll will generate the secret to register in an authenticator app
+func (handler *MfaHandler) Enroll(c echo.Context) error {
+	userId := c.Get("user_id").(int64)
+	ctx := c.Request().Context()
+
+	enrollment, err := handler.MfaUsecase.Enroll(ctx, userId)
+	if err != nil {
+		return handler.Response.Error(c, err)
+	}
+	return handler.Response.Success(c, "success", http.StatusOK, map[string]interface{}{"mfa": enrollment})
+}
+
+// Confirm will enable two-factor authentication and return the recovery codes, shown only once
+func (handler *MfaHandler) Confirm(c echo.Context) (err error) {
+	var param MfaCodeParam
+	if err = c.Bind(&param); err != nil {
+		return handler.Response.Error(c, err)
+	}
+	var ok bool
+	if ok, err = validateMfaCode(&param); !ok {
+		return handler.Response.Error(c, err)
+	}
+
+	userId := c.Get("user_id").(int64)
+	ctx := c.Request().Context()
+	recoveryCodes, err := handler.MfaUsecase.Confirm(ctx, userId, param.Code)
+	if err != nil {
+		return handler.Response.Error(c, err)
+	}
+	return handler.Response.Success(c, "success", http.StatusOK, map[string]interface{}{"recovery_codes": recoveryCodes})
+}
+
+// Disable will remove two-factor authentication
+func (handler *MfaHandler) Disable(c echo.Context) (err error) {
+	var param MfaCodeParam
+	if err = c.Bind(&param); err != nil {
+		return handler.Response.Error(c, err)
+	}
+	var ok bool
+	if ok, err = validateMfaCode(&param); !ok {
+		return handler.Response.Error(c, err)
+	}
+
+	userId := c.Get("user_id").(int64)
+	ctx := c.Request().Context()
+	err = handler.MfaUsecase.Disable(ctx, userId, param.Code)
+	if err != nil {
+		return handler.Response.Error(c, err)
+	}
+	return handler.Response.Success(c, "success", http.StatusOK, map[string]interface{}{})
+}
+
+func validateMfaCode(m *MfaCodeParam) (bool, error) {
+	err := validate.Struct(m)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
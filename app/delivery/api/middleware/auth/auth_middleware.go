@@ -1,36 +1,29 @@
 package auth
 
 import (
-	"net/http"
 	"time"
 
 	"github.com/RedLucky/potongin/app/delivery/api/auth"
 	"github.com/RedLucky/potongin/domain"
-	"github.com/gomodule/redigo/redis"
 	"github.com/labstack/echo/v4"
 	log "github.com/sirupsen/logrus"
 )
 
 type AuthMiddleware struct {
-	RedisPool *redis.Pool
+	Tokens domain.TokenStore
 }
 
 func (m *AuthMiddleware) Authentication(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		jwt := auth.ExtractToken(c.Request())
-		claims, err := auth.TokenValid(jwt, auth.AccessToken)
+		userId, sessionId, err := auth.Authenticate(c.Request().Context(), m.Tokens, jwt)
 		if err != nil {
 			makeLogEntry(c).Error(domain.ErrorAuthorization)
-			return c.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unathorized"})
-		}
-		// check to redis
-		conn := m.RedisPool.Get()
-		defer conn.Close()
-		userId, err := auth.GetTokenFromRedis(conn, claims["access_uuid"].(string))
-		if err != nil {
-			return c.JSON(http.StatusUnauthorized, map[string]interface{}{"error": "Unathorized"})
+			return domain.ErrorAuthorization
 		}
 		c.Set("user_id", userId)
+		c.Set("session_id", sessionId)
+		c.SetRequest(c.Request().WithContext(domain.NewContextWithActor(c.Request().Context(), userId)))
 		return next(c)
 	}
 }
@@ -51,8 +44,8 @@ func makeLogEntry(c echo.Context) *log.Entry {
 }
 
 // InitMiddleware initialize the middleware
-func New(redisPool *redis.Pool) *AuthMiddleware {
+func New(tokens domain.TokenStore) *AuthMiddleware {
 	return &AuthMiddleware{
-		RedisPool: redisPool,
+		Tokens: tokens,
 	}
 }
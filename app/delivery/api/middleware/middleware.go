@@ -1,14 +1,22 @@
 package middleware
 
 import (
+	"fmt"
+	"net"
 	"net/http"
+	"strings"
 	"time"
 
+	"github.com/RedLucky/potongin/domain"
+	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 	"github.com/rs/cors"
 	log "github.com/sirupsen/logrus"
 )
 
+// request ids given by the caller longer than this are replaced
+const MaxRequestIDLength = 64
+
 // GoMiddleware represent the data-struct for middleware
 type CustomMiddleware struct {
 	// another stuff , may be needed by middleware
@@ -31,6 +39,54 @@ func (m *CustomMiddleware) MiddlewareLogging(next echo.HandlerFunc) echo.Handler
 	}
 }
 
+// ClientInfo attach the ip, user agent and device of the caller to the request context,
+// with the id of the request sent back in X-Request-ID
+func (m *CustomMiddleware) ClientInfo(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		requestId := c.Request().Header.Get(echo.HeaderXRequestID)
+		if requestId == "" || len(requestId) > MaxRequestIDLength {
+			requestId = uuid.New().String()
+		}
+		c.Response().Header().Set(echo.HeaderXRequestID, requestId)
+
+		userAgent := c.Request().UserAgent()
+		device := c.Request().Header.Get("X-Device-Name")
+		if device == "" {
+			device = DeviceFromUserAgent(userAgent)
+		}
+		ctx := domain.NewContextWithClientInfo(c.Request().Context(), domain.ClientInfo{
+			IP:        c.RealIP(),
+			UserAgent: userAgent,
+			Referer:   c.Request().Referer(),
+			Device:    device,
+			RequestID: requestId,
+		})
+		c.SetRequest(c.Request().WithContext(ctx))
+		return next(c)
+	}
+}
+
+// ForwardedIPExtractor read the ip of the caller from X-Forwarded-For, only
+// trusting the proxies of the given ranges (CIDRs or single addresses) to set it
+func ForwardedIPExtractor(trustedProxies []string) (echo.IPExtractor, error) {
+	options := []echo.TrustOption{echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false)}
+	for _, proxy := range trustedProxies {
+		if !strings.Contains(proxy, "/") {
+			if ip := net.ParseIP(proxy); ip != nil && ip.To4() != nil {
+				proxy += "/32"
+			} else {
+				proxy += "/128"
+			}
+		}
+		_, ipRange, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("server.trusted_proxies: %w", err)
+		}
+		options = append(options, echo.TrustIPRange(ipRange))
+	}
+	return echo.ExtractIPFromXFFHeader(options...), nil
+}
+
 // Handle is the endpoint to get stats.
 func (s *CustomMiddleware) Handle(c echo.Context) error {
 	return c.JSON(http.StatusOK, s)
@@ -51,12 +107,29 @@ func makeLogEntry(c echo.Context) *log.Entry {
 	})
 }
 
+// DeviceFromUserAgent name the device of the client from its user agent
+func DeviceFromUserAgent(userAgent string) string {
+	switch {
+	case userAgent == "":
+		return "unknown"
+	case strings.Contains(userAgent, "iPad") || strings.Contains(userAgent, "Tablet"):
+		return "tablet"
+	case strings.Contains(userAgent, "Mobi") || strings.Contains(userAgent, "Android") || strings.Contains(userAgent, "iPhone"):
+		return "mobile"
+	case strings.Contains(userAgent, "Windows") || strings.Contains(userAgent, "Macintosh") || strings.Contains(userAgent, "Linux"):
+		return "desktop"
+	default:
+		return "other"
+	}
+}
+
 // InitMiddleware initialize the middleware
 func New() *CustomMiddleware {
 	corsMiddleware := cors.New(cors.Options{
 		AllowedOrigins: []string{"*"},
-		AllowedMethods: []string{"OPTIONS", "GET", "POST", "PUT"},
-		AllowedHeaders: []string{"Content-Type", "Authorization"},
+		AllowedMethods: []string{"OPTIONS", "GET", "POST", "PUT", "PATCH", "DELETE"},
+		AllowedHeaders: []string{"Content-Type", "Authorization", "X-Request-ID"},
+		ExposedHeaders: []string{"X-Request-ID"},
 		Debug:          true,
 	})
 	return &CustomMiddleware{
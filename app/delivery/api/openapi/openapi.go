@@ -0,0 +1,318 @@
+// Package openapi build the OpenAPI 3 document of the API from the routes the
+// handlers register, the request and response bodies being described by
+// reflecting on the Go types they are bound to.
+package openapi
+
+import (
+	_ "embed"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/RedLucky/potongin/domain"
+)
+
+const Version = "3.0.3"
+
+// DocsPage is the docs UI, it renders the document served next to it at openapi.json
+//
+//go:embed docs.html
+var DocsPage []byte
+
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Tags       []Tag               `json:"tags,omitempty"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type Tag struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem hold the operations of a path by lower case method
+type PathItem map[string]*Operation
+
+type Operation struct {
+	Tags        []string              `json:"tags,omitempty"`
+	Summary     string                `json:"summary,omitempty"`
+	OperationID string                `json:"operationId"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+	Deprecated  bool                  `json:"deprecated,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Response struct {
+	Ref         string               `json:"$ref,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Headers     map[string]Header    `json:"headers,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	Responses       map[string]*Response      `json:"responses"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+// Route describe an operation: Body is a value of the type the request is
+// bound to, Data the values of the keys of the data of the envelope
+// answered with Status. Raw replace the envelope for the routes answering
+// something else, a redirect or a download. Aliases are the deprecated
+// routes answering like this one.
+type Route struct {
+	Method     string
+	Path       string
+	Tag        string
+	Summary    string
+	Auth       bool
+	Query      []Parameter
+	Body       interface{}
+	Status     int
+	Data       map[string]interface{}
+	Paginated  bool
+	Raw        *Response
+	Deprecated bool
+	Aliases    []Alias
+}
+
+// Alias is a deprecated route of an operation
+type Alias struct {
+	Method string
+	Path   string
+}
+
+// Legacy describe a deprecated route
+func Legacy(method, path string) Alias {
+	return Alias{Method: method, Path: path}
+}
+
+// deprecationHeaders are sent by the deprecated routes
+var deprecationHeaders = map[string]Header{
+	"Deprecation": {Description: "the route is deprecated (RFC 9745)", Schema: &Schema{Type: "string"}},
+	"Sunset":      {Description: "when the route stops being served (RFC 8594)", Schema: &Schema{Type: "string"}},
+	"Link":        {Description: "the successor-version route", Schema: &Schema{Type: "string"}},
+}
+
+// Query describe an optional query parameter
+func Query(name, kind, description string) Parameter {
+	return Parameter{Name: name, In: "query", Description: description, Schema: &Schema{Type: kind}}
+}
+
+// Spec collect the routes and the schemas of the document
+type Spec struct {
+	doc     Document
+	schemas *schemas
+}
+
+func New(title, version, description string) *Spec {
+	spec := &Spec{
+		doc: Document{
+			OpenAPI: Version,
+			Info:    Info{Title: title, Version: version, Description: description},
+			Paths:   map[string]PathItem{},
+			Components: Components{
+				Schemas:   map[string]*Schema{},
+				Responses: map[string]*Response{},
+				SecuritySchemes: map[string]SecurityScheme{
+					"bearer": {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+				},
+			},
+		},
+	}
+	spec.schemas = &schemas{components: spec.doc.Components.Schemas}
+	return spec
+}
+
+// Tag describe a group of operations, the tags are listed in the order they are added
+func (spec *Spec) Tag(name, description string) {
+	spec.doc.Tags = append(spec.doc.Tags, Tag{Name: name, Description: description})
+}
+
+// Envelope document the response envelope, and the error codes answered in it
+func (spec *Spec) Envelope(envelope interface{}, errors []*domain.Error) {
+	spec.schemas.of(envelope)
+
+	sorted := append([]*domain.Error(nil), errors...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Code < sorted[j].Code })
+	codes := make([]interface{}, 0, len(sorted))
+	lines := []string{"| error_code | status | message |", "|---|---|---|"}
+	for _, err := range sorted {
+		codes = append(codes, err.Code)
+		lines = append(lines, "| `"+err.Code+"` | "+strconv.Itoa(err.Status)+" | "+err.Message+" |")
+	}
+	spec.doc.Components.Schemas["ErrorCode"] = &Schema{
+		Type:        "string",
+		Description: "stable machine-readable name of the error, the errors raised by the router itself are named after their HTTP status",
+		Enum:        codes,
+	}
+	spec.doc.Components.Responses["Error"] = &Response{
+		Description: "error envelope: `error_code` name the error\n\n" + strings.Join(lines, "\n"),
+		Content: JSON(&Schema{AllOf: []*Schema{ref("Envelope"), {
+			Type:       "object",
+			Properties: map[string]*Schema{"error_code": ref("ErrorCode")},
+		}}}),
+	}
+}
+
+// Add document a route, and its aliases
+func (spec *Spec) Add(route Route) {
+	spec.add(route.Method, route.Path, route, route.Deprecated)
+	for _, alias := range route.Aliases {
+		spec.add(alias.Method, alias.Path, route, true)
+	}
+}
+
+func (spec *Spec) add(method, echoPath string, route Route, deprecated bool) {
+	path, params := Path(echoPath)
+	method = strings.ToLower(method)
+	op := &Operation{
+		Summary:     route.Summary,
+		OperationID: method + operationName(echoPath),
+		Parameters:  append(params, route.Query...),
+		Responses:   map[string]*Response{"default": {Ref: "#/components/responses/Error"}},
+		Deprecated:  deprecated,
+	}
+	if route.Tag != "" {
+		op.Tags = []string{route.Tag}
+	}
+	if route.Auth {
+		op.Security = []map[string][]string{{"bearer": {}}}
+	}
+	if route.Body != nil {
+		op.RequestBody = &RequestBody{Required: true, Content: JSON(spec.schemas.of(route.Body))}
+	}
+
+	status := route.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := &Response{Description: http.StatusText(status)}
+	if route.Raw != nil {
+		*success = *route.Raw
+	} else {
+		success.Content = JSON(spec.envelopeOf(route.Data, route.Paginated))
+	}
+	if deprecated {
+		headers := map[string]Header{}
+		for name, header := range success.Headers {
+			headers[name] = header
+		}
+		for name, header := range deprecationHeaders {
+			headers[name] = header
+		}
+		success.Headers = headers
+	}
+	op.Responses[strconv.Itoa(status)] = success
+
+	if spec.doc.Paths[path] == nil {
+		spec.doc.Paths[path] = PathItem{}
+	}
+	spec.doc.Paths[path][method] = op
+}
+
+// Schema describe the type of value, registering the named structs in the components
+func (spec *Spec) Schema(value interface{}) *Schema {
+	return spec.schemas.of(value)
+}
+
+// Document return the document of the routes added
+func (spec *Spec) Document() *Document {
+	return &spec.doc
+}
+
+// Operation find the operation documenting the echo route, nil if it is not documented
+func (doc *Document) Operation(method, echoPath string) *Operation {
+	path, _ := Path(echoPath)
+	return doc.Paths[path][strings.ToLower(method)]
+}
+
+// envelopeOf describe the envelope answered with the data
+func (spec *Spec) envelopeOf(data map[string]interface{}, paginated bool) *Schema {
+	properties := map[string]*Schema{}
+	for key, value := range data {
+		properties[key] = spec.schemas.of(value)
+	}
+	content := &Schema{
+		Type:       "object",
+		Properties: map[string]*Schema{"data": {Type: "object", Properties: properties}},
+	}
+	if paginated {
+		content.Required = []string{"meta"}
+	}
+	return &Schema{AllOf: []*Schema{ref("Envelope"), content}}
+}
+
+// Path convert an echo path to an OpenAPI one, with the parameters of the path
+func Path(echoPath string) (string, []Parameter) {
+	segments := strings.Split(echoPath, "/")
+	var params []Parameter
+	for i, segment := range segments {
+		if !strings.HasPrefix(segment, ":") {
+			continue
+		}
+		name := segment[1:]
+		schema := &Schema{Type: "string"}
+		if name == "id" || strings.HasSuffix(name, "_id") {
+			schema = &Schema{Type: "integer", Format: "int64"}
+		}
+		params = append(params, Parameter{Name: name, In: "path", Required: true, Schema: schema})
+		segments[i] = "{" + name + "}"
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// operationName turn /orgs/:org_id/members into OrgsOrgIdMembers
+func operationName(echoPath string) string {
+	var name strings.Builder
+	for _, word := range strings.FieldsFunc(echoPath, func(r rune) bool {
+		return r == '/' || r == ':' || r == '_' || r == '.' || r == '-'
+	}) {
+		name.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	return name.String()
+}
+
+// JSON is the content of a JSON body described by schema
+func JSON(schema *Schema) map[string]MediaType {
+	return map[string]MediaType{"application/json": {Schema: schema}}
+}
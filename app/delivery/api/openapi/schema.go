@@ -0,0 +1,189 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schema is the subset of the JSON schema of OpenAPI 3.0 the document use
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	AllOf                []*Schema          `json:"allOf,omitempty"`
+}
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	rawJSONType = reflect.TypeOf(json.RawMessage{})
+	schemaType  = reflect.TypeOf(&Schema{})
+)
+
+// schemas describe the Go types, the named structs are added to the
+// components once and referenced
+type schemas struct {
+	components map[string]*Schema
+}
+
+// of describe the type of value, a *Schema is returned as is
+func (s *schemas) of(value interface{}) *Schema {
+	if schema, ok := value.(*Schema); ok {
+		return schema
+	}
+	return s.ofType(reflect.TypeOf(value))
+}
+
+func (s *schemas) ofType(t reflect.Type) *Schema {
+	switch t {
+	case schemaType:
+		return &Schema{}
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case rawJSONType:
+		return &Schema{Description: "any JSON value"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		schema := s.ofType(t.Elem())
+		if schema.Ref != "" {
+			return schema
+		}
+		schema.Nullable = true
+		return schema
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: s.ofType(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.ofType(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.ofStruct(t)
+		}
+		name := strings.ToUpper(t.Name()[:1]) + t.Name()[1:]
+		if _, ok := s.components[name]; !ok {
+			// registered before it is described, for the recursive types
+			s.components[name] = &Schema{}
+			*s.components[name] = *s.ofStruct(t)
+		}
+		return ref(name)
+	default:
+		// interface{}: any value
+		return &Schema{}
+	}
+}
+
+func (s *schemas) ofStruct(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	s.addFields(schema, t)
+	return schema
+}
+
+func (s *schemas) addFields(schema *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := strings.Split(field.Tag.Get("json"), ",")
+		name := tag[0]
+		if name == "-" || (field.PkgPath != "" && !field.Anonymous) {
+			continue
+		}
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				s.addFields(schema, embedded)
+				continue
+			}
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property := s.ofType(field.Type)
+		if property.Ref == "" {
+			required := validateRules(property, field.Tag.Get("validate"))
+			if required {
+				schema.Required = append(schema.Required, name)
+			}
+		} else if hasRule(field.Tag.Get("validate"), "required") {
+			schema.Required = append(schema.Required, name)
+		}
+		schema.Properties[name] = property
+	}
+}
+
+// validateRules describe the validate tag of a field on its schema, it tells if the field is required
+func validateRules(schema *Schema, tag string) (required bool) {
+	for _, rule := range strings.Split(tag, ",") {
+		name, param := rule, ""
+		if i := strings.Index(rule, "="); i >= 0 {
+			name, param = rule[:i], rule[i+1:]
+		}
+		switch name {
+		case "required":
+			required = true
+		case "email":
+			schema.Format = "email"
+		case "url":
+			schema.Format = "uri"
+		case "oneof":
+			for _, value := range strings.Fields(param) {
+				schema.Enum = append(schema.Enum, value)
+			}
+		case "min", "max", "len":
+			if schema.Type != "string" {
+				continue
+			}
+			length, err := strconv.Atoi(param)
+			if err != nil {
+				continue
+			}
+			if name != "max" {
+				schema.MinLength = &length
+			}
+			if name != "min" {
+				schema.MaxLength = &length
+			}
+		}
+	}
+	return
+}
+
+func hasRule(tag, rule string) bool {
+	for _, r := range strings.Split(tag, ",") {
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}
+
+func ref(name string) *Schema {
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
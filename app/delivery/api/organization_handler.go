@@ -0,0 +1,191 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/RedLucky/potongin/app/delivery/api/response"
+	"github.com/RedLucky/potongin/domain"
+	"github.com/labstack/echo/v4"
+)
+
+// OrganizationHandler represent the httphandler for organizations and their members
+type OrganizationHandler struct {
+	OrganizationUsecase domain.OrganizationUsecase
+	Response            *response.JsonResponse
+}
+
+// NewOrganizationHandler will initialize the orgs/ resources endpoint
+func NewOrganizationHandler(routes *Routes, uc domain.OrganizationUsecase, response *response.JsonResponse) {
+	handler := &OrganizationHandler{
+		OrganizationUsecase: uc,
+		Response:            response,
+	}
+	e := routes.Protected
+	e.POST("/orgs", handler.Create)
+	e.GET("/orgs", handler.Fetch)
+	e.GET("/orgs/:org_id/members", handler.GetMembers)
+	e.PUT("/orgs/:org_id/members/:user_id", handler.UpdateMemberRole)
+	e.DELETE("/orgs/:org_id/members/:user_id", handler.RemoveMember)
+	e.POST("/orgs/:org_id/invitations", handler.Invite)
+	e.POST("/invitations/accept", handler.AcceptInvitation)
+
+	legacy := routes.LegacyProtected
+	legacy.POST("/orgs", handler.Create, routes.Deprecated("/v1/orgs"))
+	legacy.GET("/orgs", handler.Fetch, routes.Deprecated("/v1/orgs"))
+	legacy.GET("/orgs/:org_id/members", handler.GetMembers, routes.Deprecated("/v1/orgs/:org_id/members"))
+	legacy.PUT("/orgs/:org_id/members/:user_id", handler.UpdateMemberRole, routes.Deprecated("/v1/orgs/:org_id/members/:user_id"))
+	legacy.DELETE("/orgs/:org_id/members/:user_id", handler.RemoveMember, routes.Deprecated("/v1/orgs/:org_id/members/:user_id"))
+	legacy.POST("/orgs/:org_id/invitations", handler.Invite, routes.Deprecated("/v1/orgs/:org_id/invitations"))
+	legacy.POST("/invitations/accept", handler.AcceptInvitation, routes.Deprecated("/v1/invitations/accept"))
+}
+
+// Create will create an organization owned by the current user
+func (handler *OrganizationHandler) Create(c echo.Context) (err error) {
+	var org domain.Organization
+	if err = c.Bind(&org); err != nil {
+		return handler.Response.Error(c, err)
+	}
+	var ok bool
+	if ok, err = validateOrganizationParam(&org); !ok {
+		return handler.Response.Error(c, err)
+	}
+
+	userId := c.Get("user_id").(int64)
+	ctx := c.Request().Context()
+	if err = handler.OrganizationUsecase.Create(ctx, userId, &org); err != nil {
+		return handler.Response.Error(c, err)
+	}
+	return handler.Response.Success(c, "success", http.StatusCreated, map[string]interface{}{"organization": org})
+}
+
+// Fetch will list the organizations of the current user with its role
+func (handler *OrganizationHandler) Fetch(c echo.Context) error {
+	userId := c.Get("user_id").(int64)
+	ctx := c.Request().Context()
+
+	orgs, err := handler.OrganizationUsecase.Fetch(ctx, userId)
+	if err != nil {
+		return handler.Response.Error(c, err)
+	}
+	return handler.Response.Success(c, "success", http.StatusOK, map[string]interface{}{"organizations": orgs})
+}
+
+func (handler *OrganizationHandler) GetMembers(c echo.Context) error {
+	orgId, err := strconv.ParseInt(c.Param("org_id"), 10, 64)
+	if err != nil {
+		return handler.Response.Error(c, domain.ErrBadParamInput)
+	}
+
+	userId := c.Get("user_id").(int64)
+	ctx := c.Request().Context()
+	members, err := handler.OrganizationUsecase.GetMembers(ctx, userId, orgId)
+	if err != nil {
+		return handler.Response.Error(c, err)
+	}
+	return handler.Response.Success(c, "success", http.StatusOK, map[string]interface{}{"members": members})
+}
+
+// Invite will send an invitation to join the organization to the email
+func (handler *OrganizationHandler) Invite(c echo.Context) (err error) {
+	orgId, err := strconv.ParseInt(c.Param("org_id"), 10, 64)
+	if err != nil {
+		return handler.Response.Error(c, domain.ErrBadParamInput)
+	}
+	var param domain.InviteMember
+	if err = c.Bind(&param); err != nil {
+		return handler.Response.Error(c, err)
+	}
+	var ok bool
+	if ok, err = validateOrganizationParam(&param); !ok {
+		return handler.Response.Error(c, err)
+	}
+
+	userId := c.Get("user_id").(int64)
+	ctx := c.Request().Context()
+	_, err = handler.OrganizationUsecase.Invite(ctx, userId, orgId, param.Email, param.Role)
+	if err != nil {
+		return handler.Response.Error(c, err)
+	}
+	return handler.Response.Success(c, "invitation sent", http.StatusAccepted, map[string]interface{}{})
+}
+
+// AcceptInvitation will add the current user to the organization of the invitation
+func (handler *OrganizationHandler) AcceptInvitation(c echo.Context) (err error) {
+	var param TokenParam
+	if err = c.Bind(&param); err != nil {
+		return handler.Response.Error(c, err)
+	}
+	var ok bool
+	if ok, err = validateOrganizationParam(&param); !ok {
+		return handler.Response.Error(c, err)
+	}
+
+	userId := c.Get("user_id").(int64)
+	ctx := c.Request().Context()
+	member, err := handler.OrganizationUsecase.AcceptInvitation(ctx, userId, param.Token)
+	if err != nil {
+		return handler.Response.Error(c, err)
+	}
+	return handler.Response.Success(c, "success", http.StatusOK, map[string]interface{}{"member": member})
+}
+
+func (handler *OrganizationHandler) UpdateMemberRole(c echo.Context) (err error) {
+	orgId, memberId, err := memberParams(c)
+	if err != nil {
+		return handler.Response.Error(c, err)
+	}
+	var param domain.UpdateMemberRole
+	if err = c.Bind(&param); err != nil {
+		return handler.Response.Error(c, err)
+	}
+	var ok bool
+	if ok, err = validateOrganizationParam(&param); !ok {
+		return handler.Response.Error(c, err)
+	}
+
+	userId := c.Get("user_id").(int64)
+	ctx := c.Request().Context()
+	err = handler.OrganizationUsecase.UpdateMemberRole(ctx, userId, orgId, memberId, param.Role)
+	if err != nil {
+		return handler.Response.Error(c, err)
+	}
+	return handler.Response.Success(c, "success", http.StatusOK, map[string]interface{}{})
+}
+
+// RemoveMember will remove the member, the current user leave the
+// organization when it is itself
+func (handler *OrganizationHandler) RemoveMember(c echo.Context) error {
+	orgId, memberId, err := memberParams(c)
+	if err != nil {
+		return handler.Response.Error(c, err)
+	}
+
+	userId := c.Get("user_id").(int64)
+	ctx := c.Request().Context()
+	err = handler.OrganizationUsecase.RemoveMember(ctx, userId, orgId, memberId)
+	if err != nil {
+		return handler.Response.Error(c, err)
+	}
+	return handler.Response.Success(c, "success", http.StatusOK, map[string]interface{}{})
+}
+
+func memberParams(c echo.Context) (orgId, memberId int64, err error) {
+	orgId, err = strconv.ParseInt(c.Param("org_id"), 10, 64)
+	if err != nil {
+		return 0, 0, domain.ErrBadParamInput
+	}
+	memberId, err = strconv.ParseInt(c.Param("user_id"), 10, 64)
+	if err != nil {
+		return 0, 0, domain.ErrBadParamInput
+	}
+	return
+}
+
+func validateOrganizationParam(m interface{}) (bool, error) {
+	err := validate.Struct(m)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/RedLucky/potongin/app/delivery/api/response"
+	"github.com/RedLucky/potongin/domain"
+	"github.com/labstack/echo/v4"
+)
+
+// PrivacyHandler represent the httphandler for the personal data of the logged in user
+type PrivacyHandler struct {
+	PrivacyUsecase domain.PrivacyUsecase
+	Response       *response.JsonResponse
+}
+
+// NewPrivacyHandler will initialize the me/export resources endpoint
+func NewPrivacyHandler(routes *Routes, uc domain.PrivacyUsecase, response *response.JsonResponse) {
+	handler := &PrivacyHandler{
+		PrivacyUsecase: uc,
+		Response:       response,
+	}
+	routes.Protected.GET("/me/export", handler.Export)
+	routes.LegacyProtected.GET("/me/export", handler.Export, routes.Deprecated("/v1/me/export"))
+}
+
+// Export will download every personal data of the current user, as a single
+// JSON document or, with ?format=zip, as a ZIP archive of one file per part
+func (handler *PrivacyHandler) Export(c echo.Context) error {
+	format := c.QueryParam("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "zip" {
+		return handler.Response.Error(c, domain.ErrBadParamInput)
+	}
+
+	userId := c.Get("user_id").(int64)
+	ctx := c.Request().Context()
+	export, err := handler.PrivacyUsecase.Export(ctx, userId)
+	if err != nil {
+		return handler.Response.Error(c, err)
+	}
+
+	filename := fmt.Sprintf("potongin-export-%d-%s", userId, export.ExportedAt.Format("20060102"))
+	if format == "json" {
+		c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s.json"`, filename))
+		return c.JSON(http.StatusOK, export)
+	}
+
+	archive, err := zipExport(export)
+	if err != nil {
+		return handler.Response.Error(c, domain.ErrInternalServerError)
+	}
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s.zip"`, filename))
+	return c.Blob(http.StatusOK, "application/zip", archive)
+}
+
+func zipExport(export domain.DataExport) ([]byte, error) {
+	parts := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", export.Profile},
+		{"identities.json", export.Identities},
+		{"links.json", export.Links},
+		{"clicks.json", export.Clicks},
+		{"sessions.json", export.Sessions},
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for _, part := range parts {
+		file, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     part.name,
+			Method:   zip.Deflate,
+			Modified: export.ExportedAt,
+		})
+		if err != nil {
+			return nil, err
+		}
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		if err = encoder.Encode(part.data); err != nil {
+			return nil, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -1,58 +1,184 @@
 package response
 
 import (
+	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/RedLucky/potongin/domain"
+	"github.com/go-playground/validator"
 	"github.com/labstack/echo/v4"
 	"github.com/sirupsen/logrus"
 )
 
-type JsonResponse struct {
-	Message string                 `json:"message"`
-	Code    int                    `json:"code"`
-	Data    map[string]interface{} `json:"data"`
+// JsonResponse write the JSON envelope of the responses. It holds no state,
+// every call builds its own Envelope, so one JsonResponse serves all requests.
+type JsonResponse struct{}
+
+// Envelope is the body of every JSON response. RequestID echoes X-Request-ID,
+// Meta describe the page of a listing, ErrorCode, Fields and Details are only
+// set on errors.
+type Envelope struct {
+	Message   string                 `json:"message"`
+	Code      int                    `json:"code"`
+	RequestID string                 `json:"request_id,omitempty"`
+	ErrorCode string                 `json:"error_code,omitempty"`
+	Fields    []FieldError           `json:"fields,omitempty"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+	Meta      *Meta                  `json:"meta,omitempty"`
+	Data      map[string]interface{} `json:"data"`
+}
+
+// Meta describe the page of a listing: Count items from Offset, Limit being
+// the page size asked for, 0 for the default one
+type Meta struct {
+	Limit  int `json:"limit"`
+	Offset int `json:"offset"`
+	Count  int `json:"count"`
+}
+
+// FieldError describe a field of the request that failed its validation
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
 }
 
+var (
+	// ErrValidation is answered for the requests failing their validation, with the failed fields
+	ErrValidation = domain.NewError("validation_failed", http.StatusUnprocessableEntity, "given Param is not valid")
+	// ErrPasswordPolicy is answered for a domain.PasswordPolicyError, with its message
+	ErrPasswordPolicy = domain.NewError("password_policy", http.StatusUnprocessableEntity, "password is not valid")
+	// ErrLinkUnavailable is answered for a domain.UnavailableLinkError, with its message
+	ErrLinkUnavailable = domain.NewError("link_unavailable", http.StatusGone, "link is not available")
+)
+
 func New() *JsonResponse {
 	return &JsonResponse{}
 }
 
 func (response *JsonResponse) Success(ctx echo.Context, message string, status_code int, data map[string]interface{}) error {
-	response.Message = message
-	response.Code = status_code
-	response.Data = data
+	return ctx.JSON(status_code, newEnvelope(ctx, message, status_code, data))
+}
 
-	return ctx.JSON(response.Code, response)
+// Paginated answer a page of a listing with its meta
+func (response *JsonResponse) Paginated(ctx echo.Context, message string, status_code int, data map[string]interface{}, meta Meta) error {
+	envelope := newEnvelope(ctx, message, status_code, data)
+	envelope.Meta = &meta
+	return ctx.JSON(status_code, envelope)
 }
 
 func (response *JsonResponse) Error(ctx echo.Context, err error) error {
-	response.Message = err.Error()
-	response.Code = getStatusCode(err)
-	response.Data = nil
-
-	return ctx.JSON(response.Code, response)
-}
-func getStatusCode(err error) int {
-	if err == nil {
-		return http.StatusOK
-	}
-
-	logrus.Error(err)
-	switch err {
-	case domain.ErrInternalServerError:
-		return http.StatusInternalServerError
-	case domain.ErrNotFound:
-		return http.StatusNotFound
-	case domain.ErrConflict:
-		return http.StatusConflict
-	case domain.ErrorAuthorization:
-		return http.StatusUnauthorized
-	case domain.ErrPassword:
-		return http.StatusUnauthorized
-	case domain.ErrEmailNotFound:
-		return http.StatusNotFound
+	domainErr := DomainError(err)
+	envelope := newEnvelope(ctx, domainErr.Message, domainErr.Status, nil)
+	envelope.ErrorCode = domainErr.Code
+
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		envelope.Fields = fieldErrors(validationErrs)
+	}
+	// name every rule the password failed, so clients can show them all
+	var policyErr *domain.PasswordPolicyError
+	if errors.As(err, &policyErr) {
+		envelope.Details = map[string]interface{}{"violations": policyErr.Violations}
+	}
+	// the reason is shown on the redirect page
+	var unavailableErr *domain.UnavailableLinkError
+	if errors.As(err, &unavailableErr) {
+		envelope.Details = map[string]interface{}{"reason": unavailableErr.Reason}
+	}
+
+	return ctx.JSON(envelope.Code, envelope)
+}
+
+func newEnvelope(ctx echo.Context, message string, status_code int, data map[string]interface{}) *Envelope {
+	return &Envelope{
+		Message:   message,
+		Code:      status_code,
+		RequestID: domain.ClientInfoFromContext(ctx.Request().Context()).RequestID,
+		Data:      data,
+	}
+}
+
+// HTTPErrorHandler answer the errors returned by the handlers and the
+// middlewares, and the unknown routes, with the error envelope
+func HTTPErrorHandler(err error, ctx echo.Context) {
+	if ctx.Response().Committed {
+		return
+	}
+	if err = New().Error(ctx, err); err != nil {
+		logrus.Error(err)
+	}
+}
+
+// DomainError find the domain error answered for err, the errors unknown to
+// the domain are internal errors and their message is not shown
+func DomainError(err error) *domain.Error {
+	var domainErr *domain.Error
+	var policyErr *domain.PasswordPolicyError
+	var unavailableErr *domain.UnavailableLinkError
+	var validationErrs validator.ValidationErrors
+	var httpErr *echo.HTTPError
+
+	switch {
+	case errors.As(err, &domainErr):
+		if domainErr.Status >= http.StatusInternalServerError {
+			logrus.Error(err)
+		}
+		return domainErr
+	case errors.As(err, &policyErr):
+		return &domain.Error{Code: ErrPasswordPolicy.Code, Status: ErrPasswordPolicy.Status, Message: policyErr.Error()}
+	case errors.As(err, &unavailableErr):
+		return &domain.Error{Code: ErrLinkUnavailable.Code, Status: ErrLinkUnavailable.Status, Message: unavailableErr.Error()}
+	case errors.As(err, &validationErrs):
+		return ErrValidation
+	case errors.As(err, &httpErr):
+		// raised by echo itself: unknown route, bad binding, ...
+		message, ok := httpErr.Message.(string)
+		if !ok {
+			message = http.StatusText(httpErr.Code)
+		}
+		return &domain.Error{Code: statusCode(httpErr.Code), Status: httpErr.Code, Message: message}
+	default:
+		logrus.Error(err)
+		return domain.ErrInternalServerError
+	}
+}
+
+// statusCode name an HTTP status in the error_code style, e.g. "method_not_allowed"
+func statusCode(status int) string {
+	return strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+}
+
+func fieldErrors(validationErrs validator.ValidationErrors) []FieldError {
+	fields := make([]FieldError, 0, len(validationErrs))
+	for _, fieldErr := range validationErrs {
+		fields = append(fields, FieldError{
+			Field:   fieldErr.Field(),
+			Rule:    fieldErr.Tag(),
+			Message: fieldMessage(fieldErr),
+		})
+	}
+	return fields
+}
+
+func fieldMessage(fieldErr validator.FieldError) string {
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email"
+	case "url":
+		return "must be a valid url"
+	case "min":
+		return "must be at least " + fieldErr.Param()
+	case "max":
+		return "must be at most " + fieldErr.Param()
+	case "len":
+		return "must be exactly " + fieldErr.Param()
+	case "oneof":
+		return "must be one of " + fieldErr.Param()
 	default:
-		return http.StatusInternalServerError
+		return "is not valid"
 	}
 }
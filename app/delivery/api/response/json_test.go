@@ -0,0 +1,214 @@
+package response_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/RedLucky/potongin/app/delivery/api"
+	"github.com/RedLucky/potongin/app/delivery/api/response"
+	"github.com/RedLucky/potongin/domain"
+	"github.com/go-playground/validator"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newContext() (echo.Context, *httptest.ResponseRecorder) {
+	recorder := httptest.NewRecorder()
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	return echo.New().NewContext(request, recorder), recorder
+}
+
+func decode(t *testing.T, recorder *httptest.ResponseRecorder) response.Envelope {
+	var body response.Envelope
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+	return body
+}
+
+func TestJsonResponse_Error(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		status    int
+		errorCode string
+		message   string
+	}{
+		{"url-not-found", domain.ErrUrlNotFound, http.StatusNotFound, "url_not_found", "url not found"},
+		{"email-exist", domain.ErrEmailExist, http.StatusConflict, "email_exist", "email already exist"},
+		{"name-exist", domain.ErrNameIsExist, http.StatusConflict, "name_exist", "name is exist"},
+		{"email-not-verified", domain.ErrorEmailNotVerified, http.StatusForbidden, "email_not_verified", "email not verified"},
+		{"wrapped", fmt.Errorf("deleting account: %w", domain.ErrLastOwner), http.StatusConflict, "last_owner", domain.ErrLastOwner.Message},
+		{"unknown-error-is-hidden", errors.New("dial tcp 10.0.0.3:3306: connection refused"), http.StatusInternalServerError, "internal_error", "internal Server Error"},
+		{"echo-error", echo.NewHTTPError(http.StatusUnsupportedMediaType), http.StatusUnsupportedMediaType, "unsupported_media_type", "Unsupported Media Type"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, recorder := newContext()
+			require.NoError(t, response.New().Error(ctx, test.err))
+
+			body := decode(t, recorder)
+			assert.Equal(t, test.status, recorder.Code)
+			assert.Equal(t, test.status, body.Code)
+			assert.Equal(t, test.errorCode, body.ErrorCode)
+			assert.Equal(t, test.message, body.Message)
+		})
+	}
+}
+
+func TestJsonResponse_ErrorFields(t *testing.T) {
+	type signup struct {
+		Email    string `json:"email" validate:"required,email"`
+		Password string `json:"password" validate:"required,min=8"`
+	}
+	validate := validator.New()
+	err := validate.Struct(&signup{Email: "lucky", Password: "short"})
+	require.Error(t, err)
+
+	ctx, recorder := newContext()
+	require.NoError(t, response.New().Error(ctx, err))
+
+	body := decode(t, recorder)
+	assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+	assert.Equal(t, "validation_failed", body.ErrorCode)
+	assert.Equal(t, []response.FieldError{
+		{Field: "Email", Rule: "email", Message: "must be a valid email"},
+		{Field: "Password", Rule: "min", Message: "must be at least 8"},
+	}, body.Fields)
+}
+
+func TestJsonResponse_ErrorPasswordPolicy(t *testing.T) {
+	err := &domain.PasswordPolicyError{Violations: []domain.PolicyViolation{{Rule: "min_length", Message: "must be at least 8 characters"}}}
+
+	ctx, recorder := newContext()
+	require.NoError(t, response.New().Error(ctx, err))
+
+	body := decode(t, recorder)
+	assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+	assert.Equal(t, "password_policy", body.ErrorCode)
+	assert.Contains(t, body.Details, "violations")
+}
+
+func TestJsonResponse_Paginated(t *testing.T) {
+	ctx, recorder := newContext()
+	request := ctx.Request()
+	ctx.SetRequest(request.WithContext(domain.NewContextWithClientInfo(request.Context(), domain.ClientInfo{RequestID: "req-1"})))
+
+	meta := response.Meta{Limit: 2, Offset: 4, Count: 2}
+	require.NoError(t, response.New().Paginated(ctx, "success", http.StatusOK, map[string]interface{}{"users": []int{5, 6}}, meta))
+
+	body := decode(t, recorder)
+	assert.Equal(t, "req-1", body.RequestID)
+	assert.Equal(t, &meta, body.Meta)
+	assert.Empty(t, body.ErrorCode)
+}
+
+// fakeUsers answer every id with a user of its own
+type fakeUsers struct {
+	domain.UserUsecase
+}
+
+func (f *fakeUsers) GetByID(ctx context.Context, id int64) (domain.User, error) {
+	if id%3 == 0 {
+		return domain.User{}, domain.ErrNotFound
+	}
+	return domain.User{ID: id, Username: "user-" + strconv.FormatInt(id, 10)}, nil
+}
+
+// TestJsonResponse_Parallel share one JsonResponse and the user handler
+// between concurrent requests of different users, as the server does, run it
+// with -race
+func TestJsonResponse_Parallel(t *testing.T) {
+	handler := &api.UserHandler{UserUsecase: &fakeUsers{}, Response: response.New()}
+	e := echo.New()
+	// the caller of /me is the user of the X-User-Id header
+	e.GET("/me", handler.Me, func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			userId, _ := strconv.ParseInt(c.Request().Header.Get("X-User-Id"), 10, 64)
+			c.Set("user_id", userId)
+			return next(c)
+		}
+	})
+	e.GET("/users/:id", handler.GetByID)
+	withRequestId := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			request := c.Request()
+			info := domain.ClientInfo{RequestID: request.Header.Get(echo.HeaderXRequestID)}
+			c.SetRequest(request.WithContext(domain.NewContextWithClientInfo(request.Context(), info)))
+			return next(c)
+		}
+	}
+	e.Use(withRequestId)
+
+	var wg sync.WaitGroup
+	for i := 1; i <= 300; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			target := "/users/" + strconv.Itoa(id)
+			if id%2 == 0 {
+				target = "/me"
+			}
+			request := httptest.NewRequest(http.MethodGet, target, nil)
+			request.Header.Set("X-User-Id", strconv.Itoa(id))
+			request.Header.Set(echo.HeaderXRequestID, "req-"+strconv.Itoa(id))
+			recorder := httptest.NewRecorder()
+			e.ServeHTTP(recorder, request)
+
+			var body struct {
+				response.Envelope
+				Data struct {
+					User map[string]interface{} `json:"user"`
+				} `json:"data"`
+			}
+			if !assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body)) {
+				return
+			}
+			assert.Equal(t, "req-"+strconv.Itoa(id), body.RequestID)
+			if id%3 == 0 {
+				assert.Equal(t, http.StatusNotFound, recorder.Code)
+				assert.Equal(t, "not_found", body.ErrorCode)
+				assert.Nil(t, body.Data.User)
+				return
+			}
+			assert.Equal(t, http.StatusOK, recorder.Code)
+			assert.Empty(t, body.ErrorCode)
+			// every caller gets its own user
+			assert.Equal(t, float64(id), body.Data.User["id"])
+			assert.Equal(t, "user-"+strconv.Itoa(id), body.Data.User["username"])
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestHTTPErrorHandler(t *testing.T) {
+	e := echo.New()
+	e.HTTPErrorHandler = response.HTTPErrorHandler
+	e.POST("/createVerifyEmail", func(c echo.Context) error {
+		return domain.ErrBadParamInput
+	})
+
+	t.Run("returned-error", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/createVerifyEmail", nil))
+
+		body := decode(t, recorder)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Equal(t, "bad_param_input", body.ErrorCode)
+	})
+
+	t.Run("unknown-route", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/nowhere", nil))
+
+		body := decode(t, recorder)
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+		assert.Equal(t, "not_found", body.ErrorCode)
+	})
+}
@@ -0,0 +1,69 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Routes are the groups the handlers register their routes on: the /v1
+// resources, public or behind the authentication, and the legacy routes they
+// replace, still served until the sunset with the deprecation headers
+type Routes struct {
+	Public          *echo.Group
+	Protected       *echo.Group
+	LegacyPublic    *echo.Group
+	LegacyProtected *echo.Group
+	// DeprecatedAt and Sunset are announced by the legacy routes, when set
+	DeprecatedAt time.Time
+	Sunset       time.Time
+}
+
+// NewRoutes create the groups of the routes, authentication guarding the protected ones
+func NewRoutes(e *echo.Echo, authentication echo.MiddlewareFunc, deprecatedAt, sunset time.Time) *Routes {
+	return &Routes{
+		Public:          e.Group("/v1"),
+		Protected:       e.Group("/v1", authentication),
+		LegacyPublic:    e.Group(""),
+		LegacyProtected: e.Group("", authentication),
+		DeprecatedAt:    deprecatedAt,
+		Sunset:          sunset,
+	}
+}
+
+// Deprecated mark a legacy route replaced by successor, a /v1 path whose
+// parameters are filled from the request, an empty successor for the routes
+// without any. It sets the Deprecation (RFC 9745), Sunset (RFC 8594) and Link headers.
+func (routes *Routes) Deprecated(successor string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			header := c.Response().Header()
+			if routes.DeprecatedAt.IsZero() {
+				header.Set("Deprecation", "true")
+			} else {
+				header.Set("Deprecation", "@"+strconv.FormatInt(routes.DeprecatedAt.Unix(), 10))
+			}
+			if !routes.Sunset.IsZero() {
+				header.Set("Sunset", routes.Sunset.UTC().Format(http.TimeFormat))
+			}
+			if successor != "" {
+				header.Set("Link", "<"+successorPath(c, successor)+`>; rel="successor-version"`)
+			}
+			return next(c)
+		}
+	}
+}
+
+// successorPath fill the parameters of the successor path with the ones of the request
+func successorPath(c echo.Context, successor string) string {
+	segments := strings.Split(successor, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") {
+			segments[i] = c.Param(segment[1:])
+		}
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/RedLucky/potongin/app/delivery/api/response"
+	"github.com/RedLucky/potongin/domain"
+	"github.com/labstack/echo/v4"
+)
+
+// SessionHandler represent the httphandler for login sessions
+type SessionHandler struct {
+	SessionUsecase domain.SessionUsecase
+	Response       *response.JsonResponse
+}
+
+// NewSessionHandler will initialize the sessions/ resources endpoint
+func NewSessionHandler(routes *Routes, uc domain.SessionUsecase, response *response.JsonResponse) {
+	handler := &SessionHandler{
+		SessionUsecase: uc,
+		Response:       response,
+	}
+	e := routes.Protected
+	e.GET("/sessions", handler.FetchSession)
+	e.DELETE("/sessions", handler.RevokeAll)
+	e.DELETE("/sessions/:id", handler.Revoke)
+
+	legacy := routes.LegacyProtected
+	legacy.GET("/sessions", handler.FetchSession, routes.Deprecated("/v1/sessions"))
+	legacy.DELETE("/sessions", handler.RevokeAll, routes.Deprecated("/v1/sessions"))
+	legacy.DELETE("/sessions/:id", handler.Revoke, routes.Deprecated("/v1/sessions/:id"))
+}
+
+// FetchSession will list every active login of the current user
+func (handler *SessionHandler) FetchSession(c echo.Context) error {
+	userId := c.Get("user_id").(int64)
+	currentSession, _ := c.Get("session_id").(string)
+	ctx := c.Request().Context()
+
+	sessions, err := handler.SessionUsecase.Fetch(ctx, userId)
+	if err != nil {
+		return handler.Response.Error(c, err)
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentSession
+	}
+	return handler.Response.Success(c, "success", http.StatusOK, map[string]interface{}{"sessions": sessions})
+}
+
+// Revoke will log out the given session and invalidate its tokens
+func (handler *SessionHandler) Revoke(c echo.Context) error {
+	userId := c.Get("user_id").(int64)
+	ctx := c.Request().Context()
+
+	err := handler.SessionUsecase.Revoke(ctx, userId, c.Param("id"))
+	if err != nil {
+		return handler.Response.Error(c, err)
+	}
+	return handler.Response.Success(c, "success", http.StatusOK, map[string]interface{}{})
+}
+
+// RevokeAll will log out the current user everywhere
+func (handler *SessionHandler) RevokeAll(c echo.Context) error {
+	userId := c.Get("user_id").(int64)
+	ctx := c.Request().Context()
+
+	err := handler.SessionUsecase.RevokeAll(ctx, userId)
+	if err != nil {
+		return handler.Response.Error(c, err)
+	}
+	return handler.Response.Success(c, "success", http.StatusOK, map[string]interface{}{})
+}
@@ -7,7 +7,6 @@ import (
 	"github.com/RedLucky/potongin/app/delivery/api/response"
 	"github.com/RedLucky/potongin/domain"
 
-	"github.com/go-playground/validator"
 	"github.com/labstack/echo/v4"
 )
 
@@ -17,19 +16,25 @@ type UserHandler struct {
 	Response    *response.JsonResponse
 }
 
-var userResponse map[string]interface{}
-
 // NewUserHandler will initialize the articles/ resources endpoint
-func NewUserHandler(e *echo.Group, uc domain.UserUsecase, response *response.JsonResponse) {
+func NewUserHandler(routes *Routes, uc domain.UserUsecase, response *response.JsonResponse) {
 	handler := &UserHandler{
 		UserUsecase: uc,
 		Response:    response,
 	}
+	e := routes.Protected
 	e.GET("/users", handler.FetchUser)
 	e.GET("/me", handler.Me)
-	e.POST("/user", handler.Store)
-	e.GET("/user/:id", handler.GetByID)
-	e.DELETE("/user/:id", handler.Delete)
+	e.GET("/users/:id", handler.GetByID)
+	e.DELETE("/users/:id", handler.Delete)
+
+	legacy := routes.LegacyProtected
+	legacy.GET("/users", handler.FetchUser, routes.Deprecated("/v1/users"))
+	legacy.GET("/me", handler.Me, routes.Deprecated("/v1/me"))
+	// users sign up on /v1/users, this one has no successor
+	legacy.POST("/user", handler.Store, routes.Deprecated(""))
+	legacy.GET("/user/:id", handler.GetByID, routes.Deprecated("/v1/users/:id"))
+	legacy.DELETE("/user/:id", handler.Delete, routes.Deprecated("/v1/users/:id"))
 }
 
 func (handler *UserHandler) Me(c echo.Context) error {
@@ -40,7 +45,7 @@ func (handler *UserHandler) Me(c echo.Context) error {
 	if err != nil {
 		return handler.Response.Error(c, err)
 	}
-	userResponse = map[string]interface{}{
+	userResponse := map[string]interface{}{
 		"id":         user.ID,
 		"username":   user.Username,
 		"email":      user.Email,
@@ -90,7 +95,6 @@ func (handler *UserHandler) GetByID(c echo.Context) error {
 }
 
 func isRequestValid(m *domain.User) (bool, error) {
-	validate := validator.New()
 	err := validate.Struct(m)
 	if err != nil {
 		return false, err
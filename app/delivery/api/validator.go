@@ -0,0 +1,24 @@
+package api
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator"
+)
+
+// validate is shared by the handlers, it names the invalid fields after their
+// json tag so the clients find them in the error response
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	return v
+}
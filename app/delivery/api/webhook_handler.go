@@ -0,0 +1,122 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/RedLucky/potongin/app/delivery/api/response"
+	"github.com/RedLucky/potongin/domain"
+	"github.com/labstack/echo/v4"
+)
+
+// WebhookHandler represent the httphandler for the webhooks of the current user
+type WebhookHandler struct {
+	WebhookUsecase domain.WebhookUsecase
+	Response       *response.JsonResponse
+}
+
+// NewWebhookHandler will initialize the webhooks/ resources endpoint
+func NewWebhookHandler(routes *Routes, uc domain.WebhookUsecase, response *response.JsonResponse) {
+	handler := &WebhookHandler{
+		WebhookUsecase: uc,
+		Response:       response,
+	}
+	e := routes.Protected
+	e.POST("/webhooks", handler.Create)
+	e.GET("/webhooks", handler.Fetch)
+	e.DELETE("/webhooks/:id", handler.Delete)
+	e.GET("/webhooks/:id/deliveries", handler.Deliveries)
+	e.POST("/webhooks/:id/deliveries/:delivery_id/redeliveries", handler.Redeliver)
+
+	legacy := routes.LegacyProtected
+	legacy.POST("/webhooks", handler.Create, routes.Deprecated("/v1/webhooks"))
+	legacy.GET("/webhooks", handler.Fetch, routes.Deprecated("/v1/webhooks"))
+	legacy.DELETE("/webhooks/:id", handler.Delete, routes.Deprecated("/v1/webhooks/:id"))
+	legacy.GET("/webhooks/:id/deliveries", handler.Deliveries, routes.Deprecated("/v1/webhooks/:id/deliveries"))
+	legacy.POST("/webhooks/:id/deliveries/:delivery_id/redeliver", handler.Redeliver,
+		routes.Deprecated("/v1/webhooks/:id/deliveries/:delivery_id/redeliveries"))
+}
+
+// Create will register a webhook, its signing secret is only shown in this response
+func (handler *WebhookHandler) Create(c echo.Context) (err error) {
+	var param domain.CreateWebhook
+	if err = c.Bind(&param); err != nil {
+		return handler.Response.Error(c, err)
+	}
+	var ok bool
+	if ok, err = validateWebhookParam(&param); !ok {
+		return handler.Response.Error(c, err)
+	}
+
+	userId := c.Get("user_id").(int64)
+	webhook, secret, err := handler.WebhookUsecase.Create(c.Request().Context(), userId, param)
+	if err != nil {
+		return handler.Response.Error(c, err)
+	}
+	return handler.Response.Success(c, "success", http.StatusCreated, map[string]interface{}{"webhook": webhook, "secret": secret})
+}
+
+func (handler *WebhookHandler) Fetch(c echo.Context) error {
+	userId := c.Get("user_id").(int64)
+	webhooks, err := handler.WebhookUsecase.Fetch(c.Request().Context(), userId)
+	if err != nil {
+		return handler.Response.Error(c, err)
+	}
+	return handler.Response.Success(c, "success", http.StatusOK, map[string]interface{}{"webhooks": webhooks})
+}
+
+func (handler *WebhookHandler) Delete(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return handler.Response.Error(c, domain.ErrBadParamInput)
+	}
+	userId := c.Get("user_id").(int64)
+	if err = handler.WebhookUsecase.Delete(c.Request().Context(), userId, id); err != nil {
+		return handler.Response.Error(c, err)
+	}
+	return handler.Response.Success(c, "success", http.StatusOK, map[string]interface{}{})
+}
+
+// Deliveries will list the latest deliveries of the webhook, ?limit= up to 100
+func (handler *WebhookHandler) Deliveries(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return handler.Response.Error(c, domain.ErrBadParamInput)
+	}
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	userId := c.Get("user_id").(int64)
+
+	deliveries, err := handler.WebhookUsecase.Deliveries(c.Request().Context(), userId, id, limit)
+	if err != nil {
+		return handler.Response.Error(c, err)
+	}
+	return handler.Response.Paginated(c, "success", http.StatusOK, map[string]interface{}{"deliveries": deliveries},
+		response.Meta{Limit: limit, Count: len(deliveries)})
+}
+
+// Redeliver will queue the payload of a past delivery again
+func (handler *WebhookHandler) Redeliver(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return handler.Response.Error(c, domain.ErrBadParamInput)
+	}
+	deliveryId, err := strconv.ParseInt(c.Param("delivery_id"), 10, 64)
+	if err != nil {
+		return handler.Response.Error(c, domain.ErrBadParamInput)
+	}
+	userId := c.Get("user_id").(int64)
+
+	delivery, err := handler.WebhookUsecase.Redeliver(c.Request().Context(), userId, id, deliveryId)
+	if err != nil {
+		return handler.Response.Error(c, err)
+	}
+	return handler.Response.Success(c, "success", http.StatusAccepted, map[string]interface{}{"delivery": delivery})
+}
+
+func validateWebhookParam(m *domain.CreateWebhook) (bool, error) {
+	err := validate.Struct(m)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,60 @@
+package graph
+
+import (
+	"strconv"
+
+	"github.com/RedLucky/potongin/app/delivery/api/response"
+	"github.com/RedLucky/potongin/domain"
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+// resolverError is answered in the errors of the response, its extensions
+// carry the error_code and the HTTP status of the HTTP API
+type resolverError struct {
+	err    *domain.Error
+	fields []string
+}
+
+func (e *resolverError) Error() string {
+	return e.err.Message
+}
+
+func (e *resolverError) Extensions() map[string]interface{} {
+	extensions := map[string]interface{}{
+		"code":   e.err.Code,
+		"status": e.err.Status,
+	}
+	if len(e.fields) > 0 {
+		extensions["fields"] = e.fields
+	}
+	return extensions
+}
+
+// resolveError convert the error of a usecase, the unknown ones are internal
+// errors whose message is not shown
+func resolveError(err error) error {
+	return &resolverError{err: response.DomainError(err)}
+}
+
+// required fail with the names of the empty fields, given as name, value pairs
+func required(pairs ...string) error {
+	var missing []string
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if pairs[i+1] == "" {
+			missing = append(missing, pairs[i])
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return &resolverError{err: response.ErrValidation, fields: missing}
+}
+
+// parseID read the id of a link or an organization
+func parseID(id graphql.ID) (int64, error) {
+	parsed, err := strconv.ParseInt(string(id), 10, 64)
+	if err != nil || parsed <= 0 {
+		return 0, resolveError(domain.ErrBadParamInput)
+	}
+	return parsed, nil
+}
@@ -0,0 +1,175 @@
+package graph
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/RedLucky/potongin/domain"
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+// Resolver resolve the queries and mutations for the authenticated user,
+// the actor of the context
+type Resolver struct {
+	GeneratedUrlUsecase domain.GeneratedUrlUsecase
+	UserUsecase         domain.UserUsecase
+}
+
+type linkFilter struct {
+	Search    *string
+	Active    *bool
+	TakenDown *bool
+}
+
+type createLinkInput struct {
+	Name          string
+	SourceLink    string
+	GeneratedLink string
+	OrgId         *graphql.ID
+}
+
+type updateLinkInput struct {
+	Name          *string
+	SourceLink    *string
+	GeneratedLink *string
+}
+
+func (r *Resolver) Me(ctx context.Context) (*userResolver, error) {
+	user, err := r.UserUsecase.GetByID(ctx, domain.ActorFromContext(ctx))
+	if err != nil {
+		return nil, resolveError(err)
+	}
+	return &userResolver{user}, nil
+}
+
+func (r *Resolver) Links(ctx context.Context, args struct {
+	OrgId  *graphql.ID
+	First  *int32
+	Offset *int32
+	Filter *linkFilter
+}) (*linkConnectionResolver, error) {
+	var orgId int64
+	if args.OrgId != nil {
+		var err error
+		if orgId, err = parseID(*args.OrgId); err != nil {
+			return nil, err
+		}
+	}
+	limit, offset := int32(defaultPageSize), int32(0)
+	if args.First != nil {
+		limit = *args.First
+	}
+	if args.Offset != nil {
+		offset = *args.Offset
+	}
+	if limit < 1 || limit > maxPageSize || offset < 0 {
+		return nil, resolveError(domain.ErrBadParamInput)
+	}
+
+	actor := domain.ActorFromContext(ctx)
+	urls, total, err := r.GeneratedUrlUsecase.SearchUrls(ctx, actor, orgId, args.Filter.linkFilter(int(limit), int(offset)))
+	if err != nil {
+		return nil, resolveError(err)
+	}
+
+	connection := &linkConnectionResolver{total: int32(total), limit: limit, offset: offset, nodes: make([]*linkResolver, 0, len(urls))}
+	stats := &linkStatsLoader{usecase: r.GeneratedUrlUsecase, userId: actor, orgId: orgId}
+	for _, url := range urls {
+		link := r.link(url)
+		link.stats = stats
+		stats.urlIds = append(stats.urlIds, url.ID)
+		connection.nodes = append(connection.nodes, link)
+	}
+	return connection, nil
+}
+
+func (r *Resolver) Link(ctx context.Context, args struct{ Id graphql.ID }) (*linkResolver, error) {
+	id, err := parseID(args.Id)
+	if err != nil {
+		return nil, err
+	}
+	url, err := r.GeneratedUrlUsecase.GetUrlById(ctx, domain.ActorFromContext(ctx), strconv.FormatInt(id, 10))
+	if err == domain.ErrUrlNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, resolveError(err)
+	}
+	return r.link(url), nil
+}
+
+func (r *Resolver) CreateLink(ctx context.Context, args struct{ Input createLinkInput }) (*linkResolver, error) {
+	input := args.Input
+	if err := required("name", input.Name, "sourceLink", input.SourceLink, "generatedLink", input.GeneratedLink); err != nil {
+		return nil, err
+	}
+	url := domain.GeneratedUrl{
+		UserId:    domain.ActorFromContext(ctx),
+		Name:      input.Name,
+		Source:    input.SourceLink,
+		Generated: input.GeneratedLink,
+	}
+	if input.OrgId != nil {
+		var err error
+		if url.OrgId, err = parseID(*input.OrgId); err != nil {
+			return nil, err
+		}
+	}
+	if err := r.GeneratedUrlUsecase.CreateUrl(ctx, &url); err != nil {
+		return nil, resolveError(err)
+	}
+	return r.link(url), nil
+}
+
+func (r *Resolver) UpdateLink(ctx context.Context, args struct {
+	Id    graphql.ID
+	Input updateLinkInput
+}) (*linkResolver, error) {
+	id, err := parseID(args.Id)
+	if err != nil {
+		return nil, err
+	}
+	url := domain.GeneratedUrl{ID: id}
+	if args.Input.Name != nil {
+		url.Name = *args.Input.Name
+	}
+	if args.Input.SourceLink != nil {
+		url.Source = *args.Input.SourceLink
+	}
+	if args.Input.GeneratedLink != nil {
+		url.Generated = *args.Input.GeneratedLink
+	}
+	if err = r.GeneratedUrlUsecase.UpdateUrl(ctx, domain.ActorFromContext(ctx), &url); err != nil {
+		return nil, resolveError(err)
+	}
+	return r.link(url), nil
+}
+
+func (r *Resolver) DisableLink(ctx context.Context, args struct{ Id graphql.ID }) (*linkResolver, error) {
+	id, err := parseID(args.Id)
+	if err != nil {
+		return nil, err
+	}
+	url, err := r.GeneratedUrlUsecase.SetUrlActive(ctx, domain.ActorFromContext(ctx), strconv.FormatInt(id, 10), false)
+	if err != nil {
+		return nil, resolveError(err)
+	}
+	return r.link(url), nil
+}
+
+func (r *Resolver) link(url domain.GeneratedUrl) *linkResolver {
+	return &linkResolver{url: url, usecase: r.GeneratedUrlUsecase}
+}
+
+// linkFilter return the filter of the repository for the page, a nil filter
+// passes every link
+func (f *linkFilter) linkFilter(limit, offset int) domain.LinkFilter {
+	filter := domain.LinkFilter{Limit: limit, Offset: offset}
+	if f != nil {
+		if f.Search != nil {
+			filter.Search = *f.Search
+		}
+		filter.Active = f.Active
+		filter.TakenDown = f.TakenDown
+	}
+	return filter
+}
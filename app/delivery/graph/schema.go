@@ -0,0 +1,31 @@
+// Package graph answer the GraphQL queries of the dashboard with the links
+// and users usecases
+package graph
+
+import (
+	_ "embed"
+
+	"github.com/RedLucky/potongin/domain"
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+//go:embed schema.graphql
+var schema string
+
+const (
+	// the queries nest the links, their stats and counts: deeper ones are rejected
+	maxDepth = 8
+	// the page size of the links, when it is not given and at most
+	defaultPageSize = 20
+	maxPageSize     = 100
+	// the days of the statistics of a link, when they are not given
+	defaultStatsDays = 30
+)
+
+// NewSchema parse the schema and bind it to the resolvers of the usecases
+func NewSchema(guu domain.GeneratedUrlUsecase, uu domain.UserUsecase) *graphql.Schema {
+	return graphql.MustParseSchema(schema, &Resolver{
+		GeneratedUrlUsecase: guu,
+		UserUsecase:         uu,
+	}, graphql.MaxDepth(maxDepth))
+}
@@ -0,0 +1,310 @@
+package graph_test
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/RedLucky/potongin/app/delivery/graph"
+	"github.com/RedLucky/potongin/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeLinks and fakeUsers implement the usecase methods the resolvers call,
+// the others panic through the nil interface
+type fakeLinks struct {
+	domain.GeneratedUrlUsecase
+	urls    []domain.GeneratedUrl
+	stats   domain.LinkStats
+	err     error
+	created *domain.GeneratedUrl
+	// filter is the last filter searched, statsLoads counts the batched stats
+	filter     domain.LinkFilter
+	mu         sync.Mutex
+	statsLoads int
+}
+
+// SearchUrls filter and page the links like the repository
+func (f *fakeLinks) SearchUrls(ctx context.Context, userId, orgId int64, filter domain.LinkFilter) ([]domain.GeneratedUrl, int64, error) {
+	f.filter = filter
+	if f.err != nil {
+		return nil, 0, f.err
+	}
+	matching := []domain.GeneratedUrl{}
+	for _, url := range f.urls {
+		search := strings.ToLower(filter.Search)
+		if !strings.Contains(strings.ToLower(url.Name), search) && !strings.Contains(strings.ToLower(url.Source), search) {
+			continue
+		}
+		if filter.Active != nil && *filter.Active != (url.IsActive == "Y") {
+			continue
+		}
+		if filter.TakenDown != nil && *filter.TakenDown != (url.TakenDownAt != nil) {
+			continue
+		}
+		matching = append(matching, url)
+	}
+	total := int64(len(matching))
+	if filter.Offset >= len(matching) {
+		return []domain.GeneratedUrl{}, total, nil
+	}
+	matching = matching[filter.Offset:]
+	if len(matching) > filter.Limit {
+		matching = matching[:filter.Limit]
+	}
+	return matching, total, nil
+}
+
+func (f *fakeLinks) GetWorkspaceStats(ctx context.Context, userId, orgId int64, urlIds []int64, days int) (map[int64]domain.LinkStats, error) {
+	f.mu.Lock()
+	f.statsLoads++
+	f.mu.Unlock()
+	stats := map[int64]domain.LinkStats{}
+	for _, urlId := range urlIds {
+		stats[urlId] = domain.LinkStats{TotalClicks: urlId * int64(days)}
+	}
+	return stats, f.err
+}
+
+func (f *fakeLinks) GetUrlById(ctx context.Context, userId int64, urlId string) (domain.GeneratedUrl, error) {
+	for _, url := range f.urls {
+		if url.UserId == userId && strconv.FormatInt(url.ID, 10) == urlId {
+			return url, nil
+		}
+	}
+	return domain.GeneratedUrl{}, domain.ErrUrlNotFound
+}
+
+func (f *fakeLinks) GetUrlStats(ctx context.Context, userId int64, urlId string, days int) (domain.LinkStats, error) {
+	f.stats.TotalClicks = int64(days)
+	return f.stats, f.err
+}
+
+func (f *fakeLinks) CreateUrl(ctx context.Context, url *domain.GeneratedUrl) error {
+	f.created = url
+	url.ID = 9
+	url.IsActive = "Y"
+	return f.err
+}
+
+func (f *fakeLinks) SetUrlActive(ctx context.Context, userId int64, urlId string, active bool) (domain.GeneratedUrl, error) {
+	if f.err != nil {
+		return domain.GeneratedUrl{}, f.err
+	}
+	url := f.urls[0]
+	url.IsActive = "N"
+	return url, nil
+}
+
+type fakeUsers struct {
+	domain.UserUsecase
+}
+
+func (f *fakeUsers) GetByID(ctx context.Context, id int64) (domain.User, error) {
+	return domain.User{ID: id, Username: "alice", EmailVerified: "Y", CreatedAt: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)}, nil
+}
+
+func links() []domain.GeneratedUrl {
+	takenDown := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+	return []domain.GeneratedUrl{
+		{ID: 1, UserId: 7, Name: "Docs", Source: "example.com/docs", Generated: "docs", IsActive: "Y"},
+		{ID: 2, UserId: 7, Name: "Blog", Source: "example.com/blog", Generated: "blog", IsActive: "N"},
+		{ID: 3, UserId: 7, Name: "Shop", Source: "shop.example.com", Generated: "shop", IsActive: "Y", TakenDownAt: &takenDown, TakedownReason: "phishing"},
+	}
+}
+
+// exec run the query as the user 7 and decode its response
+func exec(t *testing.T, links *fakeLinks, query string, variables map[string]interface{}) (data map[string]interface{}, errors []map[string]interface{}) {
+	schema := graph.NewSchema(links, &fakeUsers{})
+	ctx := domain.NewContextWithActor(context.Background(), 7)
+	body, err := json.Marshal(schema.Exec(ctx, query, "", variables))
+	require.NoError(t, err)
+
+	var response struct {
+		Data   map[string]interface{}   `json:"data"`
+		Errors []map[string]interface{} `json:"errors"`
+	}
+	require.NoError(t, json.Unmarshal(body, &response))
+	return response.Data, response.Errors
+}
+
+func TestMe(t *testing.T) {
+	data, errors := exec(t, &fakeLinks{}, `{ me { id username emailVerified createdAt } }`, nil)
+
+	assert.Empty(t, errors)
+	assert.Equal(t, map[string]interface{}{"id": "7", "username": "alice", "emailVerified": true, "createdAt": "2021-01-01T00:00:00Z"}, data["me"])
+}
+
+func TestLinks(t *testing.T) {
+	query := `query($filter: LinkFilter, $first: Int) {
+		links(first: $first, filter: $filter) { totalCount hasNextPage nodes { id name active takedownReason } }
+	}`
+
+	t.Run("page", func(t *testing.T) {
+		data, errors := exec(t, &fakeLinks{urls: links()}, query, map[string]interface{}{"first": 2})
+
+		assert.Empty(t, errors)
+		connection := data["links"].(map[string]interface{})
+		assert.Equal(t, float64(3), connection["totalCount"])
+		assert.Equal(t, true, connection["hasNextPage"])
+		assert.Len(t, connection["nodes"], 2)
+	})
+
+	t.Run("filter", func(t *testing.T) {
+		fake := &fakeLinks{urls: links()}
+		filter := map[string]interface{}{"search": "EXAMPLE.COM/", "active": true}
+		data, errors := exec(t, fake, query, map[string]interface{}{"filter": filter})
+
+		assert.Empty(t, errors)
+		active := true
+		assert.Equal(t, domain.LinkFilter{Search: "EXAMPLE.COM/", Active: &active, Limit: 20}, fake.filter)
+		connection := data["links"].(map[string]interface{})
+		assert.Equal(t, float64(1), connection["totalCount"])
+		assert.Equal(t, false, connection["hasNextPage"])
+		assert.Equal(t, []interface{}{map[string]interface{}{"id": "1", "name": "Docs", "active": true, "takedownReason": nil}}, connection["nodes"])
+	})
+
+	t.Run("taken-down", func(t *testing.T) {
+		filter := map[string]interface{}{"takenDown": true}
+		data, _ := exec(t, &fakeLinks{urls: links()}, query, map[string]interface{}{"filter": filter})
+
+		nodes := data["links"].(map[string]interface{})["nodes"].([]interface{})
+		require.Len(t, nodes, 1)
+		assert.Equal(t, "phishing", nodes[0].(map[string]interface{})["takedownReason"])
+	})
+
+	t.Run("page-too-large", func(t *testing.T) {
+		_, errors := exec(t, &fakeLinks{urls: links()}, query, map[string]interface{}{"first": 1000})
+
+		require.Len(t, errors, 1)
+		assert.Equal(t, "bad_param_input", errors[0]["extensions"].(map[string]interface{})["code"])
+	})
+}
+
+func TestLinksPageBounds(t *testing.T) {
+	query := `query($first: Int, $offset: Int) {
+		links(first: $first, offset: $offset) { totalCount hasNextPage offset nodes { id } }
+	}`
+
+	t.Run("last-page", func(t *testing.T) {
+		fake := &fakeLinks{urls: links()}
+		data, errors := exec(t, fake, query, map[string]interface{}{"first": 2, "offset": 2})
+
+		assert.Empty(t, errors)
+		assert.Equal(t, domain.LinkFilter{Limit: 2, Offset: 2}, fake.filter)
+		connection := data["links"].(map[string]interface{})
+		assert.Equal(t, float64(3), connection["totalCount"])
+		assert.Equal(t, false, connection["hasNextPage"])
+		assert.Equal(t, []interface{}{map[string]interface{}{"id": "3"}}, connection["nodes"])
+	})
+
+	t.Run("past-the-end", func(t *testing.T) {
+		data, errors := exec(t, &fakeLinks{urls: links()}, query, map[string]interface{}{"offset": 10})
+
+		assert.Empty(t, errors)
+		connection := data["links"].(map[string]interface{})
+		assert.Equal(t, float64(3), connection["totalCount"])
+		assert.Equal(t, false, connection["hasNextPage"])
+		assert.Equal(t, []interface{}{}, connection["nodes"])
+	})
+
+	for name, variables := range map[string]map[string]interface{}{
+		"negative-offset": {"offset": -1},
+		"empty-page":      {"first": 0},
+		"largest-page":    {"first": 101},
+	} {
+		t.Run(name, func(t *testing.T) {
+			fake := &fakeLinks{urls: links()}
+			_, errors := exec(t, fake, query, variables)
+
+			require.Len(t, errors, 1)
+			assert.Equal(t, "bad_param_input", errors[0]["extensions"].(map[string]interface{})["code"])
+			assert.Zero(t, fake.filter)
+		})
+	}
+}
+
+func TestLinksStats(t *testing.T) {
+	fake := &fakeLinks{urls: links()}
+	data, errors := exec(t, fake, `{ links {
+		nodes { id week: stats(days: 7) { totalClicks } month: stats { totalClicks } again: stats(days: 7) { totalClicks } }
+	} }`, nil)
+
+	assert.Empty(t, errors)
+	// one load for the page by number of days, not one by link
+	assert.Equal(t, 2, fake.statsLoads)
+	nodes := data["links"].(map[string]interface{})["nodes"].([]interface{})
+	require.Len(t, nodes, 3)
+	assert.Equal(t, map[string]interface{}{
+		"id":    "3",
+		"week":  map[string]interface{}{"totalClicks": float64(21)},
+		"month": map[string]interface{}{"totalClicks": float64(90)},
+		"again": map[string]interface{}{"totalClicks": float64(21)},
+	}, nodes[2])
+}
+
+func TestLink(t *testing.T) {
+	fake := &fakeLinks{urls: links(), stats: domain.LinkStats{
+		Daily:   []domain.ClickCount{{Key: "2021-03-01", Clicks: 2}},
+		Devices: []domain.ClickCount{{Key: "iPhone", Clicks: 2}},
+	}}
+
+	data, errors := exec(t, fake, `{ link(id: "1") { name stats(days: 7) { totalClicks daily { key clicks } devices { key } referers { key } } } }`, nil)
+	assert.Empty(t, errors)
+	assert.Equal(t, map[string]interface{}{
+		"name": "Docs",
+		"stats": map[string]interface{}{
+			"totalClicks": float64(7),
+			"daily":       []interface{}{map[string]interface{}{"key": "2021-03-01", "clicks": float64(2)}},
+			"devices":     []interface{}{map[string]interface{}{"key": "iPhone"}},
+			"referers":    []interface{}{},
+		},
+	}, data["link"])
+
+	data, _ = exec(t, fake, `{ link(id: "1") { stats { totalClicks } } }`, nil)
+	assert.Equal(t, map[string]interface{}{"stats": map[string]interface{}{"totalClicks": float64(30)}}, data["link"])
+
+	data, errors = exec(t, fake, `{ link(id: "4") { name } }`, nil)
+	assert.Empty(t, errors)
+	assert.Nil(t, data["link"])
+}
+
+func TestMutations(t *testing.T) {
+	t.Run("create", func(t *testing.T) {
+		fake := &fakeLinks{}
+		data, errors := exec(t, fake, `mutation { createLink(input: {name: "Docs", sourceLink: "https://example.com", generatedLink: "docs", orgId: "10"}) { id active orgId } }`, nil)
+
+		assert.Empty(t, errors)
+		assert.Equal(t, map[string]interface{}{"id": "9", "active": true, "orgId": "10"}, data["createLink"])
+		assert.Equal(t, int64(7), fake.created.UserId)
+	})
+
+	t.Run("create-validation", func(t *testing.T) {
+		_, errors := exec(t, &fakeLinks{}, `mutation { createLink(input: {name: "Docs", sourceLink: "", generatedLink: ""}) { id } }`, nil)
+
+		require.Len(t, errors, 1)
+		extensions := errors[0]["extensions"].(map[string]interface{})
+		assert.Equal(t, "validation_failed", extensions["code"])
+		assert.Equal(t, []interface{}{"sourceLink", "generatedLink"}, extensions["fields"])
+	})
+
+	t.Run("disable", func(t *testing.T) {
+		data, errors := exec(t, &fakeLinks{urls: links()}, `mutation { disableLink(id: "1") { id active } }`, nil)
+
+		assert.Empty(t, errors)
+		assert.Equal(t, map[string]interface{}{"id": "1", "active": false}, data["disableLink"])
+	})
+
+	t.Run("disable-forbidden", func(t *testing.T) {
+		_, errors := exec(t, &fakeLinks{err: domain.ErrForbidden}, `mutation { disableLink(id: "1") { id } }`, nil)
+
+		require.Len(t, errors, 1)
+		assert.Equal(t, domain.ErrForbidden.Message, errors[0]["message"])
+		assert.Equal(t, map[string]interface{}{"code": "forbidden", "status": float64(403)}, errors[0]["extensions"])
+	})
+}
@@ -0,0 +1,171 @@
+package graph
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/RedLucky/potongin/domain"
+	graphql "github.com/graph-gophers/graphql-go"
+)
+
+type userResolver struct {
+	user domain.User
+}
+
+func (r *userResolver) Id() graphql.ID          { return id(r.user.ID) }
+func (r *userResolver) Username() string        { return r.user.Username }
+func (r *userResolver) Email() string           { return r.user.Email }
+func (r *userResolver) Name() string            { return r.user.Name }
+func (r *userResolver) EmailVerified() bool     { return r.user.EmailVerified == "Y" }
+func (r *userResolver) Role() string            { return r.user.Role }
+func (r *userResolver) CreatedAt() graphql.Time { return graphql.Time{Time: r.user.CreatedAt} }
+
+// linkResolver resolve a link, stats is set for the links of a page to load
+// their stats together
+type linkResolver struct {
+	url     domain.GeneratedUrl
+	usecase domain.GeneratedUrlUsecase
+	stats   *linkStatsLoader
+}
+
+func (r *linkResolver) Id() graphql.ID           { return id(r.url.ID) }
+func (r *linkResolver) Name() string             { return r.url.Name }
+func (r *linkResolver) SourceLink() string       { return r.url.Source }
+func (r *linkResolver) GeneratedLink() string    { return r.url.Generated }
+func (r *linkResolver) TotalHits() int32         { return int32(r.url.TotalHits) }
+func (r *linkResolver) Active() bool             { return r.url.IsActive == "Y" }
+func (r *linkResolver) StartDate() *graphql.Time { return optionalTime(&r.url.StartDate) }
+func (r *linkResolver) EndDate() *graphql.Time   { return optionalTime(&r.url.EndDate) }
+func (r *linkResolver) TakenDownAt() *graphql.Time {
+	return optionalTime(r.url.TakenDownAt)
+}
+func (r *linkResolver) CreatedAt() graphql.Time { return graphql.Time{Time: r.url.CreatedAt} }
+func (r *linkResolver) UpdatedAt() graphql.Time { return graphql.Time{Time: r.url.UpdatedAt} }
+
+func (r *linkResolver) OrgId() *graphql.ID {
+	if r.url.OrgId == 0 {
+		return nil
+	}
+	orgId := id(r.url.OrgId)
+	return &orgId
+}
+
+func (r *linkResolver) TakedownReason() *string {
+	if r.url.TakenDownAt == nil {
+		return nil
+	}
+	return &r.url.TakedownReason
+}
+
+// Stats is resolved only when it is asked, by the usecase checking the link is
+// still visible. The links of a page share one load per number of days.
+func (r *linkResolver) Stats(ctx context.Context, args struct{ Days *int32 }) (*linkStatsResolver, error) {
+	days := defaultStatsDays
+	if args.Days != nil {
+		days = int(*args.Days)
+	}
+	if r.stats != nil {
+		byUrl, err := r.stats.load(ctx, days)
+		if err != nil {
+			return nil, resolveError(err)
+		}
+		stats, ok := byUrl[r.url.ID]
+		if !ok {
+			return nil, resolveError(domain.ErrUrlNotFound)
+		}
+		return &linkStatsResolver{stats}, nil
+	}
+	stats, err := r.usecase.GetUrlStats(ctx, domain.ActorFromContext(ctx), strconv.FormatInt(r.url.ID, 10), days)
+	if err != nil {
+		return nil, resolveError(err)
+	}
+	return &linkStatsResolver{stats}, nil
+}
+
+// linkStatsLoader load the stats of every link of a page the first time one
+// of them is resolved, the fields of a query are resolved concurrently
+type linkStatsLoader struct {
+	usecase domain.GeneratedUrlUsecase
+	userId  int64
+	orgId   int64
+	urlIds  []int64
+
+	mu    sync.Mutex
+	loads map[int]*statsLoad
+}
+
+type statsLoad struct {
+	once  sync.Once
+	stats map[int64]domain.LinkStats
+	err   error
+}
+
+func (l *linkStatsLoader) load(ctx context.Context, days int) (map[int64]domain.LinkStats, error) {
+	l.mu.Lock()
+	if l.loads == nil {
+		l.loads = map[int]*statsLoad{}
+	}
+	load, ok := l.loads[days]
+	if !ok {
+		load = &statsLoad{}
+		l.loads[days] = load
+	}
+	l.mu.Unlock()
+
+	load.once.Do(func() {
+		load.stats, load.err = l.usecase.GetWorkspaceStats(ctx, l.userId, l.orgId, l.urlIds, days)
+	})
+	return load.stats, load.err
+}
+
+type linkConnectionResolver struct {
+	nodes  []*linkResolver
+	total  int32
+	limit  int32
+	offset int32
+}
+
+func (r *linkConnectionResolver) Nodes() []*linkResolver { return r.nodes }
+func (r *linkConnectionResolver) TotalCount() int32      { return r.total }
+func (r *linkConnectionResolver) Limit() int32           { return r.limit }
+func (r *linkConnectionResolver) Offset() int32          { return r.offset }
+func (r *linkConnectionResolver) HasNextPage() bool      { return r.offset+int32(len(r.nodes)) < r.total }
+
+type linkStatsResolver struct {
+	stats domain.LinkStats
+}
+
+func (r *linkStatsResolver) Since() graphql.Time     { return graphql.Time{Time: r.stats.Since} }
+func (r *linkStatsResolver) TotalClicks() int32      { return int32(r.stats.TotalClicks) }
+func (r *linkStatsResolver) Daily() []*clickCount    { return clickCounts(r.stats.Daily) }
+func (r *linkStatsResolver) Devices() []*clickCount  { return clickCounts(r.stats.Devices) }
+func (r *linkStatsResolver) Referers() []*clickCount { return clickCounts(r.stats.Referers) }
+
+type clickCount struct {
+	count domain.ClickCount
+}
+
+func (r *clickCount) Key() string   { return r.count.Key }
+func (r *clickCount) Clicks() int32 { return int32(r.count.Clicks) }
+
+func clickCounts(counts []domain.ClickCount) []*clickCount {
+	resolvers := make([]*clickCount, 0, len(counts))
+	for _, count := range counts {
+		resolvers = append(resolvers, &clickCount{count})
+	}
+	return resolvers
+}
+
+func id(i int64) graphql.ID {
+	return graphql.ID(strconv.FormatInt(i, 10))
+}
+
+// optionalTime resolve the zero and nil times as null
+func optionalTime(t *time.Time) *graphql.Time {
+	if t == nil || t.IsZero() {
+		return nil
+	}
+	return &graphql.Time{Time: *t}
+}
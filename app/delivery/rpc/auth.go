@@ -0,0 +1,57 @@
+package rpc
+
+import (
+	"context"
+
+	"github.com/RedLucky/potongin/app/delivery/rpc/pb"
+	"github.com/RedLucky/potongin/domain"
+)
+
+// AuthServer serve the AuthService with the auth usecase
+type AuthServer struct {
+	pb.UnimplementedAuthServiceServer
+	AuthUsecase domain.AuthUsecase
+}
+
+func (s *AuthServer) Login(ctx context.Context, req *pb.LoginRequest) (*pb.LoginResponse, error) {
+	credentials := domain.Auth{Email: req.GetEmail(), Password: req.GetPassword()}
+	if err := validate.Struct(&credentials); err != nil {
+		return nil, Error(err)
+	}
+
+	jwtResults, err := s.AuthUsecase.Authenticate(ctx, credentials.Email, credentials.Password)
+	if err != nil {
+		return nil, Error(err)
+	}
+	return loginMessage(jwtResults), nil
+}
+
+func (s *AuthServer) LoginMfa(ctx context.Context, req *pb.LoginMfaRequest) (*pb.LoginResponse, error) {
+	mfa := domain.MfaLogin{MfaToken: req.GetMfaToken(), Code: req.GetCode()}
+	if err := validate.Struct(&mfa); err != nil {
+		return nil, Error(err)
+	}
+
+	jwtResults, err := s.AuthUsecase.VerifyMfa(ctx, mfa.MfaToken, mfa.Code)
+	if err != nil {
+		return nil, Error(err)
+	}
+	return loginMessage(jwtResults), nil
+}
+
+func (s *AuthServer) Logout(ctx context.Context, req *pb.LogoutRequest) (*pb.LogoutResponse, error) {
+	if req.GetAccessToken() == "" || req.GetRefreshToken() == "" {
+		return nil, Error(domain.ErrBadParamInput)
+	}
+	if err := s.AuthUsecase.Logout(ctx, req.GetAccessToken(), req.GetRefreshToken()); err != nil {
+		return nil, Error(domain.ErrorAuthorization)
+	}
+	return &pb.LogoutResponse{}, nil
+}
+
+func loginMessage(jwtResults domain.JwtResults) *pb.LoginResponse {
+	if jwtResults.MfaRequired {
+		return &pb.LoginResponse{MfaRequired: true, MfaToken: jwtResults.MfaToken}
+	}
+	return &pb.LoginResponse{AccessToken: jwtResults.AccessToken, RefreshToken: jwtResults.RefreshToken}
+}
@@ -0,0 +1,83 @@
+package rpc
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/RedLucky/potongin/app/delivery/api/response"
+	"github.com/go-playground/validator"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorDomain is the domain of the ErrorInfo details of the errors
+const errorDomain = "potongin"
+
+// validate check the requests with the rules of the domain, the invalid
+// fields are named after their json tag, which is the name of the proto field
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	return v
+}
+
+// codes of the HTTP statuses of the domain errors
+var statusCodes = map[int]codes.Code{
+	http.StatusBadRequest:           codes.InvalidArgument,
+	http.StatusUnauthorized:         codes.Unauthenticated,
+	http.StatusForbidden:            codes.PermissionDenied,
+	http.StatusNotFound:             codes.NotFound,
+	http.StatusMethodNotAllowed:     codes.Unimplemented,
+	http.StatusConflict:             codes.AlreadyExists,
+	http.StatusGone:                 codes.NotFound,
+	http.StatusUnsupportedMediaType: codes.InvalidArgument,
+	http.StatusUnprocessableEntity:  codes.InvalidArgument,
+	http.StatusLocked:               codes.FailedPrecondition,
+	http.StatusTooManyRequests:      codes.ResourceExhausted,
+	http.StatusNotImplemented:       codes.Unimplemented,
+	http.StatusServiceUnavailable:   codes.Unavailable,
+	http.StatusGatewayTimeout:       codes.DeadlineExceeded,
+}
+
+// Error convert err to the status answered for it: the code follows the HTTP
+// status of its domain error and the error_code of the HTTP API is the reason
+// of the ErrorInfo details. The failed fields of a validation are BadRequest details.
+func Error(err error) error {
+	domainErr := response.DomainError(err)
+	code, ok := statusCodes[domainErr.Status]
+	if !ok {
+		code = codes.Internal
+	}
+
+	st := status.New(code, domainErr.Message)
+	info := &errdetails.ErrorInfo{Reason: domainErr.Code, Domain: errorDomain}
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		badRequest := &errdetails.BadRequest{}
+		for _, fieldErr := range validationErrs {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       fieldErr.Field(),
+				Description: fieldErr.Tag(),
+			})
+		}
+		st, err = st.WithDetails(info, badRequest)
+	} else {
+		st, err = st.WithDetails(info)
+	}
+	if err != nil {
+		// the details always marshal, the status is still answered without them
+		return status.Error(code, domainErr.Message)
+	}
+	return st.Err()
+}
@@ -0,0 +1,135 @@
+package rpc
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"net"
+	"strings"
+
+	"github.com/RedLucky/potongin/app/delivery/api/auth"
+	"github.com/RedLucky/potongin/app/delivery/api/middleware"
+	"github.com/RedLucky/potongin/domain"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// APIKey let an internal service call the gRPC API as the user UserId
+type APIKey struct {
+	Name   string `mapstructure:"name"`
+	Key    string `mapstructure:"key"`
+	UserId int64  `mapstructure:"user_id"`
+}
+
+// Authenticator authenticate the calls with the access token of the
+// authorization metadata, like the HTTP API, or with an API key in x-api-key
+type Authenticator struct {
+	Tokens  domain.TokenStore
+	APIKeys []APIKey
+}
+
+// NewAuthenticator create the authenticator with the API keys of grpc.api_keys
+func NewAuthenticator(tokens domain.TokenStore) (*Authenticator, error) {
+	var keys []APIKey
+	if err := viper.UnmarshalKey(`grpc.api_keys`, &keys); err != nil {
+		return nil, err
+	}
+	return &Authenticator{Tokens: tokens, APIKeys: keys}, nil
+}
+
+// Unary authenticate the calls of the methods that are not public, the
+// context of the handler carries the caller as the actor
+func (a *Authenticator) Unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if publicMethods[info.FullMethod] {
+		return handler(ctx, req)
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	var userId int64
+	var err error
+	if key := first(md, "x-api-key"); key != "" {
+		userId, err = a.apiKeyUser(key)
+	} else {
+		userId, err = a.tokenUser(ctx, bearerToken(first(md, "authorization")))
+	}
+	if err != nil {
+		log.WithField("method", info.FullMethod).Error(domain.ErrorAuthorization)
+		return nil, Error(domain.ErrorAuthorization)
+	}
+	return handler(domain.NewContextWithActor(ctx, userId), req)
+}
+
+// tokenUser find the owner of the access token
+func (a *Authenticator) tokenUser(ctx context.Context, token string) (int64, error) {
+	userId, _, err := auth.Authenticate(ctx, a.Tokens, token)
+	return userId, err
+}
+
+// apiKeyUser find the user of the API key, the keys are compared in constant time
+func (a *Authenticator) apiKeyUser(key string) (int64, error) {
+	sum := sha256.Sum256([]byte(key))
+	for _, apiKey := range a.APIKeys {
+		expected := sha256.Sum256([]byte(apiKey.Key))
+		if apiKey.Key != "" && subtle.ConstantTimeCompare(sum[:], expected[:]) == 1 {
+			return apiKey.UserId, nil
+		}
+	}
+	return 0, domain.ErrorAuthorization
+}
+
+// ClientInfo attach the ip, user agent and device of the caller to the
+// context, with the id of the request sent back in the x-request-id header
+func ClientInfo(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	requestId := first(md, "x-request-id")
+	if requestId == "" || len(requestId) > middleware.MaxRequestIDLength {
+		requestId = uuid.New().String()
+	}
+	if err := grpc.SetHeader(ctx, metadata.Pairs("x-request-id", requestId)); err != nil {
+		log.Error(err)
+	}
+
+	userAgent := first(md, "user-agent")
+	device := first(md, "x-device-name")
+	if device == "" {
+		device = middleware.DeviceFromUserAgent(userAgent)
+	}
+	ctx = domain.NewContextWithClientInfo(ctx, domain.ClientInfo{
+		IP:        peerIP(ctx),
+		UserAgent: userAgent,
+		Device:    device,
+		RequestID: requestId,
+	})
+	return handler(ctx, req)
+}
+
+func first(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func bearerToken(authorization string) string {
+	parts := strings.Split(authorization, " ")
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
+		return ""
+	}
+	return parts[1]
+}
+
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
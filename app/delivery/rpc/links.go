@@ -0,0 +1,97 @@
+package rpc
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/RedLucky/potongin/app/delivery/rpc/pb"
+	"github.com/RedLucky/potongin/domain"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// LinkServer serve the LinkService with the generated url usecase
+type LinkServer struct {
+	pb.UnimplementedLinkServiceServer
+	GeneratedUrlUsecase domain.GeneratedUrlUsecase
+}
+
+func (s *LinkServer) CreateLink(ctx context.Context, req *pb.CreateLinkRequest) (*pb.Link, error) {
+	generateUrl := domain.GeneratedUrl{
+		Name:      req.GetName(),
+		Source:    req.GetSourceLink(),
+		Generated: req.GetGeneratedLink(),
+		OrgId:     req.GetOrgId(),
+	}
+	if err := validate.Struct(&generateUrl); err != nil {
+		return nil, Error(err)
+	}
+
+	generateUrl.UserId = domain.ActorFromContext(ctx)
+	if err := s.GeneratedUrlUsecase.CreateUrl(ctx, &generateUrl); err != nil {
+		return nil, Error(err)
+	}
+	return linkMessage(generateUrl), nil
+}
+
+func (s *LinkServer) GetLink(ctx context.Context, req *pb.GetLinkRequest) (*pb.Link, error) {
+	generateUrl, err := s.GeneratedUrlUsecase.GetUrlById(ctx, domain.ActorFromContext(ctx), strconv.FormatInt(req.GetId(), 10))
+	if err != nil {
+		return nil, Error(err)
+	}
+	return linkMessage(generateUrl), nil
+}
+
+func (s *LinkServer) ListLinks(ctx context.Context, req *pb.ListLinksRequest) (*pb.ListLinksResponse, error) {
+	generateUrls, err := s.GeneratedUrlUsecase.GetUrlByWorkspace(ctx, domain.ActorFromContext(ctx), req.GetOrgId())
+	if err != nil {
+		return nil, Error(err)
+	}
+	links := make([]*pb.Link, 0, len(generateUrls))
+	for _, generateUrl := range generateUrls {
+		links = append(links, linkMessage(generateUrl))
+	}
+	return &pb.ListLinksResponse{Links: links}, nil
+}
+
+func (s *LinkServer) ResolveLink(ctx context.Context, req *pb.ResolveLinkRequest) (*pb.ResolveLinkResponse, error) {
+	origin, err := s.GeneratedUrlUsecase.HitUrl(ctx, req.GetGeneratedLink())
+	if err != nil {
+		return nil, Error(err)
+	}
+	return &pb.ResolveLinkResponse{SourceLink: origin}, nil
+}
+
+func linkMessage(generateUrl domain.GeneratedUrl) *pb.Link {
+	return &pb.Link{
+		Id:             generateUrl.ID,
+		UserId:         generateUrl.UserId,
+		OrgId:          generateUrl.OrgId,
+		Name:           generateUrl.Name,
+		SourceLink:     generateUrl.Source,
+		GeneratedLink:  generateUrl.Generated,
+		TotalHits:      generateUrl.TotalHits,
+		Active:         generateUrl.IsActive == "Y",
+		StartDate:      timestamp(generateUrl.StartDate),
+		EndDate:        timestamp(generateUrl.EndDate),
+		TakenDownAt:    timestampPtr(generateUrl.TakenDownAt),
+		TakedownReason: generateUrl.TakedownReason,
+		CreatedAt:      timestamp(generateUrl.CreatedAt),
+		UpdatedAt:      timestamp(generateUrl.UpdatedAt),
+	}
+}
+
+// timestamp convert t, the zero time is left unset
+func timestamp(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
+
+func timestampPtr(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamp(*t)
+}
@@ -0,0 +1,5 @@
+// Package pb hold the protobuf messages and gRPC services of the API,
+// generated from the .proto files of this directory
+package pb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative links.proto auth.proto users.proto
@@ -0,0 +1,217 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+// LinkServiceClient is the client API for LinkService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type LinkServiceClient interface {
+	// CreateLink create a link in the personal workspace, or in the organization of org_id
+	CreateLink(ctx context.Context, in *CreateLinkRequest, opts ...grpc.CallOption) (*Link, error)
+	// GetLink return a link of a workspace of the caller
+	GetLink(ctx context.Context, in *GetLinkRequest, opts ...grpc.CallOption) (*Link, error)
+	// ListLinks list the links of the personal workspace, or of the organization of org_id
+	ListLinks(ctx context.Context, in *ListLinksRequest, opts ...grpc.CallOption) (*ListLinksResponse, error)
+	// ResolveLink count a visit of the generated link and return its source, it needs no authentication
+	ResolveLink(ctx context.Context, in *ResolveLinkRequest, opts ...grpc.CallOption) (*ResolveLinkResponse, error)
+}
+
+type linkServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewLinkServiceClient(cc grpc.ClientConnInterface) LinkServiceClient {
+	return &linkServiceClient{cc}
+}
+
+func (c *linkServiceClient) CreateLink(ctx context.Context, in *CreateLinkRequest, opts ...grpc.CallOption) (*Link, error) {
+	out := new(Link)
+	err := c.cc.Invoke(ctx, "/potongin.v1.LinkService/CreateLink", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *linkServiceClient) GetLink(ctx context.Context, in *GetLinkRequest, opts ...grpc.CallOption) (*Link, error) {
+	out := new(Link)
+	err := c.cc.Invoke(ctx, "/potongin.v1.LinkService/GetLink", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *linkServiceClient) ListLinks(ctx context.Context, in *ListLinksRequest, opts ...grpc.CallOption) (*ListLinksResponse, error) {
+	out := new(ListLinksResponse)
+	err := c.cc.Invoke(ctx, "/potongin.v1.LinkService/ListLinks", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *linkServiceClient) ResolveLink(ctx context.Context, in *ResolveLinkRequest, opts ...grpc.CallOption) (*ResolveLinkResponse, error) {
+	out := new(ResolveLinkResponse)
+	err := c.cc.Invoke(ctx, "/potongin.v1.LinkService/ResolveLink", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// LinkServiceServer is the server API for LinkService service.
+// All implementations must embed UnimplementedLinkServiceServer
+// for forward compatibility
+type LinkServiceServer interface {
+	// CreateLink create a link in the personal workspace, or in the organization of org_id
+	CreateLink(context.Context, *CreateLinkRequest) (*Link, error)
+	// GetLink return a link of a workspace of the caller
+	GetLink(context.Context, *GetLinkRequest) (*Link, error)
+	// ListLinks list the links of the personal workspace, or of the organization of org_id
+	ListLinks(context.Context, *ListLinksRequest) (*ListLinksResponse, error)
+	// ResolveLink count a visit of the generated link and return its source, it needs no authentication
+	ResolveLink(context.Context, *ResolveLinkRequest) (*ResolveLinkResponse, error)
+	mustEmbedUnimplementedLinkServiceServer()
+}
+
+// UnimplementedLinkServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedLinkServiceServer struct {
+}
+
+func (UnimplementedLinkServiceServer) CreateLink(context.Context, *CreateLinkRequest) (*Link, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateLink not implemented")
+}
+func (UnimplementedLinkServiceServer) GetLink(context.Context, *GetLinkRequest) (*Link, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLink not implemented")
+}
+func (UnimplementedLinkServiceServer) ListLinks(context.Context, *ListLinksRequest) (*ListLinksResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListLinks not implemented")
+}
+func (UnimplementedLinkServiceServer) ResolveLink(context.Context, *ResolveLinkRequest) (*ResolveLinkResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResolveLink not implemented")
+}
+func (UnimplementedLinkServiceServer) mustEmbedUnimplementedLinkServiceServer() {}
+
+// UnsafeLinkServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to LinkServiceServer will
+// result in compilation errors.
+type UnsafeLinkServiceServer interface {
+	mustEmbedUnimplementedLinkServiceServer()
+}
+
+func RegisterLinkServiceServer(s grpc.ServiceRegistrar, srv LinkServiceServer) {
+	s.RegisterService(&LinkService_ServiceDesc, srv)
+}
+
+func _LinkService_CreateLink_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateLinkRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LinkServiceServer).CreateLink(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/potongin.v1.LinkService/CreateLink",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LinkServiceServer).CreateLink(ctx, req.(*CreateLinkRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LinkService_GetLink_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetLinkRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LinkServiceServer).GetLink(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/potongin.v1.LinkService/GetLink",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LinkServiceServer).GetLink(ctx, req.(*GetLinkRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LinkService_ListLinks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListLinksRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LinkServiceServer).ListLinks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/potongin.v1.LinkService/ListLinks",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LinkServiceServer).ListLinks(ctx, req.(*ListLinksRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LinkService_ResolveLink_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResolveLinkRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LinkServiceServer).ResolveLink(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/potongin.v1.LinkService/ResolveLink",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LinkServiceServer).ResolveLink(ctx, req.(*ResolveLinkRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// LinkService_ServiceDesc is the grpc.ServiceDesc for LinkService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var LinkService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "potongin.v1.LinkService",
+	HandlerType: (*LinkServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "CreateLink",
+			Handler:    _LinkService_CreateLink_Handler,
+		},
+		{
+			MethodName: "GetLink",
+			Handler:    _LinkService_GetLink_Handler,
+		},
+		{
+			MethodName: "ListLinks",
+			Handler:    _LinkService_ListLinks_Handler,
+		},
+		{
+			MethodName: "ResolveLink",
+			Handler:    _LinkService_ResolveLink_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "links.proto",
+}
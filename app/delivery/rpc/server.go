@@ -0,0 +1,27 @@
+// Package rpc serve the links, auth and users usecases over gRPC, next to
+// the HTTP API and with the same authentication
+package rpc
+
+import (
+	"github.com/RedLucky/potongin/app/delivery/rpc/pb"
+	"github.com/RedLucky/potongin/domain"
+	"google.golang.org/grpc"
+)
+
+// publicMethods are called without any token nor API key
+var publicMethods = map[string]bool{
+	"/potongin.v1.LinkService/ResolveLink": true,
+	"/potongin.v1.AuthService/Login":       true,
+	"/potongin.v1.AuthService/LoginMfa":    true,
+	"/potongin.v1.AuthService/Logout":      true,
+}
+
+// NewServer create the gRPC server of the services, the calls get the client
+// info then are authenticated by authenticator
+func NewServer(authenticator *Authenticator, guu domain.GeneratedUrlUsecase, au domain.AuthUsecase, uu domain.UserUsecase) *grpc.Server {
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(ClientInfo, authenticator.Unary))
+	pb.RegisterLinkServiceServer(server, &LinkServer{GeneratedUrlUsecase: guu})
+	pb.RegisterAuthServiceServer(server, &AuthServer{AuthUsecase: au})
+	pb.RegisterUserServiceServer(server, &UserServer{UserUsecase: uu})
+	return server
+}
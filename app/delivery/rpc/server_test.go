@@ -0,0 +1,245 @@
+package rpc_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/RedLucky/potongin/app/delivery/api/auth"
+	"github.com/RedLucky/potongin/app/delivery/rpc"
+	"github.com/RedLucky/potongin/app/delivery/rpc/pb"
+	"github.com/RedLucky/potongin/domain"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// fakeLinks, fakeAuth and fakeUsers implement the few usecase methods the
+// services call, the others panic through the nil interface
+type fakeLinks struct {
+	domain.GeneratedUrlUsecase
+	created *domain.GeneratedUrl
+	err     error
+}
+
+func (f *fakeLinks) CreateUrl(ctx context.Context, url *domain.GeneratedUrl) error {
+	f.created = url
+	url.ID = 10
+	url.IsActive = "Y"
+	return f.err
+}
+
+func (f *fakeLinks) GetUrlById(ctx context.Context, userId int64, urlId string) (domain.GeneratedUrl, error) {
+	if f.err != nil {
+		return domain.GeneratedUrl{}, f.err
+	}
+	return domain.GeneratedUrl{ID: 10, UserId: userId, Name: "docs"}, nil
+}
+
+func (f *fakeLinks) HitUrl(ctx context.Context, generated string) (string, error) {
+	return "example.com/" + generated, f.err
+}
+
+type fakeAuth struct {
+	domain.AuthUsecase
+	err error
+}
+
+func (f *fakeAuth) Authenticate(ctx context.Context, email, password string) (domain.JwtResults, error) {
+	return domain.JwtResults{AccessToken: "access", RefreshToken: "refresh"}, f.err
+}
+
+type fakeUsers struct {
+	domain.UserUsecase
+}
+
+func (f *fakeUsers) GetByID(ctx context.Context, id int64) (domain.User, error) {
+	return domain.User{ID: id, Username: "alice", EmailVerified: "Y"}, nil
+}
+
+// fakeTokens answer the token lookup of the access tokens of sessions
+type fakeTokens struct {
+	domain.TokenStore
+	sessions map[string]int64
+}
+
+func (f *fakeTokens) GetTokenSession(ctx context.Context, uuid string) (int64, string, error) {
+	userId, ok := f.sessions[uuid]
+	if !ok {
+		return 0, "", domain.ErrCacheMiss
+	}
+	return userId, "session", nil
+}
+
+func (f *fakeTokens) TouchSession(ctx context.Context, sessionId string, lastSeen time.Time) error {
+	return nil
+}
+
+type client struct {
+	links pb.LinkServiceClient
+	auth  pb.AuthServiceClient
+	users pb.UserServiceClient
+}
+
+func newClient(t *testing.T, links *fakeLinks, authUc *fakeAuth, sessions map[string]int64) client {
+	authenticator := &rpc.Authenticator{
+		Tokens:  &fakeTokens{sessions: sessions},
+		APIKeys: []rpc.APIKey{{Name: "link-service", Key: "secret-key", UserId: 3}},
+	}
+	server := rpc.NewServer(authenticator, links, authUc, &fakeUsers{})
+
+	listener := bufconn.Listen(1 << 20)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithInsecure(),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return client{
+		links: pb.NewLinkServiceClient(conn),
+		auth:  pb.NewAuthServiceClient(conn),
+		users: pb.NewUserServiceClient(conn),
+	}
+}
+
+func withMetadata(pairs ...string) context.Context {
+	return metadata.NewOutgoingContext(context.Background(), metadata.Pairs(pairs...))
+}
+
+func errorReason(t *testing.T, err error) string {
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			return info.Reason
+		}
+	}
+	t.Fatalf("no ErrorInfo in %v", err)
+	return ""
+}
+
+func TestAuthentication(t *testing.T) {
+	viper.Set(`authentication.duration_access`, 15)
+	viper.Set(`authentication.duration_refresh`, 18)
+	viper.Set(`authentication.jwt_signature_access_key`, "access-secret")
+	viper.Set(`authentication.jwt_signature_refresh_key`, "refresh-secret")
+	token, err := auth.CreateToken(&domain.User{ID: 7}, "session")
+	require.NoError(t, err)
+	revoked, err := auth.CreateToken(&domain.User{ID: 8}, "session")
+	require.NoError(t, err)
+
+	c := newClient(t, &fakeLinks{}, &fakeAuth{}, map[string]int64{token.AccessUUID: 7})
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		userId int64
+	}{
+		{"access-token", withMetadata("authorization", "Bearer "+token.AccessToken), 7},
+		{"api-key", withMetadata("x-api-key", "secret-key"), 3},
+		{"no-credentials", context.Background(), 0},
+		{"revoked-token", withMetadata("authorization", "Bearer "+revoked.AccessToken), 0},
+		{"invalid-token", withMetadata("authorization", "Bearer not-a-token"), 0},
+		{"wrong-api-key", withMetadata("x-api-key", "secret-kez"), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user, err := c.users.GetMe(tt.ctx, &pb.GetMeRequest{})
+			if tt.userId == 0 {
+				assert.Equal(t, codes.Unauthenticated, status.Code(err))
+				assert.Equal(t, "unauthorized", errorReason(t, err))
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.userId, user.Id)
+			assert.True(t, user.EmailVerified)
+		})
+	}
+
+	t.Run("public-method", func(t *testing.T) {
+		var header metadata.MD
+		res, err := c.links.ResolveLink(withMetadata("x-request-id", "req-1"), &pb.ResolveLinkRequest{GeneratedLink: "docs"}, grpc.Header(&header))
+		require.NoError(t, err)
+		assert.Equal(t, "example.com/docs", res.SourceLink)
+		assert.Equal(t, []string{"req-1"}, header.Get("x-request-id"))
+	})
+}
+
+func TestLinkService(t *testing.T) {
+	links := &fakeLinks{}
+	c := newClient(t, links, &fakeAuth{}, nil)
+	ctx := withMetadata("x-api-key", "secret-key")
+
+	link, err := c.links.CreateLink(ctx, &pb.CreateLinkRequest{Name: "docs", SourceLink: "https://example.com", GeneratedLink: "docs"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), link.Id)
+	assert.True(t, link.Active)
+	assert.Equal(t, int64(3), links.created.UserId)
+
+	link, err = c.links.GetLink(ctx, &pb.GetLinkRequest{Id: 10})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), link.UserId)
+	assert.Nil(t, link.TakenDownAt)
+}
+
+func TestErrors(t *testing.T) {
+	t.Run("validation", func(t *testing.T) {
+		c := newClient(t, &fakeLinks{}, &fakeAuth{}, nil)
+		_, err := c.links.CreateLink(withMetadata("x-api-key", "secret-key"), &pb.CreateLinkRequest{Name: "docs"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		assert.Equal(t, "validation_failed", errorReason(t, err))
+
+		var fields []string
+		for _, detail := range status.Convert(err).Details() {
+			if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+				for _, violation := range badRequest.FieldViolations {
+					fields = append(fields, violation.Field)
+				}
+			}
+		}
+		assert.Equal(t, []string{"source_link", "generated_link"}, fields)
+	})
+
+	tests := []struct {
+		name   string
+		err    error
+		code   codes.Code
+		reason string
+	}{
+		{"not-found", domain.ErrUrlNotFound, codes.NotFound, "url_not_found"},
+		{"forbidden", domain.ErrForbidden, codes.PermissionDenied, "forbidden"},
+		{"conflict", domain.ErrUrlGeneratedExist, codes.AlreadyExists, "url_generated_exist"},
+		{"too-many-attempts", domain.ErrTooManyAttempts, codes.ResourceExhausted, "too_many_attempts"},
+		{"unknown", errors.New("connection refused"), codes.Internal, "internal_error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newClient(t, &fakeLinks{err: tt.err}, &fakeAuth{err: tt.err}, nil)
+
+			_, err := c.links.GetLink(withMetadata("x-api-key", "secret-key"), &pb.GetLinkRequest{Id: 10})
+			assert.Equal(t, tt.code, status.Code(err))
+			assert.Equal(t, tt.reason, errorReason(t, err))
+
+			_, err = c.auth.Login(context.Background(), &pb.LoginRequest{Email: "alice@example.com", Password: "secret"})
+			assert.Equal(t, tt.code, status.Code(err))
+			assert.Equal(t, tt.reason, errorReason(t, err))
+		})
+	}
+
+	t.Run("internal-message-hidden", func(t *testing.T) {
+		c := newClient(t, &fakeLinks{err: errors.New("connection refused")}, &fakeAuth{}, nil)
+		_, err := c.links.ResolveLink(context.Background(), &pb.ResolveLinkRequest{GeneratedLink: "docs"})
+		assert.Equal(t, domain.ErrInternalServerError.Message, status.Convert(err).Message())
+	})
+}
@@ -0,0 +1,40 @@
+package rpc
+
+import (
+	"context"
+
+	"github.com/RedLucky/potongin/app/delivery/rpc/pb"
+	"github.com/RedLucky/potongin/domain"
+)
+
+// UserServer serve the UserService with the user usecase
+type UserServer struct {
+	pb.UnimplementedUserServiceServer
+	UserUsecase domain.UserUsecase
+}
+
+func (s *UserServer) GetMe(ctx context.Context, req *pb.GetMeRequest) (*pb.User, error) {
+	return s.getUser(ctx, domain.ActorFromContext(ctx))
+}
+
+func (s *UserServer) GetUser(ctx context.Context, req *pb.GetUserRequest) (*pb.User, error) {
+	return s.getUser(ctx, req.GetId())
+}
+
+func (s *UserServer) getUser(ctx context.Context, id int64) (*pb.User, error) {
+	user, err := s.UserUsecase.GetByID(ctx, id)
+	if err != nil {
+		return nil, Error(err)
+	}
+	return &pb.User{
+		Id:            user.ID,
+		Username:      user.Username,
+		Email:         user.Email,
+		Name:          user.Name,
+		EmailVerified: user.EmailVerified == "Y",
+		Role:          user.Role,
+		SuspendedAt:   timestampPtr(user.SuspendedAt),
+		CreatedAt:     timestamp(user.CreatedAt),
+		UpdatedAt:     timestamp(user.UpdatedAt),
+	}, nil
+}
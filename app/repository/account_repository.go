@@ -0,0 +1,161 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/RedLucky/potongin/domain"
+	"github.com/jinzhu/gorm"
+	"github.com/sirupsen/logrus"
+)
+
+type AccountRepository struct {
+	Mysql *gorm.DB
+}
+
+// NewAccountRepository will create an object that represent the domain.AccountRepository interface
+func NewAccountRepository(conn *gorm.DB) domain.AccountRepository {
+	return &AccountRepository{conn}
+}
+
+func (repo *AccountRepository) getUser(query string, args ...interface{}) (user domain.User, err error) {
+	err = repo.Mysql.Model(&domain.User{}).Where(query, args...).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return domain.User{}, domain.ErrNotFound
+	}
+	if err != nil {
+		logrus.Error(err)
+		return domain.User{}, err
+	}
+	return
+}
+
+func (repo *AccountRepository) GetUserById(ctx context.Context, id int64) (domain.User, error) {
+	return repo.getUser("id = ?", id)
+}
+
+func (repo *AccountRepository) GetUserByEmail(ctx context.Context, email string) (domain.User, error) {
+	return repo.getUser("email = ?", email)
+}
+
+func (repo *AccountRepository) GetUserByUsername(ctx context.Context, username string) (domain.User, error) {
+	return repo.getUser("username = ?", username)
+}
+
+func (repo *AccountRepository) UpdateProfile(ctx context.Context, user *domain.User) error {
+	return repo.Mysql.Model(&domain.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+		"name":       user.Name,
+		"username":   user.Username,
+		"updated_at": user.UpdatedAt,
+	}).Error
+}
+
+func (repo *AccountRepository) UpdatePassword(ctx context.Context, userId int64, hashedPassword string) error {
+	return repo.Mysql.Model(&domain.User{}).Where("id = ?", userId).Updates(map[string]interface{}{
+		"password":   hashedPassword,
+		"updated_at": time.Now(),
+	}).Error
+}
+
+// CreateEmailChange replace the pending email change of the user
+func (repo *AccountRepository) CreateEmailChange(ctx context.Context, change *domain.EmailChange) error {
+	return repo.Mysql.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("user_id = ?", change.UserId).Delete(&domain.EmailChange{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(change).Error
+	})
+}
+
+func (repo *AccountRepository) GetEmailChange(ctx context.Context, token string) (change domain.EmailChange, err error) {
+	err = repo.Mysql.Model(&domain.EmailChange{}).Where("token = ?", token).First(&change).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return domain.EmailChange{}, domain.ErrorTokenNotFound
+	}
+	if err != nil {
+		logrus.Error(err)
+		return domain.EmailChange{}, err
+	}
+	return
+}
+
+// ApplyEmailChange switch the email of the user to the verified new address
+func (repo *AccountRepository) ApplyEmailChange(ctx context.Context, change domain.EmailChange) error {
+	return repo.Mysql.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&domain.User{}).Where("id = ?", change.UserId).Updates(map[string]interface{}{
+			"email":          change.NewEmail,
+			"email_verified": "Y",
+			"updated_at":     time.Now(),
+		}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", change.UserId).Delete(&domain.EmailChange{}).Error
+	})
+}
+
+// DeleteUser delete the user with everything it owns, its links are moved to
+// the user transferTo when it is not zero
+func (repo *AccountRepository) DeleteUser(ctx context.Context, userId, transferTo int64) error {
+	return repo.Mysql.Transaction(func(tx *gorm.DB) (err error) {
+		// the links of organization workspaces stay with the organization
+		links := tx.Model(&domain.GeneratedUrl{}).Where("user_id = ? and org_id = 0", userId)
+		if transferTo != 0 {
+			err = links.Updates(map[string]interface{}{"user_id": transferTo, "updated_at": time.Now()}).Error
+		} else {
+			// the visits of the deleted links go with them
+			linkIds := tx.Model(&domain.GeneratedUrl{}).Select("id").Where("user_id = ? and org_id = 0", userId).QueryExpr()
+			err = tx.Where("url_id in (?)", linkIds).Delete(&domain.ClickEvent{}).Error
+			if err != nil {
+				return err
+			}
+			err = links.Delete(&domain.GeneratedUrl{}).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		owned := []interface{}{
+			&domain.VerifyEmail{},
+			&domain.EmailChange{},
+			&domain.UserIdentity{},
+			&domain.MfaRecoveryCode{},
+			&domain.UserMfa{},
+			&domain.OrganizationMember{},
+		}
+		for _, model := range owned {
+			if err = tx.Where("user_id = ?", userId).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Where("id = ?", userId).Delete(&domain.User{}).Error
+	})
+}
+
+// IsSoleOwner tell if the user is the last owner of an organization
+func (repo *AccountRepository) IsSoleOwner(ctx context.Context, userId int64) (bool, error) {
+	var count int64
+	err := repo.Mysql.Raw(`select count(*) from organization_members m
+		where m.user_id = ? and m.role = ?
+		and (select count(*) from organization_members o where o.org_id = m.org_id and o.role = ?) = 1`,
+		userId, domain.RoleOwner, domain.RoleOwner).Row().Scan(&count)
+	if err != nil {
+		logrus.Error(err)
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (repo *AccountRepository) ShareOrganization(ctx context.Context, userId, otherId int64) (bool, error) {
+	var count int64
+	err := repo.Mysql.Raw(`select count(*) from organization_members m
+		join organization_members o on o.org_id = m.org_id
+		where m.user_id = ? and o.user_id = ?`, userId, otherId).Row().Scan(&count)
+	if err != nil {
+		logrus.Error(err)
+		return false, err
+	}
+	return count > 0, nil
+}
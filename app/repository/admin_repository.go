@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/RedLucky/potongin/domain"
+	"github.com/jinzhu/gorm"
+	"github.com/sirupsen/logrus"
+)
+
+type AdminRepository struct {
+	Mysql *gorm.DB
+}
+
+var adminUserFields = "users.id, users.username, users.email, users.name, users.email_verified, users.role, " +
+	"users.suspended_at, users.suspend_reason, users.reset_required, users.created_at, " +
+	"count(generated_urls.id) as link_count, coalesce(sum(generated_urls.total_hits), 0) as total_hits"
+
+// NewAdminRepository will create an object that represent the domain.AdminRepository interface
+func NewAdminRepository(conn *gorm.DB) domain.AdminRepository {
+	return &AdminRepository{conn}
+}
+
+func (repo *AdminRepository) adminUsers() *gorm.DB {
+	return repo.Mysql.Table("users").Select(adminUserFields).
+		Joins("left join generated_urls on generated_urls.user_id = users.id").
+		Group("users.id")
+}
+
+func (repo *AdminRepository) GetRole(ctx context.Context, userId int64) (string, error) {
+	var user domain.User
+	err := repo.Mysql.Model(&domain.User{}).Select("role").Where("id = ?", userId).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", domain.ErrNotFound
+	}
+	return user.Role, err
+}
+
+// SearchUsers match the query against the email, the username and the name,
+// lower() keeps it case insensitive on PostgreSQL as on the MySQL collations
+func (repo *AdminRepository) SearchUsers(ctx context.Context, query string, limit, offset int) (users []domain.AdminUser, err error) {
+	db := repo.adminUsers()
+	if query != "" {
+		like := "%" + strings.ToLower(query) + "%"
+		db = db.Where("lower(users.email) like ? or lower(users.username) like ? or lower(users.name) like ?", like, like, like)
+	}
+	err = db.Order("users.id").Limit(limit).Offset(offset).Scan(&users).Error
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+	return
+}
+
+func (repo *AdminRepository) GetUser(ctx context.Context, userId int64) (user domain.AdminUser, err error) {
+	var users []domain.AdminUser
+	err = repo.adminUsers().Where("users.id = ?", userId).Scan(&users).Error
+	if err != nil {
+		logrus.Error(err)
+		return domain.AdminUser{}, err
+	}
+	if len(users) == 0 {
+		return domain.AdminUser{}, domain.ErrNotFound
+	}
+	return users[0], nil
+}
+
+func (repo *AdminRepository) GetLinks(ctx context.Context, userId int64) (links []domain.GeneratedUrl, err error) {
+	err = repo.Mysql.Model(&domain.GeneratedUrl{}).Where("user_id = ?", userId).Order("id").Find(&links).Error
+	return
+}
+
+func (repo *AdminRepository) GetLink(ctx context.Context, linkId int64) (link domain.GeneratedUrl, err error) {
+	err = repo.Mysql.Model(&domain.GeneratedUrl{}).Where("id = ?", linkId).First(&link).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return domain.GeneratedUrl{}, domain.ErrUrlNotFound
+	}
+	return
+}
+
+// SetSuspension suspend the user, or lift the suspension when suspendedAt is nil
+func (repo *AdminRepository) SetSuspension(ctx context.Context, userId int64, suspendedAt *time.Time, reason string) error {
+	return repo.Mysql.Model(&domain.User{}).Where("id = ?", userId).Updates(map[string]interface{}{
+		"suspended_at":   suspendedAt,
+		"suspend_reason": reason,
+		"updated_at":     time.Now(),
+	}).Error
+}
+
+func (repo *AdminRepository) VerifyEmail(ctx context.Context, userId int64) error {
+	return repo.Mysql.Model(&domain.User{}).Where("id = ?", userId).Updates(map[string]interface{}{
+		"email_verified": "Y",
+		"updated_at":     time.Now(),
+	}).Error
+}
+
+// ForcePasswordReset refuse the current password until it is reset with the token
+func (repo *AdminRepository) ForcePasswordReset(ctx context.Context, reset *domain.ResetPassword) error {
+	return repo.Mysql.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&domain.User{}).Where("id = ?", reset.UserId).Updates(map[string]interface{}{
+			"reset_required": "Y",
+			"updated_at":     time.Now(),
+		}).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Where("user_id = ? and used_at is null", reset.UserId).Delete(&domain.ResetPassword{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(reset).Error
+	})
+}
+
+// SetTakedown take the link down, or restore it when takenDownAt is nil
+func (repo *AdminRepository) SetTakedown(ctx context.Context, linkId int64, takenDownAt *time.Time, reason string) error {
+	return repo.Mysql.Model(&domain.GeneratedUrl{}).Where("id = ?", linkId).Updates(map[string]interface{}{
+		"taken_down_at":   takenDownAt,
+		"takedown_reason": reason,
+		"updated_at":      time.Now(),
+	}).Error
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/RedLucky/potongin/domain"
+	"github.com/jinzhu/gorm"
+	"github.com/sirupsen/logrus"
+)
+
+type AuditRepository struct {
+	Mysql *gorm.DB
+}
+
+// NewAuditRepository will create an object that represent the domain.AuditRepository interface
+func NewAuditRepository(conn *gorm.DB) domain.AuditRepository {
+	return &AuditRepository{conn}
+}
+
+func (repo *AuditRepository) Store(ctx context.Context, entry *domain.AuditLog) error {
+	return repo.Mysql.Create(entry).Error
+}
+
+func (repo *AuditRepository) Fetch(ctx context.Context, filter domain.AuditFilter) (logs []domain.AuditLog, err error) {
+	query := repo.Mysql.Model(&domain.AuditLog{})
+	if filter.ActorId != 0 {
+		query = query.Where("actor_id = ?", filter.ActorId)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.TargetType != "" {
+		query = query.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetId != "" {
+		query = query.Where("target_id = ?", filter.TargetId)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("created_at < ?", filter.To)
+	}
+	if filter.BeforeId != 0 {
+		query = query.Where("id < ?", filter.BeforeId)
+	}
+	err = query.Order("id desc").Limit(filter.Limit).Offset(filter.Offset).Find(&logs).Error
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+	return
+}
@@ -48,6 +48,16 @@ func (r *AuthRepository) GetUserByUsername(ctx context.Context, username string)
 	return user, nil
 }
 
+func (r *AuthRepository) GetUserById(ctx context.Context, id int64) (user domain.User, err error) {
+	err = r.Mysql.Model(&domain.User{}).Where("id = ?", id).First(&user).Error
+	if err != nil {
+		logrus.Error(err)
+		return domain.User{}, err
+	}
+
+	return user, nil
+}
+
 func (r *AuthRepository) IsExistEmail(email string) (result domain.User, err error) {
 	err = r.Mysql.Model(&domain.User{}).Where("email = ?", email).First(&result).Error
 	if err != nil {
@@ -107,6 +117,13 @@ func (r *AuthRepository) VerifyTokenAccount(ctx context.Context, userId int64) e
 	return err
 }
 
+func (r *AuthRepository) UpdatePassword(ctx context.Context, userId int64, hashedPassword string) error {
+	return r.Mysql.Model(&domain.User{}).Where("id = ?", userId).Updates(map[string]interface{}{
+		"password":   hashedPassword,
+		"updated_at": time.Now(),
+	}).Error
+}
+
 func (r *AuthRepository) DeletePreviousVerifyEmail(userId int64) error {
 	err := r.Mysql.Model(&domain.VerifyEmail{}).Where("user_id = ?", userId).Delete(&domain.VerifyEmail{}).Error
 	return err
@@ -119,3 +136,60 @@ func (r *AuthRepository) CreateVerifyEmail(verifyEmail *domain.VerifyEmail) erro
 	}
 	return nil
 }
+
+func (r *AuthRepository) GetIdentity(ctx context.Context, provider, subject string) (identity domain.UserIdentity, err error) {
+	err = r.Mysql.Model(&domain.UserIdentity{}).Where("provider = ? and subject = ?", provider, subject).First(&identity).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return domain.UserIdentity{}, domain.ErrNotFound
+	}
+	if err != nil {
+		logrus.Error(err)
+		return domain.UserIdentity{}, err
+	}
+	return identity, nil
+}
+
+func (r *AuthRepository) CreateIdentity(ctx context.Context, identity *domain.UserIdentity) error {
+	return r.Mysql.Create(identity).Error
+}
+
+// CreateResetPassword replace the pending reset password token of the user
+func (r *AuthRepository) CreateResetPassword(ctx context.Context, reset *domain.ResetPassword) error {
+	return r.Mysql.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("user_id = ? and used_at is null", reset.UserId).Delete(&domain.ResetPassword{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(reset).Error
+	})
+}
+
+func (r *AuthRepository) GetResetPassword(ctx context.Context, token string) (reset domain.ResetPassword, err error) {
+	err = r.Mysql.Model(&domain.ResetPassword{}).Where("token = ?", token).First(&reset).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return domain.ResetPassword{}, domain.ErrorTokenNotFound
+	}
+	if err != nil {
+		logrus.Error(err)
+		return domain.ResetPassword{}, err
+	}
+	return
+}
+
+// ResetPassword burn the token and set the new password, only once
+func (r *AuthRepository) ResetPassword(ctx context.Context, reset domain.ResetPassword, hashedPassword string) error {
+	return r.Mysql.Transaction(func(tx *gorm.DB) error {
+		db := tx.Model(&domain.ResetPassword{}).Where("id = ? and used_at is null", reset.ID).Update("used_at", time.Now())
+		if db.Error != nil {
+			return db.Error
+		}
+		if db.RowsAffected != 1 {
+			return domain.ErrorTokenNotFound
+		}
+		return tx.Model(&domain.User{}).Where("id = ?", reset.UserId).Updates(map[string]interface{}{
+			"password":       hashedPassword,
+			"reset_required": "N",
+			"updated_at":     time.Now(),
+		}).Error
+	})
+}
@@ -3,10 +3,10 @@ package repository
 import (
 	"context"
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/RedLucky/potongin/domain"
-	"github.com/gomodule/redigo/redis"
 	"github.com/jinzhu/gorm"
 	"github.com/sirupsen/logrus"
 )
@@ -29,12 +29,12 @@ func (repo *GeneratedUrlRepository) InsertUrl(ctx context.Context, url *domain.G
 
 func (repo *GeneratedUrlRepository) UpdateUrl(ctx context.Context, url *domain.GeneratedUrl) (err error) {
 	err = repo.Mysql.Model(&domain.GeneratedUrl{}).Where("id = ?", url.ID).Updates(
-		domain.GeneratedUrl{Source: url.Source, Generated: url.Generated}).Error
+		domain.GeneratedUrl{Name: url.Name, Source: url.Source, Generated: url.Generated, IsActive: url.IsActive, UpdatedAt: url.UpdatedAt}).Error
 	return
 }
 
 func (repo *GeneratedUrlRepository) GetUrlByUserId(ctx context.Context, userId int64) (generateUrls []domain.GeneratedUrl, err error) {
-	err = repo.Mysql.Model(&domain.GeneratedUrl{}).Where("user_id = ?", userId).Find(&generateUrls).Error
+	err = repo.Mysql.Model(&domain.GeneratedUrl{}).Where("user_id = ? and org_id = 0", userId).Find(&generateUrls).Error
 	if err != nil {
 		logrus.Error(err)
 		return nil, err
@@ -42,6 +42,61 @@ func (repo *GeneratedUrlRepository) GetUrlByUserId(ctx context.Context, userId i
 	return
 }
 
+func (repo *GeneratedUrlRepository) GetUrlByOrgId(ctx context.Context, orgId int64) (generateUrls []domain.GeneratedUrl, err error) {
+	err = repo.Mysql.Model(&domain.GeneratedUrl{}).Where("org_id = ?", orgId).Find(&generateUrls).Error
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+	return
+}
+
+// workspace restrict the links to the personal workspace of the user, or to
+// the organization when orgId isn't 0
+func workspace(query *gorm.DB, userId, orgId int64) *gorm.DB {
+	if orgId != 0 {
+		return query.Where("org_id = ?", orgId)
+	}
+	return query.Where("user_id = ? and org_id = 0", userId)
+}
+
+func (repo *GeneratedUrlRepository) SearchUrls(ctx context.Context, userId, orgId int64, filter domain.LinkFilter) (generateUrls []domain.GeneratedUrl, total int64, err error) {
+	query := workspace(repo.Mysql.Model(&domain.GeneratedUrl{}), userId, orgId)
+	if filter.Search != "" {
+		like := "%" + strings.ToLower(filter.Search) + "%"
+		query = query.Where("lower(name) like ? or lower(source) like ? or lower(generated) like ?", like, like, like)
+	}
+	if filter.Active != nil {
+		if *filter.Active {
+			query = query.Where("is_active = ?", "Y")
+		} else {
+			query = query.Where("is_active <> ?", "Y")
+		}
+	}
+	if filter.TakenDown != nil {
+		if *filter.TakenDown {
+			query = query.Where("taken_down_at is not null")
+		} else {
+			query = query.Where("taken_down_at is null")
+		}
+	}
+	if err = query.Count(&total).Error; err != nil {
+		logrus.Error(err)
+		return nil, 0, err
+	}
+	// mysql can't skip rows without a limit
+	query = query.Order("id asc")
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit).Offset(filter.Offset)
+	}
+	generateUrls = []domain.GeneratedUrl{}
+	if err = query.Find(&generateUrls).Error; err != nil {
+		logrus.Error(err)
+		return nil, 0, err
+	}
+	return
+}
+
 func (repo *GeneratedUrlRepository) GetUrlById(ctx context.Context, urlId string) (generateUrl domain.GeneratedUrl, err error) {
 	err = repo.Mysql.Model(&domain.GeneratedUrl{}).Where("id = ?", urlId).First(&generateUrl).Error
 	if err != nil {
@@ -78,11 +133,11 @@ func (repo *GeneratedUrlRepository) IsExistUrlOrigin(ctx context.Context, urlOri
 func (repo *GeneratedUrlRepository) IsExistUrlGenerated(ctx context.Context, urlGenerated string) (result bool, err error) {
 	var generateUrl domain.GeneratedUrl
 	err = repo.Mysql.Model(&domain.GeneratedUrl{}).Where(" ? between start_date and end_date and is_active = ? ", time.Now(), "Y").First(&generateUrl, "generated = ?", urlGenerated).Error
-	if err != nil {
-		logrus.Error(err)
-		return false, err
-	}
+	// an unknown code is not a failure of the lookup
 	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
 		logrus.Error(err)
 		return false, err
 	}
@@ -92,7 +147,7 @@ func (repo *GeneratedUrlRepository) IsExistUrlGenerated(ctx context.Context, url
 
 func (repo *GeneratedUrlRepository) CheckDoubleNameByUserId(ctx context.Context, name string, userId int64) (result bool, err error) {
 	var generateUrl domain.GeneratedUrl
-	err = repo.Mysql.Model(&domain.GeneratedUrl{}).Where("name = ? and user_id = ?", name, userId).First(&generateUrl).Error
+	err = repo.Mysql.Model(&domain.GeneratedUrl{}).Where("name = ? and user_id = ? and org_id = 0", name, userId).First(&generateUrl).Error
 
 	if err != nil {
 		logrus.Error(err)
@@ -107,24 +162,181 @@ func (repo *GeneratedUrlRepository) CheckDoubleNameByUserId(ctx context.Context,
 	return
 }
 
-func (repo *GeneratedUrlRepository) HitUrl(ctx context.Context, urlId, total int64) (err error) {
-	err = repo.Mysql.Model(&domain.GeneratedUrl{}).Where("id = ?", urlId).Updates(
-		domain.GeneratedUrl{TotalHits: total}).Error
+func (repo *GeneratedUrlRepository) CheckDoubleNameByOrgId(ctx context.Context, name string, orgId int64) (result bool, err error) {
+	var generateUrl domain.GeneratedUrl
+	err = repo.Mysql.Model(&domain.GeneratedUrl{}).Where("name = ? and org_id = ?", name, orgId).First(&generateUrl).Error
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// HitUrl increment the hits in the database, the concurrent visits are all
+// counted
+func (repo *GeneratedUrlRepository) HitUrl(ctx context.Context, urlId int64) (err error) {
+	err = repo.Mysql.Model(&domain.GeneratedUrl{}).Where("id = ?", urlId).Update(
+		"total_hits", gorm.Expr("total_hits + 1")).Error
+	return
+}
+
+func (repo *GeneratedUrlRepository) InsertClickEvent(ctx context.Context, event *domain.ClickEvent) error {
+	return repo.Mysql.Create(event).Error
+}
+
+// GetClickStats count the visits of the link since a time, in total and by
+// day, device and referer, the busiest first
+func (repo *GeneratedUrlRepository) GetClickStats(ctx context.Context, urlId int64, since time.Time) (stats domain.LinkStats, err error) {
+	stats.Since = since
+	clicks := repo.Mysql.Model(&domain.ClickEvent{}).Where("url_id = ? and created_at >= ?", urlId, since)
+	if err = clicks.Count(&stats.TotalClicks).Error; err != nil {
+		return domain.LinkStats{}, err
+	}
+	if stats.Daily, err = countClicks(clicks, "date(created_at)", "bucket asc"); err != nil {
+		return domain.LinkStats{}, err
+	}
+	if stats.Devices, err = countClicks(clicks, "device", "clicks desc"); err != nil {
+		return domain.LinkStats{}, err
+	}
+	if stats.Referers, err = countClicks(clicks, "referer", "clicks desc"); err != nil {
+		return domain.LinkStats{}, err
+	}
 	return
 }
 
-// using redis
-func (repo *GeneratedUrlRepository) GetUrlFromCache(redisCon redis.Conn, generatedUrl string) (res string, err error) {
-	res, err = redis.String(redisCon.Do("HGET", generatedUrl, "source_url"))
+// GetWorkspaceClickStats count the visits like GetClickStats, with one query
+// per bucket for every link
+func (repo *GeneratedUrlRepository) GetWorkspaceClickStats(ctx context.Context, userId, orgId int64, urlIds []int64, since time.Time) (stats map[int64]domain.LinkStats, err error) {
+	stats = map[int64]domain.LinkStats{}
+	if len(urlIds) == 0 {
+		return stats, nil
+	}
+	var visible []int64
+	err = workspace(repo.Mysql.Model(&domain.GeneratedUrl{}), userId, orgId).Where("id in (?)", urlIds).Pluck("id", &visible).Error
+	if err != nil || len(visible) == 0 {
+		return stats, err
+	}
+
+	totals := make(map[int64]int64, len(visible))
+	clicks := repo.Mysql.Model(&domain.ClickEvent{}).Where("url_id in (?) and created_at >= ?", visible, since)
+	rows, err := clicks.Select("url_id, count(*)").Group("url_id").Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var urlId, count int64
+		if err = rows.Scan(&urlId, &count); err != nil {
+			return nil, err
+		}
+		totals[urlId] = count
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	daily, err := countUrlClicks(clicks, "date(created_at)", "bucket asc")
+	if err != nil {
+		return nil, err
+	}
+	devices, err := countUrlClicks(clicks, "device", "clicks desc")
+	if err != nil {
+		return nil, err
+	}
+	referers, err := countUrlClicks(clicks, "referer", "clicks desc")
+	if err != nil {
+		return nil, err
+	}
+	for _, urlId := range visible {
+		stats[urlId] = domain.LinkStats{
+			Since:       since,
+			TotalClicks: totals[urlId],
+			Daily:       append([]domain.ClickCount{}, daily[urlId]...),
+			Devices:     append([]domain.ClickCount{}, devices[urlId]...),
+			Referers:    append([]domain.ClickCount{}, referers[urlId]...),
+		}
+	}
 	return
 }
 
-func (repo *GeneratedUrlRepository) SetUrlToCache(redisCon redis.Conn, generatedUrl, sourceUrl string) error {
-	_, err := redisCon.Do("HSET", generatedUrl, "source_url", sourceUrl)
-	return err
+// countClicks group the clicks by the column expression
+func countClicks(clicks *gorm.DB, column, order string) (counts []domain.ClickCount, err error) {
+	rows, err := clicks.Select(column + " as bucket, count(*) as clicks").Group(column).Order(order).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	counts = []domain.ClickCount{}
+	for rows.Next() {
+		var bucket interface{}
+		var count domain.ClickCount
+		if err = rows.Scan(&bucket, &count.Clicks); err != nil {
+			return nil, err
+		}
+		count.Key = bucketKey(bucket)
+		counts = append(counts, count)
+	}
+	return counts, rows.Err()
+}
+
+// countUrlClicks is countClicks by link id
+func countUrlClicks(clicks *gorm.DB, column, order string) (counts map[int64][]domain.ClickCount, err error) {
+	rows, err := clicks.Select("url_id, " + column + " as bucket, count(*) as clicks").Group("url_id, " + column).Order("url_id, " + order).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	counts = map[int64][]domain.ClickCount{}
+	for rows.Next() {
+		var urlId int64
+		var bucket interface{}
+		var count domain.ClickCount
+		if err = rows.Scan(&urlId, &bucket, &count.Clicks); err != nil {
+			return nil, err
+		}
+		count.Key = bucketKey(bucket)
+		counts[urlId] = append(counts[urlId], count)
+	}
+	return counts, rows.Err()
 }
 
-func (repo *GeneratedUrlRepository) SetUrlExpCache(redisCon redis.Conn, generatedUrl string, duration int) error {
-	_, err := redisCon.Do("EXPIRE", generatedUrl, duration*60) //duration in seconds
-	return err
+func bucketKey(bucket interface{}) string {
+	switch bucket := bucket.(type) {
+	case time.Time:
+		// date() is parsed as a time by the drivers with parseTime
+		return bucket.Format("2006-01-02")
+	case []byte:
+		return string(bucket)
+	case string:
+		return bucket
+	}
+	return ""
+}
+
+func (repo *GeneratedUrlRepository) IsOwnerSuspended(ctx context.Context, userId int64) (bool, error) {
+	var count int64
+	err := repo.Mysql.Model(&domain.User{}).Where("id = ? and suspended_at is not null", userId).Count(&count).Error
+	return count > 0, err
+}
+
+// ExpireUrls deactivate the active links whose end date passed. Links without
+// end date keep a zero end date, before their start date. A link is returned
+// by the instance that deactivated it only.
+func (repo *GeneratedUrlRepository) ExpireUrls(ctx context.Context, now time.Time) (expired []domain.GeneratedUrl, err error) {
+	var candidates []domain.GeneratedUrl
+	err = repo.Mysql.Where("is_active = ? and end_date <= ? and end_date > start_date", "Y", now).Find(&candidates).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, url := range candidates {
+		db := repo.Mysql.Model(&domain.GeneratedUrl{}).Where("id = ? and is_active = ?", url.ID, "Y").
+			Updates(map[string]interface{}{"is_active": "N", "updated_at": now})
+		if db.Error != nil {
+			return expired, db.Error
+		}
+		if db.RowsAffected == 1 {
+			url.IsActive = "N"
+			expired = append(expired, url)
+		}
+	}
+	return
 }
@@ -0,0 +1,123 @@
+package repository_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/RedLucky/potongin/app/repository"
+	"github.com/RedLucky/potongin/domain"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeneratedUrlRepository_GetClickStats(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	gdb, _ := gorm.Open("mysql", db)
+	generatedUrlRepo := repository.NewGeneratedUrlRepository(gdb)
+	since := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM `click_events` WHERE (url_id = ? and created_at >= ?)")).
+		WithArgs(5, since).
+		WillReturnRows(sqlmock.NewRows([]string{"count(*)"}).AddRow(3))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT date(created_at) as bucket, count(*) as clicks FROM `click_events` WHERE (url_id = ? and created_at >= ?) GROUP BY date(created_at) ORDER BY bucket asc")).
+		WithArgs(5, since).
+		WillReturnRows(sqlmock.NewRows([]string{"bucket", "clicks"}).
+			AddRow(since, 1).
+			AddRow([]byte("2021-03-02"), 2))
+	mock.ExpectQuery(regexp.QuoteMeta("GROUP BY device ORDER BY clicks desc")).
+		WithArgs(5, since).
+		WillReturnRows(sqlmock.NewRows([]string{"bucket", "clicks"}).AddRow("iPhone", 3))
+	mock.ExpectQuery(regexp.QuoteMeta("GROUP BY referer ORDER BY clicks desc")).
+		WithArgs(5, since).
+		WillReturnRows(sqlmock.NewRows([]string{"bucket", "clicks"}))
+
+	stats, err := generatedUrlRepo.GetClickStats(context.TODO(), 5, since)
+
+	require.NoError(t, err)
+	assert.Equal(t, domain.LinkStats{
+		Since:       since,
+		TotalClicks: 3,
+		Daily:       []domain.ClickCount{{Key: "2021-03-01", Clicks: 1}, {Key: "2021-03-02", Clicks: 2}},
+		Devices:     []domain.ClickCount{{Key: "iPhone", Clicks: 3}},
+		Referers:    []domain.ClickCount{},
+	}, stats)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGeneratedUrlRepository_SearchUrls(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	gdb, _ := gorm.Open("mysql", db)
+	generatedUrlRepo := repository.NewGeneratedUrlRepository(gdb)
+	active := false
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM `generated_urls` WHERE (org_id = ?) AND (lower(name) like ? or lower(source) like ? or lower(generated) like ?) AND (is_active <> ?)")).
+		WithArgs(10, "%docs%", "%docs%", "%docs%", "Y").
+		WillReturnRows(sqlmock.NewRows([]string{"count(*)"}).AddRow(3))
+	mock.ExpectQuery(regexp.QuoteMeta("ORDER BY id asc LIMIT 2 OFFSET 2")).
+		WithArgs(10, "%docs%", "%docs%", "%docs%", "Y").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "org_id", "name"}).AddRow(7, 10, "Docs"))
+
+	urls, total, err := generatedUrlRepo.SearchUrls(context.TODO(), 1, 10, domain.LinkFilter{Search: "DOCS", Active: &active, Limit: 2, Offset: 2})
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	assert.Equal(t, []domain.GeneratedUrl{{ID: 7, OrgId: 10, Name: "Docs"}}, urls)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGeneratedUrlRepository_GetWorkspaceClickStats(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	gdb, _ := gorm.Open("mysql", db)
+	generatedUrlRepo := repository.NewGeneratedUrlRepository(gdb)
+	since := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	// the link 9 of another workspace is left out
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT id FROM `generated_urls` WHERE (user_id = ? and org_id = 0) AND (id in (?,?,?))")).
+		WithArgs(1, 5, 6, 9).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5).AddRow(6))
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT url_id, count(*) FROM `click_events` WHERE (url_id in (?,?) and created_at >= ?) GROUP BY url_id")).
+		WithArgs(5, 6, since).
+		WillReturnRows(sqlmock.NewRows([]string{"url_id", "count(*)"}).AddRow(5, 3))
+	mock.ExpectQuery(regexp.QuoteMeta("GROUP BY url_id, date(created_at) ORDER BY url_id, bucket asc")).
+		WithArgs(5, 6, since).
+		WillReturnRows(sqlmock.NewRows([]string{"url_id", "bucket", "clicks"}).
+			AddRow(5, since, 1).
+			AddRow(5, []byte("2021-03-02"), 2))
+	mock.ExpectQuery(regexp.QuoteMeta("GROUP BY url_id, device ORDER BY url_id, clicks desc")).
+		WithArgs(5, 6, since).
+		WillReturnRows(sqlmock.NewRows([]string{"url_id", "bucket", "clicks"}).AddRow(5, "iPhone", 3))
+	mock.ExpectQuery(regexp.QuoteMeta("GROUP BY url_id, referer ORDER BY url_id, clicks desc")).
+		WithArgs(5, 6, since).
+		WillReturnRows(sqlmock.NewRows([]string{"url_id", "bucket", "clicks"}))
+
+	stats, err := generatedUrlRepo.GetWorkspaceClickStats(context.TODO(), 1, 0, []int64{5, 6, 9}, since)
+
+	require.NoError(t, err)
+	assert.Equal(t, map[int64]domain.LinkStats{
+		5: {
+			Since:       since,
+			TotalClicks: 3,
+			Daily:       []domain.ClickCount{{Key: "2021-03-01", Clicks: 1}, {Key: "2021-03-02", Clicks: 2}},
+			Devices:     []domain.ClickCount{{Key: "iPhone", Clicks: 3}},
+			Referers:    []domain.ClickCount{},
+		},
+		6: {Since: since, Daily: []domain.ClickCount{}, Devices: []domain.ClickCount{}, Referers: []domain.ClickCount{}},
+	}, stats)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,201 @@
+//go:build integration
+// +build integration
+
+package repository_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/RedLucky/potongin/app/repository"
+	"github.com/RedLucky/potongin/config/db"
+	"github.com/RedLucky/potongin/domain"
+	"github.com/jinzhu/gorm"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The integration tests run the repositories against a real database, its
+// tables are dropped and created again so give them a database of their own:
+//
+//	POTONGIN_TEST_DB_DRIVER=mysql POTONGIN_TEST_DB_DSN='potongin:secret@tcp(localhost:3306)/potongin_test?parseTime=1' \
+//		go test -tags integration ./app/repository/
+//	POTONGIN_TEST_DB_DRIVER=postgres POTONGIN_TEST_DB_DSN='host=localhost dbname=potongin_test sslmode=disable binary_parameters=yes' \
+//		go test -tags integration ./app/repository/
+//	POTONGIN_TEST_DB_DRIVER=sqlite3 POTONGIN_TEST_DB_DSN=':memory:' go test -tags integration ./app/repository/
+func integrationDB(t *testing.T) *gorm.DB {
+	driver, dsn := os.Getenv("POTONGIN_TEST_DB_DRIVER"), os.Getenv("POTONGIN_TEST_DB_DSN")
+	if driver == "" || dsn == "" {
+		t.Skip("POTONGIN_TEST_DB_DRIVER and POTONGIN_TEST_DB_DSN are not set")
+	}
+	database, err := db.Open(driver, dsn)
+	require.NoError(t, err)
+	conn := database.Conn
+	t.Cleanup(func() { conn.Close() })
+
+	require.NoError(t, conn.DropTableIfExists(append(db.Models, &db.SchemaMigration{})...).Error)
+	migrator, err := db.NewMigrator(database)
+	require.NoError(t, err)
+	_, err = migrator.Up()
+	require.NoError(t, err)
+	return conn
+}
+
+func storeUser(t *testing.T, conn *gorm.DB, username, email string) domain.User {
+	user := domain.User{Username: username, Email: email, Password: "hash", Name: username, EmailVerified: "Y", Role: domain.UserRoleUser}
+	require.NoError(t, repository.NewUserRepository(conn).Store(&user))
+	require.NotZero(t, user.ID)
+	return user
+}
+
+func TestIntegration_Users(t *testing.T) {
+	conn := integrationDB(t)
+	userRepo := repository.NewUserRepository(conn)
+	alice := storeUser(t, conn, "alice", "Alice@Example.com")
+	storeUser(t, conn, "bob", "bob@example.com")
+
+	user, err := userRepo.GetByEmail("Alice@Example.com")
+	require.NoError(t, err)
+	assert.Equal(t, alice.ID, user.ID)
+
+	// the search ignores the case on every database
+	users, err := repository.NewAdminRepository(conn).SearchUsers(context.TODO(), "ALICE@", 10, 0)
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	assert.Equal(t, "alice", users[0].Username)
+	assert.Equal(t, int64(0), users[0].TotalHits)
+}
+
+func TestIntegration_Links(t *testing.T) {
+	conn := integrationDB(t)
+	linkRepo := repository.NewGeneratedUrlRepository(conn)
+	alice := storeUser(t, conn, "alice", "alice@example.com")
+	now := time.Now().UTC().Truncate(time.Second)
+
+	link := domain.GeneratedUrl{UserId: alice.ID, Name: "docs", Source: "example.com/docs", Generated: "docs", IsActive: "Y",
+		StartDate: now.Add(-time.Hour), EndDate: now.Add(-time.Minute), CreatedAt: now, UpdatedAt: now}
+	require.NoError(t, linkRepo.InsertUrl(context.TODO(), &link))
+	for i := 0; i < 3; i++ {
+		require.NoError(t, linkRepo.HitUrl(context.TODO(), link.ID))
+	}
+
+	yesterday := now.Add(-24 * time.Hour)
+	for _, event := range []domain.ClickEvent{
+		{UrlId: link.ID, Device: "iPhone", Referer: "news.example.com", Anonymized: "N", CreatedAt: yesterday},
+		{UrlId: link.ID, Device: "iPhone", Anonymized: "N", CreatedAt: now},
+		{UrlId: link.ID, Device: "Desktop", Anonymized: "N", CreatedAt: now},
+	} {
+		event := event
+		require.NoError(t, linkRepo.InsertClickEvent(context.TODO(), &event))
+	}
+
+	stats, err := linkRepo.GetClickStats(context.TODO(), link.ID, yesterday.Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), stats.TotalClicks)
+	assert.Len(t, stats.Daily, 2)
+	assert.Equal(t, domain.ClickCount{Key: "iPhone", Clicks: 2}, stats.Devices[0])
+
+	blog := domain.GeneratedUrl{UserId: alice.ID, Name: "blog", Source: "example.com/blog", Generated: "blog", IsActive: "N",
+		CreatedAt: now, UpdatedAt: now}
+	require.NoError(t, linkRepo.InsertUrl(context.TODO(), &blog))
+	other := domain.GeneratedUrl{UserId: alice.ID, OrgId: 10, Name: "team", Source: "example.com/team", Generated: "team", IsActive: "Y",
+		CreatedAt: now, UpdatedAt: now}
+	require.NoError(t, linkRepo.InsertUrl(context.TODO(), &other))
+
+	// the filters and the page run in the database
+	found, total, err := linkRepo.SearchUrls(context.TODO(), alice.ID, 0, domain.LinkFilter{Search: "EXAMPLE.COM/", Limit: 1, Offset: 1})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	require.Len(t, found, 1)
+	assert.Equal(t, blog.ID, found[0].ID)
+	active, takenDown := true, false
+	found, total, err = linkRepo.SearchUrls(context.TODO(), alice.ID, 0, domain.LinkFilter{Active: &active, TakenDown: &takenDown})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	require.Len(t, found, 1)
+	assert.Equal(t, link.ID, found[0].ID)
+
+	byUrl, err := linkRepo.GetWorkspaceClickStats(context.TODO(), alice.ID, 0, []int64{link.ID, blog.ID, other.ID}, yesterday.Add(-time.Hour))
+	require.NoError(t, err)
+	require.Len(t, byUrl, 2)
+	assert.Equal(t, stats, byUrl[link.ID])
+	assert.Equal(t, int64(0), byUrl[blog.ID].TotalClicks)
+
+	users, err := repository.NewAdminRepository(conn).SearchUsers(context.TODO(), "", 10, 0)
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	assert.Equal(t, int64(3), users[0].LinkCount)
+	assert.Equal(t, int64(3), users[0].TotalHits)
+
+	expired, err := linkRepo.ExpireUrls(context.TODO(), now)
+	require.NoError(t, err)
+	require.Len(t, expired, 1)
+	stored, err := linkRepo.GetUrlById(context.TODO(), strconv.FormatInt(link.ID, 10))
+	require.NoError(t, err)
+	assert.Equal(t, "N", stored.IsActive)
+}
+
+func TestIntegration_Organizations(t *testing.T) {
+	conn := integrationDB(t)
+	orgRepo := repository.NewOrganizationRepository(conn)
+	accountRepo := repository.NewAccountRepository(conn)
+	alice := storeUser(t, conn, "alice", "alice@example.com")
+	bob := storeUser(t, conn, "bob", "bob@example.com")
+
+	org := domain.Organization{Name: "Acme", CreatedBy: alice.ID}
+	require.NoError(t, orgRepo.Create(context.TODO(), &org, &domain.OrganizationMember{UserId: alice.ID, Role: domain.RoleOwner}))
+	require.NoError(t, conn.Create(&domain.OrganizationMember{OrgId: org.ID, UserId: bob.ID, Role: domain.RoleViewer}).Error)
+
+	orgs, err := orgRepo.FetchByUserId(context.TODO(), bob.ID)
+	require.NoError(t, err)
+	require.Len(t, orgs, 1)
+	assert.Equal(t, domain.RoleViewer, orgs[0].Role)
+
+	soleOwner, err := accountRepo.IsSoleOwner(context.TODO(), alice.ID)
+	require.NoError(t, err)
+	assert.True(t, soleOwner)
+	shared, err := accountRepo.ShareOrganization(context.TODO(), alice.ID, bob.ID)
+	require.NoError(t, err)
+	assert.True(t, shared)
+
+	require.NoError(t, accountRepo.DeleteUser(context.TODO(), bob.ID, 0))
+	members, err := orgRepo.GetMembers(context.TODO(), org.ID)
+	require.NoError(t, err)
+	assert.Len(t, members, 1)
+}
+
+func TestIntegration_AuditAndWebhooks(t *testing.T) {
+	conn := integrationDB(t)
+	alice := storeUser(t, conn, "alice", "alice@example.com")
+	diff := json.RawMessage(`{"name":{"from":"docs","to":"guides"}}`)
+
+	auditRepo := repository.NewAuditRepository(conn)
+	require.NoError(t, auditRepo.Store(context.TODO(), &domain.AuditLog{ActorId: alice.ID, Action: domain.AuditLinkUpdate, TargetType: "link", TargetId: "1", Diff: diff}))
+	logs, err := auditRepo.Fetch(context.TODO(), domain.AuditFilter{ActorId: alice.ID, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	assert.JSONEq(t, string(diff), string(logs[0].Diff))
+
+	webhookRepo := repository.NewWebhookRepository(conn)
+	webhook := domain.Webhook{UserId: alice.ID, Url: "https://example.com/hook", Events: "link.updated", Secret: "secret", Active: "Y"}
+	require.NoError(t, webhookRepo.Create(context.TODO(), &webhook))
+	now := time.Now().UTC().Truncate(time.Second)
+	delivery := domain.WebhookDelivery{WebhookId: webhook.ID, EventId: "event-1", Event: "link.updated", Payload: diff, Status: domain.DeliveryPending, NextAttemptAt: now}
+	require.NoError(t, webhookRepo.CreateDelivery(context.TODO(), &delivery))
+
+	due, err := webhookRepo.FetchDueDeliveries(context.TODO(), now.Add(time.Second), 10)
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	assert.JSONEq(t, string(diff), string(due[0].Payload))
+
+	claimed, err := webhookRepo.ClaimDelivery(context.TODO(), due[0], now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.True(t, claimed)
+	claimed, err = webhookRepo.ClaimDelivery(context.TODO(), due[0], now.Add(time.Minute))
+	require.NoError(t, err)
+	assert.False(t, claimed)
+}
@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/RedLucky/potongin/domain"
+)
+
+// MemoryCache is a domain.Cache kept in the process: it hold capacity entries
+// at most and evict the least recently used one to make room
+type MemoryCache struct {
+	capacity int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// recent order the entries, the most recently used first
+	recent *list.List
+}
+
+type memoryEntry struct {
+	key       string
+	value     string
+	expiresAt time.Time
+}
+
+// NewMemoryCache will create an object that represent the domain.Cache interface
+func NewMemoryCache(capacity int) domain.Cache {
+	return &MemoryCache{
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		recent:   list.New(),
+	}
+}
+
+func (c *MemoryCache) Get(ctx context.Context, key string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return "", domain.ErrCacheMiss
+	}
+	entry := element.Value.(*memoryEntry)
+	if !entry.expiresAt.IsZero() && !time.Now().Before(entry.expiresAt) {
+		c.remove(element)
+		return "", domain.ErrCacheMiss
+	}
+	c.recent.MoveToFront(element)
+	return entry.value, nil
+}
+
+func (c *MemoryCache) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = time.Now().Add(ttl)
+	}
+	if element, ok := c.entries[key]; ok {
+		entry := element.Value.(*memoryEntry)
+		entry.value, entry.expiresAt = value, expiresAt
+		c.recent.MoveToFront(element)
+		return nil
+	}
+
+	c.entries[key] = c.recent.PushFront(&memoryEntry{key: key, value: value, expiresAt: expiresAt})
+	for c.capacity > 0 && c.recent.Len() > c.capacity {
+		c.remove(c.recent.Back())
+	}
+	return nil
+}
+
+func (c *MemoryCache) Delete(ctx context.Context, keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		if element, ok := c.entries[key]; ok {
+			c.remove(element)
+		}
+	}
+	return nil
+}
+
+func (c *MemoryCache) remove(element *list.Element) {
+	c.recent.Remove(element)
+	delete(c.entries, element.Value.(*memoryEntry).key)
+}
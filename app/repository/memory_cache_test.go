@@ -0,0 +1,60 @@
+package repository_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/RedLucky/potongin/app/repository"
+	"github.com/RedLucky/potongin/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryCache(t *testing.T) {
+	ctx := context.TODO()
+
+	t.Run("get-set-delete", func(t *testing.T) {
+		cache := repository.NewMemoryCache(10)
+		require.NoError(t, cache.Set(ctx, "docs", "example.com/docs", 0))
+
+		value, err := cache.Get(ctx, "docs")
+		assert.NoError(t, err)
+		assert.Equal(t, "example.com/docs", value)
+
+		require.NoError(t, cache.Delete(ctx, "docs", "blog"))
+		_, err = cache.Get(ctx, "docs")
+		assert.Equal(t, domain.ErrCacheMiss, err)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		cache := repository.NewMemoryCache(10)
+		require.NoError(t, cache.Set(ctx, "docs", "example.com/docs", 20*time.Millisecond))
+		require.NoError(t, cache.Set(ctx, "blog", "example.com/blog", time.Hour))
+		time.Sleep(30 * time.Millisecond)
+
+		_, err := cache.Get(ctx, "docs")
+		assert.Equal(t, domain.ErrCacheMiss, err)
+		_, err = cache.Get(ctx, "blog")
+		assert.NoError(t, err)
+	})
+
+	t.Run("least-recently-used-evicted", func(t *testing.T) {
+		cache := repository.NewMemoryCache(3)
+		for i := 0; i < 3; i++ {
+			require.NoError(t, cache.Set(ctx, strconv.Itoa(i), "value", 0))
+		}
+		// 0 is used again, 1 is now the least recently used
+		_, err := cache.Get(ctx, "0")
+		require.NoError(t, err)
+		require.NoError(t, cache.Set(ctx, "3", "value", 0))
+
+		_, err = cache.Get(ctx, "1")
+		assert.Equal(t, domain.ErrCacheMiss, err)
+		for _, key := range []string{"0", "2", "3"} {
+			_, err = cache.Get(ctx, key)
+			assert.NoError(t, err, key)
+		}
+	})
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/RedLucky/potongin/domain"
+	"github.com/jinzhu/gorm"
+	"github.com/sirupsen/logrus"
+)
+
+type MfaRepository struct {
+	Mysql *gorm.DB
+}
+
+func NewMfaRepository(conn *gorm.DB) domain.MfaRepository {
+	return &MfaRepository{conn}
+}
+
+func (repo *MfaRepository) GetByUserId(ctx context.Context, userId int64) (mfa domain.UserMfa, err error) {
+	err = repo.Mysql.Model(&domain.UserMfa{}).Where("user_id = ?", userId).First(&mfa).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return domain.UserMfa{}, domain.ErrNotFound
+	}
+	if err != nil {
+		logrus.Error(err)
+		return domain.UserMfa{}, err
+	}
+	return
+}
+
+// Store replace the pending or enabled secret of the user
+func (repo *MfaRepository) Store(ctx context.Context, mfa *domain.UserMfa) error {
+	return repo.Mysql.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("user_id = ?", mfa.UserId).Delete(&domain.UserMfa{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(mfa).Error
+	})
+}
+
+func (repo *MfaRepository) Enable(ctx context.Context, userId int64, step int64) error {
+	return repo.Mysql.Model(&domain.UserMfa{}).Where("user_id = ?", userId).Updates(map[string]interface{}{
+		"enabled":        "Y",
+		"last_used_step": step,
+		"confirmed_at":   time.Now(),
+	}).Error
+}
+
+// UpdateLastUsedStep record the time step of an accepted code. It return false
+// when a code of the same or a later step was already accepted (replay).
+func (repo *MfaRepository) UpdateLastUsedStep(ctx context.Context, userId int64, step int64) (bool, error) {
+	db := repo.Mysql.Model(&domain.UserMfa{}).Where("user_id = ? and last_used_step < ?", userId, step).
+		Update("last_used_step", step)
+	if db.Error != nil {
+		return false, db.Error
+	}
+	return db.RowsAffected == 1, nil
+}
+
+func (repo *MfaRepository) Delete(ctx context.Context, userId int64) error {
+	return repo.Mysql.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("user_id = ?", userId).Delete(&domain.MfaRecoveryCode{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userId).Delete(&domain.UserMfa{}).Error
+	})
+}
+
+func (repo *MfaRepository) ReplaceRecoveryCodes(ctx context.Context, userId int64, codes []domain.MfaRecoveryCode) error {
+	return repo.Mysql.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("user_id = ?", userId).Delete(&domain.MfaRecoveryCode{}).Error
+		if err != nil {
+			return err
+		}
+		for i := range codes {
+			if err = tx.Create(&codes[i]).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// UseRecoveryCode burn the recovery code, it return false when the code is unknown or already used
+func (repo *MfaRepository) UseRecoveryCode(ctx context.Context, userId int64, codeHash string) (bool, error) {
+	db := repo.Mysql.Model(&domain.MfaRecoveryCode{}).Where("user_id = ? and code_hash = ? and used = ?", userId, codeHash, "N").
+		Updates(map[string]interface{}{"used": "Y", "used_at": time.Now()})
+	if db.Error != nil {
+		return false, db.Error
+	}
+	return db.RowsAffected == 1, nil
+}
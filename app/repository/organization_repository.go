@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/RedLucky/potongin/domain"
+	"github.com/jinzhu/gorm"
+	"github.com/sirupsen/logrus"
+)
+
+type OrganizationRepository struct {
+	Mysql *gorm.DB
+}
+
+// NewOrganizationRepository will create an object that represent the domain.OrganizationRepository interface
+func NewOrganizationRepository(conn *gorm.DB) domain.OrganizationRepository {
+	return &OrganizationRepository{conn}
+}
+
+// Create store the organization with its first owner
+func (repo *OrganizationRepository) Create(ctx context.Context, org *domain.Organization, owner *domain.OrganizationMember) error {
+	return repo.Mysql.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(org).Error; err != nil {
+			return err
+		}
+		owner.OrgId = org.ID
+		return tx.Create(owner).Error
+	})
+}
+
+// FetchByUserId return the organizations of the user with its role in each
+func (repo *OrganizationRepository) FetchByUserId(ctx context.Context, userId int64) (orgs []domain.Organization, err error) {
+	rows, err := repo.Mysql.Table("organizations").
+		Select("organizations.id, organizations.name, organizations.created_by, organizations.created_at, organizations.updated_at, organization_members.role").
+		Joins("join organization_members on organization_members.org_id = organizations.id").
+		Where("organization_members.user_id = ?", userId).
+		Order("organizations.name").Rows()
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var org domain.Organization
+		err = rows.Scan(&org.ID, &org.Name, &org.CreatedBy, &org.CreatedAt, &org.UpdatedAt, &org.Role)
+		if err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, org)
+	}
+	return orgs, rows.Err()
+}
+
+func (repo *OrganizationRepository) GetMember(ctx context.Context, orgId, userId int64) (member domain.OrganizationMember, err error) {
+	err = repo.Mysql.Model(&domain.OrganizationMember{}).Where("org_id = ? and user_id = ?", orgId, userId).First(&member).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return domain.OrganizationMember{}, domain.ErrNotFound
+	}
+	if err != nil {
+		logrus.Error(err)
+		return domain.OrganizationMember{}, err
+	}
+	return
+}
+
+func (repo *OrganizationRepository) GetMembers(ctx context.Context, orgId int64) (members []domain.OrganizationMember, err error) {
+	err = repo.Mysql.Model(&domain.OrganizationMember{}).Where("org_id = ?", orgId).Order("created_at").Find(&members).Error
+	return
+}
+
+func (repo *OrganizationRepository) CountOwners(ctx context.Context, orgId int64) (count int64, err error) {
+	err = repo.Mysql.Model(&domain.OrganizationMember{}).Where("org_id = ? and role = ?", orgId, domain.RoleOwner).Count(&count).Error
+	return
+}
+
+func (repo *OrganizationRepository) UpdateMemberRole(ctx context.Context, orgId, userId int64, role string) error {
+	return repo.Mysql.Model(&domain.OrganizationMember{}).Where("org_id = ? and user_id = ?", orgId, userId).
+		Updates(map[string]interface{}{"role": role, "updated_at": time.Now()}).Error
+}
+
+func (repo *OrganizationRepository) RemoveMember(ctx context.Context, orgId, userId int64) error {
+	return repo.Mysql.Where("org_id = ? and user_id = ?", orgId, userId).Delete(&domain.OrganizationMember{}).Error
+}
+
+// CreateInvitation replace the pending invitation of the same email
+func (repo *OrganizationRepository) CreateInvitation(ctx context.Context, invitation *domain.OrganizationInvitation) error {
+	return repo.Mysql.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("org_id = ? and email = ? and accepted_at is null", invitation.OrgId, invitation.Email).
+			Delete(&domain.OrganizationInvitation{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(invitation).Error
+	})
+}
+
+func (repo *OrganizationRepository) GetInvitation(ctx context.Context, token string) (invitation domain.OrganizationInvitation, err error) {
+	err = repo.Mysql.Model(&domain.OrganizationInvitation{}).Where("token = ?", token).First(&invitation).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return domain.OrganizationInvitation{}, domain.ErrorTokenNotFound
+	}
+	if err != nil {
+		logrus.Error(err)
+		return domain.OrganizationInvitation{}, err
+	}
+	return
+}
+
+// AcceptInvitation burn the invitation and add the member, only once
+func (repo *OrganizationRepository) AcceptInvitation(ctx context.Context, invitation domain.OrganizationInvitation, member *domain.OrganizationMember) error {
+	return repo.Mysql.Transaction(func(tx *gorm.DB) error {
+		db := tx.Model(&domain.OrganizationInvitation{}).Where("id = ? and accepted_at is null", invitation.ID).
+			Update("accepted_at", time.Now())
+		if db.Error != nil {
+			return db.Error
+		}
+		if db.RowsAffected != 1 {
+			return domain.ErrorTokenNotFound
+		}
+		return tx.Create(member).Error
+	})
+}
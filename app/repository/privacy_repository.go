@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/RedLucky/potongin/domain"
+	"github.com/jinzhu/gorm"
+	"github.com/sirupsen/logrus"
+)
+
+type PrivacyRepository struct {
+	Mysql *gorm.DB
+}
+
+// NewPrivacyRepository will create an object that represent the domain.PrivacyRepository interface
+func NewPrivacyRepository(conn *gorm.DB) domain.PrivacyRepository {
+	return &PrivacyRepository{conn}
+}
+
+func (repo *PrivacyRepository) GetUserById(ctx context.Context, id int64) (user domain.User, err error) {
+	err = repo.Mysql.Model(&domain.User{}).Where("id = ?", id).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return domain.User{}, domain.ErrNotFound
+	}
+	if err != nil {
+		logrus.Error(err)
+		return domain.User{}, err
+	}
+	return
+}
+
+func (repo *PrivacyRepository) GetIdentities(ctx context.Context, userId int64) (identities []domain.UserIdentity, err error) {
+	err = repo.Mysql.Model(&domain.UserIdentity{}).Where("user_id = ?", userId).Find(&identities).Error
+	return
+}
+
+func (repo *PrivacyRepository) GetLinks(ctx context.Context, userId int64) (links []domain.GeneratedUrl, err error) {
+	err = repo.Mysql.Model(&domain.GeneratedUrl{}).Where("user_id = ?", userId).Find(&links).Error
+	return
+}
+
+// GetClickEvents return the visits of every link of the user
+func (repo *PrivacyRepository) GetClickEvents(ctx context.Context, userId int64) (events []domain.ClickEvent, err error) {
+	links := repo.Mysql.Model(&domain.GeneratedUrl{}).Select("id").Where("user_id = ?", userId).QueryExpr()
+	err = repo.Mysql.Model(&domain.ClickEvent{}).Where("url_id in (?)", links).Order("created_at").Find(&events).Error
+	return
+}
+
+func (repo *PrivacyRepository) GetClickEventsToAnonymize(ctx context.Context, before time.Time, limit int) (events []domain.ClickEvent, err error) {
+	err = repo.Mysql.Model(&domain.ClickEvent{}).Where("anonymized = ? and created_at < ?", "N", before).
+		Order("id").Limit(limit).Find(&events).Error
+	return
+}
+
+func (repo *PrivacyRepository) AnonymizeClickEvent(ctx context.Context, id int64, ip string) error {
+	return repo.Mysql.Model(&domain.ClickEvent{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"ip":         ip,
+		"anonymized": "Y",
+	}).Error
+}
+
+// DeleteOrphanedData delete the visits of deleted links and every record
+// still owned by a deleted user
+func (repo *PrivacyRepository) DeleteOrphanedData(ctx context.Context) (deletedClicks, deletedRecords int64, err error) {
+	err = repo.Mysql.Transaction(func(tx *gorm.DB) error {
+		users := tx.Model(&domain.User{}).Select("id").QueryExpr()
+		// the links of organization workspaces outlive their creator
+		db := tx.Where("org_id = 0 and user_id not in (?)", users).Delete(&domain.GeneratedUrl{})
+		if db.Error != nil {
+			return db.Error
+		}
+		deletedRecords += db.RowsAffected
+
+		links := tx.Model(&domain.GeneratedUrl{}).Select("id").QueryExpr()
+		db = tx.Where("url_id not in (?)", links).Delete(&domain.ClickEvent{})
+		if db.Error != nil {
+			return db.Error
+		}
+		deletedClicks = db.RowsAffected
+
+		owned := []interface{}{
+			&domain.VerifyEmail{},
+			&domain.EmailChange{},
+			&domain.UserIdentity{},
+			&domain.MfaRecoveryCode{},
+			&domain.UserMfa{},
+			&domain.OrganizationMember{},
+			&domain.ResetPassword{},
+			&domain.Webhook{},
+		}
+		for _, model := range owned {
+			db = tx.Where("user_id not in (?)", users).Delete(model)
+			if db.Error != nil {
+				return db.Error
+			}
+			deletedRecords += db.RowsAffected
+		}
+
+		webhooks := tx.Model(&domain.Webhook{}).Select("id").QueryExpr()
+		db = tx.Where("webhook_id not in (?)", webhooks).Delete(&domain.WebhookDelivery{})
+		if db.Error != nil {
+			return db.Error
+		}
+		deletedRecords += db.RowsAffected
+		return nil
+	})
+	return
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/RedLucky/potongin/domain"
+	"github.com/gomodule/redigo/redis"
+)
+
+type RedisCache struct {
+	Pool *redis.Pool
+}
+
+// NewRedisCache will create an object that represent the domain.Cache interface
+func NewRedisCache(pool *redis.Pool) domain.Cache {
+	return &RedisCache{pool}
+}
+
+func (c *RedisCache) Get(ctx context.Context, key string) (string, error) {
+	conn, err := c.Pool.GetContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	value, err := redis.String(conn.Do("GET", key))
+	if err == redis.ErrNil {
+		return "", domain.ErrCacheMiss
+	}
+	return value, err
+}
+
+func (c *RedisCache) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	conn, err := c.Pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if ttl > 0 {
+		_, err = conn.Do("SET", key, value, "PX", ttl.Milliseconds())
+	} else {
+		_, err = conn.Do("SET", key, value)
+	}
+	return err
+}
+
+func (c *RedisCache) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	conn, err := c.Pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Do("DEL", redis.Args{}.AddFlat(keys)...)
+	return err
+}
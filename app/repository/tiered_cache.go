@@ -0,0 +1,153 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/RedLucky/potongin/domain"
+	"github.com/gomodule/redigo/redis"
+	"github.com/sirupsen/logrus"
+)
+
+// CacheInvalidationChannel carry the keys deleted by an instance, the other
+// instances drop them from their memory
+const CacheInvalidationChannel = "potongin:cache:invalidate"
+
+// an idle subscription is pinged so a dead connection is noticed
+const invalidationPingInterval = 30 * time.Second
+
+// TieredCache keep the entries of the remote cache in the memory of the
+// process too, for LocalTTL at most. The deleted keys are published on
+// CacheInvalidationChannel, an invalidation missed while the subscription is
+// down is bounded by LocalTTL.
+type TieredCache struct {
+	Local    domain.Cache
+	Remote   domain.Cache
+	LocalTTL time.Duration
+	Pool     *redis.Pool
+}
+
+// NewTieredCache will create an object that represent the domain.Cache interface
+func NewTieredCache(local, remote domain.Cache, localTTL time.Duration, pool *redis.Pool) domain.Cache {
+	return &TieredCache{
+		Local:    local,
+		Remote:   remote,
+		LocalTTL: localTTL,
+		Pool:     pool,
+	}
+}
+
+func (c *TieredCache) Get(ctx context.Context, key string) (string, error) {
+	if value, err := c.Local.Get(ctx, key); err == nil {
+		return value, nil
+	}
+	value, err := c.Remote.Get(ctx, key)
+	if err != nil {
+		return "", err
+	}
+	c.Local.Set(ctx, key, value, c.LocalTTL)
+	return value, nil
+}
+
+func (c *TieredCache) Set(ctx context.Context, key, value string, ttl time.Duration) error {
+	if err := c.Remote.Set(ctx, key, value, ttl); err != nil {
+		return err
+	}
+	localTTL := c.LocalTTL
+	if ttl > 0 && ttl < localTTL {
+		localTTL = ttl
+	}
+	return c.Local.Set(ctx, key, value, localTTL)
+}
+
+func (c *TieredCache) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	c.Local.Delete(ctx, keys...)
+	if err := c.Remote.Delete(ctx, keys...); err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(keys)
+	if err != nil {
+		return err
+	}
+	conn, err := c.Pool.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	_, err = conn.Do("PUBLISH", CacheInvalidationChannel, payload)
+	return err
+}
+
+// StartCacheInvalidation drop from local the keys published on
+// CacheInvalidationChannel until stop is closed, the subscription is opened
+// again after a failure
+func StartCacheInvalidation(pool *redis.Pool, local domain.Cache, stop <-chan struct{}) {
+	go func() {
+		for {
+			if err := listenInvalidations(pool, local, stop); err != nil {
+				logrus.WithError(err).Error("cache invalidation subscription lost")
+			}
+			select {
+			case <-stop:
+				return
+			case <-time.After(time.Second):
+			}
+		}
+	}()
+}
+
+// listenInvalidations return nil once stop is closed, or the error that broke the subscription
+func listenInvalidations(pool *redis.Pool, local domain.Cache, stop <-chan struct{}) error {
+	conn := pool.Get()
+	defer conn.Close()
+	psc := redis.PubSubConn{Conn: conn}
+	if err := psc.Subscribe(CacheInvalidationChannel); err != nil {
+		return err
+	}
+
+	// the connection is closed once the pinging goroutine is over
+	done, exited := make(chan struct{}), make(chan struct{})
+	defer func() {
+		close(done)
+		<-exited
+	}()
+	go func() {
+		defer close(exited)
+		ticker := time.NewTicker(invalidationPingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				psc.Ping("")
+			case <-stop:
+				psc.Unsubscribe()
+				return
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	for {
+		switch message := psc.ReceiveWithTimeout(3 * invalidationPingInterval).(type) {
+		case redis.Message:
+			var keys []string
+			if err := json.Unmarshal(message.Data, &keys); err != nil {
+				logrus.Error(err)
+				continue
+			}
+			local.Delete(context.Background(), keys...)
+		case redis.Subscription:
+			if message.Count == 0 {
+				return nil
+			}
+		case error:
+			return message
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/RedLucky/potongin/app/repository"
+	"github.com/RedLucky/potongin/config/cache"
+	"github.com/RedLucky/potongin/domain"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTieredCache(t *testing.T) {
+	redis := cache.Open(cache.Options{Address: miniredis.RunT(t).Addr()})
+	defer redis.Close()
+	ctx := context.TODO()
+	stop := make(chan struct{})
+	defer close(stop)
+
+	// two instances sharing the redis
+	remote := repository.NewRedisCache(redis.Pool)
+	localA, localB := repository.NewMemoryCache(10), repository.NewMemoryCache(10)
+	instanceA := repository.NewTieredCache(localA, remote, time.Minute, redis.Pool)
+	instanceB := repository.NewTieredCache(localB, remote, time.Minute, redis.Pool)
+	repository.StartCacheInvalidation(redis.Pool, localB, stop)
+
+	require.NoError(t, instanceA.Set(ctx, "link:docs", "example.com/docs", time.Hour))
+	value, err := instanceB.Get(ctx, "link:docs")
+	require.NoError(t, err)
+	assert.Equal(t, "example.com/docs", value)
+	value, err = localB.Get(ctx, "link:docs")
+	require.NoError(t, err, "the remote entry is kept in memory")
+
+	// a local entry outlives the remote one until it is invalidated
+	require.NoError(t, remote.Delete(ctx, "link:docs"))
+	_, err = instanceB.Get(ctx, "link:docs")
+	assert.NoError(t, err)
+
+	require.NoError(t, instanceA.Delete(ctx, "link:docs"))
+	assert.Eventually(t, func() bool {
+		_, err := instanceB.Get(ctx, "link:docs")
+		return err == domain.ErrCacheMiss
+	}, time.Second, 10*time.Millisecond)
+}
@@ -63,7 +63,7 @@ func TestUserRepository_Store(t *testing.T) {
 	defer db.Close()
 	gdb, _ := gorm.Open("mysql", db)
 	userRepo := repository.NewUserRepository(gdb)
-	queryInsert := "INSERT INTO `users` (`username`,`email`,`password`,`name`,`email_verified`,`updated_at`,`created_at`) VALUES (?,?,?,?,?,?,?)"
+	queryInsert := "INSERT INTO `users` (`username`,`email`,`password`,`name`,`email_verified`,`suspended_at`,`suspend_reason`,`updated_at`,`created_at`) VALUES (?,?,?,?,?,?,?,?,?)"
 	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("LFR123456"), bcrypt.DefaultCost)
 	user := &domain.User{
 		Username:      "LFR123",
@@ -76,7 +76,7 @@ func TestUserRepository_Store(t *testing.T) {
 	}
 	mock.ExpectBegin()
 	mock.ExpectExec(queryInsert).WithArgs(
-		user.Username, user.Email, user.Password, user.Name, user.EmailVerified, user.UpdatedAt, user.CreatedAt).WillReturnResult(sqlmock.NewResult(12, 1))
+		user.Username, user.Email, user.Password, user.Name, user.EmailVerified, user.SuspendedAt, user.SuspendReason, user.UpdatedAt, user.CreatedAt).WillReturnResult(sqlmock.NewResult(12, 1))
 	mock.ExpectCommit()
 
 	err = userRepo.Store(user)
@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/RedLucky/potongin/domain"
+	"github.com/jinzhu/gorm"
+	"github.com/sirupsen/logrus"
+)
+
+type WebhookRepository struct {
+	Mysql *gorm.DB
+}
+
+// NewWebhookRepository will create an object that represent the domain.WebhookRepository interface
+func NewWebhookRepository(conn *gorm.DB) domain.WebhookRepository {
+	return &WebhookRepository{conn}
+}
+
+func (repo *WebhookRepository) Create(ctx context.Context, webhook *domain.Webhook) error {
+	return repo.Mysql.Create(webhook).Error
+}
+
+func (repo *WebhookRepository) FetchByUserId(ctx context.Context, userId int64) (webhooks []domain.Webhook, err error) {
+	err = repo.Mysql.Where("user_id = ?", userId).Order("id").Find(&webhooks).Error
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+	return
+}
+
+func (repo *WebhookRepository) GetById(ctx context.Context, webhookId int64) (webhook domain.Webhook, err error) {
+	err = repo.Mysql.Where("id = ?", webhookId).First(&webhook).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return domain.Webhook{}, domain.ErrNotFound
+	}
+	return
+}
+
+// Delete remove the webhook with its deliveries
+func (repo *WebhookRepository) Delete(ctx context.Context, webhookId int64) error {
+	return repo.Mysql.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_id = ?", webhookId).Delete(&domain.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", webhookId).Delete(&domain.Webhook{}).Error
+	})
+}
+
+// FetchSubscribed list the active webhooks of the user subscribed to event
+func (repo *WebhookRepository) FetchSubscribed(ctx context.Context, userId int64, event string) (webhooks []domain.Webhook, err error) {
+	var active []domain.Webhook
+	err = repo.Mysql.Where("user_id = ? and active = ?", userId, "Y").Find(&active).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, webhook := range active {
+		if webhook.Subscribed(event) {
+			webhooks = append(webhooks, webhook)
+		}
+	}
+	return
+}
+
+func (repo *WebhookRepository) CreateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	return repo.Mysql.Create(delivery).Error
+}
+
+func (repo *WebhookRepository) GetDelivery(ctx context.Context, deliveryId int64) (delivery domain.WebhookDelivery, err error) {
+	err = repo.Mysql.Where("id = ?", deliveryId).First(&delivery).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return domain.WebhookDelivery{}, domain.ErrNotFound
+	}
+	return
+}
+
+func (repo *WebhookRepository) FetchDeliveries(ctx context.Context, webhookId int64, limit int) (deliveries []domain.WebhookDelivery, err error) {
+	err = repo.Mysql.Where("webhook_id = ?", webhookId).Order("id desc").Limit(limit).Find(&deliveries).Error
+	if err != nil {
+		logrus.Error(err)
+		return nil, err
+	}
+	return
+}
+
+func (repo *WebhookRepository) FetchDueDeliveries(ctx context.Context, now time.Time, limit int) (deliveries []domain.WebhookDelivery, err error) {
+	err = repo.Mysql.Where("status = ? and next_attempt_at <= ?", domain.DeliveryPending, now).
+		Order("next_attempt_at").Limit(limit).Find(&deliveries).Error
+	return
+}
+
+func (repo *WebhookRepository) ClaimDelivery(ctx context.Context, delivery domain.WebhookDelivery, leaseUntil time.Time) (bool, error) {
+	db := repo.Mysql.Model(&domain.WebhookDelivery{}).
+		Where("id = ? and status = ? and next_attempt_at = ?", delivery.ID, domain.DeliveryPending, delivery.NextAttemptAt).
+		UpdateColumn("next_attempt_at", leaseUntil)
+	return db.RowsAffected == 1, db.Error
+}
+
+func (repo *WebhookRepository) UpdateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	return repo.Mysql.Save(delivery).Error
+}
@@ -0,0 +1,276 @@
+package usecase
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"github.com/RedLucky/potongin/domain"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// a requested email change must be verified within a day
+const emailChangeDuration = 24 * time.Hour
+
+type AccountUsecase struct {
+	AccountRepo    domain.AccountRepository
+	AuditRepo      domain.AuditRepository
+	contextTimeout time.Duration
+	Tokens         domain.TokenStore
+	Mailer         domain.Mailer
+	passwordPolicy PasswordPolicy
+}
+
+// NewAccountUsecase will create new an AccountUsecase object representation of domain.AccountUsecase interface
+func NewAccountUsecase(repo domain.AccountRepository, auditRepo domain.AuditRepository, timeout time.Duration, tokens domain.TokenStore, mailer domain.Mailer) domain.AccountUsecase {
+	return &AccountUsecase{
+		AccountRepo:    repo,
+		AuditRepo:      auditRepo,
+		contextTimeout: timeout,
+		Tokens:         tokens,
+		Mailer:         mailer,
+		passwordPolicy: NewPasswordPolicy(),
+	}
+}
+
+func (uc *AccountUsecase) UpdateProfile(c context.Context, userId int64, profile domain.UpdateProfile) (user domain.User, err error) {
+	ctx, cancel := context.WithTimeout(c, uc.contextTimeout)
+	defer cancel()
+
+	user, err = uc.AccountRepo.GetUserById(ctx, userId)
+	if err != nil {
+		return domain.User{}, err
+	}
+	before := user
+
+	name := strings.TrimSpace(profile.Name)
+	if name != "" {
+		user.Name = name
+	}
+	username := strings.TrimSpace(profile.Username)
+	if username != "" && username != user.Username {
+		_, err = uc.AccountRepo.GetUserByUsername(ctx, username)
+		if err == nil {
+			return domain.User{}, domain.ErrAccountExist
+		} else if err != domain.ErrNotFound {
+			return domain.User{}, err
+		}
+		user.Username = username
+	}
+
+	user.UpdatedAt = time.Now()
+	if err = uc.AccountRepo.UpdateProfile(ctx, &user); err != nil {
+		return domain.User{}, err
+	}
+	recordAudit(ctx, uc.AuditRepo, domain.AuditLog{
+		ActorId:    userId,
+		Action:     domain.AuditUserUpdate,
+		TargetType: domain.AuditTargetUser,
+		TargetId:   auditId(userId),
+		Diff:       auditDiff(before, user),
+	})
+	return user, nil
+}
+
+// RequestEmailChange keep the new email aside until the link sent to it is
+// opened, the current email stay in use meanwhile
+func (uc *AccountUsecase) RequestEmailChange(c context.Context, userId int64, email, password string) (encodedString string, err error) {
+	ctx, cancel := context.WithTimeout(c, uc.contextTimeout)
+	defer cancel()
+
+	user, err := uc.AccountRepo.GetUserById(ctx, userId)
+	if err != nil {
+		return "", err
+	}
+	if err = verifyPassword(user.Password, password); err != nil {
+		return "", domain.ErrPassword
+	}
+
+	email = strings.TrimSpace(email)
+	_, err = uc.AccountRepo.GetUserByEmail(ctx, email)
+	if err == nil {
+		return "", domain.ErrEmailExist
+	} else if err != domain.ErrNotFound {
+		return "", err
+	}
+
+	change := domain.EmailChange{
+		UserId:    userId,
+		NewEmail:  email,
+		Token:     uuid.New().String(),
+		ExpiresAt: time.Now().Add(emailChangeDuration),
+		CreatedAt: time.Now(),
+	}
+	if err = uc.AccountRepo.CreateEmailChange(ctx, &change); err != nil {
+		return "", err
+	}
+	encodedString = base64.StdEncoding.EncodeToString([]byte(change.Token))
+	// the link goes to the new address, proving it belongs to the user
+	if err = sendLink(ctx, uc.Mailer, email, "confirm your new email",
+		"Open the link below within a day to use this email for your account. Ignore this mail if you didn't ask for it.",
+		confirmEmailPage, encodedString); err != nil {
+		return "", err
+	}
+	logrus.WithField("user_id", userId).Info("email change requested")
+	return
+}
+
+func (uc *AccountUsecase) ConfirmEmailChange(c context.Context, token string) error {
+	ctx, cancel := context.WithTimeout(c, uc.contextTimeout)
+	defer cancel()
+
+	decodedByte, err := base64.StdEncoding.DecodeString(token)
+	if err != nil {
+		return domain.ErrorTokenNotFound
+	}
+	change, err := uc.AccountRepo.GetEmailChange(ctx, string(decodedByte))
+	if err != nil {
+		return err
+	}
+	if time.Now().After(change.ExpiresAt) {
+		return domain.ErrorTokenNotFound
+	}
+
+	// the address may have been taken since the request
+	_, err = uc.AccountRepo.GetUserByEmail(ctx, change.NewEmail)
+	if err == nil {
+		return domain.ErrEmailExist
+	} else if err != domain.ErrNotFound {
+		return err
+	}
+	user, err := uc.AccountRepo.GetUserById(ctx, change.UserId)
+	if err != nil {
+		return err
+	}
+	if err = uc.AccountRepo.ApplyEmailChange(ctx, change); err != nil {
+		return err
+	}
+	recordAudit(ctx, uc.AuditRepo, domain.AuditLog{
+		ActorId:    change.UserId,
+		Action:     domain.AuditUserEmailChange,
+		TargetType: domain.AuditTargetUser,
+		TargetId:   auditId(change.UserId),
+		Diff:       auditDiff(map[string]string{"email": user.Email}, map[string]string{"email": change.NewEmail}),
+	})
+	return nil
+}
+
+// ChangePassword replace the password and log out every other session
+func (uc *AccountUsecase) ChangePassword(c context.Context, userId int64, sessionId, currentPassword, newPassword string) error {
+	ctx, cancel := context.WithTimeout(c, uc.contextTimeout)
+	defer cancel()
+
+	user, err := uc.AccountRepo.GetUserById(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if err = verifyPassword(user.Password, currentPassword); err != nil {
+		return domain.ErrPassword
+	}
+	if err = uc.passwordPolicy.Validate(newPassword, user); err != nil {
+		return err
+	}
+
+	hashedPassword, err := hash(newPassword)
+	if err != nil {
+		return err
+	}
+	if err = uc.AccountRepo.UpdatePassword(ctx, userId, string(hashedPassword)); err != nil {
+		return err
+	}
+	recordAudit(ctx, uc.AuditRepo, domain.AuditLog{
+		ActorId:    userId,
+		Action:     domain.AuditUserPasswordChange,
+		TargetType: domain.AuditTargetUser,
+		TargetId:   auditId(userId),
+	})
+	return revokeUserSessions(ctx, uc.Tokens, userId, sessionId)
+}
+
+// DeleteAccount delete the user, its personal links are transferred to the
+// user transferTo (a username) when given and deleted otherwise
+func (uc *AccountUsecase) DeleteAccount(c context.Context, userId int64, password, transferTo string) error {
+	ctx, cancel := context.WithTimeout(c, uc.contextTimeout)
+	defer cancel()
+
+	user, err := uc.AccountRepo.GetUserById(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if err = verifyPassword(user.Password, password); err != nil {
+		return domain.ErrPassword
+	}
+
+	// an organization can't be left without owner
+	soleOwner, err := uc.AccountRepo.IsSoleOwner(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if soleOwner {
+		return domain.ErrLastOwner
+	}
+
+	var transferToId int64
+	if transferTo != "" {
+		recipient, err := uc.AccountRepo.GetUserByUsername(ctx, transferTo)
+		if err != nil {
+			return err
+		}
+		if recipient.ID == userId {
+			return domain.ErrBadParamInput
+		}
+		// the links go to someone the user already works with, never to a
+		// stranger who didn't ask for them
+		shared, err := uc.AccountRepo.ShareOrganization(ctx, userId, recipient.ID)
+		if err != nil {
+			return err
+		}
+		if !shared {
+			return domain.ErrTransferNotAllowed
+		}
+		transferToId = recipient.ID
+	}
+
+	if err = uc.AccountRepo.DeleteUser(ctx, userId, transferToId); err != nil {
+		return err
+	}
+	recordAudit(ctx, uc.AuditRepo, domain.AuditLog{
+		ActorId:    userId,
+		Action:     domain.AuditUserDelete,
+		TargetType: domain.AuditTargetUser,
+		TargetId:   auditId(userId),
+		Diff:       auditDiff(user, nil),
+	})
+	// the personal links of the user go with it, or to the recipient
+	links := domain.AuditLog{
+		ActorId:    userId,
+		Action:     domain.AuditLinkDelete,
+		TargetType: domain.AuditTargetUser,
+		TargetId:   auditId(userId),
+	}
+	if transferToId != 0 {
+		links.Action = domain.AuditLinkTransfer
+		links.Diff = auditDiff(map[string]int64{"user_id": userId}, map[string]int64{"user_id": transferToId})
+	}
+	recordAudit(ctx, uc.AuditRepo, links)
+	return revokeUserSessions(ctx, uc.Tokens, userId, "")
+}
+
+// revokeUserSessions log out every session of the user except the kept one
+func revokeUserSessions(ctx context.Context, tokens domain.TokenStore, userId int64, keepSessionId string) error {
+	sessions, err := tokens.GetSessionsByUser(ctx, userId)
+	if err != nil {
+		return domain.ErrInternalServerError
+	}
+	for _, session := range sessions {
+		if session.ID == keepSessionId {
+			continue
+		}
+		if err = tokens.DeleteSession(ctx, session); err != nil {
+			return domain.ErrInternalServerError
+		}
+	}
+	return nil
+}
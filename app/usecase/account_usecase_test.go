@@ -0,0 +1,249 @@
+package usecase_test
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/RedLucky/potongin/app/usecase"
+	"github.com/RedLucky/potongin/domain"
+	"github.com/RedLucky/potongin/domain/mocks"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func accountUserMock(t *testing.T) domain.User {
+	hashed, err := bcrypt.GenerateFromPassword([]byte("Potongin2021"), bcrypt.MinCost)
+	require.NoError(t, err)
+	return domain.User{
+		ID:       1,
+		Username: "LFR",
+		Email:    "lucky@kryptopos.com",
+		Name:     "Lucky Fernanda",
+		Password: string(hashed),
+	}
+}
+
+func TestAccountUsecase_UpdateProfile(t *testing.T) {
+	repository := new(mocks.AccountRepository)
+	userMock := accountUserMock(t)
+
+	t.Run("success", func(t *testing.T) {
+		repository.On("GetUserById", mock.Anything, userMock.ID).Return(userMock, nil).Once()
+		repository.On("GetUserByUsername", mock.Anything, "redlucky").Return(domain.User{}, domain.ErrNotFound).Once()
+		repository.On("UpdateProfile", mock.Anything, mock.AnythingOfType("*domain.User")).Return(nil).Once()
+
+		uc := usecase.NewAccountUsecase(repository, newAuditRepository(), time.Second*5, nil, newMailer())
+		user, err := uc.UpdateProfile(context.TODO(), userMock.ID, domain.UpdateProfile{Username: "redlucky"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "redlucky", user.Username)
+		assert.Equal(t, userMock.Name, user.Name)
+		repository.AssertExpectations(t)
+	})
+
+	t.Run("username-taken", func(t *testing.T) {
+		repository.On("GetUserById", mock.Anything, userMock.ID).Return(userMock, nil).Once()
+		repository.On("GetUserByUsername", mock.Anything, "taken").Return(domain.User{ID: 2}, nil).Once()
+
+		uc := usecase.NewAccountUsecase(repository, newAuditRepository(), time.Second*5, nil, newMailer())
+		_, err := uc.UpdateProfile(context.TODO(), userMock.ID, domain.UpdateProfile{Username: "taken"})
+
+		assert.Equal(t, domain.ErrAccountExist, err)
+		repository.AssertExpectations(t)
+	})
+}
+
+func TestAccountUsecase_EmailChange(t *testing.T) {
+	repository := new(mocks.AccountRepository)
+	userMock := accountUserMock(t)
+
+	t.Run("request", func(t *testing.T) {
+		viper.Set(`mail.link_base_url`, "https://potong.in/")
+		var stored *domain.EmailChange
+		repository.On("GetUserById", mock.Anything, userMock.ID).Return(userMock, nil).Once()
+		repository.On("GetUserByEmail", mock.Anything, "new@kryptopos.com").Return(domain.User{}, domain.ErrNotFound).Once()
+		repository.On("CreateEmailChange", mock.Anything, mock.AnythingOfType("*domain.EmailChange")).
+			Run(func(args mock.Arguments) { stored = args.Get(1).(*domain.EmailChange) }).Return(nil).Once()
+
+		mailer := newMailer()
+
+		uc := usecase.NewAccountUsecase(repository, newAuditRepository(), time.Second*5, nil, mailer)
+		token, err := uc.RequestEmailChange(context.TODO(), userMock.ID, "new@kryptopos.com", "Potongin2021")
+
+		require.NoError(t, err)
+		decoded, _ := base64.StdEncoding.DecodeString(token)
+		assert.Equal(t, stored.Token, string(decoded))
+		assert.Equal(t, "new@kryptopos.com", stored.NewEmail)
+		// the link is sent to the new address, not to the current one
+		mailer.AssertCalled(t, "Send", mock.Anything, mailWithLink("new@kryptopos.com", "/confirm-email", token))
+		repository.AssertExpectations(t)
+	})
+
+	t.Run("wrong-password", func(t *testing.T) {
+		repository.On("GetUserById", mock.Anything, userMock.ID).Return(userMock, nil).Once()
+
+		uc := usecase.NewAccountUsecase(repository, newAuditRepository(), time.Second*5, nil, newMailer())
+		_, err := uc.RequestEmailChange(context.TODO(), userMock.ID, "new@kryptopos.com", "wrong")
+
+		assert.Equal(t, domain.ErrPassword, err)
+		repository.AssertExpectations(t)
+	})
+
+	t.Run("email-taken", func(t *testing.T) {
+		repository.On("GetUserById", mock.Anything, userMock.ID).Return(userMock, nil).Once()
+		repository.On("GetUserByEmail", mock.Anything, "taken@kryptopos.com").Return(domain.User{ID: 2}, nil).Once()
+
+		uc := usecase.NewAccountUsecase(repository, newAuditRepository(), time.Second*5, nil, newMailer())
+		_, err := uc.RequestEmailChange(context.TODO(), userMock.ID, "taken@kryptopos.com", "Potongin2021")
+
+		assert.Equal(t, domain.ErrEmailExist, err)
+		repository.AssertExpectations(t)
+	})
+
+	t.Run("confirm", func(t *testing.T) {
+		change := domain.EmailChange{UserId: userMock.ID, NewEmail: "new@kryptopos.com", Token: "token", ExpiresAt: time.Now().Add(time.Hour)}
+		repository.On("GetEmailChange", mock.Anything, "token").Return(change, nil).Once()
+		repository.On("GetUserByEmail", mock.Anything, change.NewEmail).Return(domain.User{}, domain.ErrNotFound).Once()
+		repository.On("GetUserById", mock.Anything, userMock.ID).Return(userMock, nil).Once()
+		repository.On("ApplyEmailChange", mock.Anything, change).Return(nil).Once()
+
+		uc := usecase.NewAccountUsecase(repository, newAuditRepository(), time.Second*5, nil, newMailer())
+		err := uc.ConfirmEmailChange(context.TODO(), base64.StdEncoding.EncodeToString([]byte("token")))
+
+		assert.NoError(t, err)
+		repository.AssertExpectations(t)
+	})
+
+	t.Run("confirm-expired", func(t *testing.T) {
+		change := domain.EmailChange{UserId: userMock.ID, NewEmail: "new@kryptopos.com", Token: "expired", ExpiresAt: time.Now().Add(-time.Minute)}
+		repository.On("GetEmailChange", mock.Anything, "expired").Return(change, nil).Once()
+
+		uc := usecase.NewAccountUsecase(repository, newAuditRepository(), time.Second*5, nil, newMailer())
+		err := uc.ConfirmEmailChange(context.TODO(), base64.StdEncoding.EncodeToString([]byte("expired")))
+
+		assert.Equal(t, domain.ErrorTokenNotFound, err)
+		repository.AssertExpectations(t)
+	})
+}
+
+func TestAccountUsecase_ChangePassword(t *testing.T) {
+	repository := new(mocks.AccountRepository)
+	userMock := accountUserMock(t)
+
+	t.Run("wrong-current-password", func(t *testing.T) {
+		repository.On("GetUserById", mock.Anything, userMock.ID).Return(userMock, nil).Once()
+
+		uc := usecase.NewAccountUsecase(repository, newAuditRepository(), time.Second*5, nil, newMailer())
+		err := uc.ChangePassword(context.TODO(), userMock.ID, "session", "wrong", "Potongin2022")
+
+		assert.Equal(t, domain.ErrPassword, err)
+		repository.AssertExpectations(t)
+	})
+
+	t.Run("weak-new-password", func(t *testing.T) {
+		repository.On("GetUserById", mock.Anything, userMock.ID).Return(userMock, nil).Once()
+
+		uc := usecase.NewAccountUsecase(repository, newAuditRepository(), time.Second*5, nil, newMailer())
+		err := uc.ChangePassword(context.TODO(), userMock.ID, "session", "Potongin2021", "123456")
+
+		var policyErr *domain.PasswordPolicyError
+		assert.True(t, errors.As(err, &policyErr))
+		repository.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+		repository.AssertExpectations(t)
+	})
+
+	t.Run("other-sessions-revoked", func(t *testing.T) {
+		repository.On("GetUserById", mock.Anything, userMock.ID).Return(userMock, nil).Once()
+		repository.On("UpdatePassword", mock.Anything, userMock.ID, mock.AnythingOfType("string")).Return(nil).Once()
+		current := domain.Session{ID: "session", UserId: userMock.ID}
+		other := domain.Session{ID: "other", UserId: userMock.ID}
+		tokens := new(mocks.TokenStore)
+		tokens.On("GetSessionsByUser", mock.Anything, userMock.ID).Return([]domain.Session{current, other}, nil).Once()
+		tokens.On("DeleteSession", mock.Anything, other).Return(nil).Once()
+
+		uc := usecase.NewAccountUsecase(repository, newAuditRepository(), time.Second*5, tokens, newMailer())
+		err := uc.ChangePassword(context.TODO(), userMock.ID, "session", "Potongin2021", "Potongin2022")
+
+		assert.NoError(t, err)
+		repository.AssertExpectations(t)
+		tokens.AssertExpectations(t)
+		tokens.AssertNotCalled(t, "DeleteSession", mock.Anything, current)
+	})
+}
+
+func TestAccountUsecase_DeleteAccount(t *testing.T) {
+	repository := new(mocks.AccountRepository)
+	userMock := accountUserMock(t)
+
+	t.Run("wrong-password", func(t *testing.T) {
+		repository.On("GetUserById", mock.Anything, userMock.ID).Return(userMock, nil).Once()
+
+		uc := usecase.NewAccountUsecase(repository, newAuditRepository(), time.Second*5, nil, newMailer())
+		err := uc.DeleteAccount(context.TODO(), userMock.ID, "wrong", "")
+
+		assert.Equal(t, domain.ErrPassword, err)
+		repository.AssertExpectations(t)
+	})
+
+	t.Run("transfer-to-self", func(t *testing.T) {
+		repository.On("GetUserById", mock.Anything, userMock.ID).Return(userMock, nil).Once()
+		repository.On("IsSoleOwner", mock.Anything, userMock.ID).Return(false, nil).Once()
+		repository.On("GetUserByUsername", mock.Anything, userMock.Username).Return(userMock, nil).Once()
+
+		uc := usecase.NewAccountUsecase(repository, newAuditRepository(), time.Second*5, nil, newMailer())
+		err := uc.DeleteAccount(context.TODO(), userMock.ID, "Potongin2021", userMock.Username)
+
+		assert.Equal(t, domain.ErrBadParamInput, err)
+		repository.AssertNotCalled(t, "DeleteUser", mock.Anything, mock.Anything, mock.Anything)
+		repository.AssertExpectations(t)
+	})
+
+	t.Run("transfer-outside-organizations", func(t *testing.T) {
+		stranger := domain.User{ID: 9, Username: "stranger"}
+		repository.On("GetUserById", mock.Anything, userMock.ID).Return(userMock, nil).Once()
+		repository.On("IsSoleOwner", mock.Anything, userMock.ID).Return(false, nil).Once()
+		repository.On("GetUserByUsername", mock.Anything, "stranger").Return(stranger, nil).Once()
+		repository.On("ShareOrganization", mock.Anything, userMock.ID, int64(9)).Return(false, nil).Once()
+
+		uc := usecase.NewAccountUsecase(repository, newAuditRepository(), time.Second*5, nil, newMailer())
+		err := uc.DeleteAccount(context.TODO(), userMock.ID, "Potongin2021", "stranger")
+
+		assert.Equal(t, domain.ErrTransferNotAllowed, err)
+		repository.AssertNotCalled(t, "DeleteUser", mock.Anything, mock.Anything, mock.Anything)
+		repository.AssertExpectations(t)
+	})
+
+	t.Run("transfer-to-member", func(t *testing.T) {
+		colleague := domain.User{ID: 8, Username: "colleague"}
+		repository.On("GetUserById", mock.Anything, userMock.ID).Return(userMock, nil).Once()
+		repository.On("IsSoleOwner", mock.Anything, userMock.ID).Return(false, nil).Once()
+		repository.On("GetUserByUsername", mock.Anything, "colleague").Return(colleague, nil).Once()
+		repository.On("ShareOrganization", mock.Anything, userMock.ID, int64(8)).Return(true, nil).Once()
+		repository.On("DeleteUser", mock.Anything, userMock.ID, int64(8)).Return(nil).Once()
+		tokens := new(mocks.TokenStore)
+		tokens.On("GetSessionsByUser", mock.Anything, userMock.ID).Return([]domain.Session{}, nil).Once()
+
+		uc := usecase.NewAccountUsecase(repository, newAuditRepository(), time.Second*5, tokens, newMailer())
+		err := uc.DeleteAccount(context.TODO(), userMock.ID, "Potongin2021", "colleague")
+
+		assert.NoError(t, err)
+		repository.AssertExpectations(t)
+	})
+
+	t.Run("sole-owner", func(t *testing.T) {
+		repository.On("GetUserById", mock.Anything, userMock.ID).Return(userMock, nil).Once()
+		repository.On("IsSoleOwner", mock.Anything, userMock.ID).Return(true, nil).Once()
+
+		uc := usecase.NewAccountUsecase(repository, newAuditRepository(), time.Second*5, nil, newMailer())
+		err := uc.DeleteAccount(context.TODO(), userMock.ID, "Potongin2021", "")
+
+		assert.Equal(t, domain.ErrLastOwner, err)
+		repository.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,252 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/RedLucky/potongin/domain"
+	"github.com/sirupsen/logrus"
+)
+
+// maximum users returned by one search
+const maxSearchLimit = 100
+
+type AdminUsecase struct {
+	AdminRepo      domain.AdminRepository
+	AuditRepo      domain.AuditRepository
+	contextTimeout time.Duration
+	Tokens         domain.TokenStore
+	Cache          domain.Cache
+	Mailer         domain.Mailer
+}
+
+// NewAdminUsecase will create new an AdminUsecase object representation of domain.AdminUsecase interface
+func NewAdminUsecase(repo domain.AdminRepository, auditRepo domain.AuditRepository, timeout time.Duration, tokens domain.TokenStore, cache domain.Cache, mailer domain.Mailer) domain.AdminUsecase {
+	return &AdminUsecase{
+		AdminRepo:      repo,
+		AuditRepo:      auditRepo,
+		contextTimeout: timeout,
+		Tokens:         tokens,
+		Cache:          cache,
+		Mailer:         mailer,
+	}
+}
+
+func (uc *AdminUsecase) IsAdmin(c context.Context, userId int64) (bool, error) {
+	ctx, cancel := context.WithTimeout(c, uc.contextTimeout)
+	defer cancel()
+
+	role, err := uc.AdminRepo.GetRole(ctx, userId)
+	if err == domain.ErrNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return role == domain.UserRoleAdmin, nil
+}
+
+func (uc *AdminUsecase) SearchUsers(c context.Context, query string, limit, offset int) ([]domain.AdminUser, error) {
+	ctx, cancel := context.WithTimeout(c, uc.contextTimeout)
+	defer cancel()
+
+	if limit <= 0 || limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return uc.AdminRepo.SearchUsers(ctx, strings.TrimSpace(query), limit, offset)
+}
+
+func (uc *AdminUsecase) GetUser(c context.Context, userId int64) (user domain.AdminUser, links []domain.GeneratedUrl, err error) {
+	ctx, cancel := context.WithTimeout(c, uc.contextTimeout)
+	defer cancel()
+
+	user, err = uc.AdminRepo.GetUser(ctx, userId)
+	if err != nil {
+		return domain.AdminUser{}, nil, err
+	}
+	links, err = uc.AdminRepo.GetLinks(ctx, userId)
+	if err != nil {
+		return domain.AdminUser{}, nil, err
+	}
+	return
+}
+
+// Suspend block the login of the user, log it out and stop the redirects of its links
+func (uc *AdminUsecase) Suspend(c context.Context, userId int64, reason string) error {
+	ctx, cancel := context.WithTimeout(c, uc.contextTimeout)
+	defer cancel()
+
+	user, err := uc.AdminRepo.GetUser(ctx, userId)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if err = uc.AdminRepo.SetSuspension(ctx, userId, &now, reason); err != nil {
+		return err
+	}
+	suspended := user
+	suspended.SuspendedAt, suspended.SuspendReason = &now, reason
+	uc.recordUser(ctx, domain.AuditUserSuspend, user, suspended)
+
+	if err = revokeUserSessions(ctx, uc.Tokens, userId, ""); err != nil {
+		return err
+	}
+	links, err := uc.AdminRepo.GetLinks(ctx, userId)
+	if err != nil {
+		return err
+	}
+	for _, link := range links {
+		uc.purgeLinkCache(ctx, link)
+	}
+	return nil
+}
+
+func (uc *AdminUsecase) Unsuspend(c context.Context, userId int64) error {
+	ctx, cancel := context.WithTimeout(c, uc.contextTimeout)
+	defer cancel()
+
+	user, err := uc.AdminRepo.GetUser(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if err = uc.AdminRepo.SetSuspension(ctx, userId, nil, ""); err != nil {
+		return err
+	}
+	unsuspended := user
+	unsuspended.SuspendedAt, unsuspended.SuspendReason = nil, ""
+	uc.recordUser(ctx, domain.AuditUserUnsuspend, user, unsuspended)
+	return nil
+}
+
+func (uc *AdminUsecase) VerifyEmail(c context.Context, userId int64) error {
+	ctx, cancel := context.WithTimeout(c, uc.contextTimeout)
+	defer cancel()
+
+	user, err := uc.AdminRepo.GetUser(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if err = uc.AdminRepo.VerifyEmail(ctx, userId); err != nil {
+		return err
+	}
+	verified := user
+	verified.EmailVerified = "Y"
+	uc.recordUser(ctx, domain.AuditUserVerifyEmail, user, verified)
+	return nil
+}
+
+// ForcePasswordReset refuse the current password of the user and log it out,
+// it must choose a new password with the reset token sent to it
+func (uc *AdminUsecase) ForcePasswordReset(c context.Context, userId int64) (encodedString string, err error) {
+	ctx, cancel := context.WithTimeout(c, uc.contextTimeout)
+	defer cancel()
+
+	user, err := uc.AdminRepo.GetUser(ctx, userId)
+	if err != nil {
+		return "", err
+	}
+	reset, encodedString := newResetPassword(userId)
+	if err = uc.AdminRepo.ForcePasswordReset(ctx, &reset); err != nil {
+		return "", err
+	}
+	resetRequired := user
+	resetRequired.ResetRequired = "Y"
+	uc.recordUser(ctx, domain.AuditUserForceReset, user, resetRequired)
+
+	if err = revokeUserSessions(ctx, uc.Tokens, userId, ""); err != nil {
+		return "", err
+	}
+	if err = sendLink(ctx, uc.Mailer, user.Email, "choose a new password",
+		"An administrator asked you to choose a new password before logging in again. Open the link below within an hour to choose it.",
+		resetPasswordPage, encodedString); err != nil {
+		return "", err
+	}
+	logrus.WithField("user_id", userId).Info("password reset forced")
+	return
+}
+
+// UnlockAccount lift the lockout of the user after too many failed logins
+func (uc *AdminUsecase) UnlockAccount(c context.Context, userId int64) error {
+	ctx, cancel := context.WithTimeout(c, uc.contextTimeout)
+	defer cancel()
+
+	user, err := uc.AdminRepo.GetUser(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if err = uc.Tokens.UnlockAccount(ctx, user.Email); err != nil {
+		return domain.ErrInternalServerError
+	}
+	recordAudit(ctx, uc.AuditRepo, domain.AuditLog{
+		Action:     domain.AuditUserUnlock,
+		TargetType: domain.AuditTargetUser,
+		TargetId:   auditId(user.ID),
+	})
+	return nil
+}
+
+// TakeDownLink stop the redirect of the link, its visitors get the reason instead
+func (uc *AdminUsecase) TakeDownLink(c context.Context, linkId int64, reason string) error {
+	ctx, cancel := context.WithTimeout(c, uc.contextTimeout)
+	defer cancel()
+
+	link, err := uc.AdminRepo.GetLink(ctx, linkId)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if err = uc.AdminRepo.SetTakedown(ctx, linkId, &now, reason); err != nil {
+		return err
+	}
+	takenDown := link
+	takenDown.TakenDownAt, takenDown.TakedownReason = &now, reason
+	uc.recordLink(ctx, domain.AuditLinkTakedown, link, takenDown)
+
+	uc.purgeLinkCache(ctx, link)
+	return nil
+}
+
+func (uc *AdminUsecase) RestoreLink(c context.Context, linkId int64) error {
+	ctx, cancel := context.WithTimeout(c, uc.contextTimeout)
+	defer cancel()
+
+	link, err := uc.AdminRepo.GetLink(ctx, linkId)
+	if err != nil {
+		return err
+	}
+	if err = uc.AdminRepo.SetTakedown(ctx, linkId, nil, ""); err != nil {
+		return err
+	}
+	restored := link
+	restored.TakenDownAt, restored.TakedownReason = nil, ""
+	uc.recordLink(ctx, domain.AuditLinkRestore, link, restored)
+	return nil
+}
+
+func (uc *AdminUsecase) recordUser(ctx context.Context, action string, before, after domain.AdminUser) {
+	recordAudit(ctx, uc.AuditRepo, domain.AuditLog{
+		Action:     action,
+		TargetType: domain.AuditTargetUser,
+		TargetId:   auditId(before.ID),
+		Diff:       auditDiff(before, after),
+	})
+}
+
+func (uc *AdminUsecase) recordLink(ctx context.Context, action string, before, after domain.GeneratedUrl) {
+	recordAudit(ctx, uc.AuditRepo, domain.AuditLog{
+		Action:     action,
+		TargetType: domain.AuditTargetLink,
+		TargetId:   auditId(before.ID),
+		Diff:       auditDiff(before, after),
+	})
+}
+
+// purgeLinkCache drop the cached source of the link, the next hit check it again
+func (uc *AdminUsecase) purgeLinkCache(ctx context.Context, link domain.GeneratedUrl) {
+	if err := uc.Cache.Delete(ctx, linkCacheKey(link.Generated)); err != nil {
+		logrus.Error(err)
+	}
+}
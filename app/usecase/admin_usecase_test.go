@@ -0,0 +1,198 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/RedLucky/potongin/app/usecase"
+	"github.com/RedLucky/potongin/domain"
+	"github.com/RedLucky/potongin/domain/mocks"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminUsecase_IsAdmin(t *testing.T) {
+	repository := new(mocks.AdminRepository)
+	uc := usecase.NewAdminUsecase(repository, newAuditRepository(), time.Second*5, nil, nil, newMailer())
+
+	t.Run("admin", func(t *testing.T) {
+		repository.On("GetRole", mock.Anything, int64(1)).Return(domain.UserRoleAdmin, nil).Once()
+
+		isAdmin, err := uc.IsAdmin(context.TODO(), 1)
+
+		assert.NoError(t, err)
+		assert.True(t, isAdmin)
+	})
+
+	t.Run("user", func(t *testing.T) {
+		repository.On("GetRole", mock.Anything, int64(2)).Return(domain.UserRoleUser, nil).Once()
+
+		isAdmin, err := uc.IsAdmin(context.TODO(), 2)
+
+		assert.NoError(t, err)
+		assert.False(t, isAdmin)
+	})
+
+	t.Run("unknown-user", func(t *testing.T) {
+		repository.On("GetRole", mock.Anything, int64(3)).Return("", domain.ErrNotFound).Once()
+
+		isAdmin, err := uc.IsAdmin(context.TODO(), 3)
+
+		assert.NoError(t, err)
+		assert.False(t, isAdmin)
+	})
+	repository.AssertExpectations(t)
+}
+
+func TestAdminUsecase_SearchUsers(t *testing.T) {
+	repository := new(mocks.AdminRepository)
+	repository.On("SearchUsers", mock.Anything, "lucky", 100, 0).Return([]domain.AdminUser{{ID: 1}}, nil).Once()
+
+	uc := usecase.NewAdminUsecase(repository, newAuditRepository(), time.Second*5, nil, nil, newMailer())
+	users, err := uc.SearchUsers(context.TODO(), " lucky ", 1000, -1)
+
+	assert.NoError(t, err)
+	assert.Len(t, users, 1)
+	repository.AssertExpectations(t)
+}
+
+func TestAdminUsecase_GetUser(t *testing.T) {
+	repository := new(mocks.AdminRepository)
+	uc := usecase.NewAdminUsecase(repository, newAuditRepository(), time.Second*5, nil, nil, newMailer())
+
+	t.Run("success", func(t *testing.T) {
+		repository.On("GetUser", mock.Anything, int64(1)).Return(domain.AdminUser{ID: 1}, nil).Once()
+		repository.On("GetLinks", mock.Anything, int64(1)).Return([]domain.GeneratedUrl{{ID: 7}}, nil).Once()
+
+		user, links, err := uc.GetUser(context.TODO(), 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), user.ID)
+		assert.Len(t, links, 1)
+	})
+
+	t.Run("not-found", func(t *testing.T) {
+		repository.On("GetUser", mock.Anything, int64(2)).Return(domain.AdminUser{}, domain.ErrNotFound).Once()
+
+		_, _, err := uc.GetUser(context.TODO(), 2)
+
+		assert.Equal(t, domain.ErrNotFound, err)
+	})
+	repository.AssertExpectations(t)
+}
+
+func TestAdminUsecase_Unsuspend(t *testing.T) {
+	repository := new(mocks.AdminRepository)
+	repository.On("GetUser", mock.Anything, int64(1)).Return(domain.AdminUser{ID: 1}, nil).Once()
+	repository.On("SetSuspension", mock.Anything, int64(1), (*time.Time)(nil), "").Return(nil).Once()
+
+	uc := usecase.NewAdminUsecase(repository, newAuditRepository(), time.Second*5, nil, nil, newMailer())
+	err := uc.Unsuspend(context.TODO(), 1)
+
+	assert.NoError(t, err)
+	repository.AssertExpectations(t)
+}
+
+func TestAdminUsecase_VerifyEmail(t *testing.T) {
+	repository := new(mocks.AdminRepository)
+	uc := usecase.NewAdminUsecase(repository, newAuditRepository(), time.Second*5, nil, nil, newMailer())
+
+	t.Run("success", func(t *testing.T) {
+		repository.On("GetUser", mock.Anything, int64(1)).Return(domain.AdminUser{ID: 1}, nil).Once()
+		repository.On("VerifyEmail", mock.Anything, int64(1)).Return(nil).Once()
+
+		err := uc.VerifyEmail(context.TODO(), 1)
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("something-wrong-db", func(t *testing.T) {
+		repository.On("GetUser", mock.Anything, int64(1)).Return(domain.AdminUser{ID: 1}, nil).Once()
+		repository.On("VerifyEmail", mock.Anything, int64(1)).Return(errors.New("unexpected error")).Once()
+
+		err := uc.VerifyEmail(context.TODO(), 1)
+
+		assert.Error(t, err)
+	})
+	repository.AssertExpectations(t)
+}
+
+func TestAdminUsecase_UnlockAccount(t *testing.T) {
+	repository := new(mocks.AdminRepository)
+	repository.On("GetUser", mock.Anything, int64(1)).Return(domain.AdminUser{ID: 1, Email: "lucky@kryptopos.com"}, nil).Once()
+	tokens := new(mocks.TokenStore)
+	tokens.On("UnlockAccount", mock.Anything, "lucky@kryptopos.com").Return(nil).Once()
+	auditRepository := new(mocks.AuditRepository)
+	auditRepository.On("Store", mock.Anything, mock.MatchedBy(func(log *domain.AuditLog) bool {
+		return log.Action == domain.AuditUserUnlock && log.TargetId == "1"
+	})).Return(nil).Once()
+
+	uc := usecase.NewAdminUsecase(repository, auditRepository, time.Second*5, tokens, nil, newMailer())
+	err := uc.UnlockAccount(context.TODO(), 1)
+
+	assert.NoError(t, err)
+	repository.AssertExpectations(t)
+	tokens.AssertExpectations(t)
+	auditRepository.AssertExpectations(t)
+}
+
+func TestAdminUsecase_ForcePasswordReset(t *testing.T) {
+	viper.Set(`mail.link_base_url`, "https://potong.in/")
+
+	repository := new(mocks.AdminRepository)
+	repository.On("GetUser", mock.Anything, int64(1)).Return(domain.AdminUser{ID: 1, Email: "lucky@kryptopos.com"}, nil).Once()
+	repository.On("ForcePasswordReset", mock.Anything, mock.AnythingOfType("*domain.ResetPassword")).Return(nil).Once()
+	tokens := new(mocks.TokenStore)
+	tokens.On("GetSessionsByUser", mock.Anything, int64(1)).Return([]domain.Session{}, nil).Once()
+	mailer := new(mocks.Mailer)
+	mailer.On("Send", mock.Anything, mock.Anything).Return(nil).Once()
+
+	token, err := usecase.NewAdminUsecase(repository, newAuditRepository(), time.Second*5, tokens, nil, mailer).ForcePasswordReset(context.TODO(), 1)
+
+	require.NoError(t, err)
+	// the user can't log in before choosing a new password with the mailed link
+	mailer.AssertCalled(t, "Send", mock.Anything, mailWithLink("lucky@kryptopos.com", "/reset-password", token))
+	repository.AssertExpectations(t)
+}
+
+func TestAdminUsecase_RestoreLink(t *testing.T) {
+	repository := new(mocks.AdminRepository)
+	uc := usecase.NewAdminUsecase(repository, newAuditRepository(), time.Second*5, nil, nil, newMailer())
+
+	t.Run("success", func(t *testing.T) {
+		repository.On("GetLink", mock.Anything, int64(7)).Return(domain.GeneratedUrl{ID: 7}, nil).Once()
+		repository.On("SetTakedown", mock.Anything, int64(7), (*time.Time)(nil), "").Return(nil).Once()
+
+		err := uc.RestoreLink(context.TODO(), 7)
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("link-not-found", func(t *testing.T) {
+		repository.On("GetLink", mock.Anything, int64(8)).Return(domain.GeneratedUrl{}, domain.ErrUrlNotFound).Once()
+
+		err := uc.RestoreLink(context.TODO(), 8)
+
+		assert.Equal(t, domain.ErrUrlNotFound, err)
+	})
+	repository.AssertExpectations(t)
+}
+
+func TestAdminUsecase_TakeDownLink(t *testing.T) {
+	repository := new(mocks.AdminRepository)
+	repository.On("GetLink", mock.Anything, int64(7)).Return(domain.GeneratedUrl{ID: 7, Generated: "docs"}, nil).Once()
+	repository.On("SetTakedown", mock.Anything, int64(7), mock.AnythingOfType("*time.Time"), "phishing").Return(nil).Once()
+	cache := new(mocks.Cache)
+	cache.On("Delete", mock.Anything, "link:docs").Return(nil).Once()
+
+	uc := usecase.NewAdminUsecase(repository, newAuditRepository(), time.Second*5, nil, cache, newMailer())
+	err := uc.TakeDownLink(context.TODO(), 7, "phishing")
+
+	assert.NoError(t, err)
+	repository.AssertExpectations(t)
+	cache.AssertExpectations(t)
+}
@@ -0,0 +1,136 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/RedLucky/potongin/domain"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// maximum audit logs returned by one query
+	maxAuditLimit = 500
+	// audit logs read at once while exporting
+	auditExportBatch = 500
+)
+
+// fields never written to the audit log
+var auditRedactedFields = map[string]bool{"password": true, "updated_at": true}
+
+type AuditUsecase struct {
+	AuditRepo      domain.AuditRepository
+	contextTimeout time.Duration
+}
+
+// NewAuditUsecase will create new an AuditUsecase object representation of domain.AuditUsecase interface
+func NewAuditUsecase(repo domain.AuditRepository, timeout time.Duration) domain.AuditUsecase {
+	return &AuditUsecase{
+		AuditRepo:      repo,
+		contextTimeout: timeout,
+	}
+}
+
+func (uc *AuditUsecase) Fetch(c context.Context, filter domain.AuditFilter) ([]domain.AuditLog, error) {
+	ctx, cancel := context.WithTimeout(c, uc.contextTimeout)
+	defer cancel()
+
+	if filter.Limit <= 0 || filter.Limit > maxAuditLimit {
+		filter.Limit = maxAuditLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	return uc.AuditRepo.Fetch(ctx, filter)
+}
+
+// Export read the logs by batches, the timeout applies to each batch so large
+// exports are not cut
+func (uc *AuditUsecase) Export(c context.Context, filter domain.AuditFilter, w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	filter.Limit = auditExportBatch
+	filter.Offset = 0
+	for {
+		ctx, cancel := context.WithTimeout(c, uc.contextTimeout)
+		logs, err := uc.AuditRepo.Fetch(ctx, filter)
+		cancel()
+		if err != nil {
+			return err
+		}
+		for _, entry := range logs {
+			if err = encoder.Encode(entry); err != nil {
+				return err
+			}
+		}
+		if len(logs) < auditExportBatch {
+			return nil
+		}
+		filter.BeforeId = logs[len(logs)-1].ID
+	}
+}
+
+// recordAudit append the entry to the audit log with the caller of the current
+// request. The audited action already happened, so a failure is only logged.
+func recordAudit(ctx context.Context, repo domain.AuditRepository, entry domain.AuditLog) {
+	client := domain.ClientInfoFromContext(ctx)
+	if entry.ActorId == 0 {
+		entry.ActorId = domain.ActorFromContext(ctx)
+	}
+	entry.IP = client.IP
+	entry.RequestId = client.RequestID
+	entry.CreatedAt = time.Now()
+	if err := repo.Store(ctx, &entry); err != nil {
+		logrus.WithFields(logrus.Fields{
+			"action":     entry.Action,
+			"actor_id":   entry.ActorId,
+			"target_id":  entry.TargetId,
+			"request_id": entry.RequestId,
+		}).Error("failed to write audit log: ", err)
+	}
+}
+
+// auditDiff return the fields changed between before and after, either may be
+// nil for a creation or a deletion
+func auditDiff(before, after interface{}) json.RawMessage {
+	beforeFields, afterFields := auditFields(before), auditFields(after)
+	diff := make(map[string]map[string]interface{})
+	for field, value := range afterFields {
+		if previous, ok := beforeFields[field]; !ok || !reflect.DeepEqual(previous, value) {
+			diff[field] = map[string]interface{}{"before": beforeFields[field], "after": value}
+		}
+	}
+	for field, value := range beforeFields {
+		if _, ok := afterFields[field]; !ok {
+			diff[field] = map[string]interface{}{"before": value, "after": nil}
+		}
+	}
+	if len(diff) == 0 {
+		return nil
+	}
+	encoded, _ := json.Marshal(diff)
+	return encoded
+}
+
+func auditFields(value interface{}) map[string]interface{} {
+	fields := make(map[string]interface{})
+	if value == nil {
+		return fields
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return fields
+	}
+	json.Unmarshal(encoded, &fields)
+	for field := range auditRedactedFields {
+		delete(fields, field)
+	}
+	return fields
+}
+
+func auditId(id int64) string {
+	return strconv.FormatInt(id, 10)
+}
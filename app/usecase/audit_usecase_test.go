@@ -0,0 +1,96 @@
+package usecase_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/RedLucky/potongin/app/usecase"
+	"github.com/RedLucky/potongin/domain"
+	"github.com/RedLucky/potongin/domain/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// newAuditRepository accept every audit log, for the tests not about auditing
+func newAuditRepository() *mocks.AuditRepository {
+	repository := new(mocks.AuditRepository)
+	repository.On("Store", mock.Anything, mock.AnythingOfType("*domain.AuditLog")).Return(nil)
+	return repository
+}
+
+func TestAuditUsecase_Fetch(t *testing.T) {
+	repository := new(mocks.AuditRepository)
+	repository.On("Fetch", mock.Anything, domain.AuditFilter{Action: domain.AuditLogin, Limit: 500}).
+		Return([]domain.AuditLog{{ID: 2}, {ID: 1}}, nil).Once()
+
+	uc := usecase.NewAuditUsecase(repository, time.Second*5)
+	logs, err := uc.Fetch(context.TODO(), domain.AuditFilter{Action: domain.AuditLogin, Limit: 10000, Offset: -1})
+
+	assert.NoError(t, err)
+	assert.Len(t, logs, 2)
+	repository.AssertExpectations(t)
+}
+
+func TestAuditUsecase_Export(t *testing.T) {
+	repository := new(mocks.AuditRepository)
+	firstBatch := make([]domain.AuditLog, 500)
+	for i := range firstBatch {
+		firstBatch[i] = domain.AuditLog{ID: int64(1000 - i), Action: domain.AuditLinkCreate}
+	}
+	repository.On("Fetch", mock.Anything, domain.AuditFilter{Limit: 500}).Return(firstBatch, nil).Once()
+	repository.On("Fetch", mock.Anything, domain.AuditFilter{Limit: 500, BeforeId: 501}).
+		Return([]domain.AuditLog{{ID: 3, Action: domain.AuditLogout}}, nil).Once()
+
+	uc := usecase.NewAuditUsecase(repository, time.Second*5)
+	var out bytes.Buffer
+	err := uc.Export(context.TODO(), domain.AuditFilter{Limit: 10, Offset: 20}, &out)
+	require.NoError(t, err)
+
+	lines := 0
+	scanner := bufio.NewScanner(&out)
+	var last domain.AuditLog
+	for scanner.Scan() {
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &last))
+		lines++
+	}
+	assert.Equal(t, 501, lines)
+	assert.Equal(t, domain.AuditLogout, last.Action)
+	repository.AssertExpectations(t)
+}
+
+func TestAuditUsecase_Record(t *testing.T) {
+	userRepository := new(mocks.UserRepository)
+	userMock := domain.User{ID: 7, Username: "LFR", Email: "lucky@kryptopos.com", Password: "hashed"}
+	userRepository.On("GetByID", int64(7)).Return(userMock, nil).Once()
+	userRepository.On("Delete", int64(7)).Return(nil).Once()
+
+	auditRepository := new(mocks.AuditRepository)
+	auditRepository.On("Store", mock.Anything, mock.AnythingOfType("*domain.AuditLog")).Run(func(args mock.Arguments) {
+		entry := args.Get(1).(*domain.AuditLog)
+		assert.Equal(t, int64(1), entry.ActorId)
+		assert.Equal(t, domain.AuditUserDelete, entry.Action)
+		assert.Equal(t, "7", entry.TargetId)
+		assert.Equal(t, "10.0.0.1", entry.IP)
+		assert.Equal(t, "request-1", entry.RequestId)
+
+		var diff map[string]map[string]interface{}
+		require.NoError(t, json.Unmarshal(entry.Diff, &diff))
+		assert.Equal(t, "LFR", diff["username"]["before"])
+		assert.Nil(t, diff["username"]["after"])
+		assert.NotContains(t, diff, "password")
+	}).Return(nil).Once()
+
+	ctx := domain.NewContextWithClientInfo(context.TODO(), domain.ClientInfo{IP: "10.0.0.1", RequestID: "request-1"})
+	ctx = domain.NewContextWithActor(ctx, 1)
+	uc := usecase.NewUserUsecase(userRepository, auditRepository, time.Second*5)
+	err := uc.Delete(ctx, 7)
+
+	assert.NoError(t, err)
+	userRepository.AssertExpectations(t)
+	auditRepository.AssertExpectations(t)
+}
@@ -0,0 +1,210 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/RedLucky/potongin/app/delivery/api/auth"
+	"github.com/RedLucky/potongin/domain"
+	"github.com/sirupsen/logrus"
+)
+
+var usernameSanitizer = regexp.MustCompile(`[^a-z0-9]`)
+
+// OidcLoginURL start the authorization code flow and return the provider login page
+func (uc *AuthUsecase) OidcLoginURL(c context.Context, providerName string) (string, error) {
+	ctx, cancel := context.WithTimeout(c, uc.contextTimeout)
+	defer cancel()
+
+	provider, ok := uc.OidcProviders[providerName]
+	if !ok {
+		return "", domain.ErrNotFound
+	}
+
+	state, err := auth.RandomString()
+	if err != nil {
+		return "", domain.ErrInternalServerError
+	}
+	nonce, err := auth.RandomString()
+	if err != nil {
+		return "", domain.ErrInternalServerError
+	}
+	codeVerifier, err := auth.RandomString()
+	if err != nil {
+		return "", domain.ErrInternalServerError
+	}
+
+	err = uc.Tokens.SaveOidcState(ctx, state, domain.OidcState{Provider: providerName, Nonce: nonce, CodeVerifier: codeVerifier})
+	if err != nil {
+		return "", domain.ErrInternalServerError
+	}
+
+	loginURL, err := provider.AuthCodeURL(ctx, state, nonce, codeVerifier)
+	if err != nil {
+		logrus.Error(err)
+		return "", domain.ErrInternalServerError
+	}
+	return loginURL, nil
+}
+
+// OidcCallback finish the authorization code flow: the id token is validated,
+// the identity linked to a local user (created when needed) and our own tokens issued
+func (uc *AuthUsecase) OidcCallback(c context.Context, providerName, code, state string) (token domain.JwtResults, err error) {
+	ctx, cancel := context.WithTimeout(c, uc.contextTimeout)
+	defer cancel()
+
+	provider, ok := uc.OidcProviders[providerName]
+	if !ok {
+		return domain.JwtResults{}, domain.ErrNotFound
+	}
+
+	saved, err := uc.Tokens.ConsumeOidcState(ctx, state)
+	if err == domain.ErrCacheMiss || (err == nil && saved.Provider != providerName) {
+		return domain.JwtResults{}, domain.ErrorAuthorization
+	} else if err != nil {
+		return domain.JwtResults{}, domain.ErrInternalServerError
+	}
+
+	idToken, err := provider.Exchange(ctx, code, saved.CodeVerifier)
+	if err != nil {
+		logrus.Error(err)
+		return domain.JwtResults{}, domain.ErrorAuthorization
+	}
+	identity, err := provider.VerifyIDToken(ctx, idToken, saved.Nonce)
+	if err != nil {
+		logrus.Error(err)
+		return domain.JwtResults{}, domain.ErrorAuthorization
+	}
+
+	user, err := uc.linkIdentity(ctx, providerName, identity)
+	if err != nil {
+		return domain.JwtResults{}, err
+	}
+	return uc.completeLogin(ctx, user)
+}
+
+// linkIdentity return the user of the identity, linking it by email to an
+// existing user or creating a new user with a pre-verified email
+func (uc *AuthUsecase) linkIdentity(ctx context.Context, providerName string, identity auth.OidcIdentity) (user domain.User, err error) {
+	linked, err := uc.AuthRepo.GetIdentity(ctx, providerName, identity.Subject)
+	if err == nil {
+		return uc.AuthRepo.GetUserById(ctx, linked.UserId)
+	} else if err != domain.ErrNotFound {
+		return domain.User{}, domain.ErrInternalServerError
+	}
+
+	// only an email verified by the provider may be trusted to link or create an account
+	if err = identity.Validate(); err != nil {
+		return domain.User{}, err
+	}
+
+	user, err = uc.AuthRepo.GetUserByEmail(ctx, identity.Email)
+	if err != nil {
+		user, err = uc.registerOidcUser(ctx, identity)
+		if err != nil {
+			return domain.User{}, err
+		}
+	} else if user.EmailVerified != "Y" {
+		if err = uc.claimUnverifiedUser(ctx, user); err != nil {
+			return domain.User{}, err
+		}
+	}
+
+	err = uc.AuthRepo.CreateIdentity(ctx, &domain.UserIdentity{
+		UserId:    user.ID,
+		Provider:  providerName,
+		Subject:   identity.Subject,
+		Email:     identity.Email,
+		CreatedAt: time.Now(),
+	})
+	return
+}
+
+// claimUnverifiedUser hand an account whose email was never verified to the
+// owner of the email proven by the provider. Whoever signed up with it may not
+// own it, so its password, second factor and sessions are dropped.
+func (uc *AuthUsecase) claimUnverifiedUser(ctx context.Context, user domain.User) error {
+	hashedPassword, err := randomPasswordHash()
+	if err != nil {
+		return err
+	}
+	if err = uc.AuthRepo.UpdatePassword(ctx, user.ID, hashedPassword); err != nil {
+		return domain.ErrInternalServerError
+	}
+	if err = uc.MfaRepo.Delete(ctx, user.ID); err != nil && err != domain.ErrNotFound {
+		return domain.ErrInternalServerError
+	}
+	if err = revokeUserSessions(ctx, uc.Tokens, user.ID, ""); err != nil {
+		return err
+	}
+	if err = uc.AuthRepo.VerifyTokenAccount(ctx, user.ID); err != nil {
+		return domain.ErrInternalServerError
+	}
+	return nil
+}
+
+func (uc *AuthUsecase) registerOidcUser(ctx context.Context, identity auth.OidcIdentity) (user domain.User, err error) {
+	username, err := uc.availableUsername(ctx, identity.Email)
+	if err != nil {
+		return domain.User{}, err
+	}
+	hashedPassword, err := randomPasswordHash()
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	name := identity.Name
+	if name == "" {
+		name = username
+	}
+	user = domain.User{
+		Username:      username,
+		Email:         identity.Email,
+		Password:      hashedPassword,
+		Name:          name,
+		EmailVerified: "Y",
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	}
+	err = uc.AuthRepo.RegisterUser(ctx, &user)
+	return
+}
+
+// randomPasswordHash return the hash of a password nobody knows, the account
+// has no usable password until the user sets one
+func randomPasswordHash() (string, error) {
+	randomPassword, err := auth.RandomString()
+	if err != nil {
+		return "", domain.ErrInternalServerError
+	}
+	hashedPassword, err := hash(randomPassword)
+	if err != nil {
+		return "", err
+	}
+	return string(hashedPassword), nil
+}
+
+// availableUsername derive a free username (12 chars max) from the email
+func (uc *AuthUsecase) availableUsername(ctx context.Context, email string) (string, error) {
+	base := usernameSanitizer.ReplaceAllString(strings.ToLower(strings.Split(email, "@")[0]), "")
+	if len(base) > 8 {
+		base = base[:8]
+	}
+	if base == "" {
+		base = "user"
+	}
+
+	candidate := base
+	for i := 0; i < 10; i++ {
+		existUsername, _ := uc.AuthRepo.GetUserByUsername(ctx, candidate)
+		if existUsername == (domain.User{}) {
+			return candidate, nil
+		}
+		candidate = fmt.Sprintf("%s%04d", base, rand.Intn(10000))
+	}
+	return "", domain.ErrAccountExist
+}
@@ -0,0 +1,133 @@
+package usecase_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/RedLucky/potongin/app/delivery/api/auth"
+	"github.com/RedLucky/potongin/app/usecase"
+	"github.com/RedLucky/potongin/domain"
+	"github.com/RedLucky/potongin/domain/mocks"
+	jwt "github.com/golang-jwt/jwt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// newOidcProvider serve a provider answering any code with an id token of
+// the email for the nonce
+func newOidcProvider(t *testing.T, email, nonce string) *auth.OidcProvider {
+	key, err := auth.GenerateKey("RS256")
+	require.NoError(t, err)
+
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 server.URL,
+			"authorization_endpoint": server.URL + "/authorize",
+			"token_endpoint":         server.URL + "/token",
+			"jwks_uri":               server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		ks := auth.NewKeySet(time.Hour)
+		ks.Add(key)
+		json.NewEncoder(w).Encode(ks.JWKS(time.Now()))
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		token := jwt.NewWithClaims(key.Method, jwt.MapClaims{
+			"iss":            server.URL,
+			"aud":            "potongin",
+			"sub":            "user-42",
+			"email":          email,
+			"email_verified": true,
+			"nonce":          nonce,
+			"exp":            time.Now().Add(time.Minute).Unix(),
+			"iat":            time.Now().Unix(),
+		})
+		token.Header["kid"] = key.Kid
+		idToken, _ := token.SignedString(key.PrivateKey)
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "opaque", "id_token": idToken})
+	})
+	server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return auth.NewOidcProvider("company", auth.OidcProviderConfig{
+		Issuer:      server.URL,
+		ClientID:    "potongin",
+		RedirectURL: "http://localhost/v1/auth/oauth/company/callback",
+	})
+}
+
+func TestAuthUsecase_OidcCallback(t *testing.T) {
+	providers := map[string]*auth.OidcProvider{"company": newOidcProvider(t, "lucky@kryptopos.com", "nonce")}
+	state := domain.OidcState{Provider: "company", Nonce: "nonce", CodeVerifier: "verifier"}
+
+	t.Run("unverified-account-claimed", func(t *testing.T) {
+		// signed up by someone who never proved owning the email
+		squatted := domain.User{ID: 7, Email: "lucky@kryptopos.com", Password: "$2a$10$attacker", EmailVerified: "N"}
+		repository := new(mocks.AuthRepository)
+		repository.On("GetIdentity", mock.Anything, "company", "user-42").Return(domain.UserIdentity{}, domain.ErrNotFound).Once()
+		repository.On("GetUserByEmail", mock.Anything, "lucky@kryptopos.com").Return(squatted, nil).Once()
+		repository.On("UpdatePassword", mock.Anything, int64(7), mock.MatchedBy(func(hashed string) bool {
+			return hashed != "" && hashed != squatted.Password
+		})).Return(nil).Once()
+		repository.On("VerifyTokenAccount", mock.Anything, int64(7)).Return(nil).Once()
+		repository.On("CreateIdentity", mock.Anything, mock.AnythingOfType("*domain.UserIdentity")).Return(nil).Once()
+		mfaRepository := new(mocks.MfaRepository)
+		mfaRepository.On("Delete", mock.Anything, int64(7)).Return(nil).Once()
+		mfaRepository.On("GetByUserId", mock.Anything, int64(7)).Return(domain.UserMfa{}, domain.ErrNotFound).Once()
+		tokens := new(mocks.TokenStore)
+		tokens.On("ConsumeOidcState", mock.Anything, "the-state").Return(state, nil).Once()
+		squatterSession := domain.Session{ID: "squatter", UserId: 7}
+		tokens.On("GetSessionsByUser", mock.Anything, int64(7)).Return([]domain.Session{squatterSession}, nil).Once()
+		tokens.On("DeleteSession", mock.Anything, squatterSession).Return(nil).Once()
+		tokens.On("SaveTokens", mock.Anything, mock.AnythingOfType("domain.User"), mock.AnythingOfType("domain.JwtResults")).Return(nil).Once()
+		tokens.On("SaveSession", mock.Anything, mock.AnythingOfType("domain.Session")).Return(nil).Once()
+
+		uc := usecase.NewAuthUsecase(repository, mfaRepository, newAuditRepository(), time.Second*5, tokens, providers, newMailer())
+		token, err := uc.OidcCallback(context.TODO(), "company", "the-code", "the-state")
+
+		require.NoError(t, err)
+		assert.NotEmpty(t, token.AccessToken)
+		repository.AssertExpectations(t)
+		mfaRepository.AssertExpectations(t)
+		tokens.AssertExpectations(t)
+	})
+
+	t.Run("verified-account-linked", func(t *testing.T) {
+		repository := new(mocks.AuthRepository)
+		repository.On("GetIdentity", mock.Anything, "company", "user-42").Return(domain.UserIdentity{}, domain.ErrNotFound).Once()
+		repository.On("GetUserByEmail", mock.Anything, "lucky@kryptopos.com").Return(domain.User{ID: 1, EmailVerified: "Y"}, nil).Once()
+		repository.On("CreateIdentity", mock.Anything, mock.AnythingOfType("*domain.UserIdentity")).Return(nil).Once()
+		mfaRepository := new(mocks.MfaRepository)
+		mfaRepository.On("GetByUserId", mock.Anything, int64(1)).Return(domain.UserMfa{}, domain.ErrNotFound).Once()
+		tokens := new(mocks.TokenStore)
+		tokens.On("ConsumeOidcState", mock.Anything, "the-state").Return(state, nil).Once()
+		tokens.On("SaveTokens", mock.Anything, mock.AnythingOfType("domain.User"), mock.AnythingOfType("domain.JwtResults")).Return(nil).Once()
+		tokens.On("SaveSession", mock.Anything, mock.AnythingOfType("domain.Session")).Return(nil).Once()
+
+		uc := usecase.NewAuthUsecase(repository, mfaRepository, newAuditRepository(), time.Second*5, tokens, providers, newMailer())
+		_, err := uc.OidcCallback(context.TODO(), "company", "the-code", "the-state")
+
+		require.NoError(t, err)
+		repository.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+		mfaRepository.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+		tokens.AssertNotCalled(t, "GetSessionsByUser", mock.Anything, mock.Anything)
+	})
+
+	t.Run("unknown-state", func(t *testing.T) {
+		tokens := new(mocks.TokenStore)
+		tokens.On("ConsumeOidcState", mock.Anything, "forged").Return(domain.OidcState{}, domain.ErrCacheMiss).Once()
+
+		uc := usecase.NewAuthUsecase(new(mocks.AuthRepository), new(mocks.MfaRepository), newAuditRepository(), time.Second*5, tokens, providers, newMailer())
+		_, err := uc.OidcCallback(context.TODO(), "company", "the-code", "forged")
+
+		assert.Equal(t, domain.ErrorAuthorization, err)
+	})
+}
@@ -3,29 +3,45 @@ package usecase
 import (
 	"context"
 	"encoding/base64"
-	"fmt"
 	"time"
 
 	"github.com/RedLucky/potongin/app/delivery/api/auth"
 	"github.com/RedLucky/potongin/domain"
-	"github.com/gomodule/redigo/redis"
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// a reset password token must be used within an hour
+const resetPasswordDuration = time.Hour
+
 type AuthUsecase struct {
 	AuthRepo       domain.AuthRepository
+	MfaRepo        domain.MfaRepository
+	AuditRepo      domain.AuditRepository
 	contextTimeout time.Duration
-	RedisPool      *redis.Pool
+	Tokens         domain.TokenStore
+	OidcProviders  map[string]*auth.OidcProvider
+	Mailer         domain.Mailer
+
+	loginProtection loginProtection
+	passwordPolicy  PasswordPolicy
 }
 
 // NewUserUsecase will create new an USerUsecase object representation of domain.UserUsecase interface
-func NewAuthUsecase(repo domain.AuthRepository, timeout time.Duration, redisPool *redis.Pool) domain.AuthUsecase {
+func NewAuthUsecase(repo domain.AuthRepository, mfaRepo domain.MfaRepository, auditRepo domain.AuditRepository, timeout time.Duration, tokens domain.TokenStore, oidcProviders map[string]*auth.OidcProvider, mailer domain.Mailer) domain.AuthUsecase {
 	return &AuthUsecase{
 		AuthRepo:       repo,
+		MfaRepo:        mfaRepo,
+		AuditRepo:      auditRepo,
 		contextTimeout: timeout,
-		RedisPool:      redisPool,
+		Tokens:         tokens,
+		OidcProviders:  oidcProviders,
+		Mailer:         mailer,
+
+		loginProtection: newLoginProtection(),
+		passwordPolicy:  NewPasswordPolicy(),
 	}
 }
 
@@ -42,6 +58,10 @@ func (uc *AuthUsecase) SignUp(c context.Context, user *domain.User) (err error)
 		return domain.ErrAccountExist
 	}
 
+	if err = uc.passwordPolicy.Validate(user.Password, *user); err != nil {
+		return err
+	}
+
 	hashedPassword, err := hash(user.Password)
 	if err != nil {
 		return err
@@ -55,48 +75,246 @@ func (uc *AuthUsecase) SignUp(c context.Context, user *domain.User) (err error)
 	if err != nil {
 		return err
 	}
+	recordAudit(ctx, uc.AuditRepo, domain.AuditLog{
+		ActorId:    user.ID,
+		Action:     domain.AuditSignup,
+		TargetType: domain.AuditTargetUser,
+		TargetId:   auditId(user.ID),
+		Diff:       auditDiff(nil, user),
+	})
 
-	encodedString, err := uc.CreateVerifyEmail(ctx, user.Email)
-	fmt.Println(encodedString)
-	return
+	// the account exists, the link can be sent again
+	if _, err = uc.CreateVerifyEmail(ctx, user.Email); err != nil {
+		logrus.WithField("user_id", user.ID).WithError(err).Warn("failed to send the email verification link")
+	}
+	return nil
 }
 
 func (uc *AuthUsecase) Authenticate(c context.Context, email, password string) (token domain.JwtResults, err error) {
 	ctx, cancel := context.WithTimeout(c, uc.contextTimeout)
 	defer cancel()
 	user := domain.User{}
+	ip := domain.ClientInfoFromContext(ctx).IP
 
+	// refuse early while the account or the ip is backing off or locked
+	if err = uc.loginAllowed(ctx, email, ip); err != nil {
+		return domain.JwtResults{}, err
+	}
+
+	// unknown email and wrong password must look the same, in message and in timing
 	user, err = uc.AuthRepo.GetUserByEmail(ctx, email)
 	if err != nil {
-		return domain.JwtResults{}, err
+		compareDummyPassword(password)
+		uc.loginProtection.loginFailed(ctx, uc.Tokens, email, ip)
+		recordAudit(ctx, uc.AuditRepo, domain.AuditLog{
+			Action:     domain.AuditLoginFailed,
+			TargetType: domain.AuditTargetUser,
+		})
+		return domain.JwtResults{}, domain.ErrInvalidCredentials
 	}
 
 	err = verifyPassword(user.Password, password)
-	if err != nil && err == bcrypt.ErrMismatchedHashAndPassword {
-		return domain.JwtResults{}, domain.ErrPassword
+	if err != nil {
+		uc.loginFailed(ctx, email, ip, user.Email)
+		recordAudit(ctx, uc.AuditRepo, domain.AuditLog{
+			Action:     domain.AuditLoginFailed,
+			TargetType: domain.AuditTargetUser,
+			TargetId:   auditId(user.ID),
+		})
+		return domain.JwtResults{}, domain.ErrInvalidCredentials
 	}
+	if user.ResetRequired == "Y" {
+		return domain.JwtResults{}, domain.ErrPasswordResetRequired
+	}
+
 	// check is verified email?
 	ok, err := uc.AuthRepo.IsVerifiedEmail(email)
 	if err != nil && !ok {
 		return domain.JwtResults{}, domain.ErrorEmailNotVerified
 	}
 
-	token, err = auth.CreateToken(&user)
+	token, err = uc.completeLogin(ctx, user)
+	// the failures are kept until the second factor is verified too
+	if err == nil && !token.MfaRequired {
+		uc.resetLoginFailures(ctx, email)
+	}
+	return
+}
+
+// loginAllowed refuse the login while the account or the ip is backing off or locked
+func (uc *AuthUsecase) loginAllowed(ctx context.Context, email, ip string) error {
+	wait, err := uc.Tokens.LoginBackoff(ctx, email, ip)
+	if err != nil {
+		return domain.ErrInternalServerError
+	}
+	if wait > 0 {
+		return domain.ErrTooManyAttempts
+	}
+	locked, err := uc.Tokens.IsAccountLocked(ctx, email)
+	if err != nil {
+		return domain.ErrInternalServerError
+	}
+	if locked {
+		return domain.ErrAccountLocked
+	}
+	return nil
+}
+
+// loginFailed count a wrong password or mfa code of the account, and mail the
+// link unlocking it to its owner once it gets locked. The mail is sent in the
+// background, an unknown email is locked too and must not answer faster.
+func (uc *AuthUsecase) loginFailed(ctx context.Context, email, ip, ownerEmail string) {
+	unlockToken := uc.loginProtection.loginFailed(ctx, uc.Tokens, email, ip)
+	if unlockToken == "" {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), uc.contextTimeout)
+		defer cancel()
+		sendLink(ctx, uc.Mailer, ownerEmail, "your account is locked",
+			"Your account was locked after too many failed logins. If it was you, open the link below to unlock it, otherwise change your password once unlocked.",
+			unlockAccountPage, unlockToken)
+	}()
+}
+
+func (uc *AuthUsecase) resetLoginFailures(ctx context.Context, email string) {
+	if err := uc.Tokens.ResetLoginFailures(ctx, email); err != nil {
+		logrus.Error(err)
+	}
+}
+
+// completeLogin issue the tokens of an authenticated user, or the mfa pending
+// token when a second step is required
+func (uc *AuthUsecase) completeLogin(ctx context.Context, user domain.User) (token domain.JwtResults, err error) {
+	if user.SuspendedAt != nil {
+		return domain.JwtResults{}, domain.ErrAccountSuspended
+	}
+
+	mfa, err := uc.MfaRepo.GetByUserId(ctx, user.ID)
+	if err != nil && err != domain.ErrNotFound {
+		return domain.JwtResults{}, domain.ErrInternalServerError
+	}
+	if mfa.Enabled == "Y" {
+		return uc.createMfaChallenge(ctx, user)
+	}
+
+	return uc.createSession(ctx, user)
+}
+
+// VerifyMfa exchange the mfa pending token of the first login step and a valid
+// TOTP or recovery code for the real tokens
+func (uc *AuthUsecase) VerifyMfa(c context.Context, mfaToken, code string) (token domain.JwtResults, err error) {
+	ctx, cancel := context.WithTimeout(c, uc.contextTimeout)
+	defer cancel()
+
+	claims, err := auth.MfaTokenValid(mfaToken)
+	if err != nil {
+		return domain.JwtResults{}, domain.ErrorAuthorization
+	}
+
+	pending, err := uc.Tokens.ExistMfaChallenge(ctx, claims.MfaUUID)
 	if err != nil {
 		return domain.JwtResults{}, domain.ErrInternalServerError
 	}
-	// set on redis cache pool
-	conn := uc.RedisPool.Get()
-	defer conn.Close()
-	if err = auth.SaveToken(conn, user, token); err != nil {
-		fmt.Println(err)
+	if !pending {
+		return domain.JwtResults{}, domain.ErrorAuthorization
+	}
+
+	user, err := uc.AuthRepo.GetUserById(ctx, claims.UserId)
+	if err != nil {
+		return domain.JwtResults{}, domain.ErrorAuthorization
+	}
+	// the wrong codes count with the wrong passwords of the account, whatever
+	// the challenge they are tried on
+	ip := domain.ClientInfoFromContext(ctx).IP
+	if err = uc.loginAllowed(ctx, user.Email, ip); err != nil {
+		return domain.JwtResults{}, err
+	}
+
+	mfa, err := uc.MfaRepo.GetByUserId(ctx, claims.UserId)
+	if err != nil || mfa.Enabled != "Y" {
+		return domain.JwtResults{}, domain.ErrorAuthorization
+	}
+	ok, err := checkMfaCode(ctx, uc.MfaRepo, mfa, code)
+	if err != nil {
 		return domain.JwtResults{}, domain.ErrInternalServerError
 	}
+	if !ok {
+		uc.loginFailed(ctx, user.Email, ip, user.Email)
+		attempts, err := uc.Tokens.CountMfaAttempt(ctx, claims.MfaUUID)
+		if err == nil && attempts >= auth.MaxMfaAttempts {
+			uc.Tokens.ConsumeMfaChallenge(ctx, claims.MfaUUID)
+		}
+		return domain.JwtResults{}, domain.ErrInvalidMfaCode
+	}
+
+	// the challenge can only be exchanged once
+	userId, err := uc.Tokens.ConsumeMfaChallenge(ctx, claims.MfaUUID)
+	if err != nil || userId != claims.UserId {
+		return domain.JwtResults{}, domain.ErrorAuthorization
+	}
+	// the user may have been suspended since the first step
+	if user.SuspendedAt != nil {
+		return domain.JwtResults{}, domain.ErrAccountSuspended
+	}
+	token, err = uc.createSession(ctx, domain.User{ID: userId})
+	if err == nil {
+		uc.resetLoginFailures(ctx, user.Email)
+	}
+	return
+}
+
+func (uc *AuthUsecase) createMfaChallenge(ctx context.Context, user domain.User) (token domain.JwtResults, err error) {
+	mfaToken, mfaUUID, err := auth.CreateMfaToken(&user)
+	if err != nil {
+		return domain.JwtResults{}, domain.ErrInternalServerError
+	}
+
+	if err = uc.Tokens.SaveMfaChallenge(ctx, mfaUUID, user.ID); err != nil {
+		return domain.JwtResults{}, domain.ErrInternalServerError
+	}
+	return domain.JwtResults{MfaRequired: true, MfaToken: mfaToken}, nil
+}
+
+// createSession issue a new token pair for the user and record it as a new login session
+func (uc *AuthUsecase) createSession(ctx context.Context, user domain.User) (token domain.JwtResults, err error) {
+	client := domain.ClientInfoFromContext(ctx)
+	session := domain.Session{
+		ID:         uuid.New().String(),
+		UserId:     user.ID,
+		Device:     client.Device,
+		IP:         client.IP,
+		UserAgent:  client.UserAgent,
+		CreatedAt:  time.Now(),
+		LastSeenAt: time.Now(),
+	}
+
+	token, err = auth.CreateToken(&user, session.ID)
+	if err != nil {
+		return domain.JwtResults{}, domain.ErrInternalServerError
+	}
+	session.AccessUUID = token.AccessUUID
+	session.RefreshUUID = token.RefreshUUID
+
+	if err = uc.Tokens.SaveTokens(ctx, user, token); err != nil {
+		logrus.WithField("user_id", user.ID).WithError(err).Error("failed to save the tokens of a new session")
+		return domain.JwtResults{}, domain.ErrInternalServerError
+	}
+	if err = uc.Tokens.SaveSession(ctx, session); err != nil {
+		logrus.WithField("user_id", user.ID).WithError(err).Error("failed to save a new session")
+		return domain.JwtResults{}, domain.ErrInternalServerError
+	}
+	recordAudit(ctx, uc.AuditRepo, domain.AuditLog{
+		ActorId:    user.ID,
+		Action:     domain.AuditLogin,
+		TargetType: domain.AuditTargetSession,
+		TargetId:   session.ID,
+	})
 	return
 }
 
 func (uc *AuthUsecase) GenerateNewAccessToken(c echo.Context) (token domain.JwtResults, err error) {
-	_, cancel := context.WithTimeout(c.Request().Context(), uc.contextTimeout)
+	ctx, cancel := context.WithTimeout(c.Request().Context(), uc.contextTimeout)
 	defer cancel()
 	var user domain.User
 
@@ -110,27 +328,83 @@ func (uc *AuthUsecase) GenerateNewAccessToken(c echo.Context) (token domain.JwtR
 	// get uuid token from jwt
 	res, ok := claims["refresh_uuid"].(string)
 	if !ok {
-		return domain.JwtResults{}, err
+		return domain.JwtResults{}, domain.ErrorAuthorization
 	}
-	// get user_id to redis
-	conn := uc.RedisPool.Get()
-	defer conn.Close()
-	userId, err := auth.GetTokenFromRedis(conn, res)
-	if err != nil {
+	// consume the refresh token, it can only be exchanged once
+	userId, sessionId, err := uc.Tokens.ConsumeRefreshToken(ctx, res)
+	if err == domain.ErrRefreshTokenReused {
+		uc.revokeTokenFamily(ctx, userId, sessionId, res)
+		return domain.JwtResults{}, err
+	} else if err != nil {
 		return domain.JwtResults{}, err
 	}
 	user.ID = userId
+
+	session, err := uc.Tokens.GetSession(ctx, sessionId)
+	if err != nil {
+		// token issued before sessions existed or session already revoked
+		return domain.JwtResults{}, domain.ErrorAuthorization
+	}
+	if session.RefreshUUID != res {
+		// the family already moved on to a newer refresh token
+		uc.revokeTokenFamily(ctx, userId, sessionId, res)
+		return domain.JwtResults{}, domain.ErrRefreshTokenReused
+	}
+
 	// generate new refresh token
-	token, err = auth.CreateToken(&user)
-	// set on redis cache pool
-	if err = auth.SaveToken(conn, user, token); err != nil {
+	token, err = auth.CreateToken(&user, session.ID)
+	if err != nil {
+		return domain.JwtResults{}, err
+	}
+	if err = uc.Tokens.SaveTokens(ctx, user, token); err != nil {
+		return domain.JwtResults{}, err
+	}
+	// the access token of the previous pair must not outlive its refresh token
+	if err = uc.Tokens.DeleteToken(ctx, session.AccessUUID); err != nil {
 		return domain.JwtResults{}, err
 	}
-	err = auth.DeleteTokenRedis(conn, res)
+	session.AccessUUID = token.AccessUUID
+	session.RefreshUUID = token.RefreshUUID
+	session.Generation++
+	session.LastSeenAt = time.Now()
+	if err = uc.Tokens.SaveSession(ctx, session); err != nil {
+		return domain.JwtResults{}, err
+	}
+	recordAudit(c.Request().Context(), uc.AuditRepo, domain.AuditLog{
+		ActorId:    userId,
+		Action:     domain.AuditTokenRefresh,
+		TargetType: domain.AuditTargetSession,
+		TargetId:   session.ID,
+	})
 	return
 }
 
-func (uc *AuthUsecase) Logout(accessToken string, refreshToken string) (err error) {
+// revokeTokenFamily end the session a replayed refresh token belongs to, which
+// invalidate every access and refresh token issued down its chain
+func (uc *AuthUsecase) revokeTokenFamily(ctx context.Context, userId int64, sessionId, refreshUUID string) {
+	client := domain.ClientInfoFromContext(ctx)
+	entry := logrus.WithFields(logrus.Fields{
+		"incident":     "refresh_token_reuse",
+		"user_id":      userId,
+		"session_id":   sessionId,
+		"refresh_uuid": refreshUUID,
+		"ip":           client.IP,
+		"user_agent":   client.UserAgent,
+	})
+
+	session, err := uc.Tokens.GetSession(ctx, sessionId)
+	if err != nil {
+		entry.Error("security incident: refresh token reused, session already gone")
+		return
+	}
+	if err = uc.Tokens.DeleteSession(ctx, session); err != nil {
+		entry.WithError(err).Error("security incident: refresh token reused, failed to revoke session")
+		return
+	}
+	entry.Error("security incident: refresh token reused, session revoked")
+}
+
+func (uc *AuthUsecase) Logout(ctx context.Context, accessToken string, refreshToken string) (err error) {
 	access, err := auth.TokenValid(accessToken, auth.AccessToken)
 	if err != nil {
 		return err
@@ -141,11 +415,26 @@ func (uc *AuthUsecase) Logout(accessToken string, refreshToken string) (err erro
 		return err
 	}
 
-	conn := uc.RedisPool.Get()
-	defer conn.Close()
-	err = auth.DeleteTokenRedis(conn, access["access_uuid"].(string))
-	err = auth.DeleteTokenRedis(conn, refresh["refresh_uuid"].(string))
+	userId, _, _ := uc.Tokens.GetTokenSession(ctx, access["access_uuid"].(string))
+	err = uc.Tokens.DeleteToken(ctx, access["access_uuid"].(string))
+	err = uc.Tokens.DeleteToken(ctx, refresh["refresh_uuid"].(string))
 
+	// end the session the tokens belong to
+	if sessionId, ok := access["session_id"].(string); ok {
+		session, errSession := uc.Tokens.GetSession(ctx, sessionId)
+		if errSession == nil {
+			err = uc.Tokens.DeleteSession(ctx, session)
+		}
+	}
+	if err == nil && userId != 0 {
+		sessionId, _ := access["session_id"].(string)
+		recordAudit(ctx, uc.AuditRepo, domain.AuditLog{
+			ActorId:    userId,
+			Action:     domain.AuditLogout,
+			TargetType: domain.AuditTargetSession,
+			TargetId:   sessionId,
+		})
+	}
 	return
 }
 
@@ -183,6 +472,8 @@ func (uc *AuthUsecase) CreateVerifyEmail(ctx context.Context, email string) (enc
 		return "", err
 	}
 	encodedString = base64.StdEncoding.EncodeToString([]byte(verifyEmail.Token))
+	err = sendLink(ctx, uc.Mailer, user.Email, "verify your email",
+		"Open the link below to verify your email.", verifyEmailPage, encodedString)
 	return
 }
 
@@ -212,7 +503,118 @@ func (uc *AuthUsecase) VerifyEmail(ctx context.Context, token string) error {
 	return nil
 }
 
+// UnlockAccountByToken lift the lockout with the token sent to the account owner
+func (uc *AuthUsecase) UnlockAccountByToken(c context.Context, token string) error {
+	ctx, cancel := context.WithTimeout(c, uc.contextTimeout)
+	defer cancel()
+
+	email, err := uc.Tokens.ConsumeUnlockToken(ctx, token)
+	if err == domain.ErrCacheMiss {
+		return domain.ErrorTokenNotFound
+	} else if err != nil {
+		return domain.ErrInternalServerError
+	}
+	if err = uc.Tokens.UnlockAccount(ctx, email); err != nil {
+		return domain.ErrInternalServerError
+	}
+	return nil
+}
+
+// CreateResetPassword create a reset password token for the email. An unknown
+// email is not an error, so emails can't be probed.
+func (uc *AuthUsecase) CreateResetPassword(c context.Context, email string) (encodedString string, err error) {
+	ctx, cancel := context.WithTimeout(c, uc.contextTimeout)
+	defer cancel()
+
+	user, err := uc.AuthRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		return "", nil
+	}
+	reset, encodedString := newResetPassword(user.ID)
+	if err = uc.AuthRepo.CreateResetPassword(ctx, &reset); err != nil {
+		return "", err
+	}
+	if err = sendLink(ctx, uc.Mailer, user.Email, "reset your password",
+		"Open the link below within an hour to choose a new password. Ignore this mail if you didn't ask for it.",
+		resetPasswordPage, encodedString); err != nil {
+		return "", err
+	}
+	logrus.WithField("user_id", user.ID).Info("reset password requested")
+	return
+}
+
+func (uc *AuthUsecase) VerifyResetPassword(c context.Context, token string) error {
+	ctx, cancel := context.WithTimeout(c, uc.contextTimeout)
+	defer cancel()
+
+	_, err := uc.getResetPassword(ctx, token)
+	return err
+}
+
+// ResetPassword set the new password and log out every session of the user
+func (uc *AuthUsecase) ResetPassword(c context.Context, password, confirmPassword, token string) error {
+	ctx, cancel := context.WithTimeout(c, uc.contextTimeout)
+	defer cancel()
+
+	if password != confirmPassword {
+		return domain.ErrBadParamInput
+	}
+	reset, err := uc.getResetPassword(ctx, token)
+	if err != nil {
+		return err
+	}
+	user, err := uc.AuthRepo.GetUserById(ctx, reset.UserId)
+	if err != nil {
+		return domain.ErrorTokenNotFound
+	}
+	if err = uc.passwordPolicy.Validate(password, user); err != nil {
+		return err
+	}
+
+	hashedPassword, err := hash(password)
+	if err != nil {
+		return err
+	}
+	if err = uc.AuthRepo.ResetPassword(ctx, reset, string(hashedPassword)); err != nil {
+		return err
+	}
+	recordAudit(ctx, uc.AuditRepo, domain.AuditLog{
+		ActorId:    user.ID,
+		Action:     domain.AuditPasswordReset,
+		TargetType: domain.AuditTargetUser,
+		TargetId:   auditId(user.ID),
+	})
+
+	return revokeUserSessions(ctx, uc.Tokens, user.ID, "")
+}
+
 // private function
+func (uc *AuthUsecase) getResetPassword(ctx context.Context, token string) (domain.ResetPassword, error) {
+	decodedByte, err := base64.StdEncoding.DecodeString(token)
+	if err != nil {
+		return domain.ResetPassword{}, domain.ErrorTokenNotFound
+	}
+	reset, err := uc.AuthRepo.GetResetPassword(ctx, string(decodedByte))
+	if err != nil {
+		return domain.ResetPassword{}, err
+	}
+	if reset.UsedAt != nil || time.Now().After(reset.ExpiresAt) {
+		return domain.ResetPassword{}, domain.ErrorTokenNotFound
+	}
+	return reset, nil
+}
+
+// newResetPassword return a reset password token of the user and its encoded form to send
+func newResetPassword(userId int64) (domain.ResetPassword, string) {
+	reset := domain.ResetPassword{
+		UserId:    userId,
+		Token:     uuid.New().String(),
+		ExpiresAt: time.Now().Add(resetPasswordDuration),
+		CreatedAt: time.Now(),
+	}
+	return reset, base64.StdEncoding.EncodeToString([]byte(reset.Token))
+}
+
 func verifyPassword(hashedPassword, password string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
 }
@@ -0,0 +1,390 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/RedLucky/potongin/app/delivery/api/auth"
+	"github.com/RedLucky/potongin/app/usecase"
+	"github.com/RedLucky/potongin/domain"
+	"github.com/RedLucky/potongin/domain/mocks"
+	"github.com/labstack/echo/v4"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestAuthUsecase_Authenticate(t *testing.T) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte("Potongin2021"), bcrypt.MinCost)
+	require.NoError(t, err)
+	user := domain.User{ID: 1, Email: "lucky@kryptopos.com", Password: string(hashed)}
+
+	t.Run("backing-off", func(t *testing.T) {
+		repository := new(mocks.AuthRepository)
+		tokens := new(mocks.TokenStore)
+		tokens.On("LoginBackoff", mock.Anything, "lucky@kryptopos.com", "").Return(2*time.Second, nil).Once()
+
+		uc := usecase.NewAuthUsecase(repository, new(mocks.MfaRepository), newAuditRepository(), time.Second*5, tokens, nil, newMailer())
+		_, err := uc.Authenticate(context.TODO(), "lucky@kryptopos.com", "Potongin2021")
+
+		assert.Equal(t, domain.ErrTooManyAttempts, err)
+		repository.AssertNotCalled(t, "GetUserByEmail", mock.Anything, mock.Anything)
+	})
+
+	t.Run("locked", func(t *testing.T) {
+		repository := new(mocks.AuthRepository)
+		tokens := new(mocks.TokenStore)
+		tokens.On("LoginBackoff", mock.Anything, "lucky@kryptopos.com", "").Return(time.Duration(0), nil).Once()
+		tokens.On("IsAccountLocked", mock.Anything, "lucky@kryptopos.com").Return(true, nil).Once()
+
+		uc := usecase.NewAuthUsecase(repository, new(mocks.MfaRepository), newAuditRepository(), time.Second*5, tokens, nil, newMailer())
+		_, err := uc.Authenticate(context.TODO(), "lucky@kryptopos.com", "Potongin2021")
+
+		assert.Equal(t, domain.ErrAccountLocked, err)
+		repository.AssertNotCalled(t, "GetUserByEmail", mock.Anything, mock.Anything)
+	})
+
+	t.Run("wrong-password-backs-off", func(t *testing.T) {
+		repository := new(mocks.AuthRepository)
+		repository.On("GetUserByEmail", mock.Anything, "lucky@kryptopos.com").Return(user, nil).Once()
+		tokens := new(mocks.TokenStore)
+		tokens.On("LoginBackoff", mock.Anything, "lucky@kryptopos.com", "").Return(time.Duration(0), nil).Once()
+		tokens.On("IsAccountLocked", mock.Anything, "lucky@kryptopos.com").Return(false, nil).Once()
+		tokens.On("RecordLoginFailure", mock.Anything, "lucky@kryptopos.com", "", 15*time.Minute).Return(4, 4, nil).Once()
+		// the account waits from its third failure, doubling each time
+		tokens.On("SetLoginBackoff", mock.Anything, "lucky@kryptopos.com", "", 2*time.Second, time.Duration(0)).Return(nil).Once()
+
+		uc := usecase.NewAuthUsecase(repository, new(mocks.MfaRepository), newAuditRepository(), time.Second*5, tokens, nil, newMailer())
+		_, err := uc.Authenticate(context.TODO(), "lucky@kryptopos.com", "guess")
+
+		assert.Equal(t, domain.ErrInvalidCredentials, err)
+		tokens.AssertExpectations(t)
+		tokens.AssertNotCalled(t, "LockAccount", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("locked-out-after-max-failures", func(t *testing.T) {
+		repository := new(mocks.AuthRepository)
+		repository.On("GetUserByEmail", mock.Anything, "lucky@kryptopos.com").Return(user, nil).Once()
+		tokens := new(mocks.TokenStore)
+		tokens.On("LoginBackoff", mock.Anything, "lucky@kryptopos.com", "").Return(time.Duration(0), nil).Once()
+		tokens.On("IsAccountLocked", mock.Anything, "lucky@kryptopos.com").Return(false, nil).Once()
+		tokens.On("RecordLoginFailure", mock.Anything, "lucky@kryptopos.com", "", 15*time.Minute).Return(10, 10, nil).Once()
+		tokens.On("SetLoginBackoff", mock.Anything, "lucky@kryptopos.com", "", 128*time.Second, time.Duration(0)).Return(nil).Once()
+		tokens.On("LockAccount", mock.Anything, "lucky@kryptopos.com", 30*time.Minute).Return(nil).Once()
+		var unlockToken string
+		tokens.On("SaveUnlockToken", mock.Anything, mock.AnythingOfType("string"), "lucky@kryptopos.com", 24*time.Hour).Run(func(args mock.Arguments) {
+			unlockToken = args.String(1)
+		}).Return(nil).Once()
+		sent := make(chan struct{})
+		mailer := new(mocks.Mailer)
+		mailer.On("Send", mock.Anything, mock.Anything).Run(func(mock.Arguments) { close(sent) }).Return(nil).Once()
+
+		uc := usecase.NewAuthUsecase(repository, new(mocks.MfaRepository), newAuditRepository(), time.Second*5, tokens, nil, mailer)
+		_, err := uc.Authenticate(context.TODO(), "lucky@kryptopos.com", "guess")
+
+		assert.Equal(t, domain.ErrInvalidCredentials, err)
+		tokens.AssertExpectations(t)
+		// the unlock link is mailed in the background
+		select {
+		case <-sent:
+		case <-time.After(5 * time.Second):
+			t.Fatal("the unlock link was not mailed")
+		}
+		mailer.AssertCalled(t, "Send", mock.Anything, mailWithLink("lucky@kryptopos.com", "/unlock-account", unlockToken))
+	})
+
+	// unknown email and wrong password write the same audit log, taking the same time
+	for name, known := range map[string]bool{"wrong-password-audited": true, "unknown-email-audited": false} {
+		known := known
+		t.Run(name, func(t *testing.T) {
+			repository := new(mocks.AuthRepository)
+			if known {
+				repository.On("GetUserByEmail", mock.Anything, "lucky@kryptopos.com").Return(user, nil).Once()
+			} else {
+				repository.On("GetUserByEmail", mock.Anything, "lucky@kryptopos.com").Return(domain.User{}, domain.ErrNotFound).Once()
+			}
+			tokens := new(mocks.TokenStore)
+			tokens.On("LoginBackoff", mock.Anything, "lucky@kryptopos.com", "").Return(time.Duration(0), nil).Once()
+			tokens.On("IsAccountLocked", mock.Anything, "lucky@kryptopos.com").Return(false, nil).Once()
+			tokens.On("RecordLoginFailure", mock.Anything, "lucky@kryptopos.com", "", 15*time.Minute).Return(1, 1, nil).Once()
+			tokens.On("SetLoginBackoff", mock.Anything, "lucky@kryptopos.com", "", time.Duration(0), time.Duration(0)).Return(nil).Once()
+			auditRepository := new(mocks.AuditRepository)
+			auditRepository.On("Store", mock.Anything, mock.MatchedBy(func(log *domain.AuditLog) bool {
+				return log.Action == domain.AuditLoginFailed
+			})).Return(nil).Once()
+
+			uc := usecase.NewAuthUsecase(repository, new(mocks.MfaRepository), auditRepository, time.Second*5, tokens, nil, newMailer())
+			_, err := uc.Authenticate(context.TODO(), "lucky@kryptopos.com", "guess")
+
+			assert.Equal(t, domain.ErrInvalidCredentials, err)
+			auditRepository.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAuthUsecase_VerifyMfa(t *testing.T) {
+	viper.Set(`authentication.login_protection.backoff_after`, 10)
+	viper.Set(`authentication.login_protection.max_failures`, 4)
+	t.Cleanup(func() {
+		viper.Set(`authentication.login_protection.backoff_after`, 3)
+		viper.Set(`authentication.login_protection.max_failures`, 10)
+	})
+	viper.Set(`authentication.duration_access`, 15)
+	viper.Set(`authentication.duration_refresh`, 18)
+	viper.Set(`authentication.jwt_signature_access_key`, "access-secret")
+	viper.Set(`authentication.jwt_signature_refresh_key`, "refresh-secret")
+	viper.Set(`authentication.jwt_signature_mfa_key`, "mfa-secret")
+	secret, err := auth.GenerateTotpSecret()
+	require.NoError(t, err)
+	hashed, err := bcrypt.GenerateFromPassword([]byte("Potongin2021"), bcrypt.MinCost)
+	require.NoError(t, err)
+	user := domain.User{ID: 1, Email: "lucky@kryptopos.com", Password: string(hashed)}
+
+	newUsecase := func() domain.AuthUsecase {
+		repository := new(mocks.AuthRepository)
+		repository.On("GetUserByEmail", mock.Anything, "lucky@kryptopos.com").Return(user, nil)
+		repository.On("IsVerifiedEmail", "lucky@kryptopos.com").Return(true, nil)
+		repository.On("GetUserById", mock.Anything, int64(1)).Return(user, nil)
+		mfaRepository := new(mocks.MfaRepository)
+		mfaRepository.On("GetByUserId", mock.Anything, int64(1)).Return(domain.UserMfa{UserId: 1, Secret: secret, Enabled: "Y"}, nil)
+		mfaRepository.On("UseRecoveryCode", mock.Anything, int64(1), mock.AnythingOfType("string")).Return(false, nil)
+		mfaRepository.On("UpdateLastUsedStep", mock.Anything, int64(1), mock.AnythingOfType("int64")).Return(true, nil)
+		return usecase.NewAuthUsecase(repository, mfaRepository, newAuditRepository(), time.Second*5, auth.NewMemoryTokenStore(), nil, newMailer())
+	}
+
+	t.Run("success", func(t *testing.T) {
+		uc := newUsecase()
+		challenge, err := uc.Authenticate(context.TODO(), "lucky@kryptopos.com", "Potongin2021")
+		require.NoError(t, err)
+		require.True(t, challenge.MfaRequired)
+		code, err := auth.TotpCode(secret, time.Now())
+		require.NoError(t, err)
+
+		token, err := uc.VerifyMfa(context.TODO(), challenge.MfaToken, code)
+
+		require.NoError(t, err)
+		assert.NotEmpty(t, token.AccessToken)
+	})
+
+	t.Run("wrong-codes-over-many-challenges-lock", func(t *testing.T) {
+		uc := newUsecase()
+		// a new challenge for each guess, the password step doesn't clear the failures
+		for i := 0; i < 4; i++ {
+			challenge, err := uc.Authenticate(context.TODO(), "lucky@kryptopos.com", "Potongin2021")
+			require.NoError(t, err)
+			_, err = uc.VerifyMfa(context.TODO(), challenge.MfaToken, "guess-guess")
+			require.Equal(t, domain.ErrInvalidMfaCode, err)
+		}
+
+		_, err := uc.Authenticate(context.TODO(), "lucky@kryptopos.com", "Potongin2021")
+		assert.Equal(t, domain.ErrAccountLocked, err)
+	})
+
+	t.Run("open-challenge-refused-once-locked", func(t *testing.T) {
+		uc := newUsecase()
+		open, err := uc.Authenticate(context.TODO(), "lucky@kryptopos.com", "Potongin2021")
+		require.NoError(t, err)
+		for i := 0; i < 4; i++ {
+			_, err = uc.VerifyMfa(context.TODO(), open.MfaToken, "guess-guess")
+			require.Equal(t, domain.ErrInvalidMfaCode, err)
+		}
+		code, err := auth.TotpCode(secret, time.Now())
+		require.NoError(t, err)
+
+		_, err = uc.VerifyMfa(context.TODO(), open.MfaToken, code)
+
+		assert.Equal(t, domain.ErrAccountLocked, err)
+	})
+}
+
+func TestAuthUsecase_UnlockAccountByToken(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		tokens := new(mocks.TokenStore)
+		tokens.On("ConsumeUnlockToken", mock.Anything, "unlock-token").Return("lucky@kryptopos.com", nil).Once()
+		tokens.On("UnlockAccount", mock.Anything, "lucky@kryptopos.com").Return(nil).Once()
+
+		uc := usecase.NewAuthUsecase(new(mocks.AuthRepository), new(mocks.MfaRepository), newAuditRepository(), time.Second*5, tokens, nil, newMailer())
+		err := uc.UnlockAccountByToken(context.TODO(), "unlock-token")
+
+		assert.NoError(t, err)
+		tokens.AssertExpectations(t)
+	})
+
+	t.Run("unknown-token", func(t *testing.T) {
+		tokens := new(mocks.TokenStore)
+		tokens.On("ConsumeUnlockToken", mock.Anything, "forged").Return("", domain.ErrCacheMiss).Once()
+
+		uc := usecase.NewAuthUsecase(new(mocks.AuthRepository), new(mocks.MfaRepository), newAuditRepository(), time.Second*5, tokens, nil, newMailer())
+		err := uc.UnlockAccountByToken(context.TODO(), "forged")
+
+		assert.Equal(t, domain.ErrorTokenNotFound, err)
+		tokens.AssertNotCalled(t, "UnlockAccount", mock.Anything, mock.Anything)
+	})
+}
+
+// newLoginSession store the token pair of a new session of the user, as a login does
+func newLoginSession(t *testing.T, tokens domain.TokenStore, userId int64) domain.JwtResults {
+	return storeSession(t, tokens, domain.Session{ID: "session-1", UserId: userId, CreatedAt: time.Now()})
+}
+
+// storeSession issue the token pair of the session and store both
+func storeSession(t *testing.T, tokens domain.TokenStore, session domain.Session) domain.JwtResults {
+	viper.Set(`authentication.duration_access`, 15)
+	viper.Set(`authentication.duration_refresh`, 18)
+	viper.Set(`authentication.jwt_signature_access_key`, "access-secret")
+	viper.Set(`authentication.jwt_signature_refresh_key`, "refresh-secret")
+	user := domain.User{ID: session.UserId}
+	token, err := auth.CreateToken(&user, session.ID)
+	require.NoError(t, err)
+	session.AccessUUID = token.AccessUUID
+	session.RefreshUUID = token.RefreshUUID
+	require.NoError(t, tokens.SaveTokens(context.TODO(), user, token))
+	require.NoError(t, tokens.SaveSession(context.TODO(), session))
+	return token
+}
+
+// refresh exchange the refresh token for a new pair
+func refresh(uc domain.AuthUsecase, refreshToken string) (domain.JwtResults, error) {
+	req := httptest.NewRequest(http.MethodPost, "/v1/auth/refresh", nil)
+	req.Header.Set("Authorization", "Bearer "+refreshToken)
+	return uc.GenerateNewAccessToken(echo.New().NewContext(req, httptest.NewRecorder()))
+}
+
+func TestAuthUsecase_GenerateNewAccessToken(t *testing.T) {
+	t.Run("rotation", func(t *testing.T) {
+		tokens := auth.NewMemoryTokenStore()
+		first := newLoginSession(t, tokens, 1)
+		uc := usecase.NewAuthUsecase(new(mocks.AuthRepository), new(mocks.MfaRepository), newAuditRepository(), time.Second*5, tokens, nil, newMailer())
+
+		second, err := refresh(uc, first.RefreshToken)
+
+		require.NoError(t, err)
+		assert.Equal(t, "session-1", second.SessionID)
+		assert.NotEqual(t, first.RefreshUUID, second.RefreshUUID)
+		// the access token of the previous pair is revoked with it
+		_, _, err = tokens.GetTokenSession(context.TODO(), first.AccessUUID)
+		assert.Equal(t, domain.ErrCacheMiss, err)
+		userId, sessionId, err := tokens.GetTokenSession(context.TODO(), second.AccessUUID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), userId)
+		assert.Equal(t, "session-1", sessionId)
+		session, err := tokens.GetSession(context.TODO(), "session-1")
+		require.NoError(t, err)
+		assert.Equal(t, second.RefreshUUID, session.RefreshUUID)
+		assert.Equal(t, int64(1), session.Generation)
+
+		// the new refresh token rotates again
+		_, err = refresh(uc, second.RefreshToken)
+		assert.NoError(t, err)
+	})
+
+	t.Run("replay-revokes-the-family", func(t *testing.T) {
+		tokens := auth.NewMemoryTokenStore()
+		first := newLoginSession(t, tokens, 1)
+		uc := usecase.NewAuthUsecase(new(mocks.AuthRepository), new(mocks.MfaRepository), newAuditRepository(), time.Second*5, tokens, nil, newMailer())
+		second, err := refresh(uc, first.RefreshToken)
+		require.NoError(t, err)
+
+		_, err = refresh(uc, first.RefreshToken)
+
+		assert.Equal(t, domain.ErrRefreshTokenReused, err)
+		_, err = tokens.GetSession(context.TODO(), "session-1")
+		assert.Equal(t, domain.ErrCacheMiss, err)
+		// the pair issued down the chain is revoked too
+		_, _, err = tokens.GetTokenSession(context.TODO(), second.AccessUUID)
+		assert.Equal(t, domain.ErrCacheMiss, err)
+		_, err = refresh(uc, second.RefreshToken)
+		assert.Error(t, err)
+	})
+
+	t.Run("superseded-refresh-token", func(t *testing.T) {
+		tokens := auth.NewMemoryTokenStore()
+		first := newLoginSession(t, tokens, 1)
+		// the session already moved on to another refresh token
+		require.NoError(t, tokens.SaveSession(context.TODO(), domain.Session{ID: "session-1", UserId: 1, AccessUUID: "newer-access", RefreshUUID: "newer-refresh"}))
+		uc := usecase.NewAuthUsecase(new(mocks.AuthRepository), new(mocks.MfaRepository), newAuditRepository(), time.Second*5, tokens, nil, newMailer())
+
+		_, err := refresh(uc, first.RefreshToken)
+
+		assert.Equal(t, domain.ErrRefreshTokenReused, err)
+		_, err = tokens.GetSession(context.TODO(), "session-1")
+		assert.Equal(t, domain.ErrCacheMiss, err)
+	})
+
+	t.Run("revoked-session", func(t *testing.T) {
+		tokens := auth.NewMemoryTokenStore()
+		first := newLoginSession(t, tokens, 1)
+		session, err := tokens.GetSession(context.TODO(), "session-1")
+		require.NoError(t, err)
+		require.NoError(t, tokens.DeleteSession(context.TODO(), session))
+		uc := usecase.NewAuthUsecase(new(mocks.AuthRepository), new(mocks.MfaRepository), newAuditRepository(), time.Second*5, tokens, nil, newMailer())
+
+		_, err = refresh(uc, first.RefreshToken)
+
+		assert.Equal(t, domain.ErrCacheMiss, err)
+	})
+}
+
+func TestAuthUsecase_CreateResetPassword(t *testing.T) {
+	viper.Set(`mail.link_base_url`, "https://potong.in/")
+
+	t.Run("link-mailed", func(t *testing.T) {
+		repository := new(mocks.AuthRepository)
+		repository.On("GetUserByEmail", mock.Anything, "lucky@kryptopos.com").Return(domain.User{ID: 1, Email: "lucky@kryptopos.com"}, nil).Once()
+		repository.On("CreateResetPassword", mock.Anything, mock.AnythingOfType("*domain.ResetPassword")).Return(nil).Once()
+		mailer := new(mocks.Mailer)
+		uc := usecase.NewAuthUsecase(repository, new(mocks.MfaRepository), newAuditRepository(), time.Second*5, nil, nil, mailer)
+		mailer.On("Send", mock.Anything, mock.Anything).Return(nil).Once()
+
+		token, err := uc.CreateResetPassword(context.TODO(), "lucky@kryptopos.com")
+
+		require.NoError(t, err)
+		mailer.AssertCalled(t, "Send", mock.Anything, mailWithLink("lucky@kryptopos.com", "/reset-password", token))
+	})
+
+	t.Run("unknown-email", func(t *testing.T) {
+		repository := new(mocks.AuthRepository)
+		repository.On("GetUserByEmail", mock.Anything, "nobody@kryptopos.com").Return(domain.User{}, domain.ErrNotFound).Once()
+		mailer := new(mocks.Mailer)
+
+		_, err := usecase.NewAuthUsecase(repository, new(mocks.MfaRepository), newAuditRepository(), time.Second*5, nil, nil, mailer).CreateResetPassword(context.TODO(), "nobody@kryptopos.com")
+
+		assert.NoError(t, err)
+		mailer.AssertNotCalled(t, "Send", mock.Anything, mock.Anything)
+	})
+
+	t.Run("mail-fails", func(t *testing.T) {
+		repository := new(mocks.AuthRepository)
+		repository.On("GetUserByEmail", mock.Anything, "lucky@kryptopos.com").Return(domain.User{ID: 1, Email: "lucky@kryptopos.com"}, nil).Once()
+		repository.On("CreateResetPassword", mock.Anything, mock.AnythingOfType("*domain.ResetPassword")).Return(nil).Once()
+		mailer := new(mocks.Mailer)
+		mailer.On("Send", mock.Anything, mock.Anything).Return(errors.New("connection refused")).Once()
+
+		_, err := usecase.NewAuthUsecase(repository, new(mocks.MfaRepository), newAuditRepository(), time.Second*5, nil, nil, mailer).CreateResetPassword(context.TODO(), "lucky@kryptopos.com")
+
+		assert.Equal(t, domain.ErrInternalServerError, err)
+	})
+}
+
+func TestAuthUsecase_CreateVerifyEmail(t *testing.T) {
+	viper.Set(`mail.link_base_url`, "https://potong.in/")
+
+	repository := new(mocks.AuthRepository)
+	repository.On("IsExistEmail", "lucky@kryptopos.com").Return(domain.User{ID: 1, Email: "lucky@kryptopos.com"}, nil).Once()
+	repository.On("IsVerifiedEmail", "lucky@kryptopos.com").Return(false, nil).Once()
+	repository.On("DeletePreviousVerifyEmail", int64(1)).Return(nil).Once()
+	repository.On("CreateVerifyEmail", mock.AnythingOfType("*domain.VerifyEmail")).Return(nil).Once()
+	mailer := new(mocks.Mailer)
+	mailer.On("Send", mock.Anything, mock.Anything).Return(nil).Once()
+
+	token, err := usecase.NewAuthUsecase(repository, new(mocks.MfaRepository), newAuditRepository(), time.Second*5, nil, nil, mailer).CreateVerifyEmail(context.TODO(), "lucky@kryptopos.com")
+
+	require.NoError(t, err)
+	mailer.AssertCalled(t, "Send", mock.Anything, mailWithLink("lucky@kryptopos.com", "/verify-email", token))
+}
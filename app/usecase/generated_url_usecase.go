@@ -7,48 +7,55 @@ import (
 	"time"
 
 	"github.com/RedLucky/potongin/domain"
-	"github.com/gomodule/redigo/redis"
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
+	"golang.org/x/sync/singleflight"
 )
 
 type GeneratedUrlUsecase struct {
 	GeneratedRepo  domain.GeneratedUrlRepository
+	OrgRepo        domain.OrganizationRepository
+	AuditRepo      domain.AuditRepository
+	WebhookRepo    domain.WebhookRepository
 	contextTimeout time.Duration
-	RedisPool      *redis.Pool
+	Cache          domain.Cache
+
+	// lookups coalesce the concurrent loads of a code missing in the cache
+	lookups singleflight.Group
 }
 
-type UrlCache struct {
-	GenerateUrlId int64  `redis:"generate_url_id"`
-	SourceUrl     string `redis:"source_url"`
+// the statistics of a link cover a year at most
+const maxStatsDays = 366
+
+// linkCacheKey is the key of the cached source of a generated link, an
+// empty source marks a code that is not redirected
+func linkCacheKey(generated string) string {
+	return "link:" + generated
 }
 
-func NewGeneratedUrlUsecase(repo domain.GeneratedUrlRepository, timeout time.Duration, redis *redis.Pool) domain.GeneratedUrlUsecase {
+func NewGeneratedUrlUsecase(repo domain.GeneratedUrlRepository, orgRepo domain.OrganizationRepository, auditRepo domain.AuditRepository, webhookRepo domain.WebhookRepository, timeout time.Duration, cache domain.Cache) domain.GeneratedUrlUsecase {
 	return &GeneratedUrlUsecase{
 		GeneratedRepo:  repo,
+		OrgRepo:        orgRepo,
+		AuditRepo:      auditRepo,
+		WebhookRepo:    webhookRepo,
 		contextTimeout: timeout,
-		RedisPool:      redis,
+		Cache:          cache,
 	}
 }
 
 func (gu *GeneratedUrlUsecase) CreateUrl(ctx context.Context, url *domain.GeneratedUrl) (err error) {
 	ctx, cancel := context.WithTimeout(ctx, gu.contextTimeout)
 	defer cancel()
-	// check url contains https or http
-	if !strings.Contains(url.Source, "https://") && !strings.Contains(url.Source, "http://") {
-		return domain.ErrUrlNotFound
+	// editors and above create links in an organization workspace
+	if url.OrgId != 0 {
+		if _, err = authorizeWorkspace(ctx, gu.OrgRepo, url.UserId, url.OrgId, domain.RoleEditor); err != nil {
+			return err
+		}
 	}
-	// check is url valid
-	reader := strings.NewReader(`{}`)
-	request, err := http.NewRequest("GET", url.Source, reader)
-	client := &http.Client{}
-	resp, err := client.Do(request)
-	if err != nil {
-		return domain.ErrUrlNotFound
+	if url.Source, err = checkSource(url.Source); err != nil {
+		return err
 	}
-	defer resp.Body.Close()
-
-	url.Source = strings.TrimPrefix(url.Source, "https://")
-	url.Source = strings.TrimPrefix(url.Source, "http://")
 	url.CreatedAt = time.Now()
 	url.UpdatedAt = time.Now()
 	url.StartDate = time.Now()
@@ -64,132 +71,428 @@ func (gu *GeneratedUrlUsecase) CreateUrl(ctx context.Context, url *domain.Genera
 		return domain.ErrUrlGeneratedExist
 	}
 
-	existNameByUserId, _ := gu.GeneratedRepo.CheckDoubleNameByUserId(ctx, url.Name, url.UserId)
-	if existNameByUserId {
+	if gu.isDoubleName(ctx, url) {
 		return domain.ErrNameIsExist
 	}
 
 	err = gu.GeneratedRepo.InsertUrl(ctx, url)
-
+	if err != nil {
+		return err
+	}
+	// the code may be cached as unknown
+	gu.purgeUrlCache(ctx, url.Generated)
+	recordAudit(ctx, gu.AuditRepo, domain.AuditLog{
+		ActorId:    url.UserId,
+		Action:     domain.AuditLinkCreate,
+		TargetType: domain.AuditTargetLink,
+		TargetId:   auditId(url.ID),
+		Diff:       auditDiff(nil, url),
+	})
+	enqueueWebhooks(ctx, gu.WebhookRepo, url.UserId, domain.WebhookLinkCreated, url)
 	return
 }
 
-func (gu *GeneratedUrlUsecase) UpdateUrl(ctx context.Context, url *domain.GeneratedUrl) (err error) {
+// checkSource tell if the source link answers, it is returned without its scheme
+func checkSource(source string) (string, error) {
 	// check url contains https or http
-	if !strings.Contains(url.Source, "https://") && !strings.Contains(url.Source, "http://") {
-		return domain.ErrUrlNotFound
+	if !strings.Contains(source, "https://") && !strings.Contains(source, "http://") {
+		return "", domain.ErrUrlNotFound
 	}
 	// check is url valid
-	reader := strings.NewReader(`{}`)
-	request, err := http.NewRequest("GET", url.Source, reader)
-	client := &http.Client{}
-	resp, err := client.Do(request)
+	request, err := http.NewRequest("GET", source, strings.NewReader(`{}`))
 	if err != nil {
-		return domain.ErrUrlNotFound
+		return "", domain.ErrUrlNotFound
 	}
-	defer resp.Body.Close()
+	resp, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return "", domain.ErrUrlNotFound
+	}
+	resp.Body.Close()
 
-	url.Source = strings.TrimPrefix(url.Source, "https://")
-	url.Source = strings.TrimPrefix(url.Source, "http://")
+	source = strings.TrimPrefix(source, "https://")
+	return strings.TrimPrefix(source, "http://"), nil
+}
 
-	existOriginUrl, _ := gu.GeneratedRepo.IsExistUrlOrigin(ctx, url.Source)
-	if existOriginUrl {
-		return domain.ErrUrlOriginExist
-	}
+func (gu *GeneratedUrlUsecase) UpdateUrl(ctx context.Context, userId int64, url *domain.GeneratedUrl) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, gu.contextTimeout)
+	defer cancel()
 
-	existGeneratedUrl, _ := gu.GeneratedRepo.IsExistUrlGenerated(ctx, url.Generated)
-	if existGeneratedUrl {
-		return domain.ErrUrlGeneratedExist
+	before, err := gu.authorizeUrl(ctx, userId, auditId(url.ID), domain.RoleEditor)
+	if err != nil {
+		return err
+	}
+	updated := before
+	if url.Name != "" && url.Name != before.Name {
+		updated.Name = url.Name
+		if gu.isDoubleName(ctx, &updated) {
+			return domain.ErrNameIsExist
+		}
 	}
+	if url.Source != "" {
+		if updated.Source, err = checkSource(url.Source); err != nil {
+			return err
+		}
+		if updated.Source != before.Source {
+			if existOriginUrl, _ := gu.GeneratedRepo.IsExistUrlOrigin(ctx, updated.Source); existOriginUrl {
+				return domain.ErrUrlOriginExist
+			}
+		}
+	}
+	if url.Generated != "" && url.Generated != before.Generated {
+		updated.Generated = url.Generated
+		if existGeneratedUrl, _ := gu.GeneratedRepo.IsExistUrlGenerated(ctx, updated.Generated); existGeneratedUrl {
+			return domain.ErrUrlGeneratedExist
+		}
+	}
+	updated.UpdatedAt = time.Now()
 
-	existNameByUserId, _ := gu.GeneratedRepo.CheckDoubleNameByUserId(ctx, url.Name, url.UserId)
-	if existNameByUserId {
-		return domain.ErrNameIsExist
+	if err = gu.saveUrl(ctx, before, updated); err != nil {
+		return err
 	}
+	*url = updated
+	return
+}
 
-	err = gu.GeneratedRepo.UpdateUrl(ctx, url)
-	// check on redis cache pool
-	conn := gu.RedisPool.Get()
-	defer conn.Close()
-	_, err = conn.Do("HDEL", url.Generated, "source_url")
+// SetUrlActive enable or disable the link, a disabled link is not redirected anymore
+func (gu *GeneratedUrlUsecase) SetUrlActive(ctx context.Context, userId int64, urlId string, active bool) (domain.GeneratedUrl, error) {
+	ctx, cancel := context.WithTimeout(ctx, gu.contextTimeout)
+	defer cancel()
+
+	before, err := gu.authorizeUrl(ctx, userId, urlId, domain.RoleEditor)
 	if err != nil {
-		return domain.ErrInternalServerError
+		return domain.GeneratedUrl{}, err
+	}
+	updated := before
+	updated.IsActive = "N"
+	if active {
+		updated.IsActive = "Y"
+	}
+	if updated.IsActive == before.IsActive {
+		return before, nil
+	}
+	updated.UpdatedAt = time.Now()
+
+	if err = gu.saveUrl(ctx, before, updated); err != nil {
+		return domain.GeneratedUrl{}, err
+	}
+	return updated, nil
+}
+
+// saveUrl update the link, audit and notify the change and drop the cached
+// source of its previous generated link
+func (gu *GeneratedUrlUsecase) saveUrl(ctx context.Context, before, updated domain.GeneratedUrl) error {
+	if err := gu.GeneratedRepo.UpdateUrl(ctx, &updated); err != nil {
+		return err
+	}
+	recordAudit(ctx, gu.AuditRepo, domain.AuditLog{
+		Action:     domain.AuditLinkUpdate,
+		TargetType: domain.AuditTargetLink,
+		TargetId:   auditId(updated.ID),
+		Diff:       auditDiff(before, updated),
+	})
+	enqueueWebhooks(ctx, gu.WebhookRepo, before.UserId, domain.WebhookLinkUpdated, updated)
+
+	if updated.Generated != before.Generated {
+		// the new code may be cached as unknown
+		gu.purgeUrlCache(ctx, before.Generated, updated.Generated)
+	} else {
+		gu.purgeUrlCache(ctx, before.Generated)
+	}
+	return nil
+}
+
+// purgeUrlCache drop the cached sources of the codes, the next hit check them again
+func (gu *GeneratedUrlUsecase) purgeUrlCache(ctx context.Context, generated ...string) {
+	keys := make([]string, 0, len(generated))
+	for _, code := range generated {
+		keys = append(keys, linkCacheKey(code))
+	}
+	if err := gu.Cache.Delete(ctx, keys...); err != nil {
+		logrus.Error(err)
 	}
-	return
 }
 
-func (gu *GeneratedUrlUsecase) GetUrlByUserId(ctx context.Context, userId int64) (results []domain.GeneratedUrl, err error) {
+func (gu *GeneratedUrlUsecase) GetUrlByWorkspace(ctx context.Context, userId, orgId int64) (results []domain.GeneratedUrl, err error) {
 	ctx, cancel := context.WithTimeout(ctx, gu.contextTimeout)
 	defer cancel()
 
-	results, err = gu.GeneratedRepo.GetUrlByUserId(ctx, userId)
+	if orgId == 0 {
+		results, err = gu.GeneratedRepo.GetUrlByUserId(ctx, userId)
+	} else {
+		if _, err = authorizeWorkspace(ctx, gu.OrgRepo, userId, orgId, domain.RoleViewer); err != nil {
+			return nil, err
+		}
+		results, err = gu.GeneratedRepo.GetUrlByOrgId(ctx, orgId)
+	}
 	if err != nil {
 		return nil, err
 	}
 	return
 }
 
-func (gu *GeneratedUrlUsecase) GetUrlById(ctx context.Context, urlId string) (results domain.GeneratedUrl, err error) {
+// SearchUrls page through the links of a workspace the user can read
+func (gu *GeneratedUrlUsecase) SearchUrls(ctx context.Context, userId, orgId int64, filter domain.LinkFilter) ([]domain.GeneratedUrl, int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, gu.contextTimeout)
+	defer cancel()
+
+	if filter.Limit < 0 || filter.Offset < 0 {
+		return nil, 0, domain.ErrBadParamInput
+	}
+	if orgId != 0 {
+		if _, err := authorizeWorkspace(ctx, gu.OrgRepo, userId, orgId, domain.RoleViewer); err != nil {
+			return nil, 0, err
+		}
+	}
+	return gu.GeneratedRepo.SearchUrls(ctx, userId, orgId, filter)
+}
+
+// GetUrlById return the link when it is in a workspace the user can read
+func (gu *GeneratedUrlUsecase) GetUrlById(ctx context.Context, userId int64, urlId string) (results domain.GeneratedUrl, err error) {
 	ctx, cancel := context.WithTimeout(ctx, gu.contextTimeout)
 	defer cancel()
 
-	results, err = gu.GeneratedRepo.GetUrlById(ctx, urlId)
+	return gu.authorizeUrl(ctx, userId, urlId, domain.RoleViewer)
+}
+
+// authorizeUrl find a link of a workspace of the user: a personal link of
+// the user or a link of an organization where they have at least minRole. The
+// links of the others are not found.
+func (gu *GeneratedUrlUsecase) authorizeUrl(ctx context.Context, userId int64, urlId, minRole string) (domain.GeneratedUrl, error) {
+	url, err := gu.GeneratedRepo.GetUrlById(ctx, urlId)
 	if err != nil {
+		return domain.GeneratedUrl{}, domain.ErrUrlNotFound
+	}
+	if url.OrgId == 0 {
+		if url.UserId != userId {
+			return domain.GeneratedUrl{}, domain.ErrUrlNotFound
+		}
+		return url, nil
+	}
+	if _, err = authorizeWorkspace(ctx, gu.OrgRepo, userId, url.OrgId, minRole); err == domain.ErrNotFound {
+		return domain.GeneratedUrl{}, domain.ErrUrlNotFound
+	} else if err != nil {
 		return domain.GeneratedUrl{}, err
 	}
-	return
+	return url, nil
 }
 
-func (gu *GeneratedUrlUsecase) HitUrl(ctx context.Context, generateUrl string) (results string, err error) {
+// GetUrlStats summarize the visits of the link since days ago, today included
+func (gu *GeneratedUrlUsecase) GetUrlStats(ctx context.Context, userId int64, urlId string, days int) (domain.LinkStats, error) {
 	ctx, cancel := context.WithTimeout(ctx, gu.contextTimeout)
 	defer cancel()
 
-	// check on redis cache pool
-	conn := gu.RedisPool.Get()
-	defer conn.Close()
+	if days < 1 || days > maxStatsDays {
+		return domain.LinkStats{}, domain.ErrBadParamInput
+	}
+	url, err := gu.authorizeUrl(ctx, userId, urlId, domain.RoleViewer)
+	if err != nil {
+		return domain.LinkStats{}, err
+	}
+	return gu.GeneratedRepo.GetClickStats(ctx, url.ID, statsSince(days))
+}
 
-	res, err := gu.GeneratedRepo.GetUrlFromCache(conn, generateUrl)
-	if err == redis.ErrNil {
-		existGeneratedUrl, _ := gu.GeneratedRepo.IsExistUrlGenerated(ctx, generateUrl)
-		if !existGeneratedUrl {
-			return "", domain.ErrUrlNotFound
+// GetWorkspaceStats summarize the visits of the links of a workspace the user
+// can read, since days ago, today included
+func (gu *GeneratedUrlUsecase) GetWorkspaceStats(ctx context.Context, userId, orgId int64, urlIds []int64, days int) (map[int64]domain.LinkStats, error) {
+	ctx, cancel := context.WithTimeout(ctx, gu.contextTimeout)
+	defer cancel()
+
+	if days < 1 || days > maxStatsDays {
+		return nil, domain.ErrBadParamInput
+	}
+	if orgId != 0 {
+		if _, err := authorizeWorkspace(ctx, gu.OrgRepo, userId, orgId, domain.RoleViewer); err != nil {
+			return nil, err
 		}
+	}
+	return gu.GeneratedRepo.GetWorkspaceClickStats(ctx, userId, orgId, urlIds, statsSince(days))
+}
 
-		results, err = gu.updateTotalHits(ctx, generateUrl)
+// statsSince is the UTC midnight starting the last days, today included
+func statsSince(days int) time.Time {
+	year, month, day := time.Now().UTC().Date()
+	return time.Date(year, month, day-days+1, 0, 0, 0, 0, time.UTC)
+}
 
-		err = gu.GeneratedRepo.SetUrlToCache(conn, generateUrl, results)
-		if err != nil {
-			return "", domain.ErrInternalServerError
-		}
-		// 60 is seconds
-		err = gu.GeneratedRepo.SetUrlExpCache(conn, generateUrl, viper.GetInt(`redis.exp_hit_url`))
-		if err != nil {
-			return "", domain.ErrInternalServerError
+// isDoubleName tell if the workspace of the url already has a link named the same
+func (gu *GeneratedUrlUsecase) isDoubleName(ctx context.Context, url *domain.GeneratedUrl) bool {
+	if url.OrgId != 0 {
+		exist, _ := gu.GeneratedRepo.CheckDoubleNameByOrgId(ctx, url.Name, url.OrgId)
+		return exist
+	}
+	exist, _ := gu.GeneratedRepo.CheckDoubleNameByUserId(ctx, url.Name, url.UserId)
+	return exist
+}
+
+func (gu *GeneratedUrlUsecase) HitUrl(ctx context.Context, generateUrl string) (results string, err error) {
+	ctx, cancel := context.WithTimeout(ctx, gu.contextTimeout)
+	defer cancel()
+
+	res, err := gu.Cache.Get(ctx, linkCacheKey(generateUrl))
+	if err == nil {
+		if res == "" {
+			return "", domain.ErrUrlNotFound
 		}
-		return
-	} else if err != nil {
-		return "", domain.ErrInternalServerError
-	} else {
 		results, err = gu.updateTotalHits(ctx, generateUrl)
+		if _, unavailable := err.(*domain.UnavailableLinkError); unavailable {
+			return "", err
+		}
 		return res, nil
 	}
 
+	// the redirects keep working on the database while the cache is down
+	cacheUp := err == domain.ErrCacheMiss
+	if !cacheUp {
+		logrus.WithError(err).Warn("link cache unavailable, reading the database")
+	}
+
+	// a burst of visits of an uncached code load it once, on its own deadline
+	// so the first visitor going away doesn't fail the others
+	loaded, err, _ := gu.lookups.Do(generateUrl, func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), gu.contextTimeout)
+		defer cancel()
+		return gu.loadUrl(ctx, generateUrl, cacheUp)
+	})
+	if err != nil {
+		return "", err
+	}
+	return gu.countHit(ctx, loaded.(domain.GeneratedUrl))
+}
+
+// loadUrl read the link of the code and cache its source. An unknown code is
+// cached for redis.exp_unknown_url seconds so scanning random codes spare the
+// database, a failed lookup is not cached.
+func (gu *GeneratedUrlUsecase) loadUrl(ctx context.Context, generateUrl string, cacheUp bool) (url domain.GeneratedUrl, err error) {
+	exist, err := gu.GeneratedRepo.IsExistUrlGenerated(ctx, generateUrl)
+	if err != nil {
+		return url, err
+	}
+	if !exist {
+		if cacheUp {
+			ttl := time.Duration(configInt(`redis.exp_unknown_url`, 30)) * time.Second
+			if err := gu.Cache.Set(ctx, linkCacheKey(generateUrl), "", ttl); err != nil {
+				logrus.Error(err)
+			}
+		}
+		return url, domain.ErrUrlNotFound
+	}
+
+	if url, err = gu.availableUrl(ctx, generateUrl); err != nil || !cacheUp {
+		return
+	}
+	// redis.exp_hit_url is in minutes
+	err = gu.Cache.Set(ctx, linkCacheKey(generateUrl), url.Source, time.Duration(viper.GetInt(`redis.exp_hit_url`))*time.Minute)
+	if err != nil {
+		logrus.Error(err)
+	}
+	return url, nil
 }
 
 func (gu *GeneratedUrlUsecase) updateTotalHits(ctx context.Context, generateUrl string) (results string, err error) {
+	ownerUrl, err := gu.availableUrl(ctx, generateUrl)
+	if err != nil {
+		return "", err
+	}
+	return gu.countHit(ctx, ownerUrl)
+}
+
+// availableUrl return the link of the code unless it is taken down or its
+// owner suspended
+func (gu *GeneratedUrlUsecase) availableUrl(ctx context.Context, generateUrl string) (ownerUrl domain.GeneratedUrl, err error) {
 	// check url punya siapa
-	ownerUrl, err := gu.GeneratedRepo.GetUrlByUrl(ctx, generateUrl)
+	ownerUrl, err = gu.GeneratedRepo.GetUrlByUrl(ctx, generateUrl)
 	if err != nil {
-		return "", domain.ErrUrlNotFound
+		return ownerUrl, domain.ErrUrlNotFound
+	}
+	if ownerUrl.TakenDownAt != nil {
+		return ownerUrl, &domain.UnavailableLinkError{Reason: ownerUrl.TakedownReason}
 	}
+	suspended, err := gu.GeneratedRepo.IsOwnerSuspended(ctx, ownerUrl.UserId)
+	if err != nil {
+		return ownerUrl, domain.ErrInternalServerError
+	}
+	if suspended {
+		return ownerUrl, &domain.UnavailableLinkError{Reason: "the owner of this link is suspended"}
+	}
+	return ownerUrl, nil
+}
 
+// countHit record the visit of the link, once per visit
+func (gu *GeneratedUrlUsecase) countHit(ctx context.Context, ownerUrl domain.GeneratedUrl) (results string, err error) {
 	// update total hit nya
 	ownerUrl.TotalHits++
 	results = ownerUrl.Source
 
-	err = gu.GeneratedRepo.HitUrl(ctx, ownerUrl.ID, ownerUrl.TotalHits)
+	err = gu.GeneratedRepo.HitUrl(ctx, ownerUrl.ID)
 	if err != nil {
 		return "", domain.ErrUrlGeneratedExist
 	}
-	return
+
+	// the analytics must never break the redirect
+	client := domain.ClientInfoFromContext(ctx)
+	click := domain.ClickEvent{
+		UrlId:      ownerUrl.ID,
+		IP:         client.IP,
+		UserAgent:  truncate(client.UserAgent, 255),
+		Device:     truncate(client.Device, 64),
+		Referer:    truncate(client.Referer, 255),
+		Anonymized: "N",
+		CreatedAt:  time.Now(),
+	}
+	if err = gu.GeneratedRepo.InsertClickEvent(ctx, &click); err != nil {
+		logrus.Error(err)
+	}
+	// the ip of the visitor is not shared with the webhooks
+	enqueueWebhooks(ctx, gu.WebhookRepo, ownerUrl.UserId, domain.WebhookLinkClicked, map[string]interface{}{
+		"link_id":        ownerUrl.ID,
+		"generated_link": ownerUrl.Generated,
+		"total_hits":     ownerUrl.TotalHits,
+		"device":         click.Device,
+		"referer":        click.Referer,
+		"clicked_at":     click.CreatedAt,
+	})
+	return results, nil
+}
+
+// ExpireUrls deactivate the links past their end date and notify their owners
+func (gu *GeneratedUrlUsecase) ExpireUrls(c context.Context) (int, error) {
+	ctx, cancel := context.WithTimeout(c, gu.contextTimeout)
+	defer cancel()
+
+	// the links deactivated before a failure are notified all the same
+	expired, err := gu.GeneratedRepo.ExpireUrls(ctx, time.Now())
+	if len(expired) == 0 {
+		return 0, err
+	}
+
+	for _, url := range expired {
+		gu.purgeUrlCache(ctx, url.Generated)
+		enqueueWebhooks(ctx, gu.WebhookRepo, url.UserId, domain.WebhookLinkExpired, url)
+	}
+	return len(expired), err
+}
+
+// StartUrlExpiry run the expiry of the links every interval until stop is closed
+func StartUrlExpiry(uc domain.GeneratedUrlUsecase, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := uc.ExpireUrls(context.Background()); err != nil {
+					logrus.Error(err)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+func truncate(value string, length int) string {
+	if len(value) > length {
+		return value[:length]
+	}
+	return value
 }
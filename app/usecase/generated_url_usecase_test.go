@@ -0,0 +1,371 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/RedLucky/potongin/app/usecase"
+	"github.com/RedLucky/potongin/domain"
+	"github.com/RedLucky/potongin/domain/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// newGeneratedUrlUsecase use a cache whose entries can always be dropped
+func newGeneratedUrlUsecase(repository *mocks.GeneratedUrlRepository, orgRepository *mocks.OrganizationRepository) domain.GeneratedUrlUsecase {
+	cache := new(mocks.Cache)
+	cache.On("Delete", mock.Anything, mock.Anything).Return(nil).Maybe()
+	return newGeneratedUrlUsecaseWithCache(repository, orgRepository, cache)
+}
+
+func newGeneratedUrlUsecaseWithCache(repository *mocks.GeneratedUrlRepository, orgRepository *mocks.OrganizationRepository, cache domain.Cache) domain.GeneratedUrlUsecase {
+	webhookRepository := new(mocks.WebhookRepository)
+	webhookRepository.On("FetchSubscribed", mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+	return usecase.NewGeneratedUrlUsecase(repository, orgRepository, newAuditRepository(), webhookRepository, time.Second*5, cache)
+}
+
+func TestGeneratedUrlUsecase_SetUrlActive(t *testing.T) {
+	personal := domain.GeneratedUrl{ID: 5, UserId: 1, Name: "docs", Generated: "docs", IsActive: "Y"}
+	shared := domain.GeneratedUrl{ID: 6, UserId: 1, OrgId: 10, Name: "blog", Generated: "blog", IsActive: "Y"}
+
+	t.Run("owner-disables", func(t *testing.T) {
+		repository := new(mocks.GeneratedUrlRepository)
+		repository.On("GetUrlById", mock.Anything, "5").Return(personal, nil).Once()
+		repository.On("UpdateUrl", mock.Anything, mock.MatchedBy(func(url *domain.GeneratedUrl) bool {
+			return url.ID == 5 && url.IsActive == "N"
+		})).Return(nil).Once()
+
+		url, err := newGeneratedUrlUsecase(repository, new(mocks.OrganizationRepository)).SetUrlActive(context.TODO(), 1, "5", false)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "N", url.IsActive)
+		repository.AssertExpectations(t)
+	})
+
+	t.Run("already-active", func(t *testing.T) {
+		repository := new(mocks.GeneratedUrlRepository)
+		repository.On("GetUrlById", mock.Anything, "5").Return(personal, nil).Once()
+
+		url, err := newGeneratedUrlUsecase(repository, new(mocks.OrganizationRepository)).SetUrlActive(context.TODO(), 1, "5", true)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Y", url.IsActive)
+		repository.AssertNotCalled(t, "UpdateUrl", mock.Anything, mock.Anything)
+	})
+
+	t.Run("link-of-another-user", func(t *testing.T) {
+		repository := new(mocks.GeneratedUrlRepository)
+		repository.On("GetUrlById", mock.Anything, "5").Return(personal, nil).Once()
+
+		_, err := newGeneratedUrlUsecase(repository, new(mocks.OrganizationRepository)).SetUrlActive(context.TODO(), 2, "5", false)
+
+		assert.Equal(t, domain.ErrUrlNotFound, err)
+		repository.AssertExpectations(t)
+	})
+
+	t.Run("viewer-can-not-disable", func(t *testing.T) {
+		repository := new(mocks.GeneratedUrlRepository)
+		repository.On("GetUrlById", mock.Anything, "6").Return(shared, nil).Once()
+		orgRepository := new(mocks.OrganizationRepository)
+		orgRepository.On("GetMember", mock.Anything, int64(10), int64(2)).Return(member(10, 2, domain.RoleViewer), nil).Once()
+
+		_, err := newGeneratedUrlUsecase(repository, orgRepository).SetUrlActive(context.TODO(), 2, "6", false)
+
+		assert.Equal(t, domain.ErrForbidden, err)
+		repository.AssertExpectations(t)
+		orgRepository.AssertExpectations(t)
+	})
+}
+
+func TestGeneratedUrlUsecase_UpdateUrl(t *testing.T) {
+	current := domain.GeneratedUrl{ID: 5, UserId: 1, Name: "docs", Source: "example.com", Generated: "docs", IsActive: "Y"}
+
+	t.Run("rename", func(t *testing.T) {
+		repository := new(mocks.GeneratedUrlRepository)
+		repository.On("GetUrlById", mock.Anything, "5").Return(current, nil).Once()
+		repository.On("CheckDoubleNameByUserId", mock.Anything, "guides", int64(1)).Return(false, nil).Once()
+		repository.On("UpdateUrl", mock.Anything, mock.MatchedBy(func(url *domain.GeneratedUrl) bool {
+			return url.Name == "guides" && url.Source == "example.com" && url.Generated == "docs"
+		})).Return(nil).Once()
+
+		url := domain.GeneratedUrl{ID: 5, Name: "guides"}
+		err := newGeneratedUrlUsecase(repository, new(mocks.OrganizationRepository)).UpdateUrl(context.TODO(), 1, &url)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "guides", url.Name)
+		assert.Equal(t, "docs", url.Generated)
+		repository.AssertExpectations(t)
+	})
+
+	t.Run("name-exist", func(t *testing.T) {
+		repository := new(mocks.GeneratedUrlRepository)
+		repository.On("GetUrlById", mock.Anything, "5").Return(current, nil).Once()
+		repository.On("CheckDoubleNameByUserId", mock.Anything, "blog", int64(1)).Return(true, nil).Once()
+
+		url := domain.GeneratedUrl{ID: 5, Name: "blog"}
+		err := newGeneratedUrlUsecase(repository, new(mocks.OrganizationRepository)).UpdateUrl(context.TODO(), 1, &url)
+
+		assert.Equal(t, domain.ErrNameIsExist, err)
+		repository.AssertNotCalled(t, "UpdateUrl", mock.Anything, mock.Anything)
+	})
+
+	t.Run("generated-exist", func(t *testing.T) {
+		repository := new(mocks.GeneratedUrlRepository)
+		repository.On("GetUrlById", mock.Anything, "5").Return(current, nil).Once()
+		repository.On("IsExistUrlGenerated", mock.Anything, "blog").Return(true, nil).Once()
+
+		url := domain.GeneratedUrl{ID: 5, Generated: "blog"}
+		err := newGeneratedUrlUsecase(repository, new(mocks.OrganizationRepository)).UpdateUrl(context.TODO(), 1, &url)
+
+		assert.Equal(t, domain.ErrUrlGeneratedExist, err)
+		repository.AssertNotCalled(t, "UpdateUrl", mock.Anything, mock.Anything)
+	})
+}
+
+func TestGeneratedUrlUsecase_GetUrlStats(t *testing.T) {
+	repository := new(mocks.GeneratedUrlRepository)
+	repository.On("GetUrlById", mock.Anything, "5").Return(domain.GeneratedUrl{ID: 5, UserId: 1}, nil).Once()
+	year, month, day := time.Now().UTC().Date()
+	since := time.Date(year, month, day-6, 0, 0, 0, 0, time.UTC)
+	stats := domain.LinkStats{Since: since, TotalClicks: 3, Devices: []domain.ClickCount{{Key: "iPhone", Clicks: 3}}}
+	repository.On("GetClickStats", mock.Anything, int64(5), since).Return(stats, nil).Once()
+	uc := newGeneratedUrlUsecase(repository, new(mocks.OrganizationRepository))
+
+	result, err := uc.GetUrlStats(context.TODO(), 1, "5", 7)
+	assert.NoError(t, err)
+	assert.Equal(t, stats, result)
+	repository.AssertExpectations(t)
+
+	_, err = uc.GetUrlStats(context.TODO(), 1, "5", 0)
+	assert.Equal(t, domain.ErrBadParamInput, err)
+}
+
+func TestGeneratedUrlUsecase_SearchUrls(t *testing.T) {
+	filter := domain.LinkFilter{Search: "docs", Limit: 20, Offset: 40}
+
+	t.Run("organization-viewer", func(t *testing.T) {
+		repository := new(mocks.GeneratedUrlRepository)
+		repository.On("SearchUrls", mock.Anything, int64(2), int64(10), filter).Return([]domain.GeneratedUrl{{ID: 6, OrgId: 10}}, int64(41), nil).Once()
+		orgRepository := new(mocks.OrganizationRepository)
+		orgRepository.On("GetMember", mock.Anything, int64(10), int64(2)).Return(member(10, 2, domain.RoleViewer), nil).Once()
+
+		urls, total, err := newGeneratedUrlUsecase(repository, orgRepository).SearchUrls(context.TODO(), 2, 10, filter)
+
+		assert.NoError(t, err)
+		assert.Len(t, urls, 1)
+		assert.Equal(t, int64(41), total)
+		repository.AssertExpectations(t)
+	})
+
+	t.Run("outsider", func(t *testing.T) {
+		repository := new(mocks.GeneratedUrlRepository)
+		orgRepository := new(mocks.OrganizationRepository)
+		orgRepository.On("GetMember", mock.Anything, int64(10), int64(3)).Return(domain.OrganizationMember{}, domain.ErrForbidden).Once()
+
+		_, _, err := newGeneratedUrlUsecase(repository, orgRepository).SearchUrls(context.TODO(), 3, 10, filter)
+
+		assert.Equal(t, domain.ErrForbidden, err)
+		repository.AssertNotCalled(t, "SearchUrls", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("negative-offset", func(t *testing.T) {
+		repository := new(mocks.GeneratedUrlRepository)
+
+		_, _, err := newGeneratedUrlUsecase(repository, new(mocks.OrganizationRepository)).SearchUrls(context.TODO(), 1, 0, domain.LinkFilter{Limit: 20, Offset: -1})
+
+		assert.Equal(t, domain.ErrBadParamInput, err)
+		repository.AssertNotCalled(t, "SearchUrls", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestGeneratedUrlUsecase_GetWorkspaceStats(t *testing.T) {
+	repository := new(mocks.GeneratedUrlRepository)
+	year, month, day := time.Now().UTC().Date()
+	since := time.Date(year, month, day-6, 0, 0, 0, 0, time.UTC)
+	stats := map[int64]domain.LinkStats{5: {Since: since, TotalClicks: 3}, 6: {Since: since}}
+	repository.On("GetWorkspaceClickStats", mock.Anything, int64(1), int64(0), []int64{5, 6}, since).Return(stats, nil).Once()
+	uc := newGeneratedUrlUsecase(repository, new(mocks.OrganizationRepository))
+
+	result, err := uc.GetWorkspaceStats(context.TODO(), 1, 0, []int64{5, 6}, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, stats, result)
+	repository.AssertExpectations(t)
+
+	_, err = uc.GetWorkspaceStats(context.TODO(), 1, 0, []int64{5, 6}, 367)
+	assert.Equal(t, domain.ErrBadParamInput, err)
+}
+
+func TestGeneratedUrlUsecase_HitUrl(t *testing.T) {
+	docs := domain.GeneratedUrl{ID: 5, UserId: 1, Source: "example.com/docs", Generated: "docs", IsActive: "Y", TotalHits: 2}
+	hit := func(repository *mocks.GeneratedUrlRepository) {
+		repository.On("GetUrlByUrl", mock.Anything, "docs").Return(docs, nil).Once()
+		repository.On("IsOwnerSuspended", mock.Anything, int64(1)).Return(false, nil).Once()
+		repository.On("HitUrl", mock.Anything, int64(5)).Return(nil).Once()
+		repository.On("InsertClickEvent", mock.Anything, mock.Anything).Return(nil).Once()
+	}
+
+	t.Run("cached", func(t *testing.T) {
+		repository := new(mocks.GeneratedUrlRepository)
+		hit(repository)
+		cache := new(mocks.Cache)
+		cache.On("Get", mock.Anything, "link:docs").Return("example.com/docs", nil).Once()
+
+		source, err := newGeneratedUrlUsecaseWithCache(repository, new(mocks.OrganizationRepository), cache).HitUrl(context.TODO(), "docs")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "example.com/docs", source)
+		repository.AssertNotCalled(t, "IsExistUrlGenerated", mock.Anything, mock.Anything)
+		cache.AssertExpectations(t)
+	})
+
+	t.Run("miss-is-cached", func(t *testing.T) {
+		repository := new(mocks.GeneratedUrlRepository)
+		repository.On("IsExistUrlGenerated", mock.Anything, "docs").Return(true, nil).Once()
+		hit(repository)
+		cache := new(mocks.Cache)
+		cache.On("Get", mock.Anything, "link:docs").Return("", domain.ErrCacheMiss).Once()
+		cache.On("Set", mock.Anything, "link:docs", "example.com/docs", mock.AnythingOfType("time.Duration")).Return(nil).Once()
+
+		source, err := newGeneratedUrlUsecaseWithCache(repository, new(mocks.OrganizationRepository), cache).HitUrl(context.TODO(), "docs")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "example.com/docs", source)
+		repository.AssertExpectations(t)
+		cache.AssertExpectations(t)
+	})
+
+	t.Run("unknown-is-cached", func(t *testing.T) {
+		repository := new(mocks.GeneratedUrlRepository)
+		repository.On("IsExistUrlGenerated", mock.Anything, "blog").Return(false, nil).Once()
+		cache := new(mocks.Cache)
+		cache.On("Get", mock.Anything, "link:blog").Return("", domain.ErrCacheMiss).Once()
+		cache.On("Set", mock.Anything, "link:blog", "", 30*time.Second).Return(nil).Once()
+
+		_, err := newGeneratedUrlUsecaseWithCache(repository, new(mocks.OrganizationRepository), cache).HitUrl(context.TODO(), "blog")
+
+		assert.Equal(t, domain.ErrUrlNotFound, err)
+		repository.AssertExpectations(t)
+		cache.AssertExpectations(t)
+	})
+
+	t.Run("cached-unknown", func(t *testing.T) {
+		repository := new(mocks.GeneratedUrlRepository)
+		cache := new(mocks.Cache)
+		cache.On("Get", mock.Anything, "link:blog").Return("", nil).Once()
+
+		_, err := newGeneratedUrlUsecaseWithCache(repository, new(mocks.OrganizationRepository), cache).HitUrl(context.TODO(), "blog")
+
+		assert.Equal(t, domain.ErrUrlNotFound, err)
+		repository.AssertNotCalled(t, "IsExistUrlGenerated", mock.Anything, mock.Anything)
+	})
+
+	t.Run("concurrent-misses-coalesced", func(t *testing.T) {
+		repository := new(mocks.GeneratedUrlRepository)
+		release := make(chan struct{})
+		repository.On("IsExistUrlGenerated", mock.Anything, "blog").Return(false, nil).Once().Run(func(mock.Arguments) { <-release })
+		cache := new(mocks.Cache)
+		cache.On("Get", mock.Anything, "link:blog").Return("", domain.ErrCacheMiss)
+		cache.On("Set", mock.Anything, "link:blog", "", mock.Anything).Return(nil).Once()
+		uc := newGeneratedUrlUsecaseWithCache(repository, new(mocks.OrganizationRepository), cache)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := uc.HitUrl(context.TODO(), "blog")
+				assert.Equal(t, domain.ErrUrlNotFound, err)
+			}()
+		}
+		// let the visits queue behind the first lookup
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		repository.AssertNumberOfCalls(t, "IsExistUrlGenerated", 1)
+	})
+
+	t.Run("concurrent-misses-load-once", func(t *testing.T) {
+		repository := new(mocks.GeneratedUrlRepository)
+		release := make(chan struct{})
+		repository.On("IsExistUrlGenerated", mock.Anything, "docs").Return(true, nil).Once().Run(func(mock.Arguments) { <-release })
+		repository.On("GetUrlByUrl", mock.Anything, "docs").Return(docs, nil).Once()
+		repository.On("IsOwnerSuspended", mock.Anything, int64(1)).Return(false, nil).Once()
+		repository.On("HitUrl", mock.Anything, int64(5)).Return(nil).Times(5)
+		repository.On("InsertClickEvent", mock.Anything, mock.Anything).Return(nil).Times(5)
+		cache := new(mocks.Cache)
+		cache.On("Get", mock.Anything, "link:docs").Return("", domain.ErrCacheMiss)
+		cache.On("Set", mock.Anything, "link:docs", "example.com/docs", mock.Anything).Return(nil).Once()
+		uc := newGeneratedUrlUsecaseWithCache(repository, new(mocks.OrganizationRepository), cache)
+
+		// the first visitor leaves before the load is done
+		first, cancel := context.WithCancel(context.TODO())
+		var wg sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			ctx := context.TODO()
+			if i == 0 {
+				ctx = first
+			}
+			wg.Add(1)
+			go func(ctx context.Context, waiting bool) {
+				defer wg.Done()
+				source, err := uc.HitUrl(ctx, "docs")
+				if waiting {
+					assert.NoError(t, err)
+					assert.Equal(t, "example.com/docs", source)
+				}
+			}(ctx, i > 0)
+		}
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+		close(release)
+		wg.Wait()
+
+		repository.AssertExpectations(t)
+		cache.AssertExpectations(t)
+	})
+
+	t.Run("lookup-fails", func(t *testing.T) {
+		repository := new(mocks.GeneratedUrlRepository)
+		repository.On("IsExistUrlGenerated", mock.Anything, "blog").Return(false, errors.New("database unavailable")).Once()
+		cache := new(mocks.Cache)
+		cache.On("Get", mock.Anything, "link:blog").Return("", domain.ErrCacheMiss).Once()
+
+		_, err := newGeneratedUrlUsecaseWithCache(repository, new(mocks.OrganizationRepository), cache).HitUrl(context.TODO(), "blog")
+
+		assert.EqualError(t, err, "database unavailable")
+		cache.AssertNotCalled(t, "Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("cache-down", func(t *testing.T) {
+		repository := new(mocks.GeneratedUrlRepository)
+		repository.On("IsExistUrlGenerated", mock.Anything, "docs").Return(true, nil).Once()
+		hit(repository)
+		cache := new(mocks.Cache)
+		cache.On("Get", mock.Anything, "link:docs").Return("", errors.New("redis unavailable")).Once()
+
+		source, err := newGeneratedUrlUsecaseWithCache(repository, new(mocks.OrganizationRepository), cache).HitUrl(context.TODO(), "docs")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "example.com/docs", source)
+		repository.AssertExpectations(t)
+		cache.AssertNotCalled(t, "Set", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("cache-set-fails", func(t *testing.T) {
+		repository := new(mocks.GeneratedUrlRepository)
+		repository.On("IsExistUrlGenerated", mock.Anything, "docs").Return(true, nil).Once()
+		hit(repository)
+		cache := new(mocks.Cache)
+		cache.On("Get", mock.Anything, "link:docs").Return("", domain.ErrCacheMiss).Once()
+		cache.On("Set", mock.Anything, "link:docs", "example.com/docs", mock.Anything).Return(errors.New("redis unavailable")).Once()
+
+		source, err := newGeneratedUrlUsecaseWithCache(repository, new(mocks.OrganizationRepository), cache).HitUrl(context.TODO(), "docs")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "example.com/docs", source)
+	})
+}
@@ -0,0 +1,117 @@
+package usecase
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/RedLucky/potongin/domain"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// loginProtection hold the brute-force protection settings of
+// `authentication.login_protection`, with safe defaults for missing keys
+type loginProtection struct {
+	window          time.Duration
+	backoffAfter    int
+	maxBackoff      time.Duration
+	maxFailures     int
+	ipMaxFailures   int
+	lockoutDuration time.Duration
+	unlockDuration  time.Duration
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     []byte
+)
+
+func newLoginProtection() loginProtection {
+	return loginProtection{
+		window:          time.Duration(configInt(`authentication.login_protection.window`, 15)) * time.Minute,
+		backoffAfter:    configInt(`authentication.login_protection.backoff_after`, 3),
+		maxBackoff:      time.Duration(configInt(`authentication.login_protection.max_backoff`, 300)) * time.Second,
+		maxFailures:     configInt(`authentication.login_protection.max_failures`, 10),
+		ipMaxFailures:   configInt(`authentication.login_protection.ip_max_failures`, 50),
+		lockoutDuration: time.Duration(configInt(`authentication.login_protection.lockout_duration`, 30)) * time.Minute,
+		unlockDuration:  time.Duration(configInt(`authentication.login_protection.unlock_duration`, 24)) * time.Hour,
+	}
+}
+
+// backoff return the delay imposed after the given number of failures,
+// doubling from one second once backoffAfter failures are reached
+func (p loginProtection) backoff(failures, after int) time.Duration {
+	if failures < after {
+		return 0
+	}
+	wait := time.Second
+	for i := after; i < failures && wait < p.maxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > p.maxBackoff {
+		wait = p.maxBackoff
+	}
+	return wait
+}
+
+// loginFailed count the failure, apply the backoff and lock the account once
+// it reached the maximum failures, returning then the token unlocking it
+func (p loginProtection) loginFailed(ctx context.Context, tokens domain.TokenStore, email, ip string) (unlockToken string) {
+	accountFailures, ipFailures, err := tokens.RecordLoginFailure(ctx, email, ip, p.window)
+	if err != nil {
+		logrus.Error(err)
+		return ""
+	}
+
+	// the ip limit is much higher, many users may share one address
+	ipBackoffAfter := p.ipMaxFailures / 2
+	err = tokens.SetLoginBackoff(ctx, email, ip, p.backoff(accountFailures, p.backoffAfter), p.backoff(ipFailures, ipBackoffAfter))
+	if err != nil {
+		logrus.Error(err)
+	}
+
+	if accountFailures < p.maxFailures {
+		return ""
+	}
+	if err = tokens.LockAccount(ctx, email, p.lockoutDuration); err != nil {
+		logrus.Error(err)
+		return ""
+	}
+	unlockToken = uuid.New().String()
+	if err = tokens.SaveUnlockToken(ctx, unlockToken, email, p.unlockDuration); err != nil {
+		logrus.Error(err)
+		return ""
+	}
+	logrus.WithFields(logrus.Fields{
+		"email":    email,
+		"ip":       ip,
+		"failures": accountFailures,
+	}).Warn("account locked after too many failed logins")
+	return unlockToken
+}
+
+// compareDummyPassword spend the same time as a real password check, so an
+// unknown email can't be told apart from a wrong password
+func compareDummyPassword(password string) {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte(uuid.New().String()), bcrypt.DefaultCost)
+	})
+	bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+}
+
+func configInt(key string, defaultValue int) int {
+	if !viper.IsSet(key) {
+		return defaultValue
+	}
+	return viper.GetInt(key)
+}
+
+func configBool(key string, defaultValue bool) bool {
+	if !viper.IsSet(key) {
+		return defaultValue
+	}
+	return viper.GetBool(key)
+}
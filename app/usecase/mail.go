@@ -0,0 +1,41 @@
+package usecase
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/RedLucky/potongin/domain"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// the pages of the front end opening the links sent by email, they post the
+// token of the link to the API
+const (
+	verifyEmailPage   = "/verify-email"
+	resetPasswordPage = "/reset-password"
+	unlockAccountPage = "/unlock-account"
+	confirmEmailPage  = "/confirm-email"
+	// accepting an invitation needs the invited user logged in
+	acceptInvitationPage = "/accept-invitation"
+)
+
+// mailLink is the link of the page carrying the token
+func mailLink(page, token string) string {
+	return strings.TrimRight(viper.GetString(`mail.link_base_url`), "/") + page + "?token=" + url.QueryEscape(token)
+}
+
+// sendLink mail the text followed by the link of the page carrying the token
+func sendLink(ctx context.Context, mailer domain.Mailer, to, subject, text, page, token string) error {
+	err := mailer.Send(ctx, domain.Mail{
+		To:      to,
+		Subject: viper.GetString(`server.application_name`) + ": " + subject,
+		Body:    text + "\n\n" + mailLink(page, token) + "\n",
+	})
+	if err != nil {
+		logrus.WithError(err).WithField("subject", subject).Error("failed to send a mail")
+		return domain.ErrInternalServerError
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package usecase_test
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/RedLucky/potongin/domain"
+	"github.com/RedLucky/potongin/domain/mocks"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/mock"
+)
+
+// newMailer accept every mail
+func newMailer() *mocks.Mailer {
+	mailer := new(mocks.Mailer)
+	mailer.On("Send", mock.Anything, mock.AnythingOfType("domain.Mail")).Return(nil)
+	return mailer
+}
+
+// mailWithLink match the mail to the address carrying the link of the page with the token
+func mailWithLink(to, page, token string) interface{} {
+	link := strings.TrimRight(viper.GetString(`mail.link_base_url`), "/") + page + "?token=" + url.QueryEscape(token)
+	return mock.MatchedBy(func(mail domain.Mail) bool {
+		return mail.To == to && strings.Contains(mail.Body, link)
+	})
+}
@@ -0,0 +1,162 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/RedLucky/potongin/app/delivery/api/auth"
+	"github.com/RedLucky/potongin/domain"
+	"github.com/skip2/go-qrcode"
+	"github.com/spf13/viper"
+)
+
+const recoveryCodeCount = 10
+
+type MfaUsecase struct {
+	MfaRepo        domain.MfaRepository
+	UserRepo       domain.UserRepository
+	contextTimeout time.Duration
+}
+
+// NewMfaUsecase will create new an MfaUsecase object representation of domain.MfaUsecase interface
+func NewMfaUsecase(repo domain.MfaRepository, userRepo domain.UserRepository, timeout time.Duration) domain.MfaUsecase {
+	return &MfaUsecase{
+		MfaRepo:        repo,
+		UserRepo:       userRepo,
+		contextTimeout: timeout,
+	}
+}
+
+// Enroll generate a new secret, it is only enforced once confirmed
+func (uc *MfaUsecase) Enroll(c context.Context, userId int64) (enrollment domain.MfaEnrollment, err error) {
+	ctx, cancel := context.WithTimeout(c, uc.contextTimeout)
+	defer cancel()
+
+	existing, err := uc.MfaRepo.GetByUserId(ctx, userId)
+	if err != nil && err != domain.ErrNotFound {
+		return domain.MfaEnrollment{}, err
+	}
+	if existing.Enabled == "Y" {
+		return domain.MfaEnrollment{}, domain.ErrMfaAlreadyEnabled
+	}
+
+	user, err := uc.UserRepo.GetByID(userId)
+	if err != nil {
+		return domain.MfaEnrollment{}, domain.ErrNotFound
+	}
+
+	secret, err := auth.GenerateTotpSecret()
+	if err != nil {
+		return domain.MfaEnrollment{}, domain.ErrInternalServerError
+	}
+	err = uc.MfaRepo.Store(ctx, &domain.UserMfa{
+		UserId:    userId,
+		Secret:    secret,
+		Enabled:   "N",
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return domain.MfaEnrollment{}, err
+	}
+
+	enrollment.Secret = secret
+	enrollment.OtpauthURI = auth.TotpURI(viper.GetString(`server.application_name`), user.Email, secret)
+	png, err := qrcode.Encode(enrollment.OtpauthURI, qrcode.Medium, 256)
+	if err != nil {
+		return domain.MfaEnrollment{}, domain.ErrInternalServerError
+	}
+	enrollment.QRCode = "data:image/png;base64," + base64.StdEncoding.EncodeToString(png)
+	return
+}
+
+// Confirm enable the second factor with a first valid code and return the recovery codes
+func (uc *MfaUsecase) Confirm(c context.Context, userId int64, code string) (recoveryCodes []string, err error) {
+	ctx, cancel := context.WithTimeout(c, uc.contextTimeout)
+	defer cancel()
+
+	mfa, err := uc.MfaRepo.GetByUserId(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if mfa.Enabled == "Y" {
+		return nil, domain.ErrMfaAlreadyEnabled
+	}
+
+	step, ok := auth.ValidateTotp(mfa.Secret, code, time.Now())
+	if !ok {
+		return nil, domain.ErrInvalidMfaCode
+	}
+	if err = uc.MfaRepo.Enable(ctx, userId, step); err != nil {
+		return nil, err
+	}
+
+	recoveryCodes, hashed, err := generateRecoveryCodes(userId)
+	if err != nil {
+		return nil, domain.ErrInternalServerError
+	}
+	if err = uc.MfaRepo.ReplaceRecoveryCodes(ctx, userId, hashed); err != nil {
+		return nil, err
+	}
+	return
+}
+
+// Disable remove the second factor, it require a valid code or recovery code
+func (uc *MfaUsecase) Disable(c context.Context, userId int64, code string) (err error) {
+	ctx, cancel := context.WithTimeout(c, uc.contextTimeout)
+	defer cancel()
+
+	mfa, err := uc.MfaRepo.GetByUserId(ctx, userId)
+	if err != nil {
+		return err
+	}
+	ok, err := checkMfaCode(ctx, uc.MfaRepo, mfa, code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return domain.ErrInvalidMfaCode
+	}
+	return uc.MfaRepo.Delete(ctx, userId)
+}
+
+// private function
+
+// checkMfaCode accept either a TOTP code not used before or an unused recovery code
+func checkMfaCode(ctx context.Context, repo domain.MfaRepository, mfa domain.UserMfa, code string) (bool, error) {
+	if step, ok := auth.ValidateTotp(mfa.Secret, strings.TrimSpace(code), time.Now()); ok {
+		return repo.UpdateLastUsedStep(ctx, mfa.UserId, step)
+	}
+	return repo.UseRecoveryCode(ctx, mfa.UserId, hashRecoveryCode(code))
+}
+
+func generateRecoveryCodes(userId int64) (codes []string, hashed []domain.MfaRecoveryCode, err error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	for i := 0; i < recoveryCodeCount; i++ {
+		random := make([]byte, 7)
+		if _, err = rand.Read(random); err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(encoding.EncodeToString(random))[:10]
+		code = code[:5] + "-" + code[5:]
+		codes = append(codes, code)
+		hashed = append(hashed, domain.MfaRecoveryCode{
+			UserId:    userId,
+			CodeHash:  hashRecoveryCode(code),
+			Used:      "N",
+			CreatedAt: time.Now(),
+		})
+	}
+	return
+}
+
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,211 @@
+package usecase_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RedLucky/potongin/app/delivery/api/auth"
+	"github.com/RedLucky/potongin/app/usecase"
+	"github.com/RedLucky/potongin/domain"
+	"github.com/RedLucky/potongin/domain/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMfaUsecase_Enroll(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		var stored *domain.UserMfa
+		mfaRepository := new(mocks.MfaRepository)
+		mfaRepository.On("GetByUserId", mock.Anything, int64(1)).Return(domain.UserMfa{}, domain.ErrNotFound).Once()
+		mfaRepository.On("Store", mock.Anything, mock.AnythingOfType("*domain.UserMfa")).Run(func(args mock.Arguments) {
+			stored = args.Get(1).(*domain.UserMfa)
+		}).Return(nil).Once()
+		userRepository := new(mocks.UserRepository)
+		userRepository.On("GetByID", int64(1)).Return(domain.User{ID: 1, Email: "lucky@kryptopos.com"}, nil).Once()
+
+		enrollment, err := usecase.NewMfaUsecase(mfaRepository, userRepository, time.Second*5).Enroll(context.TODO(), 1)
+
+		require.NoError(t, err)
+		// the secret is only enforced once confirmed
+		require.NotNil(t, stored)
+		assert.Equal(t, "N", stored.Enabled)
+		assert.Equal(t, stored.Secret, enrollment.Secret)
+		assert.Contains(t, enrollment.OtpauthURI, "secret="+enrollment.Secret)
+		assert.True(t, strings.HasPrefix(enrollment.QRCode, "data:image/png;base64,"))
+		mfaRepository.AssertExpectations(t)
+	})
+
+	t.Run("pending-enrollment-restarts", func(t *testing.T) {
+		mfaRepository := new(mocks.MfaRepository)
+		mfaRepository.On("GetByUserId", mock.Anything, int64(1)).Return(domain.UserMfa{UserId: 1, Secret: "OLD", Enabled: "N"}, nil).Once()
+		mfaRepository.On("Store", mock.Anything, mock.MatchedBy(func(mfa *domain.UserMfa) bool {
+			return mfa.Secret != "OLD" && mfa.Enabled == "N"
+		})).Return(nil).Once()
+		userRepository := new(mocks.UserRepository)
+		userRepository.On("GetByID", int64(1)).Return(domain.User{ID: 1, Email: "lucky@kryptopos.com"}, nil).Once()
+
+		_, err := usecase.NewMfaUsecase(mfaRepository, userRepository, time.Second*5).Enroll(context.TODO(), 1)
+
+		assert.NoError(t, err)
+		mfaRepository.AssertExpectations(t)
+	})
+
+	t.Run("already-enabled", func(t *testing.T) {
+		mfaRepository := new(mocks.MfaRepository)
+		mfaRepository.On("GetByUserId", mock.Anything, int64(1)).Return(domain.UserMfa{UserId: 1, Enabled: "Y"}, nil).Once()
+
+		_, err := usecase.NewMfaUsecase(mfaRepository, new(mocks.UserRepository), time.Second*5).Enroll(context.TODO(), 1)
+
+		assert.Equal(t, domain.ErrMfaAlreadyEnabled, err)
+		mfaRepository.AssertNotCalled(t, "Store", mock.Anything, mock.Anything)
+	})
+}
+
+func TestMfaUsecase_Confirm(t *testing.T) {
+	secret, err := auth.GenerateTotpSecret()
+	require.NoError(t, err)
+	pending := domain.UserMfa{UserId: 1, Secret: secret, Enabled: "N"}
+
+	t.Run("success", func(t *testing.T) {
+		code, err := auth.TotpCode(secret, time.Now())
+		require.NoError(t, err)
+		var hashed []domain.MfaRecoveryCode
+		mfaRepository := new(mocks.MfaRepository)
+		mfaRepository.On("GetByUserId", mock.Anything, int64(1)).Return(pending, nil).Once()
+		mfaRepository.On("Enable", mock.Anything, int64(1), mock.AnythingOfType("int64")).Return(nil).Once()
+		mfaRepository.On("ReplaceRecoveryCodes", mock.Anything, int64(1), mock.Anything).Run(func(args mock.Arguments) {
+			hashed = args.Get(2).([]domain.MfaRecoveryCode)
+		}).Return(nil).Once()
+
+		codes, err := usecase.NewMfaUsecase(mfaRepository, new(mocks.UserRepository), time.Second*5).Confirm(context.TODO(), 1, code)
+
+		require.NoError(t, err)
+		require.Len(t, codes, 10)
+		require.Len(t, hashed, 10)
+		for i, recovery := range codes {
+			assert.Regexp(t, `^[a-z2-7]{5}-[a-z2-7]{5}$`, recovery)
+			// only the hash of the codes is stored
+			assert.NotContains(t, hashed[i].CodeHash, strings.Replace(recovery, "-", "", 1))
+			assert.Equal(t, "N", hashed[i].Used)
+		}
+		mfaRepository.AssertExpectations(t)
+	})
+
+	t.Run("wrong-code", func(t *testing.T) {
+		mfaRepository := new(mocks.MfaRepository)
+		mfaRepository.On("GetByUserId", mock.Anything, int64(1)).Return(pending, nil).Once()
+
+		_, err := usecase.NewMfaUsecase(mfaRepository, new(mocks.UserRepository), time.Second*5).Confirm(context.TODO(), 1, "000000x")
+
+		assert.Equal(t, domain.ErrInvalidMfaCode, err)
+		mfaRepository.AssertNotCalled(t, "Enable", mock.Anything, mock.Anything, mock.Anything)
+		mfaRepository.AssertNotCalled(t, "ReplaceRecoveryCodes", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("already-enabled", func(t *testing.T) {
+		mfaRepository := new(mocks.MfaRepository)
+		mfaRepository.On("GetByUserId", mock.Anything, int64(1)).Return(domain.UserMfa{UserId: 1, Secret: secret, Enabled: "Y"}, nil).Once()
+
+		_, err := usecase.NewMfaUsecase(mfaRepository, new(mocks.UserRepository), time.Second*5).Confirm(context.TODO(), 1, "123456")
+
+		assert.Equal(t, domain.ErrMfaAlreadyEnabled, err)
+	})
+}
+
+func TestMfaUsecase_Disable(t *testing.T) {
+	secret, err := auth.GenerateTotpSecret()
+	require.NoError(t, err)
+	enabled := domain.UserMfa{UserId: 1, Secret: secret, Enabled: "Y"}
+
+	t.Run("totp-code", func(t *testing.T) {
+		code, err := auth.TotpCode(secret, time.Now())
+		require.NoError(t, err)
+		mfaRepository := new(mocks.MfaRepository)
+		mfaRepository.On("GetByUserId", mock.Anything, int64(1)).Return(enabled, nil).Once()
+		mfaRepository.On("UpdateLastUsedStep", mock.Anything, int64(1), mock.AnythingOfType("int64")).Return(true, nil).Once()
+		mfaRepository.On("Delete", mock.Anything, int64(1)).Return(nil).Once()
+
+		err = usecase.NewMfaUsecase(mfaRepository, new(mocks.UserRepository), time.Second*5).Disable(context.TODO(), 1, code)
+
+		assert.NoError(t, err)
+		mfaRepository.AssertExpectations(t)
+	})
+
+	t.Run("replayed-totp-code", func(t *testing.T) {
+		code, err := auth.TotpCode(secret, time.Now())
+		require.NoError(t, err)
+		mfaRepository := new(mocks.MfaRepository)
+		mfaRepository.On("GetByUserId", mock.Anything, int64(1)).Return(enabled, nil).Once()
+		mfaRepository.On("UpdateLastUsedStep", mock.Anything, int64(1), mock.AnythingOfType("int64")).Return(false, nil).Once()
+
+		err = usecase.NewMfaUsecase(mfaRepository, new(mocks.UserRepository), time.Second*5).Disable(context.TODO(), 1, code)
+
+		assert.Equal(t, domain.ErrInvalidMfaCode, err)
+		mfaRepository.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+	})
+
+	t.Run("unknown-code", func(t *testing.T) {
+		mfaRepository := new(mocks.MfaRepository)
+		mfaRepository.On("GetByUserId", mock.Anything, int64(1)).Return(enabled, nil).Once()
+		mfaRepository.On("UseRecoveryCode", mock.Anything, int64(1), mock.AnythingOfType("string")).Return(false, nil).Once()
+
+		err := usecase.NewMfaUsecase(mfaRepository, new(mocks.UserRepository), time.Second*5).Disable(context.TODO(), 1, "guess")
+
+		assert.Equal(t, domain.ErrInvalidMfaCode, err)
+		mfaRepository.AssertNotCalled(t, "Delete", mock.Anything, mock.Anything)
+	})
+
+	t.Run("not-enrolled", func(t *testing.T) {
+		mfaRepository := new(mocks.MfaRepository)
+		mfaRepository.On("GetByUserId", mock.Anything, int64(1)).Return(domain.UserMfa{}, domain.ErrNotFound).Once()
+
+		err := usecase.NewMfaUsecase(mfaRepository, new(mocks.UserRepository), time.Second*5).Disable(context.TODO(), 1, "123456")
+
+		assert.Equal(t, domain.ErrNotFound, err)
+	})
+}
+
+func TestMfaUsecase_RecoveryCode(t *testing.T) {
+	secret, err := auth.GenerateTotpSecret()
+	require.NoError(t, err)
+	code, err := auth.TotpCode(secret, time.Now())
+	require.NoError(t, err)
+
+	// confirm the enrollment to get the recovery codes and their stored hashes
+	var hashed []domain.MfaRecoveryCode
+	mfaRepository := new(mocks.MfaRepository)
+	mfaRepository.On("GetByUserId", mock.Anything, int64(1)).Return(domain.UserMfa{UserId: 1, Secret: secret, Enabled: "N"}, nil).Once()
+	mfaRepository.On("Enable", mock.Anything, int64(1), mock.AnythingOfType("int64")).Return(nil).Once()
+	mfaRepository.On("ReplaceRecoveryCodes", mock.Anything, int64(1), mock.Anything).Run(func(args mock.Arguments) {
+		hashed = args.Get(2).([]domain.MfaRecoveryCode)
+	}).Return(nil).Once()
+	uc := usecase.NewMfaUsecase(mfaRepository, new(mocks.UserRepository), time.Second*5)
+	codes, err := uc.Confirm(context.TODO(), 1, code)
+	require.NoError(t, err)
+
+	t.Run("disables-with-recovery-code", func(t *testing.T) {
+		mfaRepository.On("GetByUserId", mock.Anything, int64(1)).Return(domain.UserMfa{UserId: 1, Secret: secret, Enabled: "Y"}, nil).Once()
+		// typed in capitals without the dash, it is still the stored code
+		mfaRepository.On("UseRecoveryCode", mock.Anything, int64(1), hashed[3].CodeHash).Return(true, nil).Once()
+		mfaRepository.On("Delete", mock.Anything, int64(1)).Return(nil).Once()
+
+		err := uc.Disable(context.TODO(), 1, strings.ToUpper(strings.Replace(codes[3], "-", " ", 1)))
+
+		assert.NoError(t, err)
+		mfaRepository.AssertExpectations(t)
+	})
+
+	t.Run("used-recovery-code", func(t *testing.T) {
+		mfaRepository.On("GetByUserId", mock.Anything, int64(1)).Return(domain.UserMfa{UserId: 1, Secret: secret, Enabled: "Y"}, nil).Once()
+		// the repository only uses an unused code once
+		mfaRepository.On("UseRecoveryCode", mock.Anything, int64(1), hashed[3].CodeHash).Return(false, nil).Once()
+
+		err := uc.Disable(context.TODO(), 1, codes[3])
+
+		assert.Equal(t, domain.ErrInvalidMfaCode, err)
+		mfaRepository.AssertNumberOfCalls(t, "Delete", 1)
+	})
+}
@@ -0,0 +1,240 @@
+package usecase
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"github.com/RedLucky/potongin/domain"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// an invitation must be accepted within a week
+const invitationDuration = 7 * 24 * time.Hour
+
+type OrganizationUsecase struct {
+	OrgRepo        domain.OrganizationRepository
+	UserRepo       domain.UserRepository
+	contextTimeout time.Duration
+	Mailer         domain.Mailer
+}
+
+// NewOrganizationUsecase will create new an OrganizationUsecase object representation of domain.OrganizationUsecase interface
+func NewOrganizationUsecase(repo domain.OrganizationRepository, userRepo domain.UserRepository, timeout time.Duration, mailer domain.Mailer) domain.OrganizationUsecase {
+	return &OrganizationUsecase{
+		OrgRepo:        repo,
+		UserRepo:       userRepo,
+		contextTimeout: timeout,
+		Mailer:         mailer,
+	}
+}
+
+func (uc *OrganizationUsecase) Create(c context.Context, userId int64, org *domain.Organization) error {
+	ctx, cancel := context.WithTimeout(c, uc.contextTimeout)
+	defer cancel()
+
+	org.Name = strings.TrimSpace(org.Name)
+	org.CreatedBy = userId
+	org.CreatedAt = time.Now()
+	org.UpdatedAt = time.Now()
+	owner := domain.OrganizationMember{
+		UserId:    userId,
+		Role:      domain.RoleOwner,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := uc.OrgRepo.Create(ctx, org, &owner); err != nil {
+		return err
+	}
+	org.Role = domain.RoleOwner
+	return nil
+}
+
+func (uc *OrganizationUsecase) Fetch(c context.Context, userId int64) ([]domain.Organization, error) {
+	ctx, cancel := context.WithTimeout(c, uc.contextTimeout)
+	defer cancel()
+
+	return uc.OrgRepo.FetchByUserId(ctx, userId)
+}
+
+func (uc *OrganizationUsecase) GetMembers(c context.Context, userId, orgId int64) ([]domain.OrganizationMember, error) {
+	ctx, cancel := context.WithTimeout(c, uc.contextTimeout)
+	defer cancel()
+
+	if _, err := authorizeWorkspace(ctx, uc.OrgRepo, userId, orgId, domain.RoleViewer); err != nil {
+		return nil, err
+	}
+	return uc.OrgRepo.GetMembers(ctx, orgId)
+}
+
+// Invite create an invitation for the email, only an owner can invite an owner
+func (uc *OrganizationUsecase) Invite(c context.Context, userId, orgId int64, email, role string) (encodedString string, err error) {
+	ctx, cancel := context.WithTimeout(c, uc.contextTimeout)
+	defer cancel()
+
+	if !domain.ValidRole(role) {
+		return "", domain.ErrBadParamInput
+	}
+	inviter, err := authorizeWorkspace(ctx, uc.OrgRepo, userId, orgId, domain.RoleAdmin)
+	if err != nil {
+		return "", err
+	}
+	if !domain.RoleAtLeast(inviter.Role, role) {
+		return "", domain.ErrForbidden
+	}
+
+	invitation := domain.OrganizationInvitation{
+		OrgId:     orgId,
+		Email:     strings.TrimSpace(email),
+		Role:      role,
+		Token:     uuid.New().String(),
+		InvitedBy: userId,
+		ExpiresAt: time.Now().Add(invitationDuration),
+		CreatedAt: time.Now(),
+	}
+	if err = uc.OrgRepo.CreateInvitation(ctx, &invitation); err != nil {
+		return "", err
+	}
+	encodedString = base64.StdEncoding.EncodeToString([]byte(invitation.Token))
+	if err = sendLink(ctx, uc.Mailer, invitation.Email, "you are invited to an organization",
+		"You are invited to join an organization as "+role+". Open the link below within a week to accept, after logging in or signing up with this email.",
+		acceptInvitationPage, encodedString); err != nil {
+		return "", err
+	}
+	logrus.WithFields(logrus.Fields{
+		"org_id": orgId,
+		"email":  invitation.Email,
+	}).Info("member invited")
+	return
+}
+
+// AcceptInvitation add the user to the organization, the invitation must be
+// addressed to its email
+func (uc *OrganizationUsecase) AcceptInvitation(c context.Context, userId int64, token string) (member domain.OrganizationMember, err error) {
+	ctx, cancel := context.WithTimeout(c, uc.contextTimeout)
+	defer cancel()
+
+	decodedByte, err := base64.StdEncoding.DecodeString(token)
+	if err != nil {
+		return domain.OrganizationMember{}, domain.ErrorTokenNotFound
+	}
+	invitation, err := uc.OrgRepo.GetInvitation(ctx, string(decodedByte))
+	if err != nil {
+		return domain.OrganizationMember{}, err
+	}
+	if invitation.AcceptedAt != nil || time.Now().After(invitation.ExpiresAt) {
+		return domain.OrganizationMember{}, domain.ErrorTokenNotFound
+	}
+
+	user, err := uc.UserRepo.GetByID(userId)
+	if err != nil {
+		return domain.OrganizationMember{}, domain.ErrNotFound
+	}
+	if !strings.EqualFold(user.Email, invitation.Email) {
+		return domain.OrganizationMember{}, domain.ErrForbidden
+	}
+
+	_, err = uc.OrgRepo.GetMember(ctx, invitation.OrgId, userId)
+	if err == nil {
+		return domain.OrganizationMember{}, domain.ErrConflict
+	} else if err != domain.ErrNotFound {
+		return domain.OrganizationMember{}, err
+	}
+
+	member = domain.OrganizationMember{
+		OrgId:     invitation.OrgId,
+		UserId:    userId,
+		Role:      invitation.Role,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err = uc.OrgRepo.AcceptInvitation(ctx, invitation, &member); err != nil {
+		return domain.OrganizationMember{}, err
+	}
+	return member, nil
+}
+
+// UpdateMemberRole change the role of a member, only an owner can grant or
+// take the owner role and the last owner can't be demoted
+func (uc *OrganizationUsecase) UpdateMemberRole(c context.Context, userId, orgId, memberId int64, role string) error {
+	ctx, cancel := context.WithTimeout(c, uc.contextTimeout)
+	defer cancel()
+
+	if !domain.ValidRole(role) {
+		return domain.ErrBadParamInput
+	}
+	actor, err := authorizeWorkspace(ctx, uc.OrgRepo, userId, orgId, domain.RoleAdmin)
+	if err != nil {
+		return err
+	}
+	member, err := uc.OrgRepo.GetMember(ctx, orgId, memberId)
+	if err != nil {
+		return err
+	}
+	if (member.Role == domain.RoleOwner || role == domain.RoleOwner) && actor.Role != domain.RoleOwner {
+		return domain.ErrForbidden
+	}
+	if member.Role == domain.RoleOwner && role != domain.RoleOwner {
+		if err = uc.keepAnOwner(ctx, orgId); err != nil {
+			return err
+		}
+	}
+	return uc.OrgRepo.UpdateMemberRole(ctx, orgId, memberId, role)
+}
+
+// RemoveMember remove a member, or let the user leave when memberId is itself.
+// The links of the member stay in the organization.
+func (uc *OrganizationUsecase) RemoveMember(c context.Context, userId, orgId, memberId int64) error {
+	ctx, cancel := context.WithTimeout(c, uc.contextTimeout)
+	defer cancel()
+
+	minRole := domain.RoleAdmin
+	if userId == memberId {
+		minRole = domain.RoleViewer
+	}
+	actor, err := authorizeWorkspace(ctx, uc.OrgRepo, userId, orgId, minRole)
+	if err != nil {
+		return err
+	}
+	member, err := uc.OrgRepo.GetMember(ctx, orgId, memberId)
+	if err != nil {
+		return err
+	}
+	if member.Role == domain.RoleOwner {
+		if actor.Role != domain.RoleOwner {
+			return domain.ErrForbidden
+		}
+		if err = uc.keepAnOwner(ctx, orgId); err != nil {
+			return err
+		}
+	}
+	return uc.OrgRepo.RemoveMember(ctx, orgId, memberId)
+}
+
+// keepAnOwner refuse to lose an owner when it is the last one
+func (uc *OrganizationUsecase) keepAnOwner(ctx context.Context, orgId int64) error {
+	owners, err := uc.OrgRepo.CountOwners(ctx, orgId)
+	if err != nil {
+		return err
+	}
+	if owners <= 1 {
+		return domain.ErrLastOwner
+	}
+	return nil
+}
+
+// authorizeWorkspace return the membership of the user in the organization,
+// it fail unless the user has at least minRole. Non members get ErrNotFound so
+// organizations can't be probed.
+func authorizeWorkspace(ctx context.Context, repo domain.OrganizationRepository, userId, orgId int64, minRole string) (domain.OrganizationMember, error) {
+	member, err := repo.GetMember(ctx, orgId, userId)
+	if err != nil {
+		return domain.OrganizationMember{}, err
+	}
+	if !domain.RoleAtLeast(member.Role, minRole) {
+		return domain.OrganizationMember{}, domain.ErrForbidden
+	}
+	return member, nil
+}
@@ -0,0 +1,178 @@
+package usecase_test
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/RedLucky/potongin/app/usecase"
+	"github.com/RedLucky/potongin/domain"
+	"github.com/RedLucky/potongin/domain/mocks"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func member(orgId, userId int64, role string) domain.OrganizationMember {
+	return domain.OrganizationMember{OrgId: orgId, UserId: userId, Role: role}
+}
+
+func TestOrganizationUsecase_Create(t *testing.T) {
+	repository := new(mocks.OrganizationRepository)
+	repository.On("Create", mock.Anything, mock.AnythingOfType("*domain.Organization"), mock.AnythingOfType("*domain.OrganizationMember")).
+		Run(func(args mock.Arguments) {
+			owner := args.Get(2).(*domain.OrganizationMember)
+			assert.Equal(t, int64(1), owner.UserId)
+			assert.Equal(t, domain.RoleOwner, owner.Role)
+		}).Return(nil).Once()
+
+	uc := usecase.NewOrganizationUsecase(repository, new(mocks.UserRepository), time.Second*5, newMailer())
+	org := domain.Organization{Name: " Kryptopos "}
+	err := uc.Create(context.TODO(), 1, &org)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Kryptopos", org.Name)
+	assert.Equal(t, domain.RoleOwner, org.Role)
+	repository.AssertExpectations(t)
+}
+
+func TestOrganizationUsecase_Invite(t *testing.T) {
+	repository := new(mocks.OrganizationRepository)
+
+	t.Run("success", func(t *testing.T) {
+		viper.Set(`mail.link_base_url`, "https://potong.in/")
+		repository.On("GetMember", mock.Anything, int64(10), int64(1)).Return(member(10, 1, domain.RoleAdmin), nil).Once()
+		repository.On("CreateInvitation", mock.Anything, mock.AnythingOfType("*domain.OrganizationInvitation")).Return(nil).Once()
+		mailer := newMailer()
+
+		uc := usecase.NewOrganizationUsecase(repository, new(mocks.UserRepository), time.Second*5, mailer)
+		token, err := uc.Invite(context.TODO(), 1, 10, " new@kryptopos.com ", domain.RoleEditor)
+
+		assert.NoError(t, err)
+		assert.NotEmpty(t, token)
+		mailer.AssertCalled(t, "Send", mock.Anything, mailWithLink("new@kryptopos.com", "/accept-invitation", token))
+		repository.AssertExpectations(t)
+	})
+
+	t.Run("editor-can-not-invite", func(t *testing.T) {
+		repository.On("GetMember", mock.Anything, int64(10), int64(2)).Return(member(10, 2, domain.RoleEditor), nil).Once()
+
+		uc := usecase.NewOrganizationUsecase(repository, new(mocks.UserRepository), time.Second*5, newMailer())
+		_, err := uc.Invite(context.TODO(), 2, 10, "new@kryptopos.com", domain.RoleViewer)
+
+		assert.Equal(t, domain.ErrForbidden, err)
+		repository.AssertExpectations(t)
+	})
+
+	t.Run("admin-can-not-invite-owner", func(t *testing.T) {
+		repository.On("GetMember", mock.Anything, int64(10), int64(1)).Return(member(10, 1, domain.RoleAdmin), nil).Once()
+
+		uc := usecase.NewOrganizationUsecase(repository, new(mocks.UserRepository), time.Second*5, newMailer())
+		_, err := uc.Invite(context.TODO(), 1, 10, "new@kryptopos.com", domain.RoleOwner)
+
+		assert.Equal(t, domain.ErrForbidden, err)
+		repository.AssertExpectations(t)
+	})
+
+	t.Run("not-a-member", func(t *testing.T) {
+		repository.On("GetMember", mock.Anything, int64(10), int64(3)).Return(domain.OrganizationMember{}, domain.ErrNotFound).Once()
+
+		uc := usecase.NewOrganizationUsecase(repository, new(mocks.UserRepository), time.Second*5, newMailer())
+		_, err := uc.Invite(context.TODO(), 3, 10, "new@kryptopos.com", domain.RoleViewer)
+
+		assert.Equal(t, domain.ErrNotFound, err)
+		repository.AssertExpectations(t)
+	})
+}
+
+func TestOrganizationUsecase_AcceptInvitation(t *testing.T) {
+	repository := new(mocks.OrganizationRepository)
+	userRepository := new(mocks.UserRepository)
+	invitation := domain.OrganizationInvitation{
+		ID:        5,
+		OrgId:     10,
+		Email:     "lucky@kryptopos.com",
+		Role:      domain.RoleEditor,
+		Token:     "invitation",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	token := base64.StdEncoding.EncodeToString([]byte("invitation"))
+
+	t.Run("success", func(t *testing.T) {
+		repository.On("GetInvitation", mock.Anything, "invitation").Return(invitation, nil).Once()
+		userRepository.On("GetByID", int64(4)).Return(domain.User{ID: 4, Email: "Lucky@kryptopos.com"}, nil).Once()
+		repository.On("GetMember", mock.Anything, int64(10), int64(4)).Return(domain.OrganizationMember{}, domain.ErrNotFound).Once()
+		repository.On("AcceptInvitation", mock.Anything, invitation, mock.AnythingOfType("*domain.OrganizationMember")).Return(nil).Once()
+
+		uc := usecase.NewOrganizationUsecase(repository, userRepository, time.Second*5, newMailer())
+		joined, err := uc.AcceptInvitation(context.TODO(), 4, token)
+
+		assert.NoError(t, err)
+		assert.Equal(t, domain.RoleEditor, joined.Role)
+		repository.AssertExpectations(t)
+		userRepository.AssertExpectations(t)
+	})
+
+	t.Run("other-email", func(t *testing.T) {
+		repository.On("GetInvitation", mock.Anything, "invitation").Return(invitation, nil).Once()
+		userRepository.On("GetByID", int64(5)).Return(domain.User{ID: 5, Email: "other@kryptopos.com"}, nil).Once()
+
+		uc := usecase.NewOrganizationUsecase(repository, userRepository, time.Second*5, newMailer())
+		_, err := uc.AcceptInvitation(context.TODO(), 5, token)
+
+		assert.Equal(t, domain.ErrForbidden, err)
+		repository.AssertExpectations(t)
+		userRepository.AssertExpectations(t)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		expired := invitation
+		expired.ExpiresAt = time.Now().Add(-time.Minute)
+		repository.On("GetInvitation", mock.Anything, "invitation").Return(expired, nil).Once()
+
+		uc := usecase.NewOrganizationUsecase(repository, userRepository, time.Second*5, newMailer())
+		_, err := uc.AcceptInvitation(context.TODO(), 4, token)
+
+		assert.Equal(t, domain.ErrorTokenNotFound, err)
+		repository.AssertExpectations(t)
+	})
+}
+
+func TestOrganizationUsecase_LastOwner(t *testing.T) {
+	repository := new(mocks.OrganizationRepository)
+
+	t.Run("demote-last-owner", func(t *testing.T) {
+		repository.On("GetMember", mock.Anything, int64(10), int64(1)).Return(member(10, 1, domain.RoleOwner), nil).Twice()
+		repository.On("CountOwners", mock.Anything, int64(10)).Return(int64(1), nil).Once()
+
+		uc := usecase.NewOrganizationUsecase(repository, new(mocks.UserRepository), time.Second*5, newMailer())
+		err := uc.UpdateMemberRole(context.TODO(), 1, 10, 1, domain.RoleAdmin)
+
+		assert.Equal(t, domain.ErrLastOwner, err)
+		repository.AssertExpectations(t)
+	})
+
+	t.Run("leave-with-another-owner", func(t *testing.T) {
+		repository.On("GetMember", mock.Anything, int64(10), int64(1)).Return(member(10, 1, domain.RoleOwner), nil).Twice()
+		repository.On("CountOwners", mock.Anything, int64(10)).Return(int64(2), nil).Once()
+		repository.On("RemoveMember", mock.Anything, int64(10), int64(1)).Return(nil).Once()
+
+		uc := usecase.NewOrganizationUsecase(repository, new(mocks.UserRepository), time.Second*5, newMailer())
+		err := uc.RemoveMember(context.TODO(), 1, 10, 1)
+
+		assert.NoError(t, err)
+		repository.AssertExpectations(t)
+	})
+
+	t.Run("admin-can-not-remove-owner", func(t *testing.T) {
+		repository.On("GetMember", mock.Anything, int64(10), int64(2)).Return(member(10, 2, domain.RoleAdmin), nil).Once()
+		repository.On("GetMember", mock.Anything, int64(10), int64(1)).Return(member(10, 1, domain.RoleOwner), nil).Once()
+
+		uc := usecase.NewOrganizationUsecase(repository, new(mocks.UserRepository), time.Second*5, newMailer())
+		err := uc.RemoveMember(context.TODO(), 2, 10, 1)
+
+		assert.Equal(t, domain.ErrForbidden, err)
+		repository.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,162 @@
+package usecase
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/RedLucky/potongin/domain"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// names of the password policy rules, returned in the violations
+const (
+	RuleMinLength = "min_length"
+	RuleMaxLength = "max_length"
+	RuleUpper     = "uppercase"
+	RuleLower     = "lowercase"
+	RuleDigit     = "digit"
+	RuleSymbol    = "symbol"
+	RuleIdentity  = "not_identity"
+	RuleBreached  = "not_breached"
+)
+
+// PasswordPolicy is the set of rules every new password must satisfy
+type PasswordPolicy struct {
+	MinLength int
+	// bcrypt ignore everything after 72 bytes
+	MaxLength        int
+	RequireUpper     bool
+	RequireLower     bool
+	RequireDigit     bool
+	RequireSymbol    bool
+	DisallowIdentity bool
+	// BreachedDir hold the breached password hashes split by SHA-1 prefix
+	// (k-anonymity): the file named after the 5 first hex chars of the hash
+	// list the remaining 35 chars, one "SUFFIX:COUNT" per line
+	BreachedDir string
+}
+
+// NewPasswordPolicy load the policy from the `password_policy` config
+func NewPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{
+		MinLength:        configInt(`password_policy.min_length`, 8),
+		MaxLength:        configInt(`password_policy.max_length`, 72),
+		RequireUpper:     configBool(`password_policy.require_upper`, true),
+		RequireLower:     configBool(`password_policy.require_lower`, true),
+		RequireDigit:     configBool(`password_policy.require_digit`, true),
+		RequireSymbol:    configBool(`password_policy.require_symbol`, false),
+		DisallowIdentity: configBool(`password_policy.disallow_identity`, true),
+		BreachedDir:      viper.GetString(`password_policy.breached_dir`),
+	}
+}
+
+// Validate check the password of the user against every rule, it return a
+// *domain.PasswordPolicyError naming each failed rule
+func (p PasswordPolicy) Validate(password string, user domain.User) error {
+	var violations []domain.PolicyViolation
+	fail := func(rule, message string) {
+		violations = append(violations, domain.PolicyViolation{Rule: rule, Message: message})
+	}
+
+	length := len([]rune(password))
+	if length < p.MinLength {
+		fail(RuleMinLength, fmt.Sprintf("must be at least %d characters", p.MinLength))
+	}
+	if p.MaxLength > 0 && len(password) > p.MaxLength {
+		fail(RuleMaxLength, fmt.Sprintf("must be at most %d bytes", p.MaxLength))
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	if p.RequireUpper && !upper {
+		fail(RuleUpper, "must contain an uppercase letter")
+	}
+	if p.RequireLower && !lower {
+		fail(RuleLower, "must contain a lowercase letter")
+	}
+	if p.RequireDigit && !digit {
+		fail(RuleDigit, "must contain a digit")
+	}
+	if p.RequireSymbol && !symbol {
+		fail(RuleSymbol, "must contain a symbol")
+	}
+
+	if p.DisallowIdentity && containsIdentity(password, user) {
+		fail(RuleIdentity, "must not contain your username or email")
+	}
+
+	if p.isBreached(password) {
+		fail(RuleBreached, "appears in a known data breach")
+	}
+
+	if len(violations) > 0 {
+		return &domain.PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+func containsIdentity(password string, user domain.User) bool {
+	lowered := strings.ToLower(password)
+	identities := []string{user.Username, strings.Split(user.Email, "@")[0]}
+	for _, identity := range identities {
+		identity = strings.ToLower(identity)
+		// too short to be meaningful
+		if len(identity) < 3 {
+			continue
+		}
+		if strings.Contains(lowered, identity) {
+			return true
+		}
+	}
+	return false
+}
+
+// isBreached look the password hash up in the prefix file, only the prefix
+// file of the hash is read
+func (p PasswordPolicy) isBreached(password string) bool {
+	if p.BreachedDir == "" {
+		return false
+	}
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	file, err := os.Open(filepath.Join(p.BreachedDir, prefix))
+	if os.IsNotExist(err) {
+		return false
+	} else if err != nil {
+		logrus.Error(err)
+		return false
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.EqualFold(strings.SplitN(line, ":", 2)[0], suffix) {
+			return true
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		logrus.Error(err)
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package usecase_test
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/RedLucky/potongin/app/usecase"
+	"github.com/RedLucky/potongin/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func violatedRules(err error) []string {
+	policyErr, ok := err.(*domain.PasswordPolicyError)
+	if !ok {
+		return nil
+	}
+	rules := []string{}
+	for _, violation := range policyErr.Violations {
+		rules = append(rules, violation.Rule)
+	}
+	return rules
+}
+
+func TestPasswordPolicy_Validate(t *testing.T) {
+	policy := usecase.PasswordPolicy{
+		MinLength:        8,
+		MaxLength:        72,
+		RequireUpper:     true,
+		RequireLower:     true,
+		RequireDigit:     true,
+		RequireSymbol:    true,
+		DisallowIdentity: true,
+	}
+	user := domain.User{Username: "redlucky", Email: "lucky.fernanda@kryptopos.com"}
+
+	t.Run("valid", func(t *testing.T) {
+		assert.NoError(t, policy.Validate("Potong!n2021", user))
+	})
+
+	t.Run("every-failed-rule", func(t *testing.T) {
+		err := policy.Validate("abc", user)
+		assert.ElementsMatch(t, []string{
+			usecase.RuleMinLength, usecase.RuleUpper, usecase.RuleDigit, usecase.RuleSymbol,
+		}, violatedRules(err))
+	})
+
+	t.Run("too-long", func(t *testing.T) {
+		err := policy.Validate("Aa1!"+strings.Repeat("x", 72), user)
+		assert.Equal(t, []string{usecase.RuleMaxLength}, violatedRules(err))
+	})
+
+	t.Run("contains-username", func(t *testing.T) {
+		err := policy.Validate("RedLucky!2021", user)
+		assert.Equal(t, []string{usecase.RuleIdentity}, violatedRules(err))
+	})
+
+	t.Run("contains-email", func(t *testing.T) {
+		err := policy.Validate("Lucky.Fernanda#1", user)
+		assert.Equal(t, []string{usecase.RuleIdentity}, violatedRules(err))
+	})
+}
+
+func TestPasswordPolicy_Breached(t *testing.T) {
+	dir, err := ioutil.TempDir("", "breached")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	sum := sha1.Sum([]byte("P@ssw0rd"))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	content := "0018A45C4D1DEF81644B54AB7F969B88D65:1\n" + hash[5:] + ":52310\n"
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, hash[:5]), []byte(content), 0644))
+
+	policy := usecase.PasswordPolicy{MinLength: 8, BreachedDir: dir}
+
+	err = policy.Validate("P@ssw0rd", domain.User{})
+	assert.Equal(t, []string{usecase.RuleBreached}, violatedRules(err))
+
+	assert.NoError(t, policy.Validate("Potong!n2021", domain.User{}))
+}
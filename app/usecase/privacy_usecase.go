@@ -0,0 +1,134 @@
+package usecase
+
+import (
+	"context"
+	"net"
+	"time"
+
+	"github.com/RedLucky/potongin/domain"
+	"github.com/sirupsen/logrus"
+)
+
+// click events are anonymized by batches, to keep the transactions short
+const anonymizeBatchSize = 500
+
+type PrivacyUsecase struct {
+	PrivacyRepo    domain.PrivacyRepository
+	contextTimeout time.Duration
+	Tokens         domain.TokenStore
+	// raw ips of the click events are kept for ipRetention only
+	ipRetention time.Duration
+}
+
+// NewPrivacyUsecase will create new an PrivacyUsecase object representation of domain.PrivacyUsecase interface
+func NewPrivacyUsecase(repo domain.PrivacyRepository, timeout time.Duration, tokens domain.TokenStore) domain.PrivacyUsecase {
+	return &PrivacyUsecase{
+		PrivacyRepo:    repo,
+		contextTimeout: timeout,
+		Tokens:         tokens,
+		ipRetention:    time.Duration(configInt(`privacy.ip_retention_days`, 30)) * 24 * time.Hour,
+	}
+}
+
+// Export gather the personal data of the user. The ips of the visitors of its
+// links are not its data, they are exported anonymized.
+func (uc *PrivacyUsecase) Export(c context.Context, userId int64) (export domain.DataExport, err error) {
+	ctx, cancel := context.WithTimeout(c, uc.contextTimeout)
+	defer cancel()
+
+	user, err := uc.PrivacyRepo.GetUserById(ctx, userId)
+	if err != nil {
+		return domain.DataExport{}, err
+	}
+	export.ExportedAt = time.Now()
+	export.Profile = domain.ExportProfile{
+		ID:            user.ID,
+		Username:      user.Username,
+		Email:         user.Email,
+		Name:          user.Name,
+		EmailVerified: user.EmailVerified,
+		CreatedAt:     user.CreatedAt,
+		UpdatedAt:     user.UpdatedAt,
+	}
+
+	if export.Identities, err = uc.PrivacyRepo.GetIdentities(ctx, userId); err != nil {
+		return domain.DataExport{}, err
+	}
+	if export.Links, err = uc.PrivacyRepo.GetLinks(ctx, userId); err != nil {
+		return domain.DataExport{}, err
+	}
+	if export.Clicks, err = uc.PrivacyRepo.GetClickEvents(ctx, userId); err != nil {
+		return domain.DataExport{}, err
+	}
+	for i := range export.Clicks {
+		export.Clicks[i].IP = AnonymizeIP(export.Clicks[i].IP)
+	}
+
+	if export.Sessions, err = uc.Tokens.GetSessionsByUser(ctx, userId); err != nil {
+		return domain.DataExport{}, domain.ErrInternalServerError
+	}
+	return
+}
+
+// Erase anonymize the ips older than the retention period and delete the data
+// left behind by deleted accounts
+func (uc *PrivacyUsecase) Erase(ctx context.Context) (report domain.ErasureReport, err error) {
+	before := time.Now().Add(-uc.ipRetention)
+	for {
+		var events []domain.ClickEvent
+		events, err = uc.PrivacyRepo.GetClickEventsToAnonymize(ctx, before, anonymizeBatchSize)
+		if err != nil {
+			return report, err
+		}
+		for _, event := range events {
+			if err = uc.PrivacyRepo.AnonymizeClickEvent(ctx, event.ID, AnonymizeIP(event.IP)); err != nil {
+				return report, err
+			}
+			report.AnonymizedClicks++
+		}
+		if len(events) < anonymizeBatchSize {
+			break
+		}
+	}
+
+	report.DeletedClicks, report.DeletedRecords, err = uc.PrivacyRepo.DeleteOrphanedData(ctx)
+	return
+}
+
+// StartErasure run the erasure job every interval until stop is closed
+func StartErasure(uc domain.PrivacyUsecase, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				report, err := uc.Erase(context.Background())
+				if err != nil {
+					logrus.Error(err)
+					continue
+				}
+				logrus.WithFields(logrus.Fields{
+					"anonymized_clicks": report.AnonymizedClicks,
+					"deleted_clicks":    report.DeletedClicks,
+					"deleted_records":   report.DeletedRecords,
+				}).Info("personal data erased")
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// AnonymizeIP keep the network part of the ip only: the /24 of an IPv4 and
+// the /48 of an IPv6. Anything else is dropped.
+func AnonymizeIP(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String()
+	}
+	return parsed.Mask(net.CIDRMask(48, 128)).String()
+}
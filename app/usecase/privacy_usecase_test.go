@@ -0,0 +1,44 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/RedLucky/potongin/app/usecase"
+	"github.com/RedLucky/potongin/domain"
+	"github.com/RedLucky/potongin/domain/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAnonymizeIP(t *testing.T) {
+	assert.Equal(t, "203.0.113.0", usecase.AnonymizeIP("203.0.113.42"))
+	assert.Equal(t, "2001:db8:85a3::", usecase.AnonymizeIP("2001:db8:85a3:8d3:1319:8a2e:370:7348"))
+	assert.Equal(t, "", usecase.AnonymizeIP("not-an-ip"))
+}
+
+func TestPrivacyUsecase_Erase(t *testing.T) {
+	repository := new(mocks.PrivacyRepository)
+	events := []domain.ClickEvent{
+		{ID: 1, IP: "203.0.113.42"},
+		{ID: 2, IP: "198.51.100.7"},
+	}
+
+	repository.On("GetClickEventsToAnonymize", mock.Anything, mock.AnythingOfType("time.Time"), mock.AnythingOfType("int")).
+		Run(func(args mock.Arguments) {
+			// 30 days by default
+			before := args.Get(1).(time.Time)
+			assert.WithinDuration(t, time.Now().Add(-30*24*time.Hour), before, time.Minute)
+		}).Return(events, nil).Once()
+	repository.On("AnonymizeClickEvent", mock.Anything, int64(1), "203.0.113.0").Return(nil).Once()
+	repository.On("AnonymizeClickEvent", mock.Anything, int64(2), "198.51.100.0").Return(nil).Once()
+	repository.On("DeleteOrphanedData", mock.Anything).Return(int64(3), int64(4), nil).Once()
+
+	uc := usecase.NewPrivacyUsecase(repository, time.Second*5, nil)
+	report, err := uc.Erase(context.TODO())
+
+	assert.NoError(t, err)
+	assert.Equal(t, domain.ErasureReport{AnonymizedClicks: 2, DeletedClicks: 3, DeletedRecords: 4}, report)
+	repository.AssertExpectations(t)
+}
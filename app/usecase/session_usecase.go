@@ -0,0 +1,73 @@
+package usecase
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/RedLucky/potongin/domain"
+)
+
+type SessionUsecase struct {
+	contextTimeout time.Duration
+	Tokens         domain.TokenStore
+}
+
+// NewSessionUsecase will create new an SessionUsecase object representation of domain.SessionUsecase interface
+func NewSessionUsecase(timeout time.Duration, tokens domain.TokenStore) domain.SessionUsecase {
+	return &SessionUsecase{
+		contextTimeout: timeout,
+		Tokens:         tokens,
+	}
+}
+
+func (uc *SessionUsecase) Fetch(c context.Context, userId int64) (res []domain.Session, err error) {
+	ctx, cancel := context.WithTimeout(c, uc.contextTimeout)
+	defer cancel()
+
+	res, err = uc.Tokens.GetSessionsByUser(ctx, userId)
+	if err != nil {
+		return nil, domain.ErrInternalServerError
+	}
+
+	// most recently used first
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].LastSeenAt.After(res[j].LastSeenAt)
+	})
+	return
+}
+
+func (uc *SessionUsecase) Revoke(c context.Context, userId int64, sessionId string) (err error) {
+	ctx, cancel := context.WithTimeout(c, uc.contextTimeout)
+	defer cancel()
+
+	session, err := uc.Tokens.GetSession(ctx, sessionId)
+	if err == domain.ErrCacheMiss {
+		return domain.ErrNotFound
+	} else if err != nil {
+		return domain.ErrInternalServerError
+	}
+	// never reveal sessions of other users
+	if session.UserId != userId {
+		return domain.ErrNotFound
+	}
+
+	return uc.Tokens.DeleteSession(ctx, session)
+}
+
+func (uc *SessionUsecase) RevokeAll(c context.Context, userId int64) (err error) {
+	ctx, cancel := context.WithTimeout(c, uc.contextTimeout)
+	defer cancel()
+
+	sessions, err := uc.Tokens.GetSessionsByUser(ctx, userId)
+	if err != nil {
+		return domain.ErrInternalServerError
+	}
+
+	for _, session := range sessions {
+		if err = uc.Tokens.DeleteSession(ctx, session); err != nil {
+			return domain.ErrInternalServerError
+		}
+	}
+	return
+}
@@ -0,0 +1,103 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/RedLucky/potongin/app/delivery/api/auth"
+	"github.com/RedLucky/potongin/app/usecase"
+	"github.com/RedLucky/potongin/domain"
+	"github.com/RedLucky/potongin/domain/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionUsecase_Fetch(t *testing.T) {
+	t.Run("most-recent-first", func(t *testing.T) {
+		tokens := auth.NewMemoryTokenStore()
+		now := time.Now()
+		storeSession(t, tokens, domain.Session{ID: "laptop", UserId: 1, CreatedAt: now.Add(-2 * time.Hour), LastSeenAt: now.Add(-time.Hour)})
+		storeSession(t, tokens, domain.Session{ID: "phone", UserId: 1, CreatedAt: now.Add(-time.Hour), LastSeenAt: now})
+		storeSession(t, tokens, domain.Session{ID: "other-user", UserId: 2, CreatedAt: now, LastSeenAt: now})
+
+		sessions, err := usecase.NewSessionUsecase(time.Second*5, tokens).Fetch(context.TODO(), 1)
+
+		require.NoError(t, err)
+		require.Len(t, sessions, 2)
+		assert.Equal(t, "phone", sessions[0].ID)
+		assert.Equal(t, "laptop", sessions[1].ID)
+	})
+
+	t.Run("store-fails", func(t *testing.T) {
+		tokens := new(mocks.TokenStore)
+		tokens.On("GetSessionsByUser", mock.Anything, int64(1)).Return(nil, errors.New("connection refused")).Once()
+
+		_, err := usecase.NewSessionUsecase(time.Second*5, tokens).Fetch(context.TODO(), 1)
+
+		assert.Equal(t, domain.ErrInternalServerError, err)
+	})
+}
+
+func TestSessionUsecase_Revoke(t *testing.T) {
+	t.Run("own-session", func(t *testing.T) {
+		tokens := auth.NewMemoryTokenStore()
+		laptop := storeSession(t, tokens, domain.Session{ID: "laptop", UserId: 1, CreatedAt: time.Now()})
+		phone := storeSession(t, tokens, domain.Session{ID: "phone", UserId: 1, CreatedAt: time.Now()})
+		uc := usecase.NewSessionUsecase(time.Second*5, tokens)
+
+		require.NoError(t, uc.Revoke(context.TODO(), 1, "laptop"))
+
+		// the paired tokens are revoked with the session
+		_, _, err := tokens.GetTokenSession(context.TODO(), laptop.AccessUUID)
+		assert.Equal(t, domain.ErrCacheMiss, err)
+		_, _, err = tokens.GetTokenSession(context.TODO(), laptop.RefreshUUID)
+		assert.Equal(t, domain.ErrCacheMiss, err)
+		// the other sessions stay logged in
+		_, _, err = tokens.GetTokenSession(context.TODO(), phone.AccessUUID)
+		assert.NoError(t, err)
+		sessions, err := uc.Fetch(context.TODO(), 1)
+		require.NoError(t, err)
+		require.Len(t, sessions, 1)
+		assert.Equal(t, "phone", sessions[0].ID)
+	})
+
+	t.Run("session-of-another-user", func(t *testing.T) {
+		tokens := auth.NewMemoryTokenStore()
+		token := storeSession(t, tokens, domain.Session{ID: "laptop", UserId: 2, CreatedAt: time.Now()})
+
+		err := usecase.NewSessionUsecase(time.Second*5, tokens).Revoke(context.TODO(), 1, "laptop")
+
+		assert.Equal(t, domain.ErrNotFound, err)
+		_, _, err = tokens.GetTokenSession(context.TODO(), token.AccessUUID)
+		assert.NoError(t, err)
+	})
+
+	t.Run("unknown-session", func(t *testing.T) {
+		err := usecase.NewSessionUsecase(time.Second*5, auth.NewMemoryTokenStore()).Revoke(context.TODO(), 1, "laptop")
+
+		assert.Equal(t, domain.ErrNotFound, err)
+	})
+}
+
+func TestSessionUsecase_RevokeAll(t *testing.T) {
+	tokens := auth.NewMemoryTokenStore()
+	laptop := storeSession(t, tokens, domain.Session{ID: "laptop", UserId: 1, CreatedAt: time.Now()})
+	phone := storeSession(t, tokens, domain.Session{ID: "phone", UserId: 1, CreatedAt: time.Now()})
+	other := storeSession(t, tokens, domain.Session{ID: "other-user", UserId: 2, CreatedAt: time.Now()})
+	uc := usecase.NewSessionUsecase(time.Second*5, tokens)
+
+	require.NoError(t, uc.RevokeAll(context.TODO(), 1))
+
+	for _, token := range []domain.JwtResults{laptop, phone} {
+		_, _, err := tokens.GetTokenSession(context.TODO(), token.AccessUUID)
+		assert.Equal(t, domain.ErrCacheMiss, err)
+	}
+	sessions, err := uc.Fetch(context.TODO(), 1)
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+	_, _, err = tokens.GetTokenSession(context.TODO(), other.AccessUUID)
+	assert.NoError(t, err)
+}
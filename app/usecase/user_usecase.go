@@ -10,14 +10,18 @@ import (
 
 type UserUsecase struct {
 	UserRepo       domain.UserRepository
+	AuditRepo      domain.AuditRepository
 	contextTimeout time.Duration
+	passwordPolicy PasswordPolicy
 }
 
 // NewUserUsecase will create new an USerUsecase object representation of domain.UserUsecase interface
-func NewUserUsecase(repo domain.UserRepository, timeout time.Duration) domain.UserUsecase {
+func NewUserUsecase(repo domain.UserRepository, auditRepo domain.AuditRepository, timeout time.Duration) domain.UserUsecase {
 	return &UserUsecase{
 		UserRepo:       repo,
+		AuditRepo:      auditRepo,
 		contextTimeout: timeout,
+		passwordPolicy: NewPasswordPolicy(),
 	}
 }
 
@@ -88,6 +92,10 @@ func (uc *UserUsecase) Store(c context.Context, m *domain.User) (err error) {
 		return domain.ErrAccountExist
 	}
 
+	if err = uc.passwordPolicy.Validate(m.Password, *m); err != nil {
+		return err
+	}
+
 	hashedPassword, err := hash(m.Password)
 	if err != nil {
 		return err
@@ -99,6 +107,15 @@ func (uc *UserUsecase) Store(c context.Context, m *domain.User) (err error) {
 	m.UpdatedAt = time.Now()
 
 	err = uc.UserRepo.Store(m)
+	if err != nil {
+		return err
+	}
+	recordAudit(c, uc.AuditRepo, domain.AuditLog{
+		Action:     domain.AuditUserCreate,
+		TargetType: domain.AuditTargetUser,
+		TargetId:   auditId(m.ID),
+		Diff:       auditDiff(nil, m),
+	})
 	return
 }
 
@@ -112,7 +129,16 @@ func (uc *UserUsecase) Delete(c context.Context, id int64) (err error) {
 	if existUser == (domain.User{}) {
 		return domain.ErrNotFound
 	}
-	return uc.UserRepo.Delete(id)
+	if err = uc.UserRepo.Delete(id); err != nil {
+		return err
+	}
+	recordAudit(c, uc.AuditRepo, domain.AuditLog{
+		Action:     domain.AuditUserDelete,
+		TargetType: domain.AuditTargetUser,
+		TargetId:   auditId(id),
+		Diff:       auditDiff(existUser, nil),
+	})
+	return nil
 }
 
 // private function
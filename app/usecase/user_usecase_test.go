@@ -34,7 +34,7 @@ func TestUserUsecase_Fetch(t *testing.T) {
 	}
 	repository.On("Fetch").Return(usersMock, nil)
 
-	usecase := usecase.NewUserUsecase(repository, time.Second*5)
+	usecase := usecase.NewUserUsecase(repository, newAuditRepository(), time.Second*5)
 	users, err := usecase.Fetch(context.TODO())
 	for i := range users {
 		assert.Equal(t, users[i].Email, usersMock[i].Email, "user email not valid")
@@ -52,7 +52,7 @@ func TestUserUsecase_Store(t *testing.T) {
 		Username: "LFR",
 		Email:    "lucky@kryptopos.com",
 		Name:     "Lucky Fernanda",
-		Password: "123456",
+		Password: "Potongin2021",
 	}
 
 	t.Run("success", func(t *testing.T) {
@@ -62,7 +62,7 @@ func TestUserUsecase_Store(t *testing.T) {
 		repository.On("GetByUsername", mock.AnythingOfType("string")).Return(domain.User{}, nil).Once()
 		repository.On("Store", mock.AnythingOfType("*domain.User")).Return(nil).Once()
 
-		usecase := usecase.NewUserUsecase(repository, time.Second*5)
+		usecase := usecase.NewUserUsecase(repository, newAuditRepository(), time.Second*5)
 		err := usecase.Store(context.TODO(), &usersMock)
 
 		assert.NoError(t, err)
@@ -74,7 +74,7 @@ func TestUserUsecase_Store(t *testing.T) {
 		existingUser := usersMock
 		repository.On("GetByEmail", mock.AnythingOfType("string")).Return(existingUser, nil).Once()
 
-		usecase := usecase.NewUserUsecase(repository, time.Second*5)
+		usecase := usecase.NewUserUsecase(repository, newAuditRepository(), time.Second*5)
 		err := usecase.Store(context.TODO(), &usersMock)
 
 		assert.Error(t, err)
@@ -86,13 +86,27 @@ func TestUserUsecase_Store(t *testing.T) {
 		repository.On("GetByEmail", mock.AnythingOfType("string")).Return(domain.User{}, nil).Once()
 		repository.On("GetByUsername", mock.AnythingOfType("string")).Return(existingUser, nil).Once()
 
-		usecase := usecase.NewUserUsecase(repository, time.Second*5)
+		usecase := usecase.NewUserUsecase(repository, newAuditRepository(), time.Second*5)
 		err := usecase.Store(context.TODO(), &usersMock)
 
 		assert.Error(t, err)
 		repository.AssertExpectations(t)
 	})
 
+	t.Run("weak-password", func(t *testing.T) {
+		weakUser := usersMock
+		weakUser.Password = "123456"
+		repository.On("GetByEmail", mock.AnythingOfType("string")).Return(domain.User{}, nil).Once()
+		repository.On("GetByUsername", mock.AnythingOfType("string")).Return(domain.User{}, nil).Once()
+
+		usecase := usecase.NewUserUsecase(repository, newAuditRepository(), time.Second*5)
+		err := usecase.Store(context.TODO(), &weakUser)
+
+		var policyErr *domain.PasswordPolicyError
+		assert.True(t, errors.As(err, &policyErr))
+		repository.AssertExpectations(t)
+	})
+
 }
 
 func TestUserUsecase_GetByID(t *testing.T) {
@@ -108,7 +122,7 @@ func TestUserUsecase_GetByID(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		repository.On("GetByID", mock.AnythingOfType("int64")).Return(usersMock, nil).Once()
 
-		usecase := usecase.NewUserUsecase(repository, time.Second*5)
+		usecase := usecase.NewUserUsecase(repository, newAuditRepository(), time.Second*5)
 		user, err := usecase.GetByID(context.TODO(), usersMock.ID)
 
 		assert.NotNil(t, user)
@@ -119,7 +133,7 @@ func TestUserUsecase_GetByID(t *testing.T) {
 	t.Run("id-not-found", func(t *testing.T) {
 		repository.On("GetByID", mock.AnythingOfType("int64")).Return(domain.User{}, nil).Once()
 
-		usecase := usecase.NewUserUsecase(repository, time.Second*5)
+		usecase := usecase.NewUserUsecase(repository, newAuditRepository(), time.Second*5)
 		user, err := usecase.GetByID(context.TODO(), usersMock.ID)
 
 		assert.Equal(t, domain.User{}, user)
@@ -141,7 +155,7 @@ func TestUserUsecase_GetByUsername(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		repository.On("GetByUsername", mock.AnythingOfType("string")).Return(usersMock, nil).Once()
 
-		usecase := usecase.NewUserUsecase(repository, time.Second*5)
+		usecase := usecase.NewUserUsecase(repository, newAuditRepository(), time.Second*5)
 		user, err := usecase.GetByUsername(context.TODO(), usersMock.Email)
 
 		assert.NotNil(t, user)
@@ -152,7 +166,7 @@ func TestUserUsecase_GetByUsername(t *testing.T) {
 	t.Run("username-not-found", func(t *testing.T) {
 		repository.On("GetByUsername", mock.AnythingOfType("string")).Return(domain.User{}, nil).Once()
 
-		usecase := usecase.NewUserUsecase(repository, time.Second*5)
+		usecase := usecase.NewUserUsecase(repository, newAuditRepository(), time.Second*5)
 		user, err := usecase.GetByUsername(context.TODO(), usersMock.Email)
 
 		assert.Equal(t, domain.User{}, user)
@@ -175,7 +189,7 @@ func TestUserUsecase_getByEmail(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		repository.On("GetByEmail", mock.AnythingOfType("string")).Return(usersMock, nil).Once()
 
-		usecase := usecase.NewUserUsecase(repository, time.Second*5)
+		usecase := usecase.NewUserUsecase(repository, newAuditRepository(), time.Second*5)
 		user, err := usecase.GetByEmail(context.TODO(), usersMock.Email)
 
 		assert.NotNil(t, user)
@@ -186,7 +200,7 @@ func TestUserUsecase_getByEmail(t *testing.T) {
 	t.Run("email-not-found", func(t *testing.T) {
 		repository.On("GetByEmail", mock.AnythingOfType("string")).Return(domain.User{}, nil).Once()
 
-		usecase := usecase.NewUserUsecase(repository, time.Second*5)
+		usecase := usecase.NewUserUsecase(repository, newAuditRepository(), time.Second*5)
 		user, err := usecase.GetByEmail(context.TODO(), usersMock.Email)
 
 		assert.Equal(t, domain.User{}, user)
@@ -201,7 +215,7 @@ func TestUserUsecase_Update(t *testing.T) {
 		Username: "LFR",
 		Email:    "lucky@kryptopos.com",
 		Name:     "Lucky Fernanda RRRR",
-		Password: "123456",
+		Password: "Potongin2021",
 	}
 
 	t.Run("success", func(t *testing.T) {
@@ -210,7 +224,7 @@ func TestUserUsecase_Update(t *testing.T) {
 
 		repository.On("Update", mock.AnythingOfType("*domain.User")).Return(nil).Once()
 
-		usecase := usecase.NewUserUsecase(repository, time.Second*5)
+		usecase := usecase.NewUserUsecase(repository, newAuditRepository(), time.Second*5)
 		err := usecase.Update(context.TODO(), &usersMock)
 
 		assert.NoError(t, err)
@@ -224,7 +238,7 @@ func TestUserUsecase_Update(t *testing.T) {
 
 		repository.On("Update", mock.AnythingOfType("*domain.User")).Return(errors.New("record not found")).Once()
 
-		usecase := usecase.NewUserUsecase(repository, time.Second*5)
+		usecase := usecase.NewUserUsecase(repository, newAuditRepository(), time.Second*5)
 		err := usecase.Update(context.TODO(), &usersMock)
 
 		assert.Error(t, err)
@@ -241,7 +255,7 @@ func TestUserUsecase_Delete(t *testing.T) {
 		Username: "LFR",
 		Email:    "lucky@kryptopos.com",
 		Name:     "Lucky Fernanda RRRR",
-		Password: "123456",
+		Password: "Potongin2021",
 	}
 
 	t.Run("success", func(t *testing.T) {
@@ -249,7 +263,7 @@ func TestUserUsecase_Delete(t *testing.T) {
 		repository.On("Delete", mock.AnythingOfType("int64")).Return(nil).Once()
 		repository.On("GetByID", mock.AnythingOfType("int64")).Return(usersMock, nil).Once()
 
-		usecase := usecase.NewUserUsecase(repository, time.Second*5)
+		usecase := usecase.NewUserUsecase(repository, newAuditRepository(), time.Second*5)
 		err := usecase.Delete(context.TODO(), usersMock.ID)
 
 		assert.NoError(t, err)
@@ -259,7 +273,7 @@ func TestUserUsecase_Delete(t *testing.T) {
 	t.Run("user-not-found", func(t *testing.T) {
 		repository.On("GetByID", mock.AnythingOfType("int64")).Return(domain.User{}, errors.New("record not found")).Once()
 
-		usecase := usecase.NewUserUsecase(repository, time.Second*5)
+		usecase := usecase.NewUserUsecase(repository, newAuditRepository(), time.Second*5)
 		err := usecase.Delete(context.TODO(), usersMock.ID)
 
 		assert.Error(t, err)
@@ -271,7 +285,7 @@ func TestUserUsecase_Delete(t *testing.T) {
 		repository.On("GetByID", mock.AnythingOfType("int64")).Return(usersMock, nil).Once()
 		repository.On("Delete", mock.AnythingOfType("int64")).Return(errors.New("unexpected error")).Once()
 
-		usecase := usecase.NewUserUsecase(repository, time.Second*5)
+		usecase := usecase.NewUserUsecase(repository, newAuditRepository(), time.Second*5)
 		err := usecase.Delete(context.TODO(), usersMock.ID)
 
 		assert.Error(t, err)
@@ -0,0 +1,114 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// errPrivateEndpoint is the dial error of a webhook pointing inside the network
+var errPrivateEndpoint = errors.New("webhook endpoint is not a public address")
+
+// the webhooks can't reach the loopback, private, link-local, CGNAT,
+// unspecified, multicast and broadcast addresses
+var privateNetworks = parseNetworks(
+	"0.0.0.0/8", "10.0.0.0/8", "100.64.0.0/10", "127.0.0.0/8", "169.254.0.0/16", "172.16.0.0/12",
+	"192.0.0.0/24", "192.168.0.0/16", "198.18.0.0/15", "224.0.0.0/4", "240.0.0.0/4",
+	"::/128", "::1/128", "64:ff9b::/96", "fc00::/7", "fe80::/10", "ff00::/8",
+)
+
+func parseNetworks(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, networks[i], _ = net.ParseCIDR(cidr)
+	}
+	return networks
+}
+
+func isPublicIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	for _, network := range privateNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkWebhookUrl accept an http(s) url whose host only resolves to public
+// addresses
+func checkWebhookUrl(ctx context.Context, rawUrl string, allowPrivate bool) error {
+	endpoint, err := url.Parse(rawUrl)
+	if err != nil || (endpoint.Scheme != "https" && endpoint.Scheme != "http") || endpoint.Hostname() == "" {
+		return errors.New("webhook url must be an http or https url")
+	}
+	if allowPrivate {
+		return nil
+	}
+
+	host := endpoint.Hostname()
+	var addresses []net.IPAddr
+	if ip := net.ParseIP(host); ip != nil {
+		addresses = []net.IPAddr{{IP: ip}}
+	} else if addresses, err = net.DefaultResolver.LookupIPAddr(ctx, host); err != nil {
+		return fmt.Errorf("webhook host %q can't be resolved", host)
+	}
+	for _, address := range addresses {
+		if !isPublicIP(address.IP) {
+			return errPrivateEndpoint
+		}
+	}
+	return nil
+}
+
+// newWebhookClient return the client posting the deliveries. Its dialer
+// checks the address actually connected to, so a host resolving to a private
+// address after its creation is still refused, and it doesn't follow the
+// redirects.
+func newWebhookClient(timeout time.Duration, allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+				return errPrivateEndpoint
+			}
+			return nil
+		}
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			// a proxy would be dialed in place of the endpoint
+			Proxy:               nil,
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConnsPerHost: 2,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// responseSnippet keep the printable start of the body of a response
+func responseSnippet(body []byte) string {
+	snippet := strings.Map(func(r rune) rune {
+		if r < ' ' || r > '~' {
+			return ' '
+		}
+		return r
+	}, string(body))
+	return truncate(strings.Join(strings.Fields(snippet), " "), webhookResponseLimit)
+}
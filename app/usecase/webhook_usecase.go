@@ -0,0 +1,349 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/RedLucky/potongin/domain"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// deliveries sent by one run of the queue
+	webhookBatchSize = 50
+	// a claimed delivery is attempted again after the lease if its worker died
+	webhookLease = 5 * time.Minute
+	// maximum deliveries listed at once
+	maxDeliveriesLimit = 100
+	// characters of the response kept in the delivery log
+	webhookResponseLimit = 256
+)
+
+type WebhookUsecase struct {
+	WebhookRepo    domain.WebhookRepository
+	contextTimeout time.Duration
+	client         *http.Client
+	// allowPrivate let the webhooks reach the private networks, for the tests
+	// and the installs delivering inside their own network
+	allowPrivate bool
+	// a delivery is retried after retryBase, doubled on each failed attempt up
+	// to retryMax, and given up after maxAttempts
+	maxAttempts int
+	retryBase   time.Duration
+	retryMax    time.Duration
+}
+
+// NewWebhookUsecase will create new an WebhookUsecase object representation of domain.WebhookUsecase interface
+func NewWebhookUsecase(repo domain.WebhookRepository, timeout time.Duration) domain.WebhookUsecase {
+	allowPrivate := configBool(`webhook.allow_private_networks`, false)
+	return &WebhookUsecase{
+		WebhookRepo:    repo,
+		contextTimeout: timeout,
+		client:         newWebhookClient(time.Duration(configInt(`webhook.timeout`, 10))*time.Second, allowPrivate),
+		allowPrivate:   allowPrivate,
+		maxAttempts:    configInt(`webhook.max_attempts`, 8),
+		retryBase:      time.Duration(configInt(`webhook.retry_base`, 30)) * time.Second,
+		retryMax:       time.Duration(configInt(`webhook.retry_max`, 21600)) * time.Second,
+	}
+}
+
+// Create register the webhook, its secret is returned once
+func (uc *WebhookUsecase) Create(c context.Context, userId int64, param domain.CreateWebhook) (webhook domain.Webhook, secret string, err error) {
+	ctx, cancel := context.WithTimeout(c, uc.contextTimeout)
+	defer cancel()
+
+	if err = checkWebhookUrl(ctx, param.Url, uc.allowPrivate); err != nil {
+		return domain.Webhook{}, "", domain.ErrBadParamInput
+	}
+	var events []string
+	for _, event := range param.Events {
+		if !domain.ValidWebhookEvent(event) {
+			return domain.Webhook{}, "", domain.ErrBadParamInput
+		}
+		if !containsString(events, event) {
+			events = append(events, event)
+		}
+	}
+	secret, err = newWebhookSecret()
+	if err != nil {
+		return domain.Webhook{}, "", domain.ErrInternalServerError
+	}
+
+	webhook = domain.Webhook{
+		UserId:    userId,
+		Url:       param.Url,
+		Events:    strings.Join(events, ","),
+		EventList: events,
+		Secret:    secret,
+		Active:    "Y",
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err = uc.WebhookRepo.Create(ctx, &webhook); err != nil {
+		return domain.Webhook{}, "", err
+	}
+	return
+}
+
+func (uc *WebhookUsecase) Fetch(c context.Context, userId int64) ([]domain.Webhook, error) {
+	ctx, cancel := context.WithTimeout(c, uc.contextTimeout)
+	defer cancel()
+
+	return uc.WebhookRepo.FetchByUserId(ctx, userId)
+}
+
+func (uc *WebhookUsecase) Delete(c context.Context, userId, webhookId int64) error {
+	ctx, cancel := context.WithTimeout(c, uc.contextTimeout)
+	defer cancel()
+
+	if _, err := uc.getWebhook(ctx, userId, webhookId); err != nil {
+		return err
+	}
+	return uc.WebhookRepo.Delete(ctx, webhookId)
+}
+
+func (uc *WebhookUsecase) Deliveries(c context.Context, userId, webhookId int64, limit int) ([]domain.WebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(c, uc.contextTimeout)
+	defer cancel()
+
+	if _, err := uc.getWebhook(ctx, userId, webhookId); err != nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > maxDeliveriesLimit {
+		limit = maxDeliveriesLimit
+	}
+	return uc.WebhookRepo.FetchDeliveries(ctx, webhookId, limit)
+}
+
+// Redeliver queue the payload of a past delivery again, with the same event id
+func (uc *WebhookUsecase) Redeliver(c context.Context, userId, webhookId, deliveryId int64) (domain.WebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(c, uc.contextTimeout)
+	defer cancel()
+
+	if _, err := uc.getWebhook(ctx, userId, webhookId); err != nil {
+		return domain.WebhookDelivery{}, err
+	}
+	previous, err := uc.WebhookRepo.GetDelivery(ctx, deliveryId)
+	if err != nil {
+		return domain.WebhookDelivery{}, err
+	}
+	if previous.WebhookId != webhookId {
+		return domain.WebhookDelivery{}, domain.ErrNotFound
+	}
+
+	delivery := newDelivery(webhookId, previous.EventId, previous.Event, previous.Payload)
+	if err = uc.WebhookRepo.CreateDelivery(ctx, &delivery); err != nil {
+		return domain.WebhookDelivery{}, err
+	}
+	return delivery, nil
+}
+
+func (uc *WebhookUsecase) ProcessQueue(c context.Context) (int, error) {
+	ctx, cancel := context.WithTimeout(c, uc.contextTimeout)
+	deliveries, err := uc.WebhookRepo.FetchDueDeliveries(ctx, time.Now(), webhookBatchSize)
+	cancel()
+	if err != nil {
+		return 0, err
+	}
+
+	attempted := 0
+	for _, delivery := range deliveries {
+		// another instance may be working on the same queue
+		claimed, err := uc.WebhookRepo.ClaimDelivery(c, delivery, time.Now().Add(webhookLease))
+		if err != nil {
+			logrus.Error(err)
+			continue
+		}
+		if !claimed {
+			continue
+		}
+		uc.deliver(c, delivery)
+		attempted++
+	}
+	return attempted, nil
+}
+
+// deliver post the delivery to its webhook and record the outcome
+func (uc *WebhookUsecase) deliver(c context.Context, delivery domain.WebhookDelivery) {
+	webhook, err := uc.WebhookRepo.GetById(c, delivery.WebhookId)
+	switch {
+	case err == domain.ErrNotFound:
+		delivery.Status, delivery.LastError = domain.DeliveryFailed, "webhook deleted"
+	case err != nil:
+		delivery.LastError = truncate(err.Error(), 255)
+		uc.retryLater(&delivery)
+	case webhook.Active != "Y":
+		delivery.Status, delivery.LastError = domain.DeliveryFailed, "webhook disabled"
+	default:
+		uc.post(c, webhook, &delivery)
+	}
+
+	delivery.UpdatedAt = time.Now()
+	if err = uc.WebhookRepo.UpdateDelivery(c, &delivery); err != nil {
+		logrus.WithField("delivery_id", delivery.ID).Error("failed to record webhook delivery: ", err)
+	}
+}
+
+func (uc *WebhookUsecase) post(c context.Context, webhook domain.Webhook, delivery *domain.WebhookDelivery) {
+	delivery.Attempts++
+	request, err := http.NewRequestWithContext(c, http.MethodPost, webhook.Url, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		delivery.Status, delivery.LastError = domain.DeliveryFailed, truncate(err.Error(), 255)
+		return
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "potongin-webhooks")
+	request.Header.Set("X-Potongin-Event", delivery.Event)
+	request.Header.Set("X-Potongin-Delivery", delivery.EventId)
+	request.Header.Set("X-Potongin-Signature", SignWebhook(webhook.Secret, time.Now().Unix(), delivery.Payload))
+
+	response, err := uc.client.Do(request)
+	if err != nil {
+		delivery.ResponseCode, delivery.ResponseBody = 0, ""
+		delivery.LastError = truncate(err.Error(), 255)
+		if errors.Is(err, errPrivateEndpoint) {
+			delivery.Status = domain.DeliveryFailed
+			return
+		}
+		uc.retryLater(delivery)
+		return
+	}
+	defer response.Body.Close()
+	body, _ := ioutil.ReadAll(io.LimitReader(response.Body, webhookResponseLimit))
+	delivery.ResponseCode = response.StatusCode
+	delivery.ResponseBody = responseSnippet(body)
+
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		now := time.Now()
+		delivery.Status, delivery.LastError, delivery.DeliveredAt = domain.DeliverySucceeded, "", &now
+		return
+	}
+	delivery.LastError = "unexpected status " + strconv.Itoa(response.StatusCode)
+	uc.retryLater(delivery)
+}
+
+// retryLater schedule the next attempt, or give up after the last one
+func (uc *WebhookUsecase) retryLater(delivery *domain.WebhookDelivery) {
+	if delivery.Attempts >= uc.maxAttempts {
+		delivery.Status = domain.DeliveryFailed
+		return
+	}
+	backoff := uc.retryBase
+	for i := 1; i < delivery.Attempts && backoff < uc.retryMax; i++ {
+		backoff *= 2
+	}
+	if backoff > uc.retryMax {
+		backoff = uc.retryMax
+	}
+	delivery.NextAttemptAt = time.Now().Add(backoff)
+}
+
+func (uc *WebhookUsecase) getWebhook(ctx context.Context, userId, webhookId int64) (domain.Webhook, error) {
+	webhook, err := uc.WebhookRepo.GetById(ctx, webhookId)
+	if err != nil {
+		return domain.Webhook{}, err
+	}
+	// the webhooks of the other users don't exist for the caller
+	if webhook.UserId != userId {
+		return domain.Webhook{}, domain.ErrNotFound
+	}
+	return webhook, nil
+}
+
+// StartWebhookDelivery send the queued deliveries every interval until stop is closed
+func StartWebhookDelivery(uc domain.WebhookUsecase, interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := uc.ProcessQueue(context.Background()); err != nil {
+					logrus.Error(err)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// SignWebhook compute the X-Potongin-Signature header of a payload: the
+// timestamp and the hex HMAC-SHA256 of "<timestamp>.<payload>" keyed with the
+// secret of the webhook, as "t=<timestamp>,v1=<hmac>"
+func SignWebhook(secret string, timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(payload)
+	return fmt.Sprintf("t=%d,v1=%s", timestamp, hex.EncodeToString(mac.Sum(nil)))
+}
+
+// enqueueWebhooks queue the event for the webhooks of the user subscribed to
+// it. The event already happened, so a failure is only logged.
+func enqueueWebhooks(ctx context.Context, repo domain.WebhookRepository, userId int64, event string, data interface{}) {
+	webhooks, err := repo.FetchSubscribed(ctx, userId, event)
+	if err != nil {
+		logrus.WithField("event", event).Error("failed to queue webhooks: ", err)
+		return
+	}
+	if len(webhooks) == 0 {
+		return
+	}
+
+	eventId := uuid.New().String()
+	payload, err := json.Marshal(domain.WebhookEvent{ID: eventId, Event: event, CreatedAt: time.Now(), Data: data})
+	if err != nil {
+		logrus.WithField("event", event).Error("failed to queue webhooks: ", err)
+		return
+	}
+	for _, webhook := range webhooks {
+		delivery := newDelivery(webhook.ID, eventId, event, payload)
+		if err = repo.CreateDelivery(ctx, &delivery); err != nil {
+			logrus.WithFields(logrus.Fields{"event": event, "webhook_id": webhook.ID}).Error("failed to queue webhook: ", err)
+		}
+	}
+}
+
+func newDelivery(webhookId int64, eventId, event string, payload json.RawMessage) domain.WebhookDelivery {
+	return domain.WebhookDelivery{
+		WebhookId:     webhookId,
+		EventId:       eventId,
+		Event:         event,
+		Payload:       payload,
+		Status:        domain.DeliveryPending,
+		NextAttemptAt: time.Now(),
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	}
+}
+
+func newWebhookSecret() (string, error) {
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(secret), nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,299 @@
+package usecase_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RedLucky/potongin/app/usecase"
+	"github.com/RedLucky/potongin/domain"
+	"github.com/RedLucky/potongin/domain/mocks"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookUsecase_Create(t *testing.T) {
+	repository := new(mocks.WebhookRepository)
+	uc := usecase.NewWebhookUsecase(repository, time.Second*5)
+
+	t.Run("success", func(t *testing.T) {
+		repository.On("Create", mock.Anything, mock.AnythingOfType("*domain.Webhook")).Return(nil).Once()
+
+		webhook, secret, err := uc.Create(context.TODO(), 1, domain.CreateWebhook{
+			Url:    "https://93.184.216.34/hooks/potongin",
+			Events: []string{domain.WebhookLinkCreated, domain.WebhookLinkClicked, domain.WebhookLinkCreated},
+		})
+
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(secret, "whsec_"))
+		assert.Equal(t, secret, webhook.Secret)
+		assert.Equal(t, "link.created,link.clicked", webhook.Events)
+		assert.True(t, webhook.Subscribed(domain.WebhookLinkClicked))
+		assert.False(t, webhook.Subscribed(domain.WebhookLinkExpired))
+	})
+
+	t.Run("unknown-event", func(t *testing.T) {
+		_, _, err := uc.Create(context.TODO(), 1, domain.CreateWebhook{
+			Url:    "https://crm.example.com/hooks/potongin",
+			Events: []string{"user.deleted"},
+		})
+
+		assert.Equal(t, domain.ErrBadParamInput, err)
+	})
+
+	t.Run("not-http", func(t *testing.T) {
+		_, _, err := uc.Create(context.TODO(), 1, domain.CreateWebhook{
+			Url:    "ftp://crm.example.com/hooks",
+			Events: []string{domain.WebhookLinkCreated},
+		})
+
+		assert.Equal(t, domain.ErrBadParamInput, err)
+	})
+
+	t.Run("private-address", func(t *testing.T) {
+		for _, url := range []string{
+			"http://127.0.0.1:6379/",
+			"http://localhost/hooks",
+			"http://169.254.169.254/latest/meta-data/",
+			"http://10.0.0.7/hooks",
+			"http://100.64.0.1/hooks",
+			"http://[::1]/hooks",
+			"http://[::ffff:192.168.1.1]/hooks",
+			"http://0.0.0.0/hooks",
+		} {
+			_, _, err := uc.Create(context.TODO(), 1, domain.CreateWebhook{Url: url, Events: []string{domain.WebhookLinkCreated}})
+
+			assert.Equal(t, domain.ErrBadParamInput, err, url)
+		}
+	})
+	repository.AssertExpectations(t)
+}
+
+func TestWebhookUsecase_ProcessQueue(t *testing.T) {
+	// the test servers listen on the loopback
+	viper.Set(`webhook.allow_private_networks`, true)
+	defer viper.Set(`webhook.allow_private_networks`, nil)
+	webhook := domain.Webhook{ID: 3, UserId: 1, Events: domain.WebhookLinkClicked, Secret: "whsec_test", Active: "Y"}
+	payload := json.RawMessage(`{"id":"event-1","event":"link.clicked","data":{}}`)
+	pending := func() domain.WebhookDelivery {
+		return domain.WebhookDelivery{ID: 9, WebhookId: 3, EventId: "event-1", Event: domain.WebhookLinkClicked,
+			Payload: payload, Status: domain.DeliveryPending, NextAttemptAt: time.Now()}
+	}
+
+	t.Run("signed-delivery", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			assert.JSONEq(t, string(payload), string(body))
+			assert.Equal(t, domain.WebhookLinkClicked, r.Header.Get("X-Potongin-Event"))
+			assert.Equal(t, "event-1", r.Header.Get("X-Potongin-Delivery"))
+
+			signature := r.Header.Get("X-Potongin-Signature")
+			timestamp, _ := strconv.ParseInt(strings.TrimPrefix(strings.Split(signature, ",")[0], "t="), 10, 64)
+			assert.Equal(t, usecase.SignWebhook("whsec_test", timestamp, body), signature)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+		webhook.Url = server.URL
+
+		repository := new(mocks.WebhookRepository)
+		repository.On("FetchDueDeliveries", mock.Anything, mock.AnythingOfType("time.Time"), 50).Return([]domain.WebhookDelivery{pending()}, nil).Once()
+		repository.On("ClaimDelivery", mock.Anything, mock.AnythingOfType("domain.WebhookDelivery"), mock.AnythingOfType("time.Time")).Return(true, nil).Once()
+		repository.On("GetById", mock.Anything, int64(3)).Return(webhook, nil).Once()
+		repository.On("UpdateDelivery", mock.Anything, mock.AnythingOfType("*domain.WebhookDelivery")).Run(func(args mock.Arguments) {
+			delivery := args.Get(1).(*domain.WebhookDelivery)
+			assert.Equal(t, domain.DeliverySucceeded, delivery.Status)
+			assert.Equal(t, 1, delivery.Attempts)
+			assert.Equal(t, http.StatusNoContent, delivery.ResponseCode)
+			assert.NotNil(t, delivery.DeliveredAt)
+		}).Return(nil).Once()
+
+		attempted, err := usecase.NewWebhookUsecase(repository, time.Second*5).ProcessQueue(context.TODO())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, attempted)
+		repository.AssertExpectations(t)
+	})
+
+	t.Run("retry-with-backoff", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+		webhook.Url = server.URL
+
+		delivery := pending()
+		delivery.Attempts = 2
+		repository := new(mocks.WebhookRepository)
+		repository.On("FetchDueDeliveries", mock.Anything, mock.AnythingOfType("time.Time"), 50).Return([]domain.WebhookDelivery{delivery}, nil).Once()
+		repository.On("ClaimDelivery", mock.Anything, mock.AnythingOfType("domain.WebhookDelivery"), mock.AnythingOfType("time.Time")).Return(true, nil).Once()
+		repository.On("GetById", mock.Anything, int64(3)).Return(webhook, nil).Once()
+		repository.On("UpdateDelivery", mock.Anything, mock.AnythingOfType("*domain.WebhookDelivery")).Run(func(args mock.Arguments) {
+			delivery := args.Get(1).(*domain.WebhookDelivery)
+			assert.Equal(t, domain.DeliveryPending, delivery.Status)
+			assert.Equal(t, 3, delivery.Attempts)
+			assert.Equal(t, http.StatusServiceUnavailable, delivery.ResponseCode)
+			// third attempt failed: 30s doubled twice
+			assert.WithinDuration(t, time.Now().Add(2*time.Minute), delivery.NextAttemptAt, 5*time.Second)
+		}).Return(nil).Once()
+
+		_, err := usecase.NewWebhookUsecase(repository, time.Second*5).ProcessQueue(context.TODO())
+
+		assert.NoError(t, err)
+		repository.AssertExpectations(t)
+	})
+
+	t.Run("give-up", func(t *testing.T) {
+		delivery := pending()
+		delivery.Attempts = 7
+		webhook.Url = "http://127.0.0.1:1"
+		repository := new(mocks.WebhookRepository)
+		repository.On("FetchDueDeliveries", mock.Anything, mock.AnythingOfType("time.Time"), 50).Return([]domain.WebhookDelivery{delivery}, nil).Once()
+		repository.On("ClaimDelivery", mock.Anything, mock.AnythingOfType("domain.WebhookDelivery"), mock.AnythingOfType("time.Time")).Return(true, nil).Once()
+		repository.On("GetById", mock.Anything, int64(3)).Return(webhook, nil).Once()
+		repository.On("UpdateDelivery", mock.Anything, mock.AnythingOfType("*domain.WebhookDelivery")).Run(func(args mock.Arguments) {
+			delivery := args.Get(1).(*domain.WebhookDelivery)
+			assert.Equal(t, domain.DeliveryFailed, delivery.Status)
+			assert.Equal(t, 8, delivery.Attempts)
+			assert.NotEmpty(t, delivery.LastError)
+		}).Return(nil).Once()
+
+		_, err := usecase.NewWebhookUsecase(repository, time.Second*5).ProcessQueue(context.TODO())
+
+		assert.NoError(t, err)
+		repository.AssertExpectations(t)
+	})
+
+	t.Run("redirect-not-followed", func(t *testing.T) {
+		target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Error("the redirect was followed")
+		}))
+		defer target.Close()
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, target.URL, http.StatusFound)
+		}))
+		defer server.Close()
+		webhook.Url = server.URL
+
+		repository := new(mocks.WebhookRepository)
+		repository.On("FetchDueDeliveries", mock.Anything, mock.AnythingOfType("time.Time"), 50).Return([]domain.WebhookDelivery{pending()}, nil).Once()
+		repository.On("ClaimDelivery", mock.Anything, mock.AnythingOfType("domain.WebhookDelivery"), mock.AnythingOfType("time.Time")).Return(true, nil).Once()
+		repository.On("GetById", mock.Anything, int64(3)).Return(webhook, nil).Once()
+		repository.On("UpdateDelivery", mock.Anything, mock.AnythingOfType("*domain.WebhookDelivery")).Run(func(args mock.Arguments) {
+			delivery := args.Get(1).(*domain.WebhookDelivery)
+			assert.Equal(t, domain.DeliveryPending, delivery.Status)
+			assert.Equal(t, http.StatusFound, delivery.ResponseCode)
+		}).Return(nil).Once()
+
+		_, err := usecase.NewWebhookUsecase(repository, time.Second*5).ProcessQueue(context.TODO())
+
+		assert.NoError(t, err)
+		repository.AssertExpectations(t)
+	})
+
+	t.Run("response-snippet", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte("invalid\r\n\x1b[31mpayload\x00" + strings.Repeat("a", 2048)))
+		}))
+		defer server.Close()
+		webhook.Url = server.URL
+
+		repository := new(mocks.WebhookRepository)
+		repository.On("FetchDueDeliveries", mock.Anything, mock.AnythingOfType("time.Time"), 50).Return([]domain.WebhookDelivery{pending()}, nil).Once()
+		repository.On("ClaimDelivery", mock.Anything, mock.AnythingOfType("domain.WebhookDelivery"), mock.AnythingOfType("time.Time")).Return(true, nil).Once()
+		repository.On("GetById", mock.Anything, int64(3)).Return(webhook, nil).Once()
+		repository.On("UpdateDelivery", mock.Anything, mock.AnythingOfType("*domain.WebhookDelivery")).Run(func(args mock.Arguments) {
+			delivery := args.Get(1).(*domain.WebhookDelivery)
+			assert.True(t, strings.HasPrefix(delivery.ResponseBody, "invalid [31mpayload aaa"), delivery.ResponseBody)
+			assert.LessOrEqual(t, len(delivery.ResponseBody), 256)
+		}).Return(nil).Once()
+
+		_, err := usecase.NewWebhookUsecase(repository, time.Second*5).ProcessQueue(context.TODO())
+
+		assert.NoError(t, err)
+		repository.AssertExpectations(t)
+	})
+
+	t.Run("private-address-refused-at-dial", func(t *testing.T) {
+		viper.Set(`webhook.allow_private_networks`, false)
+		defer viper.Set(`webhook.allow_private_networks`, true)
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Error("the private address was reached")
+		}))
+		defer server.Close()
+		webhook.Url = server.URL
+
+		repository := new(mocks.WebhookRepository)
+		repository.On("FetchDueDeliveries", mock.Anything, mock.AnythingOfType("time.Time"), 50).Return([]domain.WebhookDelivery{pending()}, nil).Once()
+		repository.On("ClaimDelivery", mock.Anything, mock.AnythingOfType("domain.WebhookDelivery"), mock.AnythingOfType("time.Time")).Return(true, nil).Once()
+		repository.On("GetById", mock.Anything, int64(3)).Return(webhook, nil).Once()
+		repository.On("UpdateDelivery", mock.Anything, mock.AnythingOfType("*domain.WebhookDelivery")).Run(func(args mock.Arguments) {
+			delivery := args.Get(1).(*domain.WebhookDelivery)
+			assert.Equal(t, domain.DeliveryFailed, delivery.Status)
+			assert.Contains(t, delivery.LastError, "not a public address")
+		}).Return(nil).Once()
+
+		_, err := usecase.NewWebhookUsecase(repository, time.Second*5).ProcessQueue(context.TODO())
+
+		assert.NoError(t, err)
+		repository.AssertExpectations(t)
+	})
+
+	t.Run("claimed-elsewhere", func(t *testing.T) {
+		repository := new(mocks.WebhookRepository)
+		repository.On("FetchDueDeliveries", mock.Anything, mock.AnythingOfType("time.Time"), 50).Return([]domain.WebhookDelivery{pending()}, nil).Once()
+		repository.On("ClaimDelivery", mock.Anything, mock.AnythingOfType("domain.WebhookDelivery"), mock.AnythingOfType("time.Time")).Return(false, nil).Once()
+
+		attempted, err := usecase.NewWebhookUsecase(repository, time.Second*5).ProcessQueue(context.TODO())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 0, attempted)
+		repository.AssertExpectations(t)
+	})
+}
+
+func TestWebhookUsecase_Redeliver(t *testing.T) {
+	repository := new(mocks.WebhookRepository)
+	uc := usecase.NewWebhookUsecase(repository, time.Second*5)
+	previous := domain.WebhookDelivery{ID: 9, WebhookId: 3, EventId: "event-1", Event: domain.WebhookLinkCreated,
+		Payload: json.RawMessage(`{}`), Status: domain.DeliveryFailed, Attempts: 8}
+
+	t.Run("success", func(t *testing.T) {
+		repository.On("GetById", mock.Anything, int64(3)).Return(domain.Webhook{ID: 3, UserId: 1}, nil).Once()
+		repository.On("GetDelivery", mock.Anything, int64(9)).Return(previous, nil).Once()
+		repository.On("CreateDelivery", mock.Anything, mock.AnythingOfType("*domain.WebhookDelivery")).Return(nil).Once()
+
+		delivery, err := uc.Redeliver(context.TODO(), 1, 3, 9)
+
+		require.NoError(t, err)
+		assert.Equal(t, "event-1", delivery.EventId)
+		assert.Equal(t, domain.DeliveryPending, delivery.Status)
+		assert.Equal(t, 0, delivery.Attempts)
+	})
+
+	t.Run("webhook-of-another-user", func(t *testing.T) {
+		repository.On("GetById", mock.Anything, int64(3)).Return(domain.Webhook{ID: 3, UserId: 2}, nil).Once()
+
+		_, err := uc.Redeliver(context.TODO(), 1, 3, 9)
+
+		assert.Equal(t, domain.ErrNotFound, err)
+	})
+
+	t.Run("delivery-of-another-webhook", func(t *testing.T) {
+		repository.On("GetById", mock.Anything, int64(4)).Return(domain.Webhook{ID: 4, UserId: 1}, nil).Once()
+		repository.On("GetDelivery", mock.Anything, int64(9)).Return(previous, nil).Once()
+
+		_, err := uc.Redeliver(context.TODO(), 1, 4, 9)
+
+		assert.Equal(t, domain.ErrNotFound, err)
+	})
+	repository.AssertExpectations(t)
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/RedLucky/potongin/app/delivery/api/auth"
+	"github.com/RedLucky/potongin/app/repository"
+	"github.com/RedLucky/potongin/config/db"
+	"github.com/RedLucky/potongin/domain"
+	"github.com/jinzhu/gorm"
+	"github.com/labstack/echo/v4"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// app is the whole server on an in-memory SQLite database and the memory
+// stores, as started with database.driver sqlite3 and redis.driver memory
+type app struct {
+	t      *testing.T
+	router *echo.Echo
+	db     *gorm.DB
+	outbox *outbox
+}
+
+// outbox keep the mails of the app in place of sending them
+type outbox struct {
+	mu    sync.Mutex
+	mails []domain.Mail
+}
+
+func (o *outbox) Send(ctx context.Context, mail domain.Mail) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.mails = append(o.mails, mail)
+	return nil
+}
+
+// count return the number of mails sent to the address
+func (o *outbox) count(to string) int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	count := 0
+	for _, mail := range o.mails {
+		if mail.To == to {
+			count++
+		}
+	}
+	return count
+}
+
+// token return the token of the link of the last mail sent to the address
+func (o *outbox) token(t *testing.T, to string) string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for i := len(o.mails) - 1; i >= 0; i-- {
+		if o.mails[i].To != to {
+			continue
+		}
+		link, err := url.Parse(strings.TrimSpace(o.mails[i].Body[strings.LastIndex(o.mails[i].Body, "\n\n"):]))
+		require.NoError(t, err)
+		return link.Query().Get("token")
+	}
+	t.Fatalf("no mail sent to %s", to)
+	return ""
+}
+
+func newApp(t *testing.T) *app {
+	viper.Set(`authentication.duration_access`, 15)
+	viper.Set(`authentication.duration_refresh`, 18)
+	viper.Set(`authentication.jwt_signature_access_key`, "access-secret")
+	viper.Set(`authentication.jwt_signature_refresh_key`, "refresh-secret")
+
+	database, err := db.Open(db.SQLite, ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { database.Conn.Close() })
+	require.NoError(t, checkSchema(database, true))
+
+	tokens := auth.NewMemoryTokenStore()
+	mails := &outbox{}
+	uc, err := newUsecases(database.Conn, tokens, repository.NewMemoryCache(100), mails, 5*time.Second)
+	require.NoError(t, err)
+	return &app{t: t, router: newRouter(uc, nil, tokens), db: database.Conn, outbox: mails}
+}
+
+// do send the request and decode the data of the response envelope
+func (a *app) do(method, path, accessToken string, body interface{}) (int, map[string]interface{}) {
+	payload, err := json.Marshal(body)
+	require.NoError(a.t, err)
+	req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if accessToken != "" {
+		req.Header.Set(echo.HeaderAuthorization, "Bearer "+accessToken)
+	}
+	rec := httptest.NewRecorder()
+	a.router.ServeHTTP(rec, req)
+
+	var envelope struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	require.NoError(a.t, json.Unmarshal(rec.Body.Bytes(), &envelope), rec.Body.String())
+	return rec.Code, envelope.Data
+}
+
+func TestEndToEnd(t *testing.T) {
+	a := newApp(t)
+	credentials := map[string]string{"email": "alice@example.com", "password": "Correct-horse-battery-9"}
+
+	code, _ := a.do(http.MethodPost, "/v1/users", "", map[string]string{
+		"username": "alice",
+		"email":    credentials["email"],
+		"password": credentials["password"],
+		"name":     "Alice",
+	})
+	require.Equal(t, http.StatusCreated, code)
+
+	code, _ = a.do(http.MethodPost, "/v1/auth/tokens", "", credentials)
+	assert.Equal(t, domain.ErrorEmailNotVerified.Status, code, "login before the email is verified")
+
+	// the front end posts the token of the mailed link
+	code, _ = a.do(http.MethodPost, "/v1/auth/email-verifications/confirm", "", map[string]string{
+		"token": a.outbox.token(t, credentials["email"]),
+	})
+	require.Equal(t, http.StatusOK, code)
+
+	code, data := a.do(http.MethodPost, "/v1/auth/tokens", "", credentials)
+	require.Equal(t, http.StatusOK, code)
+	accessToken := data["token"].(map[string]interface{})["access_token"].(string)
+
+	// the source of a link must answer
+	source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer source.Close()
+	code, _ = a.do(http.MethodPost, "/v1/links", accessToken, map[string]string{
+		"name":           "Docs",
+		"source_link":    source.URL + "/docs",
+		"generated_link": "docs",
+		"end_date":       time.Now().Add(24 * time.Hour).Format(time.RFC3339),
+	})
+	require.Equal(t, http.StatusCreated, code)
+
+	code, data = a.do(http.MethodPost, "/v1/visits", "", map[string]string{"url_generated": "docs"})
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, strings.TrimPrefix(source.URL, "http://")+"/docs", data["origin_url"])
+
+	code, _ = a.do(http.MethodPost, "/v1/visits", "", map[string]string{"url_generated": "blog"})
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestEndToEnd_Lockout(t *testing.T) {
+	viper.Set(`authentication.login_protection.backoff_after`, 10)
+	viper.Set(`authentication.login_protection.max_failures`, 3)
+	t.Cleanup(func() {
+		viper.Set(`authentication.login_protection.backoff_after`, 3)
+		viper.Set(`authentication.login_protection.max_failures`, 10)
+	})
+	a := newApp(t)
+	credentials := map[string]string{"email": "bob@example.com", "password": "Correct-horse-battery-9"}
+	code, _ := a.do(http.MethodPost, "/v1/users", "", map[string]string{
+		"username": "bob",
+		"email":    credentials["email"],
+		"password": credentials["password"],
+		"name":     "Bob",
+	})
+	require.Equal(t, http.StatusCreated, code)
+	code, _ = a.do(http.MethodPost, "/v1/auth/email-verifications/confirm", "", map[string]string{
+		"token": a.outbox.token(t, credentials["email"]),
+	})
+	require.Equal(t, http.StatusOK, code)
+
+	for i := 0; i < 3; i++ {
+		code, _ = a.do(http.MethodPost, "/v1/auth/tokens", "", map[string]string{"email": credentials["email"], "password": "guess"})
+		require.Equal(t, domain.ErrInvalidCredentials.Status, code)
+	}
+	code, _ = a.do(http.MethodPost, "/v1/auth/tokens", "", credentials)
+	require.Equal(t, domain.ErrAccountLocked.Status, code, "the right password is refused while locked")
+
+	// the owner unlocks the account with the link mailed in the background
+	require.Eventually(t, func() bool { return a.outbox.count(credentials["email"]) == 2 }, 5*time.Second, 10*time.Millisecond)
+	code, _ = a.do(http.MethodPost, "/v1/auth/unlocks", "", map[string]string{
+		"token": a.outbox.token(t, credentials["email"]),
+	})
+	require.Equal(t, http.StatusOK, code)
+
+	code, data := a.do(http.MethodPost, "/v1/auth/tokens", "", credentials)
+	require.Equal(t, http.StatusOK, code)
+	assert.NotEmpty(t, data["token"].(map[string]interface{})["access_token"])
+}
+
+func TestEndToEnd_SpoofedForwardedFor(t *testing.T) {
+	viper.Set(`authentication.login_protection.ip_max_failures`, 4)
+	t.Cleanup(func() { viper.Set(`authentication.login_protection.ip_max_failures`, 50) })
+	a := newApp(t)
+
+	// a caller sending a new address in the headers at each attempt, on other
+	// emails, is still limited by its own address
+	login := func(i int) int {
+		req := httptest.NewRequest(http.MethodPost, "/v1/auth/tokens", strings.NewReader(fmt.Sprintf(`{"email":"nobody%d@example.com","password":"guess"}`, i)))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		req.Header.Set(echo.HeaderXForwardedFor, fmt.Sprintf("203.0.113.%d", i))
+		req.Header.Set(echo.HeaderXRealIP, fmt.Sprintf("198.51.100.%d", i))
+		rec := httptest.NewRecorder()
+		a.router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	for i := 0; i < 2; i++ {
+		require.Equal(t, domain.ErrInvalidCredentials.Status, login(i))
+	}
+	assert.Equal(t, domain.ErrTooManyAttempts.Status, login(2))
+}
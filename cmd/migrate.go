@@ -0,0 +1,92 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/RedLucky/potongin/config/db"
+)
+
+const migrateUsage = "usage: migrate up | down [steps] | status | create <name>"
+
+// runMigrate run the migrate subcommand, create only writes files and does
+// not need the database
+func runMigrate(args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+	if args[0] == "create" {
+		if len(args) != 2 {
+			return errors.New(migrateUsage)
+		}
+		files, err := db.CreateMigration(db.MigrationsDir, args[1])
+		for _, file := range files {
+			fmt.Fprintln(out, "created", file)
+		}
+		return err
+	}
+
+	database := db.New()
+	defer database.Conn.Close()
+	migrator, err := db.NewMigrator(database)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up()
+		for _, migration := range applied {
+			fmt.Fprintf(out, "applied %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Fprintln(out, "the schema is up to date")
+		}
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return errors.New(migrateUsage)
+			}
+		}
+		reverted, err := migrator.Down(steps)
+		for _, migration := range reverted {
+			fmt.Fprintf(out, "reverted %04d_%s\n", migration.Version, migration.Name)
+		}
+		return err
+	case "status":
+		status, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		for _, s := range status {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(out, "%04d_%s\t%s\n", s.Version, s.Name, applied)
+		}
+		return nil
+	}
+	return errors.New(migrateUsage)
+}
+
+// checkSchema refuse to serve an out-of-date schema, unless
+// database.auto_migrate let the pending migrations be applied on start
+func checkSchema(database *db.Database, autoMigrate bool) error {
+	migrator, err := db.NewMigrator(database)
+	if err != nil {
+		return err
+	}
+	if !autoMigrate {
+		if err = migrator.Check(); err != nil {
+			return fmt.Errorf("%w, run the migrate up command or set database.auto_migrate", err)
+		}
+		return nil
+	}
+	_, err = migrator.Up()
+	return err
+}
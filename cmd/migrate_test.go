@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/RedLucky/potongin/config/db"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckSchema(t *testing.T) {
+	database, err := db.Open(db.SQLite, ":memory:")
+	require.NoError(t, err)
+	defer database.Conn.Close()
+
+	assert.ErrorIs(t, checkSchema(database, false), db.ErrSchemaOutdated)
+	assert.NoError(t, checkSchema(database, true))
+	assert.NoError(t, checkSchema(database, false))
+}
+
+func TestRunMigrate(t *testing.T) {
+	viper.Set(`database.driver`, db.SQLite)
+	viper.Set(`database.name`, t.TempDir()+"/potongin.db")
+	defer viper.Set(`database.driver`, "")
+	defer viper.Set(`database.name`, "")
+
+	var out bytes.Buffer
+	require.NoError(t, runMigrate([]string{"up"}, &out))
+	assert.Contains(t, out.String(), "applied 0001_initial_schema")
+
+	out.Reset()
+	require.NoError(t, runMigrate([]string{"status"}, &out))
+	assert.Contains(t, out.String(), "0001_initial_schema\tapplied ")
+
+	out.Reset()
+	require.NoError(t, runMigrate([]string{"down"}, &out))
+	assert.Equal(t, "reverted 0007_create_webhooks\n", out.String())
+
+	assert.Error(t, runMigrate([]string{"sideways"}, &out))
+	assert.Error(t, runMigrate(nil, &out))
+}
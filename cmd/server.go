@@ -2,27 +2,46 @@ package main
 
 import (
 	_delivery "github.com/RedLucky/potongin/app/delivery/api"
+	"github.com/RedLucky/potongin/app/delivery/api/auth"
 	_customMiddleware "github.com/RedLucky/potongin/app/delivery/api/middleware"
 	_AuthMiddleware "github.com/RedLucky/potongin/app/delivery/api/middleware/auth"
 	"github.com/RedLucky/potongin/app/delivery/api/response"
+	_rpc "github.com/RedLucky/potongin/app/delivery/rpc"
 	_repo "github.com/RedLucky/potongin/app/repository"
 	_uc "github.com/RedLucky/potongin/app/usecase"
 	"github.com/RedLucky/potongin/config/cache"
 	"github.com/RedLucky/potongin/config/db"
+	"github.com/RedLucky/potongin/config/mail"
+	"github.com/RedLucky/potongin/domain"
 
 	"log"
+	"net"
+	"os"
 	"time"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/gomodule/redigo/redis"
+	"github.com/jinzhu/gorm"
 	"github.com/labstack/echo/v4"
 	"github.com/spf13/viper"
+	"google.golang.org/grpc"
 )
 
-var (
-	redisHost string
-)
+// usecases are served by the routes, the routes of a nil usecase are still registered
+type usecases struct {
+	auth         domain.AuthUsecase
+	user         domain.UserUsecase
+	account      domain.AccountUsecase
+	privacy      domain.PrivacyUsecase
+	session      domain.SessionUsecase
+	mfa          domain.MfaUsecase
+	organization domain.OrganizationUsecase
+	generatedUrl domain.GeneratedUrlUsecase
+	webhook      domain.WebhookUsecase
+	admin        domain.AdminUsecase
+	audit        domain.AuditUsecase
+}
 
-func init() {
+func loadConfig() {
 	viper.SetConfigFile(`config/config.json`)
 	err := viper.ReadInConfig()
 	if err != nil {
@@ -32,49 +51,245 @@ func init() {
 	if viper.GetBool(`debug`) {
 		log.Println("Service RUN on DEBUG mode")
 	}
-	redisHost = viper.GetString(`redis.host`) + ":" + viper.GetString(`redis.port`)
 }
 
 func main() {
+	loadConfig()
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:], os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
 
-	mysql := db.New().Conn
-	redis := cache.New(redisHost)
+	database := db.New()
+	if err := checkSchema(database, viper.GetBool(`database.auto_migrate`)); err != nil {
+		log.Fatal(err)
+	}
+	dbConn := database.Conn
+	stopInvalidation := make(chan struct{})
+	tokens, keyStore, linkCache, closeStores := newStores(stopInvalidation)
 
 	defer func() {
-		err := mysql.Close()
+		err := dbConn.Close()
 		if err != nil {
 			log.Fatal(err)
 		}
 	}()
+	defer closeStores()
+	defer close(stopInvalidation)
+
+	keys, err := auth.LoadKeys(keyStore)
+	if err != nil {
+		log.Fatal(err)
+	}
+	auth.Keys = keys
+	if keys != nil {
+		stopRotation := make(chan struct{})
+		defer close(stopRotation)
+		rotation := time.Duration(viper.GetInt(`authentication.key_rotation_interval`)) * time.Hour
+		keys.StartRotation(viper.GetString(`authentication.signing_algorithm`), rotation, stopRotation)
+	}
 
 	timeoutContext := time.Duration(viper.GetInt("context.timeout")) * time.Second
+	mailer, err := mail.New()
+	if err != nil {
+		log.Fatal(err)
+	}
+	uc, err := newUsecases(dbConn, tokens, linkCache, mailer, timeoutContext)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if erasure := viper.GetInt(`privacy.erasure_interval`); erasure > 0 {
+		stopErasure := make(chan struct{})
+		defer close(stopErasure)
+		_uc.StartErasure(uc.privacy, time.Duration(erasure)*time.Hour, stopErasure)
+	}
+	if delivery := viper.GetInt(`webhook.delivery_interval`); delivery > 0 {
+		stopDelivery := make(chan struct{})
+		defer close(stopDelivery)
+		_uc.StartWebhookDelivery(uc.webhook, time.Duration(delivery)*time.Second, stopDelivery)
+	}
+	if expiry := viper.GetInt(`generated_url.expiry_interval`); expiry > 0 {
+		stopExpiry := make(chan struct{})
+		defer close(stopExpiry)
+		_uc.StartUrlExpiry(uc.generatedUrl, time.Duration(expiry)*time.Second, stopExpiry)
+	}
+
+	// the gRPC API is served on its own port, when configured
+	if address := viper.GetString(`grpc.address`); address != "" {
+		grpcServer, err := newGrpcServer(uc, tokens)
+		if err != nil {
+			log.Fatal(err)
+		}
+		listener, err := net.Listen("tcp", address)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer grpcServer.GracefulStop()
+		go func() {
+			if err := grpcServer.Serve(listener); err != nil {
+				log.Fatal(err)
+			}
+		}()
+	}
+
+	r := newRouter(uc, keys, tokens)
+	if proxies := viper.GetStringSlice(`server.trusted_proxies`); len(proxies) > 0 {
+		if r.IPExtractor, err = _customMiddleware.ForwardedIPExtractor(proxies); err != nil {
+			log.Fatal(err)
+		}
+	}
+	r.Logger.Fatal(r.Start(viper.GetString("server.address")))
+}
+
+// newStores return the stores of the tokens, the signing keys and the
+// redirects. They are kept in redis, or in the memory of the process when
+// redis.driver is memory; the invalidations of the redirects are listened
+// until stop is closed.
+func newStores(stop <-chan struct{}) (tokens domain.TokenStore, keys auth.KeyStore, linkCache domain.Cache, closeStores func()) {
+	capacity := viper.GetInt(`redis.memory_capacity`)
+	if capacity <= 0 {
+		capacity = 10000
+	}
+	if viper.GetString(`redis.driver`) == "memory" {
+		return auth.NewMemoryTokenStore(), auth.NewMemoryKeyStore(), _repo.NewMemoryCache(capacity), func() {}
+	}
+
+	redisConn := cache.New()
+	pool := redisConn.Pool
+	return auth.NewTokenStore(pool), auth.NewKeyStore(pool), newLinkCache(pool, capacity, stop), func() { redisConn.Close() }
+}
+
+// newLinkCache return the cache of the redirects: redis with the entries also
+// kept in memory for redis.local_ttl seconds, invalidated until stop is closed
+func newLinkCache(pool *redis.Pool, capacity int, stop <-chan struct{}) domain.Cache {
+	localTTL := 10 * time.Second
+	if viper.IsSet(`redis.local_ttl`) {
+		localTTL = time.Duration(viper.GetInt(`redis.local_ttl`)) * time.Second
+	}
+	if localTTL <= 0 {
+		return _repo.NewRedisCache(pool)
+	}
+	local := _repo.NewMemoryCache(capacity)
+	_repo.StartCacheInvalidation(pool, local, stop)
+	return _repo.NewTieredCache(local, _repo.NewRedisCache(pool), localTTL, pool)
+}
+
+// newUsecases build the repositories and the usecases on top of them
+func newUsecases(dbConn *gorm.DB, tokens domain.TokenStore, linkCache domain.Cache, mailer domain.Mailer, timeoutContext time.Duration) (usecases, error) {
+	// audit
+	auditRepo := _repo.NewAuditRepository(dbConn)
+	auditUc := _uc.NewAuditUsecase(auditRepo, timeoutContext)
+
 	// user
-	userRepo := _repo.NewUserRepository(mysql)
-	userUc := _uc.NewUserUsecase(userRepo, timeoutContext)
+	userRepo := _repo.NewUserRepository(dbConn)
+	userUc := _uc.NewUserUsecase(userRepo, auditRepo, timeoutContext)
 
 	// auth
-	authRepo := _repo.NewAuthRepository(mysql)
-	authUc := _uc.NewAuthUsecase(authRepo, timeoutContext, redis.Pool)
+	authRepo := _repo.NewAuthRepository(dbConn)
+	mfaRepo := _repo.NewMfaRepository(dbConn)
+	oidcProviders, err := auth.LoadOidcProviders()
+	if err != nil {
+		return usecases{}, err
+	}
+	authUc := _uc.NewAuthUsecase(authRepo, mfaRepo, auditRepo, timeoutContext, tokens, oidcProviders, mailer)
+	mfaUc := _uc.NewMfaUsecase(mfaRepo, userRepo, timeoutContext)
+
+	// account
+	accountRepo := _repo.NewAccountRepository(dbConn)
+	accountUc := _uc.NewAccountUsecase(accountRepo, auditRepo, timeoutContext, tokens, mailer)
+
+	// privacy
+	privacyRepo := _repo.NewPrivacyRepository(dbConn)
+	privacyUc := _uc.NewPrivacyUsecase(privacyRepo, timeoutContext, tokens)
+
+	// session
+	sessionUc := _uc.NewSessionUsecase(timeoutContext, tokens)
+
+	// organization
+	orgRepo := _repo.NewOrganizationRepository(dbConn)
+	orgUc := _uc.NewOrganizationUsecase(orgRepo, userRepo, timeoutContext, mailer)
+
+	// webhook
+	webhookRepo := _repo.NewWebhookRepository(dbConn)
+	webhookUc := _uc.NewWebhookUsecase(webhookRepo, timeoutContext)
 
 	// generated url
-	generatedUrlRepo := _repo.NewGeneratedUrlRepository(mysql)
-	generatedUrlUc := _uc.NewGeneratedUrlUsecase(generatedUrlRepo, timeoutContext, redis.Pool)
+	generatedUrlRepo := _repo.NewGeneratedUrlRepository(dbConn)
+	generatedUrlUc := _uc.NewGeneratedUrlUsecase(generatedUrlRepo, orgRepo, auditRepo, webhookRepo, timeoutContext, linkCache)
+
+	// admin
+	adminRepo := _repo.NewAdminRepository(dbConn)
+	adminUc := _uc.NewAdminUsecase(adminRepo, auditRepo, timeoutContext, tokens, linkCache, mailer)
 
+	return usecases{
+		auth:         authUc,
+		user:         userUc,
+		account:      accountUc,
+		privacy:      privacyUc,
+		session:      sessionUc,
+		mfa:          mfaUc,
+		organization: orgUc,
+		generatedUrl: generatedUrlUc,
+		webhook:      webhookUc,
+		admin:        adminUc,
+		audit:        auditUc,
+	}, nil
+}
+
+// newRouter register every route of the API
+func newRouter(uc usecases, keys *auth.KeySet, tokens domain.TokenStore) *echo.Echo {
 	r := echo.New()
+	r.HTTPErrorHandler = response.HTTPErrorHandler
+	// the headers of the caller are not trusted, unless server.trusted_proxies are set
+	r.IPExtractor = echo.ExtractIPDirect()
 	middL := _customMiddleware.New()
-	authMiddl := _AuthMiddleware.New(redis.Pool)
+	authMiddl := _AuthMiddleware.New(tokens)
 	response := response.New()
 	r.Use(echo.WrapMiddleware(middL.CorsMiddleware.Handler))
 	r.Use(middL.MiddlewareLogging)
+	r.Use(middL.ClientInfo)
 
 	r.GET("/stats", middL.Handle)
-	_delivery.NewAuthHandler(r, authUc, response)
-	_delivery.NewHitUrlHandler(r, generatedUrlUc, response)
-	apiProtect := r.Group("")
+	_delivery.NewDocsHandler(r)
+	_delivery.NewJwksHandler(r, keys)
+	_delivery.NewGraphqlHandler(r, authMiddl.Authentication, uc.generatedUrl, uc.user)
 
-	apiProtect.Use(authMiddl.Authentication)
-	_delivery.NewUserHandler(apiProtect, userUc, response)
-	_delivery.NewGeneratedUrlHandler(apiProtect, generatedUrlUc, response)
+	routes := _delivery.NewRoutes(r, authMiddl.Authentication, configDate(`api.legacy_deprecated_at`), configDate(`api.legacy_sunset`))
+	_delivery.NewAuthHandler(routes, uc.auth, response)
+	_delivery.NewHitUrlHandler(routes, uc.generatedUrl, response)
+	_delivery.NewUserHandler(routes, uc.user, response)
+	_delivery.NewAccountHandler(routes, uc.account, response)
+	_delivery.NewPrivacyHandler(routes, uc.privacy, response)
+	_delivery.NewSessionHandler(routes, uc.session, response)
+	_delivery.NewMfaHandler(routes, uc.mfa, response)
+	_delivery.NewOrganizationHandler(routes, uc.organization, response)
+	_delivery.NewGeneratedUrlHandler(routes, uc.generatedUrl, response)
+	_delivery.NewWebhookHandler(routes, uc.webhook, response)
+	_delivery.NewAdminHandler(routes, uc.admin, uc.audit, response)
+	return r
+}
 
-	r.Logger.Fatal(r.Start(viper.GetString("server.address")))
+// newGrpcServer register the gRPC services of the links, auth and users
+func newGrpcServer(uc usecases, tokens domain.TokenStore) (*grpc.Server, error) {
+	authenticator, err := _rpc.NewAuthenticator(tokens)
+	if err != nil {
+		return nil, err
+	}
+	return _rpc.NewServer(authenticator, uc.generatedUrl, uc.auth, uc.user), nil
+}
+
+// configDate read a 2006-01-02 date of the config, zero when it is not set
+func configDate(key string) time.Time {
+	value := viper.GetString(key)
+	if value == "" {
+		return time.Time{}
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		log.Fatalf("%s: %v", key, err)
+	}
+	return date
 }
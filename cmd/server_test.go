@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	_delivery "github.com/RedLucky/potongin/app/delivery/api"
+	"github.com/RedLucky/potongin/app/delivery/api/openapi"
+	"github.com/labstack/echo/v4"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// routes return the routes registered by the handlers, without the catch-all
+// routes echo add to the groups with middlewares
+func routes(r *echo.Echo) []*echo.Route {
+	notFound := runtime.FuncForPC(reflect.ValueOf(echo.NotFoundHandler).Pointer()).Name()
+	var registered []*echo.Route
+	for _, route := range r.Routes() {
+		if route.Name == notFound {
+			continue
+		}
+		registered = append(registered, route)
+	}
+	return registered
+}
+
+// unversioned are the routes outside of /v1 that are not legacy ones
+var unversioned = map[string]bool{
+	"/stats":                 true,
+	"/openapi.json":          true,
+	"/docs":                  true,
+	"/.well-known/jwks.json": true,
+	"/graphql":               true,
+}
+
+func TestRoutesAreDocumented(t *testing.T) {
+	r := newRouter(usecases{}, nil, nil)
+	doc := _delivery.OpenAPI()
+
+	registered := map[string]bool{}
+	for _, route := range routes(r) {
+		registered[route.Method+" "+route.Path] = true
+		op := doc.Operation(route.Method, route.Path)
+		if !assert.NotNil(t, op, "%s %s is not in the OpenAPI document", route.Method, route.Path) {
+			continue
+		}
+		legacy := !strings.HasPrefix(route.Path, "/v1/") && !unversioned[route.Path]
+		assert.Equal(t, legacy, op.Deprecated, "%s %s deprecated", route.Method, route.Path)
+	}
+	for path, item := range doc.Paths {
+		for method := range item {
+			echoPath := path
+			for _, segment := range strings.Split(path, "/") {
+				if strings.HasPrefix(segment, "{") {
+					echoPath = strings.Replace(echoPath, segment, ":"+strings.Trim(segment, "{}"), 1)
+				}
+			}
+			assert.True(t, registered[strings.ToUpper(method)+" "+echoPath], "%s %s is documented but not registered", method, path)
+		}
+	}
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	r := newRouter(usecases{}, nil, nil)
+
+	recorder := httptest.NewRecorder()
+	r.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	var doc openapi.Document
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &doc))
+	assert.Equal(t, openapi.Version, doc.OpenAPI)
+
+	login := doc.Operation(http.MethodPost, "/v1/auth/tokens")
+	require.NotNil(t, login)
+	assert.Equal(t, "#/components/schemas/Auth", login.RequestBody.Content["application/json"].Schema.Ref)
+	assert.ElementsMatch(t, []string{"email", "password"}, doc.Components.Schemas["Auth"].Required)
+	assert.Contains(t, doc.Components.Schemas["GeneratedUrl"].Properties, "source_link")
+	assert.Contains(t, doc.Components.Schemas["ErrorCode"].Enum, "url_not_found")
+	assert.Contains(t, doc.Components.Schemas["ErrorCode"].Enum, "validation_failed")
+
+	// every reference resolves
+	body := recorder.Body.String()
+	for _, part := range strings.Split(body, `"$ref":"#/components/schemas/`)[1:] {
+		name := part[:strings.Index(part, `"`)]
+		assert.Contains(t, doc.Components.Schemas, name)
+	}
+
+	recorder = httptest.NewRecorder()
+	r.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "openapi.json")
+}
+
+func TestLegacyRoutesAreDeprecated(t *testing.T) {
+	viper.Set(`api.legacy_deprecated_at`, "2026-11-01")
+	viper.Set(`api.legacy_sunset`, "2027-05-01")
+	defer viper.Reset()
+	r := newRouter(usecases{}, nil, nil)
+
+	// the tokens are missing, the handler answers before reaching the usecase
+	recorder := httptest.NewRecorder()
+	r.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/logout", strings.NewReader("{}")))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Equal(t, "@1793491200", recorder.Header().Get("Deprecation"))
+	assert.Equal(t, "Sat, 01 May 2027 00:00:00 GMT", recorder.Header().Get("Sunset"))
+	assert.Equal(t, `</v1/auth/tokens>; rel="successor-version"`, recorder.Header().Get("Link"))
+
+	recorder = httptest.NewRecorder()
+	r.ServeHTTP(recorder, httptest.NewRequest(http.MethodDelete, "/v1/auth/tokens", strings.NewReader("{}")))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Empty(t, recorder.Header().Get("Deprecation"))
+	assert.Empty(t, recorder.Header().Get("Sunset"))
+}
+
+func TestGraphqlRequiresAuthentication(t *testing.T) {
+	r := newRouter(usecases{}, nil, nil)
+
+	request := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"{ me { id } }"}`))
+	request.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	recorder := httptest.NewRecorder()
+	r.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), `"error_code":"unauthorized"`)
+}
@@ -1,29 +1,197 @@
 package cache
 
 import (
-	"log"
-	"os"
+	"errors"
+	"fmt"
+	"net"
+	"time"
 
 	"github.com/gomodule/redigo/redis"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
 )
 
 type RedisConn struct {
 	Pool *redis.Pool
 }
 
-func New(redisAddress string) *RedisConn {
+// Options of the connections to redis, the timeouts are disabled when 0
+type Options struct {
+	Address  string
+	Username string
+	Password string
+	DB       int
+
+	TLS           bool
+	TLSSkipVerify bool
+
+	ConnectTimeout time.Duration
+	ReadTimeout    time.Duration
+	WriteTimeout   time.Duration
+
+	MaxIdle     int
+	MaxActive   int
+	IdleTimeout time.Duration
+	// HealthCheck is how long a connection stay idle before it is checked
+	// again when borrowed
+	HealthCheck time.Duration
+
+	// MasterName is looked up on the sentinels of SentinelAddresses, in place
+	// of Address, each time a connection is opened
+	MasterName        string
+	SentinelAddresses []string
+	SentinelPassword  string
+}
+
+// New connect to the redis of the config. The connections are opened on
+// demand, a redis down at start is only logged.
+func New() *RedisConn {
+	redisConn := Open(ConfigOptions())
+	conn := redisConn.Pool.Get()
+	defer conn.Close()
+	if _, err := conn.Do("PING"); err != nil {
+		logrus.WithError(err).Warn("redis is unavailable")
+	}
+	return redisConn
+}
+
+// ConfigOptions read the options of the redis config, the timeouts are in
+// milliseconds and the durations of the pool in seconds
+func ConfigOptions() Options {
+	return Options{
+		Address:           net.JoinHostPort(viper.GetString(`redis.host`), viper.GetString(`redis.port`)),
+		Username:          viper.GetString(`redis.username`),
+		Password:          viper.GetString(`redis.password`),
+		DB:                viper.GetInt(`redis.db`),
+		TLS:               viper.GetBool(`redis.tls`),
+		TLSSkipVerify:     viper.GetBool(`redis.tls_skip_verify`),
+		ConnectTimeout:    duration(`redis.connect_timeout`, 1000, time.Millisecond),
+		ReadTimeout:       duration(`redis.read_timeout`, 1000, time.Millisecond),
+		WriteTimeout:      duration(`redis.write_timeout`, 1000, time.Millisecond),
+		MaxIdle:           integer(`redis.max_idle`, 80),
+		MaxActive:         integer(`redis.max_active`, 12000),
+		IdleTimeout:       duration(`redis.idle_timeout`, 240, time.Second),
+		HealthCheck:       duration(`redis.health_check`, 30, time.Second),
+		MasterName:        viper.GetString(`redis.sentinel.master`),
+		SentinelAddresses: viper.GetStringSlice(`redis.sentinel.addresses`),
+		SentinelPassword:  viper.GetString(`redis.sentinel.password`),
+	}
+}
+
+// Open return the pool of the options, a failing dial is returned to the
+// borrower of the connection
+func Open(options Options) *RedisConn {
 	return &RedisConn{
 		Pool: &redis.Pool{
-			MaxIdle:   80,
-			MaxActive: 12000,
-			Dial: func() (redis.Conn, error) {
-				conn, err := redis.Dial("tcp", redisAddress)
-				if err != nil {
-					log.Printf("ERROR: fail init redis pool: %s", err.Error())
-					os.Exit(1)
+			MaxIdle:     options.MaxIdle,
+			MaxActive:   options.MaxActive,
+			IdleTimeout: options.IdleTimeout,
+			Dial:        options.dial,
+			TestOnBorrow: func(conn redis.Conn, idleSince time.Time) error {
+				if time.Since(idleSince) < options.HealthCheck {
+					return nil
+				}
+				// after a failover the old master is a replica
+				if options.MasterName != "" {
+					return checkMaster(conn)
 				}
-				return conn, err
+				_, err := conn.Do("PING")
+				return err
 			},
 		},
 	}
 }
+
+// Close the pool
+func (c *RedisConn) Close() error {
+	return c.Pool.Close()
+}
+
+func (o Options) dial() (redis.Conn, error) {
+	if o.MasterName == "" {
+		return redis.Dial("tcp", o.Address, o.dialOptions(o.Username, o.Password, o.DB)...)
+	}
+
+	address, err := o.sentinelMaster()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := redis.Dial("tcp", address, o.dialOptions(o.Username, o.Password, o.DB)...)
+	if err != nil {
+		return nil, err
+	}
+	// the sentinels may still point to a master being demoted
+	if err = checkMaster(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+func (o Options) dialOptions(username, password string, db int) []redis.DialOption {
+	return []redis.DialOption{
+		redis.DialUsername(username),
+		redis.DialPassword(password),
+		redis.DialDatabase(db),
+		redis.DialUseTLS(o.TLS),
+		redis.DialTLSSkipVerify(o.TLSSkipVerify),
+		redis.DialConnectTimeout(o.ConnectTimeout),
+		redis.DialReadTimeout(o.ReadTimeout),
+		redis.DialWriteTimeout(o.WriteTimeout),
+	}
+}
+
+// sentinelMaster ask the sentinels in turn for the address of the master
+func (o Options) sentinelMaster() (string, error) {
+	err := errors.New("redis.sentinel.addresses is empty")
+	for _, sentinel := range o.SentinelAddresses {
+		var address string
+		if address, err = o.askSentinel(sentinel); err == nil {
+			return address, nil
+		}
+	}
+	return "", fmt.Errorf("no sentinel knows the master %q: %w", o.MasterName, err)
+}
+
+func (o Options) askSentinel(sentinel string) (string, error) {
+	conn, err := redis.Dial("tcp", sentinel, o.dialOptions("", o.SentinelPassword, 0)...)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	master, err := redis.Strings(conn.Do("SENTINEL", "get-master-addr-by-name", o.MasterName))
+	if err != nil {
+		return "", err
+	}
+	if len(master) != 2 {
+		return "", fmt.Errorf("unexpected sentinel reply %q", master)
+	}
+	return net.JoinHostPort(master[0], master[1]), nil
+}
+
+// checkMaster fail when the server of the connection is not a master
+func checkMaster(conn redis.Conn) error {
+	role, err := redis.Values(conn.Do("ROLE"))
+	if err != nil {
+		return err
+	}
+	if len(role) == 0 {
+		return errors.New("empty ROLE reply")
+	}
+	if name, _ := redis.String(role[0], nil); name != "master" {
+		return fmt.Errorf("redis server is a %s, not the master", name)
+	}
+	return nil
+}
+
+// duration of the config in unit, or defaultValue when the key is not set
+func duration(key string, defaultValue int, unit time.Duration) time.Duration {
+	return time.Duration(integer(key, defaultValue)) * unit
+}
+
+func integer(key string, defaultValue int) int {
+	if viper.IsSet(key) {
+		return viper.GetInt(key)
+	}
+	return defaultValue
+}
@@ -0,0 +1,173 @@
+package cache_test
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/RedLucky/potongin/config/cache"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigOptions(t *testing.T) {
+	viper.Set(`redis.host`, "localhost")
+	viper.Set(`redis.port`, "6379")
+	viper.Set(`redis.read_timeout`, 250)
+	defer viper.Set(`redis.read_timeout`, nil)
+
+	options := cache.ConfigOptions()
+
+	assert.Equal(t, "localhost:6379", options.Address)
+	assert.Equal(t, time.Second, options.ConnectTimeout)
+	assert.Equal(t, 250*time.Millisecond, options.ReadTimeout)
+	assert.Equal(t, 30*time.Second, options.HealthCheck)
+}
+
+func TestOpen(t *testing.T) {
+	t.Run("unreachable", func(t *testing.T) {
+		redis := cache.Open(cache.Options{Address: "127.0.0.1:1", ConnectTimeout: 100 * time.Millisecond})
+		defer redis.Close()
+
+		conn := redis.Pool.Get()
+		defer conn.Close()
+		_, err := conn.Do("PING")
+		assert.Error(t, err)
+	})
+
+	t.Run("acl-user-and-database", func(t *testing.T) {
+		server := miniredis.RunT(t)
+		server.RequireUserAuth("potongin", "secret")
+
+		redis := cache.Open(cache.Options{Address: server.Addr(), Username: "potongin", Password: "secret", DB: 2})
+		defer redis.Close()
+		conn := redis.Pool.Get()
+		defer conn.Close()
+		_, err := conn.Do("SET", "greeting", "hello")
+		require.NoError(t, err)
+
+		value, err := server.DB(2).Get("greeting")
+		assert.NoError(t, err)
+		assert.Equal(t, "hello", value)
+
+		wrong := cache.Open(cache.Options{Address: server.Addr(), Username: "potongin", Password: "guess"})
+		defer wrong.Close()
+		_, err = wrong.Pool.Get().Do("PING")
+		assert.Error(t, err)
+	})
+
+	t.Run("idle-connection-checked", func(t *testing.T) {
+		server := miniredis.RunT(t)
+		redis := cache.Open(cache.Options{Address: server.Addr(), MaxIdle: 1})
+		defer redis.Close()
+
+		conn := redis.Pool.Get()
+		_, err := conn.Do("PING")
+		require.NoError(t, err)
+		conn.Close()
+
+		// the idle connection is broken by the restart
+		server.Close()
+		require.NoError(t, server.Restart())
+
+		conn = redis.Pool.Get()
+		defer conn.Close()
+		_, err = conn.Do("PING")
+		assert.NoError(t, err)
+	})
+}
+
+func TestOpen_Sentinel(t *testing.T) {
+	var replica int32
+	server := newFakeSentinel(t, &replica)
+	redis := cache.Open(cache.Options{
+		MasterName:        "potongin",
+		SentinelAddresses: []string{"127.0.0.1:1", server},
+		ConnectTimeout:    100 * time.Millisecond,
+		MaxIdle:           1,
+	})
+	defer redis.Close()
+
+	conn := redis.Pool.Get()
+	_, err := conn.Do("PING")
+	assert.NoError(t, err)
+	conn.Close()
+
+	// a demoted master is dropped from the pool and not dialed again
+	atomic.StoreInt32(&replica, 1)
+	conn = redis.Pool.Get()
+	defer conn.Close()
+	_, err = conn.Do("PING")
+	assert.Error(t, err)
+}
+
+// newFakeSentinel serve a sentinel that name itself the master of any name,
+// and answer ROLE with slave once replica is set
+func newFakeSentinel(t *testing.T, replica *int32) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	host, port, _ := net.SplitHostPort(listener.Addr().String())
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				reader := bufio.NewReader(conn)
+				for {
+					command, err := readCommand(reader)
+					if err != nil {
+						return
+					}
+					switch strings.ToUpper(command[0]) {
+					case "SENTINEL":
+						fmt.Fprintf(conn, "*2\r\n$%d\r\n%s\r\n$%d\r\n%s\r\n", len(host), host, len(port), port)
+					case "ROLE":
+						role := "master"
+						if atomic.LoadInt32(replica) == 1 {
+							role = "slave"
+						}
+						fmt.Fprintf(conn, "*1\r\n$%d\r\n%s\r\n", len(role), role)
+					default:
+						fmt.Fprint(conn, "+PONG\r\n")
+					}
+				}
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+// readCommand read a command sent as an array of bulk strings
+func readCommand(reader *bufio.Reader) ([]string, error) {
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(line)[1:])
+	if err != nil {
+		return nil, err
+	}
+	command := make([]string, count)
+	for i := range command {
+		if _, err = reader.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		if command[i], err = reader.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		command[i] = strings.TrimSpace(command[i])
+	}
+	return command, nil
+}
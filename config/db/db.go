@@ -0,0 +1,85 @@
+package db
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/jinzhu/gorm"
+	"github.com/spf13/viper"
+)
+
+// the drivers of database.driver, the repositories run on each of them
+const (
+	MySQL    = "mysql"
+	Postgres = "postgres"
+	SQLite   = "sqlite3"
+)
+
+// Database is the connection to the database of the config
+type Database struct {
+	Conn   *gorm.DB
+	Driver string
+}
+
+func New() *Database {
+	driver := viper.GetString(`database.driver`)
+	if driver == "" {
+		driver = MySQL
+	}
+	dsn, err := DSN(driver)
+	if err != nil {
+		log.Fatal(err)
+	}
+	database, err := Open(driver, dsn)
+	if err != nil {
+		log.Fatal(err)
+		panic("failed to connect database")
+	}
+	return database
+}
+
+// Open connect to the database of the driver
+func Open(driver, dsn string) (*Database, error) {
+	dbConn, err := gorm.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	if driver == SQLite {
+		// SQLite has a single writer, and each connection to :memory: its own database
+		dbConn.DB().SetMaxOpenConns(1)
+	}
+
+	return &Database{
+		Conn:   dbConn,
+		Driver: driver,
+	}, nil
+}
+
+// DSN build the data source name of the driver from the database config
+func DSN(driver string) (string, error) {
+	switch driver {
+	case MySQL:
+		return mysqlDSN(), nil
+	case Postgres:
+		return postgresDSN(), nil
+	case SQLite:
+		return sqliteDSN(), nil
+	}
+	return "", fmt.Errorf("database.driver %q is not supported, use %q, %q or %q", driver, MySQL, Postgres, SQLite)
+}
+
+// timezone is the location of the times read from the database
+func timezone() string {
+	if tz := viper.GetString(`database.timezone`); tz != "" {
+		return tz
+	}
+	return "Asia/Jakarta"
+}
+
+// port of the config, or the default one of the driver
+func port(defaultPort string) string {
+	if p := viper.GetString(`database.port`); p != "" {
+		return p
+	}
+	return defaultPort
+}
@@ -0,0 +1,42 @@
+package db_test
+
+import (
+	"testing"
+
+	"github.com/RedLucky/potongin/config/db"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDSN(t *testing.T) {
+	viper.Set(`database.host`, "localhost")
+	viper.Set(`database.user`, "potongin")
+	viper.Set(`database.pass`, "it's secret")
+	viper.Set(`database.name`, "potongin")
+
+	t.Run("mysql", func(t *testing.T) {
+		dsn, err := db.DSN(db.MySQL)
+		assert.NoError(t, err)
+		assert.Equal(t, "potongin:it's secret@tcp(localhost:3306)/potongin?loc=Asia%2FJakarta&parseTime=1", dsn)
+	})
+
+	t.Run("postgres", func(t *testing.T) {
+		viper.Set(`database.timezone`, "UTC")
+		defer viper.Set(`database.timezone`, "")
+
+		dsn, err := db.DSN(db.Postgres)
+		assert.NoError(t, err)
+		assert.Equal(t, `host='localhost' port='5432' user='potongin' password='it\'s secret' dbname='potongin' sslmode='disable' timezone='UTC' binary_parameters=yes`, dsn)
+	})
+
+	t.Run("sqlite", func(t *testing.T) {
+		dsn, err := db.DSN(db.SQLite)
+		assert.NoError(t, err)
+		assert.Equal(t, "potongin?_busy_timeout=5000", dsn)
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		_, err := db.DSN("sqlserver")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,289 @@
+package db
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// MigrationsDir is where the migrations of each driver are kept, relative to
+// the root of the repository
+const MigrationsDir = "config/db/migrations"
+
+//go:embed migrations
+var migrationFiles embed.FS
+
+// migrationFile is <version>_<name>.<up|down>.sql
+var migrationFile = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// ErrSchemaOutdated is returned when migrations are waiting to be applied
+var ErrSchemaOutdated = errors.New("the database schema is not up to date")
+
+// Migration is a versioned change of the schema, with the SQL to revert it
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus tell if and when a migration was applied
+type MigrationStatus struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// SchemaMigration is a row of the version table, one per applied migration
+type SchemaMigration struct {
+	Version   int64 `gorm:"primary_key;auto_increment:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+// TableName is the version table
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Migrations return the migrations of the driver embedded in the binary,
+// the oldest first
+func Migrations(driver string) ([]Migration, error) {
+	dir := path.Join("migrations", driver)
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for the driver %q", driver)
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		match := migrationFile.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("%s: the name of a migration is <version>_<name>.<up|down>.sql", entry.Name())
+		}
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+		content, err := fs.ReadFile(migrationFiles, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("%s: the version %d is already taken by %s", entry.Name(), version, migration.Name)
+		}
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("the migration %d_%s needs both an up and a down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrator apply the migrations of its driver and record them in the
+// version table
+type Migrator struct {
+	Conn       *gorm.DB
+	Migrations []Migration
+}
+
+func NewMigrator(database *Database) (*Migrator, error) {
+	migrations, err := Migrations(database.Driver)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{
+		Conn:       database.Conn,
+		Migrations: migrations,
+	}, nil
+}
+
+// applied return the applied migrations by version, the version table is
+// created on the first call
+func (m *Migrator) applied() (map[int64]SchemaMigration, error) {
+	if err := m.Conn.AutoMigrate(&SchemaMigration{}).Error; err != nil {
+		return nil, err
+	}
+	var rows []SchemaMigration
+	if err := m.Conn.Order("version").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]SchemaMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	applied, err := m.applied()
+	if err != nil {
+		return nil, err
+	}
+	status := make([]MigrationStatus, len(m.Migrations))
+	for i, migration := range m.Migrations {
+		status[i].Migration = migration
+		if row, ok := applied[migration.Version]; ok {
+			appliedAt := row.AppliedAt
+			status[i].AppliedAt = &appliedAt
+		}
+	}
+	return status, nil
+}
+
+// Pending return the migrations not applied yet
+func (m *Migrator) Pending() (pending []Migration, err error) {
+	status, err := m.Status()
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range status {
+		if s.AppliedAt == nil {
+			pending = append(pending, s.Migration)
+		}
+	}
+	return
+}
+
+// Check fail with ErrSchemaOutdated while migrations are pending
+func (m *Migrator) Check() error {
+	pending, err := m.Pending()
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: %d migrations pending, from %d_%s", ErrSchemaOutdated, len(pending), pending[0].Version, pending[0].Name)
+	}
+	return nil
+}
+
+// Up apply the pending migrations, the oldest first
+func (m *Migrator) Up() (applied []Migration, err error) {
+	pending, err := m.Pending()
+	if err != nil {
+		return nil, err
+	}
+	for _, migration := range pending {
+		err = m.run(migration.Up, func(tx *gorm.DB) error {
+			return tx.Create(&SchemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now()}).Error
+		})
+		if err != nil {
+			return applied, fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		applied = append(applied, migration)
+	}
+	return applied, nil
+}
+
+// Down revert the steps latest applied migrations
+func (m *Migrator) Down(steps int) (reverted []Migration, err error) {
+	status, err := m.Status()
+	if err != nil {
+		return nil, err
+	}
+	for i := len(status) - 1; i >= 0 && len(reverted) < steps; i-- {
+		if status[i].AppliedAt == nil {
+			continue
+		}
+		migration := status[i].Migration
+		err = m.run(migration.Down, func(tx *gorm.DB) error {
+			return tx.Where("version = ?", migration.Version).Delete(&SchemaMigration{}).Error
+		})
+		if err != nil {
+			return reverted, fmt.Errorf("migration %d_%s: %w", migration.Version, migration.Name, err)
+		}
+		reverted = append(reverted, migration)
+	}
+	return reverted, nil
+}
+
+// run the statements of a migration and record it in one transaction, MySQL
+// still commits each statement changing the schema on its own
+func (m *Migrator) run(script string, record func(tx *gorm.DB) error) error {
+	return m.Conn.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range splitStatements(script) {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return record(tx)
+	})
+}
+
+// splitStatements split a script on the semicolons ending a line, the
+// drivers do not all run several statements at once
+func splitStatements(script string) (statements []string) {
+	var current []string
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current = append(current, line)
+		if strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.Join(current, "\n"))
+			current = nil
+		}
+	}
+	if len(current) > 0 {
+		statements = append(statements, strings.Join(current, "\n"))
+	}
+	return
+}
+
+// CreateMigration write the empty up and down files of a new migration for
+// every driver under dir, numbered after the latest one there
+func CreateMigration(dir, name string) (files []string, err error) {
+	if !regexp.MustCompile(`^\w+$`).MatchString(name) {
+		return nil, fmt.Errorf("%q: the name of a migration is made of letters, digits and underscores", name)
+	}
+	drivers := []string{MySQL, Postgres, SQLite}
+	var latest int64
+	for _, driver := range drivers {
+		entries, err := os.ReadDir(filepath.Join(dir, driver))
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if match := migrationFile.FindStringSubmatch(entry.Name()); match != nil {
+				if version, _ := strconv.ParseInt(match[1], 10, 64); version > latest {
+					latest = version
+				}
+			}
+		}
+	}
+
+	for _, driver := range drivers {
+		for _, direction := range []string{"up", "down"} {
+			file := filepath.Join(dir, driver, fmt.Sprintf("%04d_%s.%s.sql", latest+1, name, direction))
+			content := fmt.Sprintf("-- %s, %s\n", name, direction)
+			if err = os.WriteFile(file, []byte(content), 0644); err != nil {
+				return files, err
+			}
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
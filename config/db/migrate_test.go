@@ -0,0 +1,162 @@
+package db_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/RedLucky/potongin/config/db"
+	"github.com/RedLucky/potongin/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrationsOfEveryDriver(t *testing.T) {
+	sqlite, err := db.Migrations(db.SQLite)
+	require.NoError(t, err)
+	require.NotEmpty(t, sqlite)
+
+	for _, driver := range []string{db.MySQL, db.Postgres} {
+		migrations, err := db.Migrations(driver)
+		require.NoError(t, err)
+		require.Len(t, migrations, len(sqlite), driver)
+		for i, migration := range migrations {
+			assert.Equal(t, sqlite[i].Version, migration.Version, driver)
+			assert.Equal(t, sqlite[i].Name, migration.Name, driver)
+		}
+	}
+}
+
+func TestMigrator(t *testing.T) {
+	database, err := db.Open(db.SQLite, ":memory:")
+	require.NoError(t, err)
+	defer database.Conn.Close()
+	migrator, err := db.NewMigrator(database)
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, migrator.Check(), db.ErrSchemaOutdated)
+	applied, err := migrator.Up()
+	require.NoError(t, err)
+	assert.Len(t, applied, len(migrator.Migrations))
+	assert.NoError(t, migrator.Check())
+
+	// the migrated schema has every column of the models
+	for _, model := range db.Models {
+		scope := database.Conn.NewScope(model)
+		require.True(t, database.Conn.HasTable(model), scope.TableName())
+		for _, field := range scope.GetModelStruct().StructFields {
+			if field.IsNormal {
+				assert.True(t, scope.Dialect().HasColumn(scope.TableName(), field.DBName), "%s.%s", scope.TableName(), field.DBName)
+			}
+		}
+	}
+
+	status, err := migrator.Status()
+	require.NoError(t, err)
+	for _, s := range status {
+		assert.NotNil(t, s.AppliedAt, s.Name)
+	}
+
+	reverted, err := migrator.Down(len(migrator.Migrations))
+	require.NoError(t, err)
+	assert.Len(t, reverted, len(migrator.Migrations))
+	for _, model := range db.Models {
+		assert.False(t, database.Conn.HasTable(model))
+	}
+	pending, err := migrator.Pending()
+	require.NoError(t, err)
+	assert.Len(t, pending, len(migrator.Migrations))
+}
+
+func TestCreateMigration(t *testing.T) {
+	dir := t.TempDir()
+	for _, driver := range []string{db.MySQL, db.Postgres, db.SQLite} {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, driver), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, driver, "0007_links.up.sql"), nil, 0644))
+	}
+
+	files, err := db.CreateMigration(dir, "add_link_tags")
+	require.NoError(t, err)
+	assert.Len(t, files, 6)
+	assert.FileExists(t, filepath.Join(dir, db.Postgres, "0008_add_link_tags.down.sql"))
+
+	_, err = db.CreateMigration(dir, "add link tags")
+	assert.Error(t, err)
+}
+
+// the models of the first release, before the migrations
+type baselineUser struct {
+	ID            int64  `gorm:"primary_key;auto_increment"`
+	Username      string `gorm:"size:12;not null;unique"`
+	Email         string `gorm:"size:165;not null;unique"`
+	Password      string `gorm:"size:125;not null;"`
+	Name          string `gorm:"size:125;not null;"`
+	EmailVerified string `gorm:"size:1;not null;"`
+	UpdatedAt     time.Time
+	CreatedAt     time.Time
+}
+
+func (baselineUser) TableName() string { return "users" }
+
+type baselineVerifyEmail struct {
+	ID         int64
+	Token      string
+	UserId     int64
+	Verified   string
+	CreatedAt  time.Time
+	VerifiedAt time.Time
+}
+
+func (baselineVerifyEmail) TableName() string { return "verify_emails" }
+
+type baselineGeneratedUrl struct {
+	ID        int64 `gorm:"primary_key;auto_increment"`
+	UserId    int64
+	Name      string
+	Source    string
+	Generated string
+	TotalHits int64
+	IsActive  string
+	StartDate time.Time
+	EndDate   time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (baselineGeneratedUrl) TableName() string { return "generated_urls" }
+
+func TestMigrator_BaselineDatabase(t *testing.T) {
+	database, err := db.Open(db.SQLite, ":memory:")
+	require.NoError(t, err)
+	defer database.Conn.Close()
+	require.NoError(t, database.Conn.AutoMigrate(&baselineUser{}, &baselineVerifyEmail{}, &baselineGeneratedUrl{}).Error)
+	require.NoError(t, database.Conn.Create(&baselineUser{Username: "LFR", Email: "lucky@kryptopos.com", Password: "hash", Name: "Lucky", EmailVerified: "Y"}).Error)
+	require.NoError(t, database.Conn.Create(&baselineGeneratedUrl{UserId: 1, Name: "docs", Source: "example.com/docs", Generated: "docs", IsActive: "Y"}).Error)
+
+	migrator, err := db.NewMigrator(database)
+	require.NoError(t, err)
+	_, err = migrator.Up()
+	require.NoError(t, err)
+
+	for _, model := range db.Models {
+		scope := database.Conn.NewScope(model)
+		require.True(t, database.Conn.HasTable(model), scope.TableName())
+		for _, field := range scope.GetModelStruct().StructFields {
+			if field.IsNormal {
+				assert.True(t, scope.Dialect().HasColumn(scope.TableName(), field.DBName), "%s.%s", scope.TableName(), field.DBName)
+			}
+		}
+	}
+
+	// the existing rows get the defaults of the new columns
+	var user domain.User
+	require.NoError(t, database.Conn.First(&user).Error)
+	assert.Equal(t, "lucky@kryptopos.com", user.Email)
+	assert.Equal(t, domain.UserRoleUser, user.Role)
+	assert.Equal(t, "N", user.ResetRequired)
+	var url domain.GeneratedUrl
+	require.NoError(t, database.Conn.First(&url).Error)
+	assert.Equal(t, "docs", url.Generated)
+	assert.Equal(t, int64(0), url.OrgId)
+}
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS generated_urls;
+DROP TABLE IF EXISTS verify_emails;
+DROP TABLE IF EXISTS users;
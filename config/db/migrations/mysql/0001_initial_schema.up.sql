@@ -0,0 +1,39 @@
+-- the tables of the first release, IF NOT EXISTS lets the databases created
+-- by its gorm models adopt the migrations
+
+CREATE TABLE IF NOT EXISTS users (
+    id BIGINT NOT NULL AUTO_INCREMENT,
+    username VARCHAR(12) NOT NULL UNIQUE,
+    email VARCHAR(165) NOT NULL UNIQUE,
+    password VARCHAR(125) NOT NULL,
+    name VARCHAR(125) NOT NULL,
+    email_verified VARCHAR(1) NOT NULL,
+    updated_at DATETIME NULL,
+    created_at DATETIME NULL,
+    PRIMARY KEY (id)
+);
+
+CREATE TABLE IF NOT EXISTS verify_emails (
+    id BIGINT NOT NULL AUTO_INCREMENT,
+    token VARCHAR(255),
+    user_id BIGINT,
+    verified VARCHAR(255),
+    created_at DATETIME NULL,
+    verified_at DATETIME NULL,
+    PRIMARY KEY (id)
+);
+
+CREATE TABLE IF NOT EXISTS generated_urls (
+    id BIGINT NOT NULL AUTO_INCREMENT,
+    user_id BIGINT,
+    name VARCHAR(255),
+    source VARCHAR(255),
+    generated VARCHAR(255),
+    total_hits BIGINT,
+    is_active VARCHAR(255),
+    start_date DATETIME NULL,
+    end_date DATETIME NULL,
+    created_at DATETIME NULL,
+    updated_at DATETIME NULL,
+    PRIMARY KEY (id)
+);
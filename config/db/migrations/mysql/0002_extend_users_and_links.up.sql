@@ -0,0 +1,14 @@
+-- the roles, suspensions and forced resets of the users, the workspace and
+-- the takedown of the links
+
+ALTER TABLE users
+    ADD COLUMN role VARCHAR(16) NOT NULL DEFAULT 'user',
+    ADD COLUMN suspended_at DATETIME NULL,
+    ADD COLUMN suspend_reason VARCHAR(255),
+    ADD COLUMN reset_required VARCHAR(1) NOT NULL DEFAULT 'N';
+
+ALTER TABLE generated_urls
+    ADD COLUMN org_id BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN taken_down_at DATETIME NULL,
+    ADD COLUMN takedown_reason VARCHAR(255),
+    ADD KEY idx_generated_urls_org_id (org_id);
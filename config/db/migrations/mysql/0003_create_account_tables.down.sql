@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS mfa_recovery_codes;
+DROP TABLE IF EXISTS user_mfas;
+DROP TABLE IF EXISTS email_changes;
+DROP TABLE IF EXISTS user_identities;
+DROP TABLE IF EXISTS reset_passwords;
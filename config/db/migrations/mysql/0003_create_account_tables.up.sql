@@ -0,0 +1,55 @@
+CREATE TABLE reset_passwords (
+    id BIGINT NOT NULL AUTO_INCREMENT,
+    user_id BIGINT NOT NULL,
+    token VARCHAR(64) NOT NULL UNIQUE,
+    expires_at DATETIME NULL,
+    used_at DATETIME NULL,
+    created_at DATETIME NULL,
+    PRIMARY KEY (id),
+    KEY idx_reset_passwords_user_id (user_id)
+);
+
+CREATE TABLE user_identities (
+    id BIGINT NOT NULL AUTO_INCREMENT,
+    user_id BIGINT NOT NULL,
+    provider VARCHAR(64) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    email VARCHAR(165),
+    created_at DATETIME NULL,
+    PRIMARY KEY (id),
+    KEY idx_user_identities_user_id (user_id),
+    UNIQUE KEY idx_identity_subject (provider, subject)
+);
+
+CREATE TABLE email_changes (
+    id BIGINT NOT NULL AUTO_INCREMENT,
+    user_id BIGINT NOT NULL,
+    new_email VARCHAR(165) NOT NULL,
+    token VARCHAR(64) NOT NULL UNIQUE,
+    expires_at DATETIME NULL,
+    created_at DATETIME NULL,
+    PRIMARY KEY (id),
+    KEY idx_email_changes_user_id (user_id)
+);
+
+CREATE TABLE user_mfas (
+    id BIGINT NOT NULL AUTO_INCREMENT,
+    user_id BIGINT NOT NULL UNIQUE,
+    secret VARCHAR(64) NOT NULL,
+    enabled VARCHAR(1) NOT NULL,
+    last_used_step BIGINT,
+    created_at DATETIME NULL,
+    confirmed_at DATETIME NULL,
+    PRIMARY KEY (id)
+);
+
+CREATE TABLE mfa_recovery_codes (
+    id BIGINT NOT NULL AUTO_INCREMENT,
+    user_id BIGINT NOT NULL,
+    code_hash VARCHAR(64) NOT NULL,
+    used VARCHAR(1) NOT NULL,
+    used_at DATETIME NULL,
+    created_at DATETIME NULL,
+    PRIMARY KEY (id),
+    KEY idx_mfa_recovery_codes_user_id (user_id)
+);
@@ -0,0 +1,13 @@
+CREATE TABLE click_events (
+    id BIGINT NOT NULL AUTO_INCREMENT,
+    url_id BIGINT NOT NULL,
+    ip VARCHAR(45),
+    user_agent VARCHAR(255),
+    device VARCHAR(64),
+    referer VARCHAR(255),
+    anonymized VARCHAR(1) NOT NULL DEFAULT 'N',
+    created_at DATETIME NULL,
+    PRIMARY KEY (id),
+    KEY idx_click_events_created_at (created_at),
+    KEY idx_click_events_url_id (url_id)
+);
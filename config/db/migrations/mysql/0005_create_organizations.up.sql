@@ -0,0 +1,34 @@
+CREATE TABLE organizations (
+    id BIGINT NOT NULL AUTO_INCREMENT,
+    name VARCHAR(125) NOT NULL,
+    created_by BIGINT NOT NULL,
+    created_at DATETIME NULL,
+    updated_at DATETIME NULL,
+    PRIMARY KEY (id)
+);
+
+CREATE TABLE organization_members (
+    id BIGINT NOT NULL AUTO_INCREMENT,
+    org_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    role VARCHAR(16) NOT NULL,
+    created_at DATETIME NULL,
+    updated_at DATETIME NULL,
+    PRIMARY KEY (id),
+    KEY idx_organization_members_user_id (user_id),
+    UNIQUE KEY idx_org_member (org_id, user_id)
+);
+
+CREATE TABLE organization_invitations (
+    id BIGINT NOT NULL AUTO_INCREMENT,
+    org_id BIGINT NOT NULL,
+    email VARCHAR(165) NOT NULL,
+    role VARCHAR(16) NOT NULL,
+    token VARCHAR(64) NOT NULL UNIQUE,
+    invited_by BIGINT NOT NULL,
+    expires_at DATETIME NULL,
+    accepted_at DATETIME NULL,
+    created_at DATETIME NULL,
+    PRIMARY KEY (id),
+    KEY idx_organization_invitations_org_id (org_id)
+);
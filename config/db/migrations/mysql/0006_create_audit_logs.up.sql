@@ -0,0 +1,16 @@
+CREATE TABLE audit_logs (
+    id BIGINT NOT NULL AUTO_INCREMENT,
+    actor_id BIGINT NOT NULL,
+    action VARCHAR(64) NOT NULL,
+    target_type VARCHAR(32),
+    target_id VARCHAR(64),
+    diff TEXT,
+    ip VARCHAR(45),
+    request_id VARCHAR(64),
+    created_at DATETIME NULL,
+    PRIMARY KEY (id),
+    KEY idx_audit_logs_action (action),
+    KEY idx_audit_logs_actor_id (actor_id),
+    KEY idx_audit_logs_created_at (created_at),
+    KEY idx_audit_logs_request_id (request_id)
+);
@@ -0,0 +1,32 @@
+CREATE TABLE webhooks (
+    id BIGINT NOT NULL AUTO_INCREMENT,
+    user_id BIGINT NOT NULL,
+    url VARCHAR(2048) NOT NULL,
+    events VARCHAR(255) NOT NULL,
+    secret VARCHAR(64) NOT NULL,
+    active VARCHAR(1) NOT NULL DEFAULT 'Y',
+    created_at DATETIME NULL,
+    updated_at DATETIME NULL,
+    PRIMARY KEY (id),
+    KEY idx_webhooks_user_id (user_id)
+);
+
+CREATE TABLE webhook_deliveries (
+    id BIGINT NOT NULL AUTO_INCREMENT,
+    webhook_id BIGINT NOT NULL,
+    event_id VARCHAR(64) NOT NULL,
+    event VARCHAR(32) NOT NULL,
+    payload TEXT,
+    status VARCHAR(16) NOT NULL,
+    attempts INT,
+    next_attempt_at DATETIME NULL,
+    response_code INT,
+    response_body VARCHAR(1024),
+    last_error VARCHAR(255),
+    delivered_at DATETIME NULL,
+    created_at DATETIME NULL,
+    updated_at DATETIME NULL,
+    PRIMARY KEY (id),
+    KEY idx_delivery_due (status, next_attempt_at),
+    KEY idx_webhook_deliveries_webhook_id (webhook_id)
+);
@@ -0,0 +1,36 @@
+-- the tables of the first release, IF NOT EXISTS lets the databases created
+-- by its gorm models adopt the migrations
+
+CREATE TABLE IF NOT EXISTS users (
+    id BIGSERIAL PRIMARY KEY,
+    username VARCHAR(12) NOT NULL UNIQUE,
+    email VARCHAR(165) NOT NULL UNIQUE,
+    password VARCHAR(125) NOT NULL,
+    name VARCHAR(125) NOT NULL,
+    email_verified VARCHAR(1) NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE TABLE IF NOT EXISTS verify_emails (
+    id BIGSERIAL PRIMARY KEY,
+    token TEXT,
+    user_id BIGINT,
+    verified TEXT,
+    created_at TIMESTAMP WITH TIME ZONE,
+    verified_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE TABLE IF NOT EXISTS generated_urls (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT,
+    name TEXT,
+    source TEXT,
+    generated TEXT,
+    total_hits BIGINT,
+    is_active TEXT,
+    start_date TIMESTAMP WITH TIME ZONE,
+    end_date TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE
+);
@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_generated_urls_org_id;
+ALTER TABLE generated_urls
+    DROP COLUMN org_id,
+    DROP COLUMN taken_down_at,
+    DROP COLUMN takedown_reason;
+
+ALTER TABLE users
+    DROP COLUMN role,
+    DROP COLUMN suspended_at,
+    DROP COLUMN suspend_reason,
+    DROP COLUMN reset_required;
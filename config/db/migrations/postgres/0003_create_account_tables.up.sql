@@ -0,0 +1,50 @@
+CREATE TABLE reset_passwords (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    token VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE
+);
+CREATE INDEX idx_reset_passwords_user_id ON reset_passwords (user_id);
+
+CREATE TABLE user_identities (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    provider VARCHAR(64) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    email VARCHAR(165),
+    created_at TIMESTAMP WITH TIME ZONE
+);
+CREATE INDEX idx_user_identities_user_id ON user_identities (user_id);
+CREATE UNIQUE INDEX idx_identity_subject ON user_identities (provider, subject);
+
+CREATE TABLE email_changes (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    new_email VARCHAR(165) NOT NULL,
+    token VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE
+);
+CREATE INDEX idx_email_changes_user_id ON email_changes (user_id);
+
+CREATE TABLE user_mfas (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL UNIQUE,
+    secret VARCHAR(64) NOT NULL,
+    enabled VARCHAR(1) NOT NULL,
+    last_used_step BIGINT,
+    created_at TIMESTAMP WITH TIME ZONE,
+    confirmed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE TABLE mfa_recovery_codes (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    code_hash VARCHAR(64) NOT NULL,
+    used VARCHAR(1) NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE
+);
+CREATE INDEX idx_mfa_recovery_codes_user_id ON mfa_recovery_codes (user_id);
@@ -0,0 +1,12 @@
+CREATE TABLE click_events (
+    id BIGSERIAL PRIMARY KEY,
+    url_id BIGINT NOT NULL,
+    ip VARCHAR(45),
+    user_agent VARCHAR(255),
+    device VARCHAR(64),
+    referer VARCHAR(255),
+    anonymized VARCHAR(1) NOT NULL DEFAULT 'N',
+    created_at TIMESTAMP WITH TIME ZONE
+);
+CREATE INDEX idx_click_events_created_at ON click_events (created_at);
+CREATE INDEX idx_click_events_url_id ON click_events (url_id);
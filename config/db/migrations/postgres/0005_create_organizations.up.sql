@@ -0,0 +1,31 @@
+CREATE TABLE organizations (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(125) NOT NULL,
+    created_by BIGINT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE TABLE organization_members (
+    id BIGSERIAL PRIMARY KEY,
+    org_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    role VARCHAR(16) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE
+);
+CREATE INDEX idx_organization_members_user_id ON organization_members (user_id);
+CREATE UNIQUE INDEX idx_org_member ON organization_members (org_id, user_id);
+
+CREATE TABLE organization_invitations (
+    id BIGSERIAL PRIMARY KEY,
+    org_id BIGINT NOT NULL,
+    email VARCHAR(165) NOT NULL,
+    role VARCHAR(16) NOT NULL,
+    token VARCHAR(64) NOT NULL UNIQUE,
+    invited_by BIGINT NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    accepted_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE
+);
+CREATE INDEX idx_organization_invitations_org_id ON organization_invitations (org_id);
@@ -0,0 +1,15 @@
+CREATE TABLE audit_logs (
+    id BIGSERIAL PRIMARY KEY,
+    actor_id BIGINT NOT NULL,
+    action VARCHAR(64) NOT NULL,
+    target_type VARCHAR(32),
+    target_id VARCHAR(64),
+    diff TEXT,
+    ip VARCHAR(45),
+    request_id VARCHAR(64),
+    created_at TIMESTAMP WITH TIME ZONE
+);
+CREATE INDEX idx_audit_logs_action ON audit_logs (action);
+CREATE INDEX idx_audit_logs_actor_id ON audit_logs (actor_id);
+CREATE INDEX idx_audit_logs_created_at ON audit_logs (created_at);
+CREATE INDEX idx_audit_logs_request_id ON audit_logs (request_id);
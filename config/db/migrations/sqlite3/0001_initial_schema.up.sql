@@ -0,0 +1,36 @@
+-- the tables of the first release, IF NOT EXISTS lets the databases created
+-- by its gorm models adopt the migrations
+
+CREATE TABLE IF NOT EXISTS users (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    username VARCHAR(12) NOT NULL UNIQUE,
+    email VARCHAR(165) NOT NULL UNIQUE,
+    password VARCHAR(125) NOT NULL,
+    name VARCHAR(125) NOT NULL,
+    email_verified VARCHAR(1) NOT NULL,
+    updated_at DATETIME,
+    created_at DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS verify_emails (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    token VARCHAR(255),
+    user_id BIGINT,
+    verified VARCHAR(255),
+    created_at DATETIME,
+    verified_at DATETIME
+);
+
+CREATE TABLE IF NOT EXISTS generated_urls (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id BIGINT,
+    name VARCHAR(255),
+    source VARCHAR(255),
+    generated VARCHAR(255),
+    total_hits BIGINT,
+    is_active VARCHAR(255),
+    start_date DATETIME,
+    end_date DATETIME,
+    created_at DATETIME,
+    updated_at DATETIME
+);
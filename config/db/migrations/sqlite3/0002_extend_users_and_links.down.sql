@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_generated_urls_org_id;
+ALTER TABLE generated_urls DROP COLUMN org_id;
+ALTER TABLE generated_urls DROP COLUMN taken_down_at;
+ALTER TABLE generated_urls DROP COLUMN takedown_reason;
+
+ALTER TABLE users DROP COLUMN role;
+ALTER TABLE users DROP COLUMN suspended_at;
+ALTER TABLE users DROP COLUMN suspend_reason;
+ALTER TABLE users DROP COLUMN reset_required;
@@ -0,0 +1,12 @@
+-- the roles, suspensions and forced resets of the users, the workspace and
+-- the takedown of the links
+
+ALTER TABLE users ADD COLUMN role VARCHAR(16) NOT NULL DEFAULT 'user';
+ALTER TABLE users ADD COLUMN suspended_at DATETIME;
+ALTER TABLE users ADD COLUMN suspend_reason VARCHAR(255);
+ALTER TABLE users ADD COLUMN reset_required VARCHAR(1) NOT NULL DEFAULT 'N';
+
+ALTER TABLE generated_urls ADD COLUMN org_id BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE generated_urls ADD COLUMN taken_down_at DATETIME;
+ALTER TABLE generated_urls ADD COLUMN takedown_reason VARCHAR(255);
+CREATE INDEX idx_generated_urls_org_id ON generated_urls (org_id);
@@ -0,0 +1,31 @@
+CREATE TABLE organizations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name VARCHAR(125) NOT NULL,
+    created_by BIGINT NOT NULL,
+    created_at DATETIME,
+    updated_at DATETIME
+);
+
+CREATE TABLE organization_members (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    org_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    role VARCHAR(16) NOT NULL,
+    created_at DATETIME,
+    updated_at DATETIME
+);
+CREATE INDEX idx_organization_members_user_id ON organization_members (user_id);
+CREATE UNIQUE INDEX idx_org_member ON organization_members (org_id, user_id);
+
+CREATE TABLE organization_invitations (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    org_id BIGINT NOT NULL,
+    email VARCHAR(165) NOT NULL,
+    role VARCHAR(16) NOT NULL,
+    token VARCHAR(64) NOT NULL UNIQUE,
+    invited_by BIGINT NOT NULL,
+    expires_at DATETIME,
+    accepted_at DATETIME,
+    created_at DATETIME
+);
+CREATE INDEX idx_organization_invitations_org_id ON organization_invitations (org_id);
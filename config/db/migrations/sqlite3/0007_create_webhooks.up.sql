@@ -0,0 +1,30 @@
+CREATE TABLE webhooks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id BIGINT NOT NULL,
+    url VARCHAR(2048) NOT NULL,
+    events VARCHAR(255) NOT NULL,
+    secret VARCHAR(64) NOT NULL,
+    active VARCHAR(1) NOT NULL DEFAULT 'Y',
+    created_at DATETIME,
+    updated_at DATETIME
+);
+CREATE INDEX idx_webhooks_user_id ON webhooks (user_id);
+
+CREATE TABLE webhook_deliveries (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    webhook_id BIGINT NOT NULL,
+    event_id VARCHAR(64) NOT NULL,
+    event VARCHAR(32) NOT NULL,
+    payload TEXT,
+    status VARCHAR(16) NOT NULL,
+    attempts INTEGER,
+    next_attempt_at DATETIME,
+    response_code INTEGER,
+    response_body VARCHAR(1024),
+    last_error VARCHAR(255),
+    delivered_at DATETIME,
+    created_at DATETIME,
+    updated_at DATETIME
+);
+CREATE INDEX idx_delivery_due ON webhook_deliveries (status, next_attempt_at);
+CREATE INDEX idx_webhook_deliveries_webhook_id ON webhook_deliveries (webhook_id);
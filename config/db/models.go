@@ -0,0 +1,22 @@
+package db
+
+import "github.com/RedLucky/potongin/domain"
+
+// Models are the tables of the repositories
+var Models = []interface{}{
+	&domain.User{},
+	&domain.VerifyEmail{},
+	&domain.ResetPassword{},
+	&domain.UserIdentity{},
+	&domain.EmailChange{},
+	&domain.GeneratedUrl{},
+	&domain.ClickEvent{},
+	&domain.UserMfa{},
+	&domain.MfaRecoveryCode{},
+	&domain.Organization{},
+	&domain.OrganizationMember{},
+	&domain.OrganizationInvitation{},
+	&domain.AuditLog{},
+	&domain.Webhook{},
+	&domain.WebhookDelivery{},
+}
@@ -2,36 +2,20 @@ package db
 
 import (
 	"fmt"
-	"log"
 	"net/url"
 
-	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/mysql"
 	"github.com/spf13/viper"
 )
 
-type MysqlConn struct {
-	Conn *gorm.DB
-}
-
-func New() *MysqlConn {
+func mysqlDSN() string {
 	dbHost := viper.GetString(`database.host`)
-	dbPort := viper.GetString(`database.port`)
 	dbUser := viper.GetString(`database.user`)
 	dbPass := viper.GetString(`database.pass`)
 	dbName := viper.GetString(`database.name`)
-	connection := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s", dbUser, dbPass, dbHost, dbPort, dbName)
+	connection := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s", dbUser, dbPass, dbHost, port("3306"), dbName)
 	val := url.Values{}
 	val.Add("parseTime", "1")
-	val.Add("loc", "Asia/Jakarta")
-	dsn := fmt.Sprintf("%s?%s", connection, val.Encode())
-	dbConn, err := gorm.Open("mysql", dsn)
-
-	if err != nil {
-		log.Fatal(err)
-		panic("failed to connect database")
-	}
-
-	return &MysqlConn{
-		Conn: dbConn,
-	}
+	val.Add("loc", timezone())
+	return fmt.Sprintf("%s?%s", connection, val.Encode())
 }
@@ -0,0 +1,34 @@
+package db
+
+import (
+	"strings"
+
+	_ "github.com/jinzhu/gorm/dialects/postgres"
+	"github.com/spf13/viper"
+)
+
+func postgresDSN() string {
+	sslMode := viper.GetString(`database.sslmode`)
+	if sslMode == "" {
+		sslMode = "disable"
+	}
+	params := []string{
+		"host=" + quote(viper.GetString(`database.host`)),
+		"port=" + quote(port("5432")),
+		"user=" + quote(viper.GetString(`database.user`)),
+		"password=" + quote(viper.GetString(`database.pass`)),
+		"dbname=" + quote(viper.GetString(`database.name`)),
+		"sslmode=" + quote(sslMode),
+		"timezone=" + quote(timezone()),
+		// []byte are sent as is instead of hex encoded bytea, the JSON payloads are text columns
+		"binary_parameters=yes",
+	}
+	return strings.Join(params, " ")
+}
+
+// quote a value of the key=value DSN of lib/pq
+func quote(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
@@ -0,0 +1,15 @@
+package db
+
+import (
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	"github.com/spf13/viper"
+)
+
+// sqliteDSN is the path of the database file, ":memory:" keeps it in memory
+func sqliteDSN() string {
+	path := viper.GetString(`database.name`)
+	if path == "" {
+		path = "potongin.db"
+	}
+	return path + "?_busy_timeout=5000"
+}
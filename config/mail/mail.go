@@ -0,0 +1,161 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	netmail "net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/RedLucky/potongin/domain"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// Options of the smtp server the mails are sent through
+type Options struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	// From is the sender of the mails, like "Potongin <no-reply@potong.in>"
+	From string
+	// Timeout bound a mail sent without a deadline
+	Timeout time.Duration
+}
+
+// New return the mailer of the mail config: "smtp" send the mails through the
+// smtp server, "log" (the default) only log them, with the links they carry,
+// for development
+func New() (domain.Mailer, error) {
+	switch driver := viper.GetString(`mail.driver`); driver {
+	case "smtp":
+		return NewSmtpMailer(ConfigOptions())
+	case "", "log":
+		logrus.Warn("mail.driver is log: the mails and their tokens are only logged")
+		return NewLogMailer(), nil
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", driver)
+	}
+}
+
+// ConfigOptions read the options of the smtp config, the timeout is in seconds
+func ConfigOptions() Options {
+	timeout := viper.GetInt(`mail.smtp.timeout`)
+	if timeout <= 0 {
+		timeout = 10
+	}
+	return Options{
+		Host:     viper.GetString(`mail.smtp.host`),
+		Port:     viper.GetString(`mail.smtp.port`),
+		Username: viper.GetString(`mail.smtp.username`),
+		Password: viper.GetString(`mail.smtp.password`),
+		From:     viper.GetString(`mail.from`),
+		Timeout:  time.Duration(timeout) * time.Second,
+	}
+}
+
+// logMailer write the mails to the log in place of sending them
+type logMailer struct{}
+
+// NewLogMailer return the mailer of development, the mails are only logged
+func NewLogMailer() domain.Mailer {
+	return logMailer{}
+}
+
+func (logMailer) Send(ctx context.Context, mail domain.Mail) error {
+	logrus.WithFields(logrus.Fields{"to": mail.To, "subject": mail.Subject}).Info(mail.Body)
+	return nil
+}
+
+type smtpMailer struct {
+	options Options
+	from    *netmail.Address
+}
+
+// NewSmtpMailer return the mailer sending through the smtp server of the
+// options, with STARTTLS when the server offers it
+func NewSmtpMailer(options Options) (domain.Mailer, error) {
+	if options.Host == "" || options.Port == "" {
+		return nil, errors.New("mail.smtp.host and mail.smtp.port are required")
+	}
+	from, err := netmail.ParseAddress(options.From)
+	if err != nil {
+		return nil, fmt.Errorf("mail.from: %w", err)
+	}
+	return &smtpMailer{options: options, from: from}, nil
+}
+
+func (m *smtpMailer) Send(ctx context.Context, mail domain.Mail) error {
+	to, err := netmail.ParseAddress(mail.To)
+	if err != nil {
+		return err
+	}
+	if strings.ContainsAny(mail.Subject, "\r\n") {
+		return errors.New("mail subject must be a single line")
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.options.Timeout)
+		defer cancel()
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(m.options.Host, m.options.Port))
+	if err != nil {
+		return err
+	}
+	deadline, _ := ctx.Deadline()
+	conn.SetDeadline(deadline)
+	client, err := smtp.NewClient(conn, m.options.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err = client.StartTLS(&tls.Config{ServerName: m.options.Host}); err != nil {
+			return err
+		}
+	}
+	if m.options.Username != "" {
+		if err = client.Auth(smtp.PlainAuth("", m.options.Username, m.options.Password, m.options.Host)); err != nil {
+			return err
+		}
+	}
+	if err = client.Mail(m.from.Address); err != nil {
+		return err
+	}
+	if err = client.Rcpt(to.Address); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(m.message(to, mail)); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// message is the mail with its headers, its lines ending with CRLF
+func (m *smtpMailer) message(to *netmail.Address, mail domain.Mail) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + m.from.String() + "\r\n")
+	b.WriteString("To: " + to.String() + "\r\n")
+	b.WriteString("Subject: " + mail.Subject + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(strings.ReplaceAll(mail.Body, "\r\n", "\n"), "\n", "\r\n"))
+	return []byte(b.String())
+}
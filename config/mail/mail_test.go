@@ -0,0 +1,94 @@
+package mail_test
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/RedLucky/potongin/config/mail"
+	"github.com/RedLucky/potongin/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// smtpServer accept one session without STARTTLS nor AUTH and return its
+// commands and its data
+func smtpServer(t *testing.T) (host, port string, session <-chan []string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	lines := make(chan []string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		var received []string
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+		reply("220 localhost ESMTP")
+		data := false
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				break
+			}
+			line = strings.TrimRight(line, "\r\n")
+			received = append(received, line)
+			switch {
+			case data && line == ".":
+				data = false
+				reply("250 queued")
+			case data:
+			case strings.HasPrefix(line, "EHLO"):
+				reply("250 localhost")
+			case line == "DATA":
+				data = true
+				reply("354 go ahead")
+			case line == "QUIT":
+				reply("221 bye")
+				lines <- received
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+		lines <- received
+	}()
+	host, port, _ = net.SplitHostPort(listener.Addr().String())
+	return host, port, lines
+}
+
+func TestSmtpMailer(t *testing.T) {
+	host, port, session := smtpServer(t)
+	mailer, err := mail.NewSmtpMailer(mail.Options{Host: host, Port: port, From: "Potongin <no-reply@potong.in>", Timeout: 5 * time.Second})
+	require.NoError(t, err)
+
+	err = mailer.Send(context.TODO(), domain.Mail{To: "lucky@kryptopos.com", Subject: "Reset your password", Body: "Open the link:\n\nhttps://potong.in/reset-password?token=abc\n"})
+
+	require.NoError(t, err)
+	received := <-session
+	assert.Contains(t, received, "MAIL FROM:<no-reply@potong.in>")
+	assert.Contains(t, received, "RCPT TO:<lucky@kryptopos.com>")
+	assert.Contains(t, received, "Subject: Reset your password")
+	assert.Contains(t, received, "https://potong.in/reset-password?token=abc")
+}
+
+func TestSmtpMailer_Refused(t *testing.T) {
+	mailer, err := mail.NewSmtpMailer(mail.Options{Host: "localhost", Port: "25", From: "no-reply@potong.in", Timeout: time.Second})
+	require.NoError(t, err)
+
+	// a header can't be injected through the recipient or the subject
+	err = mailer.Send(context.TODO(), domain.Mail{To: "lucky@kryptopos.com\r\nBcc: eve@example.com", Subject: "Hi"})
+	assert.Error(t, err)
+	err = mailer.Send(context.TODO(), domain.Mail{To: "lucky@kryptopos.com", Subject: "Hi\r\nBcc: eve@example.com"})
+	assert.Error(t, err)
+
+	_, err = mail.NewSmtpMailer(mail.Options{Host: "localhost", Port: "25", From: "not an address"})
+	assert.Error(t, err)
+}
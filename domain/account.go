@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// UpdateProfile is a partial update of the profile, empty fields are left unchanged
+type UpdateProfile struct {
+	Name     string `json:"name" validate:"omitempty,max=125"`
+	Username string `json:"username" validate:"omitempty,max=12"`
+}
+
+type ChangeEmail struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
+type ChangePassword struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required"`
+}
+
+// DeleteAccount confirm the deletion with the password, the links of the
+// personal workspace are moved to the account TransferTo when given and
+// deleted otherwise
+type DeleteAccount struct {
+	Password   string `json:"password" validate:"required"`
+	TransferTo string `json:"transfer_to"`
+}
+
+// EmailChange is a requested new email, only applied once verified
+type EmailChange struct {
+	ID        int64     `json:"id" gorm:"primary_key;auto_increment"`
+	UserId    int64     `json:"user_id" gorm:"not null;index"`
+	NewEmail  string    `json:"new_email" gorm:"size:165;not null"`
+	Token     string    `json:"-" gorm:"size:64;not null;unique"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AccountUsecase represent the self-service usecases of the logged in user
+type AccountUsecase interface {
+	UpdateProfile(ctx context.Context, userId int64, profile UpdateProfile) (User, error)
+	RequestEmailChange(ctx context.Context, userId int64, email, password string) (encodedString string, err error)
+	ConfirmEmailChange(ctx context.Context, token string) error
+	ChangePassword(ctx context.Context, userId int64, sessionId, currentPassword, newPassword string) error
+	DeleteAccount(ctx context.Context, userId int64, password, transferTo string) error
+}
+
+// AccountRepository represent the account repository contract
+type AccountRepository interface {
+	GetUserById(ctx context.Context, id int64) (User, error)
+	GetUserByEmail(ctx context.Context, email string) (User, error)
+	GetUserByUsername(ctx context.Context, username string) (User, error)
+	UpdateProfile(ctx context.Context, user *User) error
+	UpdatePassword(ctx context.Context, userId int64, hashedPassword string) error
+	CreateEmailChange(ctx context.Context, change *EmailChange) error
+	GetEmailChange(ctx context.Context, token string) (EmailChange, error)
+	ApplyEmailChange(ctx context.Context, change EmailChange) error
+	DeleteUser(ctx context.Context, userId, transferTo int64) error
+	IsSoleOwner(ctx context.Context, userId int64) (bool, error)
+	// ShareOrganization tell if both users are members of a same organization
+	ShareOrganization(ctx context.Context, userId, otherId int64) (bool, error)
+}
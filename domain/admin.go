@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// AdminUser is a user as seen in the admin console, with the totals of its links
+type AdminUser struct {
+	ID            int64      `json:"id"`
+	Username      string     `json:"username"`
+	Email         string     `json:"email"`
+	Name          string     `json:"name"`
+	EmailVerified string     `json:"email_verified"`
+	Role          string     `json:"role"`
+	SuspendedAt   *time.Time `json:"suspended_at"`
+	SuspendReason string     `json:"suspend_reason"`
+	ResetRequired string     `json:"reset_required"`
+	LinkCount     int64      `json:"link_count"`
+	TotalHits     int64      `json:"total_hits"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+type ModerationReason struct {
+	Reason string `json:"reason" validate:"required,max=255"`
+}
+
+// AdminUsecase represent the moderation usecases of the admin console
+type AdminUsecase interface {
+	IsAdmin(ctx context.Context, userId int64) (bool, error)
+	SearchUsers(ctx context.Context, query string, limit, offset int) ([]AdminUser, error)
+	GetUser(ctx context.Context, userId int64) (AdminUser, []GeneratedUrl, error)
+	Suspend(ctx context.Context, userId int64, reason string) error
+	Unsuspend(ctx context.Context, userId int64) error
+	VerifyEmail(ctx context.Context, userId int64) error
+	ForcePasswordReset(ctx context.Context, userId int64) (encodedString string, err error)
+	UnlockAccount(ctx context.Context, userId int64) error
+	TakeDownLink(ctx context.Context, linkId int64, reason string) error
+	RestoreLink(ctx context.Context, linkId int64) error
+}
+
+// AdminRepository represent the admin console repository contract
+type AdminRepository interface {
+	GetRole(ctx context.Context, userId int64) (string, error)
+	SearchUsers(ctx context.Context, query string, limit, offset int) ([]AdminUser, error)
+	GetUser(ctx context.Context, userId int64) (AdminUser, error)
+	GetLinks(ctx context.Context, userId int64) ([]GeneratedUrl, error)
+	GetLink(ctx context.Context, linkId int64) (GeneratedUrl, error)
+	SetSuspension(ctx context.Context, userId int64, suspendedAt *time.Time, reason string) error
+	VerifyEmail(ctx context.Context, userId int64) error
+	ForcePasswordReset(ctx context.Context, reset *ResetPassword) error
+	SetTakedown(ctx context.Context, linkId int64, takenDownAt *time.Time, reason string) error
+}
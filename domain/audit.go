@@ -0,0 +1,97 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// AuditLog is an append-only record of a security or data-changing action.
+// Diff holds the changed fields as {"field": {"before": .., "after": ..}},
+// ActorId is 0 when nobody is logged in (e.g. a failed login).
+type AuditLog struct {
+	ID         int64           `json:"id" gorm:"primary_key;auto_increment"`
+	ActorId    int64           `json:"actor_id" gorm:"not null;index"`
+	Action     string          `json:"action" gorm:"size:64;not null;index"`
+	TargetType string          `json:"target_type" gorm:"size:32"`
+	TargetId   string          `json:"target_id" gorm:"size:64"`
+	Diff       json.RawMessage `json:"diff,omitempty" gorm:"type:text"`
+	IP         string          `json:"ip" gorm:"size:45"`
+	RequestId  string          `json:"request_id" gorm:"size:64;index"`
+	CreatedAt  time.Time       `json:"created_at" gorm:"index"`
+}
+
+// audited actions
+const (
+	AuditSignup             = "auth.signup"
+	AuditLogin              = "auth.login"
+	AuditLoginFailed        = "auth.login_failed"
+	AuditTokenRefresh       = "auth.token_refresh"
+	AuditLogout             = "auth.logout"
+	AuditPasswordReset      = "auth.password_reset"
+	AuditUserCreate         = "user.create"
+	AuditUserUpdate         = "user.update"
+	AuditUserEmailChange    = "user.email_change"
+	AuditUserPasswordChange = "user.password_change"
+	AuditUserDelete         = "user.delete"
+	AuditUserSuspend        = "user.suspend"
+	AuditUserUnsuspend      = "user.unsuspend"
+	AuditUserVerifyEmail    = "user.verify_email"
+	AuditUserForceReset     = "user.force_password_reset"
+	AuditUserUnlock         = "user.unlock"
+	AuditLinkCreate         = "link.create"
+	AuditLinkUpdate         = "link.update"
+	AuditLinkDelete         = "link.delete"
+	AuditLinkTransfer       = "link.transfer"
+	AuditLinkTakedown       = "link.takedown"
+	AuditLinkRestore        = "link.restore"
+)
+
+// audited targets
+const (
+	AuditTargetUser    = "user"
+	AuditTargetLink    = "link"
+	AuditTargetSession = "session"
+)
+
+// AuditFilter select audit logs, zero fields don't filter. Logs come newest
+// first, BeforeId pages through them without skipping concurrent inserts.
+type AuditFilter struct {
+	ActorId    int64
+	Action     string
+	TargetType string
+	TargetId   string
+	From       time.Time
+	To         time.Time
+	BeforeId   int64
+	Limit      int
+	Offset     int
+}
+
+type actorKey struct{}
+
+// NewContextWithActor return a copy of ctx carrying the id of the logged in user
+func NewContextWithActor(ctx context.Context, userId int64) context.Context {
+	return context.WithValue(ctx, actorKey{}, userId)
+}
+
+// ActorFromContext return the id of the logged in user stored in ctx, 0 if none
+func ActorFromContext(ctx context.Context) int64 {
+	userId, _ := ctx.Value(actorKey{}).(int64)
+	return userId
+}
+
+// AuditUsecase represent the audit log usecases of the admin console
+type AuditUsecase interface {
+	Fetch(ctx context.Context, filter AuditFilter) ([]AuditLog, error)
+	// Export write every log matching the filter as JSON lines
+	Export(ctx context.Context, filter AuditFilter, w io.Writer) error
+}
+
+// AuditRepository represent the audit log repository contract, logs are
+// never updated nor deleted
+type AuditRepository interface {
+	Store(ctx context.Context, entry *AuditLog) error
+	Fetch(ctx context.Context, filter AuditFilter) ([]AuditLog, error)
+}
@@ -14,6 +14,12 @@ type Auth struct {
 	Password string `json:"password" validate:"required"`
 }
 
+// second login step of a user with two-factor authentication
+type MfaLogin struct {
+	MfaToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
 type JwtResults struct {
 	AccessUUID   string `json:"access_uuid"`
 	AccessToken  string `json:"access_token"`
@@ -21,11 +27,17 @@ type JwtResults struct {
 	RefreshUUID  string `json:"refresh_uuid"`
 	AccessExp    int64  `json:"access_exp"`
 	RefreshExp   int64  `json:"refresh_exp"`
+	SessionID    string `json:"session_id"`
+	// MfaRequired is set instead of the tokens when the login must be
+	// completed with a second factor, exchanging MfaToken in VerifyMfa
+	MfaRequired bool   `json:"mfa_required"`
+	MfaToken    string `json:"mfa_token,omitempty"`
 }
 
 type JwtCustomClaims struct {
 	AccessUUID  string `json:"access_uuid"`
 	RefreshUUID string `json:"refresh_uuid"`
+	SessionID   string `json:"session_id,omitempty"`
 	jwt.StandardClaims
 }
 
@@ -38,23 +50,54 @@ type VerifyEmail struct {
 	VerifiedAt time.Time `json:"verified_at"`
 }
 
+// ResetPassword is a single use token to choose a new password
+type ResetPassword struct {
+	ID        int64      `json:"id" gorm:"primary_key;auto_increment"`
+	UserId    int64      `json:"user_id" gorm:"not null;index"`
+	Token     string     `json:"-" gorm:"size:64;not null;unique"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type ResetPasswordParam struct {
+	Token           string `json:"token" validate:"required"`
+	Password        string `json:"password" validate:"required"`
+	ConfirmPassword string `json:"confirm_password" validate:"required"`
+}
+
+// UserIdentity link a user to its account at an external identity provider
+type UserIdentity struct {
+	ID        int64     `json:"id" gorm:"primary_key;auto_increment"`
+	UserId    int64     `json:"user_id" gorm:"not null;index"`
+	Provider  string    `json:"provider" gorm:"size:64;not null;unique_index:idx_identity_subject"`
+	Subject   string    `json:"subject" gorm:"size:255;not null;unique_index:idx_identity_subject"`
+	Email     string    `json:"email" gorm:"size:165"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
 // AuthUsecase represent the authentication usecases
 type AuthUsecase interface {
 	Authenticate(ctx context.Context, email, password string) (JwtResults, error)
+	VerifyMfa(ctx context.Context, mfaToken, code string) (JwtResults, error)
 	SignUp(ctx context.Context, user *User) error
 	CreateVerifyEmail(ctx context.Context, email string) (encodedString string, err error)
 	VerifyEmail(ctx context.Context, token string) error
-	// CreateResetPassword(ctx context.Context, email string) error
-	// VerifyResetPassword(ctx context.Context, token string) error
-	// ResetPassword(ctx context.Context, password, confirm_password, token string) error
+	CreateResetPassword(ctx context.Context, email string) (encodedString string, err error)
+	VerifyResetPassword(ctx context.Context, token string) error
+	ResetPassword(ctx context.Context, password, confirmPassword, token string) error
 	GenerateNewAccessToken(ctx echo.Context) (JwtResults, error)
-	Logout(accessToken, refreshToken string) error
+	UnlockAccountByToken(ctx context.Context, token string) error
+	OidcLoginURL(ctx context.Context, provider string) (string, error)
+	OidcCallback(ctx context.Context, provider, code, state string) (JwtResults, error)
+	Logout(ctx context.Context, accessToken, refreshToken string) error
 }
 
 // AuthRepository represent the authentication repository contract
 type AuthRepository interface {
 	GetUserByEmail(ctx context.Context, email string) (User, error)
 	GetUserByUsername(ctx context.Context, email string) (User, error)
+	GetUserById(ctx context.Context, id int64) (User, error)
 	RegisterUser(ctx context.Context, user *User) error
 	IsExistEmail(email string) (result User, err error)
 	IsVerifiedEmail(email string) (result bool, err error)
@@ -64,7 +107,10 @@ type AuthRepository interface {
 	DeletePreviousVerifyEmail(userId int64) error
 	VerifyTokenEmail(ctx context.Context, token string) error
 	VerifyTokenAccount(ctx context.Context, userId int64) error
-	// CreateResetPassword(ctx context.Context, email string) error
-	// VerifyResetPassword(ctx context.Context, token string) error
-	// ResetPassword(ctx context.Context, password, confirmPassword, token string) error
+	UpdatePassword(ctx context.Context, userId int64, hashedPassword string) error
+	GetIdentity(ctx context.Context, provider, subject string) (UserIdentity, error)
+	CreateIdentity(ctx context.Context, identity *UserIdentity) error
+	CreateResetPassword(ctx context.Context, reset *ResetPassword) error
+	GetResetPassword(ctx context.Context, token string) (ResetPassword, error)
+	ResetPassword(ctx context.Context, reset ResetPassword, hashedPassword string) error
 }
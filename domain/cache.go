@@ -0,0 +1,69 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrCacheMiss is returned by the caches and the token store when the key is
+// unknown or expired
+var ErrCacheMiss = errors.New("cache miss")
+
+// Cache is a key value store whose entries expire, a miss is not an error of
+// the caller: the value is loaded from the database again
+type Cache interface {
+	// Get return ErrCacheMiss when the key is unknown or expired
+	Get(ctx context.Context, key string) (string, error)
+	// Set store the value for ttl, forever when ttl is 0
+	Set(ctx context.Context, key, value string, ttl time.Duration) error
+	Delete(ctx context.Context, keys ...string) error
+}
+
+// OidcState is kept server side between the redirect to the provider and the callback
+type OidcState struct {
+	Provider     string `json:"provider"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+}
+
+// TokenStore keep the short-lived authentication state: the issued tokens,
+// the sessions, the pending mfa challenges, the login failures and the oidc
+// states. The lookups return ErrCacheMiss when the entry is unknown or expired.
+type TokenStore interface {
+	SaveTokens(ctx context.Context, user User, jwt JwtResults) error
+	// GetTokenSession return the owner and the session of the token
+	GetTokenSession(ctx context.Context, uuid string) (userId int64, sessionId string, err error)
+	DeleteToken(ctx context.Context, uuid string) error
+	// ConsumeRefreshToken mark the refresh token as used and return its owner,
+	// with ErrRefreshTokenReused when it was already consumed before
+	ConsumeRefreshToken(ctx context.Context, uuid string) (userId int64, sessionId string, err error)
+
+	SaveSession(ctx context.Context, session Session) error
+	GetSession(ctx context.Context, sessionId string) (Session, error)
+	GetSessionsByUser(ctx context.Context, userId int64) ([]Session, error)
+	TouchSession(ctx context.Context, sessionId string, lastSeen time.Time) error
+	// DeleteSession delete the session together with the tokens it holds
+	DeleteSession(ctx context.Context, session Session) error
+
+	SaveMfaChallenge(ctx context.Context, mfaUUID string, userId int64) error
+	ExistMfaChallenge(ctx context.Context, mfaUUID string) (bool, error)
+	// CountMfaAttempt count a wrong code on the challenge and return how many were made so far
+	CountMfaAttempt(ctx context.Context, mfaUUID string) (int, error)
+	ConsumeMfaChallenge(ctx context.Context, mfaUUID string) (userId int64, err error)
+
+	// RecordLoginFailure count a failed login and return the failures made so far
+	RecordLoginFailure(ctx context.Context, email, ip string, window time.Duration) (accountFailures, ipFailures int, err error)
+	SetLoginBackoff(ctx context.Context, email, ip string, accountWait, ipWait time.Duration) error
+	// LoginBackoff return how long the account or the ip still has to wait before the next attempt
+	LoginBackoff(ctx context.Context, email, ip string) (time.Duration, error)
+	ResetLoginFailures(ctx context.Context, email string) error
+	LockAccount(ctx context.Context, email string, duration time.Duration) error
+	IsAccountLocked(ctx context.Context, email string) (bool, error)
+	UnlockAccount(ctx context.Context, email string) error
+	SaveUnlockToken(ctx context.Context, token, email string, duration time.Duration) error
+	ConsumeUnlockToken(ctx context.Context, token string) (email string, err error)
+
+	SaveOidcState(ctx context.Context, state string, value OidcState) error
+	ConsumeOidcState(ctx context.Context, state string) (OidcState, error)
+}
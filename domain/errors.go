@@ -1,29 +1,93 @@
 package domain
 
-import "errors"
+import (
+	"net/http"
+	"strings"
+)
+
+// Error is a domain error: Code is stable and machine-readable, Status is the
+// HTTP status it is answered with and Message is safe to show to the client.
+// The errors below are compared with errors.Is, so they can be wrapped.
+type Error struct {
+	Code    string
+	Status  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// registered hold the errors created by NewError, for the API documentation
+var registered []*Error
+
+// NewError create a domain error and register it, it is meant for package
+// level variables: the errors built for a single request are literals
+func NewError(code string, status int, message string) *Error {
+	err := &Error{Code: code, Status: status, Message: message}
+	registered = append(registered, err)
+	return err
+}
+
+// Errors return the errors created by NewError
+func Errors() []*Error {
+	return append([]*Error(nil), registered...)
+}
 
 var (
 	// ErrInternalServerError will throw if any the Internal Server Error happen
-	ErrInternalServerError = errors.New("internal Server Error")
+	ErrInternalServerError = NewError("internal_error", http.StatusInternalServerError, "internal Server Error")
 	// ErrNotFound will throw if the requested item is not exists
-	ErrNotFound = errors.New("your requested data is not found")
+	ErrNotFound = NewError("not_found", http.StatusNotFound, "your requested data is not found")
 	// ErrConflict will throw if the current action already exists
-	ErrConflict = errors.New("your data already exist")
+	ErrConflict = NewError("conflict", http.StatusConflict, "your data already exist")
 	// ErrBadParamInput will throw if the given request-body or params is not valid
-	ErrBadParamInput = errors.New("given Param is not valid")
+	ErrBadParamInput = NewError("bad_param_input", http.StatusBadRequest, "given Param is not valid")
 
 	// account
-	ErrAccountExist       = errors.New("account already exist")
-	ErrEmailExist         = errors.New("email already exist")
-	ErrPassword           = errors.New("wrong Password")
-	ErrEmailNotFound      = errors.New("email Not Found")
-	ErrorAuthorization    = errors.New("unathorized")
-	ErrorEmailNotVerified = errors.New("email not verified")
-	ErrorTokenNotFound    = errors.New("token not found")
+	ErrAccountExist          = NewError("account_exist", http.StatusConflict, "account already exist")
+	ErrEmailExist            = NewError("email_exist", http.StatusConflict, "email already exist")
+	ErrPassword              = NewError("wrong_password", http.StatusUnauthorized, "wrong Password")
+	ErrInvalidCredentials    = NewError("invalid_credentials", http.StatusUnauthorized, "invalid email or password")
+	ErrTooManyAttempts       = NewError("too_many_attempts", http.StatusTooManyRequests, "too many attempts, try again later")
+	ErrAccountLocked         = NewError("account_locked", http.StatusLocked, "account locked, check your email to unlock it")
+	ErrEmailNotFound         = NewError("email_not_found", http.StatusNotFound, "email Not Found")
+	ErrorAuthorization       = NewError("unauthorized", http.StatusUnauthorized, "unathorized")
+	ErrorEmailNotVerified    = NewError("email_not_verified", http.StatusForbidden, "email not verified")
+	ErrorTokenNotFound       = NewError("token_not_found", http.StatusNotFound, "token not found")
+	ErrRefreshTokenReused    = NewError("refresh_token_reused", http.StatusUnauthorized, "refresh token already used")
+	ErrInvalidMfaCode        = NewError("invalid_mfa_code", http.StatusUnauthorized, "invalid two-factor code")
+	ErrMfaAlreadyEnabled     = NewError("mfa_already_enabled", http.StatusConflict, "two-factor authentication already enabled")
+	ErrAccountSuspended      = NewError("account_suspended", http.StatusForbidden, "account suspended")
+	ErrPasswordResetRequired = NewError("password_reset_required", http.StatusForbidden, "password reset required, check your email")
+	ErrTransferNotAllowed    = NewError("transfer_not_allowed", http.StatusForbidden, "links can only be transferred to a member of one of your organizations")
+
+	// organization
+	ErrForbidden = NewError("forbidden", http.StatusForbidden, "you are not allowed to do this")
+	ErrLastOwner = NewError("last_owner", http.StatusConflict, "an organization must keep at least one owner")
 
 	// generateUrl
-	ErrUrlNotFound       = errors.New("url not found")
-	ErrUrlOriginExist    = errors.New("url origin already exist")
-	ErrUrlGeneratedExist = errors.New("url generated already exist")
-	ErrNameIsExist       = errors.New("name is exist")
+	ErrUrlNotFound       = NewError("url_not_found", http.StatusNotFound, "url not found")
+	ErrUrlOriginExist    = NewError("url_origin_exist", http.StatusConflict, "url origin already exist")
+	ErrUrlGeneratedExist = NewError("url_generated_exist", http.StatusConflict, "url generated already exist")
+	ErrNameIsExist       = NewError("name_exist", http.StatusConflict, "name is exist")
 )
+
+// PolicyViolation name a rule of the password policy the password failed
+type PolicyViolation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// PasswordPolicyError is returned when a password does not satisfy the password policy
+type PasswordPolicyError struct {
+	Violations []PolicyViolation
+}
+
+func (e *PasswordPolicyError) Error() string {
+	messages := make([]string, 0, len(e.Violations))
+	for _, violation := range e.Violations {
+		messages = append(messages, violation.Message)
+	}
+	return "password is not valid: " + strings.Join(messages, ", ")
+}
@@ -3,45 +3,124 @@ package domain
 import (
 	"context"
 	"time"
-
-	"github.com/gomodule/redigo/redis"
 )
 
 // define models
+// OrgId is the organization workspace owning the link, 0 for the personal
+// workspace of UserId. A taken down link no longer redirect, its visitors
+// get TakedownReason instead.
 type GeneratedUrl struct {
-	ID        int64     `json:"id" gorm:"primary_key;auto_increment"`
-	UserId    int64     `json:"user_id"`
-	Name      string    `json:"name" validate:"required"`
-	Source    string    `json:"source_link" validate:"required"`
-	Generated string    `json:"generated_link" validate:"required"`
-	TotalHits int64     `json:"total_hits"`
-	IsActive  string    `json:"is_active"`
-	StartDate time.Time `json:"start_date"`
-	EndDate   time.Time `json:"end_date"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID             int64      `json:"id" gorm:"primary_key;auto_increment"`
+	UserId         int64      `json:"user_id"`
+	OrgId          int64      `json:"org_id" gorm:"not null;default:0;index"`
+	Name           string     `json:"name" validate:"required"`
+	Source         string     `json:"source_link" validate:"required"`
+	Generated      string     `json:"generated_link" validate:"required"`
+	TotalHits      int64      `json:"total_hits"`
+	IsActive       string     `json:"is_active"`
+	StartDate      time.Time  `json:"start_date"`
+	EndDate        time.Time  `json:"end_date"`
+	TakenDownAt    *time.Time `json:"taken_down_at"`
+	TakedownReason string     `json:"takedown_reason" gorm:"size:255"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// UnavailableLinkError is returned instead of the source of a link that must
+// not redirect, Reason is shown to the visitor
+type UnavailableLinkError struct {
+	Reason string
+}
+
+func (e *UnavailableLinkError) Error() string {
+	return "link unavailable: " + e.Reason
+}
+
+// ClickEvent is a single visit of a generated url. IP is truncated once it is
+// older than the retention period, then Anonymized is "Y".
+type ClickEvent struct {
+	ID         int64     `json:"id" gorm:"primary_key;auto_increment"`
+	UrlId      int64     `json:"url_id" gorm:"not null;index"`
+	IP         string    `json:"ip" gorm:"size:45"`
+	UserAgent  string    `json:"user_agent" gorm:"size:255"`
+	Device     string    `json:"device" gorm:"size:64"`
+	Referer    string    `json:"referer" gorm:"size:255"`
+	Anonymized string    `json:"anonymized" gorm:"size:1;not null;default:'N'"`
+	CreatedAt  time.Time `json:"created_at" gorm:"index"`
+}
+
+// LinkStats summarize the visits of a link since a day
+type LinkStats struct {
+	Since       time.Time    `json:"since"`
+	TotalClicks int64        `json:"total_clicks"`
+	Daily       []ClickCount `json:"daily"`
+	Devices     []ClickCount `json:"devices"`
+	Referers    []ClickCount `json:"referers"`
+}
+
+// ClickCount is the number of visits of a day (2006-01-02), a device or a referer
+type ClickCount struct {
+	Key    string `json:"key"`
+	Clicks int64  `json:"clicks"`
+}
+
+// LinkFilter select the links of a workspace, zero and nil fields don't
+// filter. Search matches the name, source or generated link, ignoring case.
+// Links come oldest first, Offset applies to a page of Limit links.
+type LinkFilter struct {
+	Search    string
+	Active    *bool
+	TakenDown *bool
+	Limit     int
+	Offset    int
 }
 
 type GeneratedUrlUsecase interface {
 	CreateUrl(ctx context.Context, url *GeneratedUrl) error
-	UpdateUrl(ctx context.Context, url *GeneratedUrl) error
-	GetUrlByUserId(ctx context.Context, userId int64) ([]GeneratedUrl, error)
-	GetUrlById(ctx context.Context, urlId string) (GeneratedUrl, error)
+	// UpdateUrl change the name, source or generated link of the link url.ID
+	// for the user, the empty fields are kept; url is set to the updated link
+	UpdateUrl(ctx context.Context, userId int64, url *GeneratedUrl) error
+	// SetUrlActive enable or disable a link for the user
+	SetUrlActive(ctx context.Context, userId int64, urlId string, active bool) (GeneratedUrl, error)
+	// GetUrlByWorkspace list the links of the organization orgId, or of the
+	// personal workspace of the user when orgId is 0
+	GetUrlByWorkspace(ctx context.Context, userId, orgId int64) ([]GeneratedUrl, error)
+	// SearchUrls return the page of the links of the workspace matching the
+	// filter, with the number of every matching link
+	SearchUrls(ctx context.Context, userId, orgId int64, filter LinkFilter) ([]GeneratedUrl, int64, error)
+	GetUrlById(ctx context.Context, userId int64, urlId string) (GeneratedUrl, error)
 	HitUrl(ctx context.Context, generateUrl string) (originUrl string, err error)
+	// GetUrlStats summarize the visits of a link of a workspace of the user for the last days
+	GetUrlStats(ctx context.Context, userId int64, urlId string, days int) (LinkStats, error)
+	// GetWorkspaceStats summarize at once the visits of the links of the
+	// workspace for the last days, by link id; the links of other workspaces
+	// are left out
+	GetWorkspaceStats(ctx context.Context, userId, orgId int64, urlIds []int64, days int) (map[int64]LinkStats, error)
+	// ExpireUrls deactivate the links whose end date passed, it returns how many
+	ExpireUrls(ctx context.Context) (int, error)
 }
 
 type GeneratedUrlRepository interface {
 	InsertUrl(ctx context.Context, url *GeneratedUrl) error
 	UpdateUrl(ctx context.Context, url *GeneratedUrl) error
 	GetUrlByUserId(ctx context.Context, userId int64) ([]GeneratedUrl, error)
+	GetUrlByOrgId(ctx context.Context, orgId int64) ([]GeneratedUrl, error)
+	// SearchUrls return the matching links of the workspace of userId, or of
+	// orgId when it isn't 0, and how many match without the limit and offset
+	SearchUrls(ctx context.Context, userId, orgId int64, filter LinkFilter) ([]GeneratedUrl, int64, error)
 	GetUrlById(ctx context.Context, urlId string) (GeneratedUrl, error)
 	GetUrlByUrl(ctx context.Context, url string) (GeneratedUrl, error)
 	IsExistUrlOrigin(ctx context.Context, urlOrigin string) (bool, error)
 	IsExistUrlGenerated(ctx context.Context, urlGenerated string) (bool, error)
 	CheckDoubleNameByUserId(ctx context.Context, name string, userId int64) (bool, error)
-	HitUrl(ctx context.Context, urlId, total int64) error
-	// using redis
-	GetUrlFromCache(redisCon redis.Conn, generatedUrl string) (string, error)
-	SetUrlToCache(redisCon redis.Conn, generatedUrl, sourceUrl string) error
-	SetUrlExpCache(redisCon redis.Conn, generatedUrl string, duration int) error
+	CheckDoubleNameByOrgId(ctx context.Context, name string, orgId int64) (bool, error)
+	// HitUrl count one more visit of the link
+	HitUrl(ctx context.Context, urlId int64) error
+	InsertClickEvent(ctx context.Context, event *ClickEvent) error
+	GetClickStats(ctx context.Context, urlId int64, since time.Time) (LinkStats, error)
+	// GetWorkspaceClickStats is GetClickStats for the links of urlIds in the
+	// workspace of userId, or of orgId when it isn't 0, by link id
+	GetWorkspaceClickStats(ctx context.Context, userId, orgId int64, urlIds []int64, since time.Time) (map[int64]LinkStats, error)
+	IsOwnerSuspended(ctx context.Context, userId int64) (bool, error)
+	ExpireUrls(ctx context.Context, now time.Time) ([]GeneratedUrl, error)
 }
@@ -0,0 +1,16 @@
+package domain
+
+import "context"
+
+// Mail is a plain text email to a single address
+type Mail struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer deliver the emails of the application, like the links carrying the
+// tokens that prove the owner of an address
+type Mailer interface {
+	Send(ctx context.Context, mail Mail) error
+}
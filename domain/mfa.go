@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// UserMfa is the TOTP second factor of a user. It is only enforced once
+// Enabled is "Y", after the user confirmed a first code.
+type UserMfa struct {
+	ID           int64     `json:"id" gorm:"primary_key;auto_increment"`
+	UserId       int64     `json:"user_id" gorm:"not null;unique"`
+	Secret       string    `json:"-" gorm:"size:64;not null"`
+	Enabled      string    `json:"enabled" gorm:"size:1;not null"`
+	LastUsedStep int64     `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+	ConfirmedAt  time.Time `json:"confirmed_at"`
+}
+
+// MfaRecoveryCode is a one-time code replacing the TOTP code when the device is lost
+type MfaRecoveryCode struct {
+	ID        int64     `json:"id" gorm:"primary_key;auto_increment"`
+	UserId    int64     `json:"user_id" gorm:"not null;index"`
+	CodeHash  string    `json:"-" gorm:"size:64;not null"`
+	Used      string    `json:"used" gorm:"size:1;not null"`
+	UsedAt    time.Time `json:"used_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// MfaEnrollment is what the user needs to register the secret in an authenticator app
+type MfaEnrollment struct {
+	Secret     string `json:"secret"`
+	OtpauthURI string `json:"otpauth_uri"`
+	QRCode     string `json:"qr_code"`
+}
+
+// MfaUsecase represent the two-factor authentication usecases
+type MfaUsecase interface {
+	Enroll(ctx context.Context, userId int64) (MfaEnrollment, error)
+	Confirm(ctx context.Context, userId int64, code string) (recoveryCodes []string, err error)
+	Disable(ctx context.Context, userId int64, code string) error
+}
+
+// MfaRepository represent the two-factor authentication repository contract
+type MfaRepository interface {
+	GetByUserId(ctx context.Context, userId int64) (UserMfa, error)
+	Store(ctx context.Context, mfa *UserMfa) error
+	Enable(ctx context.Context, userId int64, step int64) error
+	UpdateLastUsedStep(ctx context.Context, userId int64, step int64) (bool, error)
+	Delete(ctx context.Context, userId int64) error
+	ReplaceRecoveryCodes(ctx context.Context, userId int64, codes []MfaRecoveryCode) error
+	UseRecoveryCode(ctx context.Context, userId int64, codeHash string) (bool, error)
+}
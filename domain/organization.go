@@ -0,0 +1,100 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// roles of the organization members, from the most to the least privileged
+const (
+	RoleOwner  = "owner"
+	RoleAdmin  = "admin"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+var roleRanks = map[string]int{
+	RoleViewer: 1,
+	RoleEditor: 2,
+	RoleAdmin:  3,
+	RoleOwner:  4,
+}
+
+// ValidRole tell if role is one of the member roles
+func ValidRole(role string) bool {
+	_, ok := roleRanks[role]
+	return ok
+}
+
+// RoleAtLeast tell if role grant everything minRole does
+func RoleAtLeast(role, minRole string) bool {
+	return roleRanks[role] >= roleRanks[minRole] && roleRanks[role] > 0
+}
+
+// Organization is a team sharing a workspace of links. A link without
+// organization belong to the personal workspace of its creator.
+type Organization struct {
+	ID        int64     `json:"id" gorm:"primary_key;auto_increment"`
+	Name      string    `json:"name" validate:"required,max=125" gorm:"size:125;not null"`
+	CreatedBy int64     `json:"created_by" gorm:"not null"`
+	Role      string    `json:"role,omitempty" gorm:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type OrganizationMember struct {
+	ID        int64     `json:"id" gorm:"primary_key;auto_increment"`
+	OrgId     int64     `json:"org_id" gorm:"not null;unique_index:idx_org_member"`
+	UserId    int64     `json:"user_id" gorm:"not null;unique_index:idx_org_member;index"`
+	Role      string    `json:"role" gorm:"size:16;not null"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// OrganizationInvitation let the owner of Email join the organization with Role
+type OrganizationInvitation struct {
+	ID         int64      `json:"id" gorm:"primary_key;auto_increment"`
+	OrgId      int64      `json:"org_id" gorm:"not null;index"`
+	Email      string     `json:"email" gorm:"size:165;not null"`
+	Role       string     `json:"role" gorm:"size:16;not null"`
+	Token      string     `json:"-" gorm:"size:64;not null;unique"`
+	InvitedBy  int64      `json:"invited_by" gorm:"not null"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	AcceptedAt *time.Time `json:"accepted_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+type InviteMember struct {
+	Email string `json:"email" validate:"required,email"`
+	Role  string `json:"role" validate:"required,oneof=owner admin editor viewer"`
+}
+
+type UpdateMemberRole struct {
+	Role string `json:"role" validate:"required,oneof=owner admin editor viewer"`
+}
+
+// OrganizationUsecase represent the organization's usecases, userId is always
+// the member doing the action
+type OrganizationUsecase interface {
+	Create(ctx context.Context, userId int64, org *Organization) error
+	Fetch(ctx context.Context, userId int64) ([]Organization, error)
+	GetMembers(ctx context.Context, userId, orgId int64) ([]OrganizationMember, error)
+	Invite(ctx context.Context, userId, orgId int64, email, role string) (encodedString string, err error)
+	AcceptInvitation(ctx context.Context, userId int64, token string) (OrganizationMember, error)
+	UpdateMemberRole(ctx context.Context, userId, orgId, memberId int64, role string) error
+	RemoveMember(ctx context.Context, userId, orgId, memberId int64) error
+}
+
+// OrganizationRepository represent the organization repository contract
+type OrganizationRepository interface {
+	Create(ctx context.Context, org *Organization, owner *OrganizationMember) error
+	FetchByUserId(ctx context.Context, userId int64) ([]Organization, error)
+	GetMember(ctx context.Context, orgId, userId int64) (OrganizationMember, error)
+	GetMembers(ctx context.Context, orgId int64) ([]OrganizationMember, error)
+	CountOwners(ctx context.Context, orgId int64) (int64, error)
+	UpdateMemberRole(ctx context.Context, orgId, userId int64, role string) error
+	RemoveMember(ctx context.Context, orgId, userId int64) error
+	CreateInvitation(ctx context.Context, invitation *OrganizationInvitation) error
+	GetInvitation(ctx context.Context, token string) (OrganizationInvitation, error)
+	AcceptInvitation(ctx context.Context, invitation OrganizationInvitation, member *OrganizationMember) error
+}
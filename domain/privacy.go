@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// ExportProfile is the profile part of a data export, without the password
+type ExportProfile struct {
+	ID            int64     `json:"id"`
+	Username      string    `json:"username"`
+	Email         string    `json:"email"`
+	Name          string    `json:"name"`
+	EmailVerified string    `json:"email_verified"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// DataExport is every personal data held about a user
+type DataExport struct {
+	ExportedAt time.Time      `json:"exported_at"`
+	Profile    ExportProfile  `json:"profile"`
+	Identities []UserIdentity `json:"identities"`
+	Links      []GeneratedUrl `json:"links"`
+	Clicks     []ClickEvent   `json:"clicks"`
+	Sessions   []Session      `json:"sessions"`
+}
+
+// ErasureReport count what one run of the erasure job changed
+type ErasureReport struct {
+	AnonymizedClicks int64 `json:"anonymized_clicks"`
+	DeletedClicks    int64 `json:"deleted_clicks"`
+	DeletedRecords   int64 `json:"deleted_records"`
+}
+
+// PrivacyUsecase represent the personal data usecases
+type PrivacyUsecase interface {
+	Export(ctx context.Context, userId int64) (DataExport, error)
+	Erase(ctx context.Context) (ErasureReport, error)
+}
+
+// PrivacyRepository represent the personal data repository contract
+type PrivacyRepository interface {
+	GetUserById(ctx context.Context, id int64) (User, error)
+	GetIdentities(ctx context.Context, userId int64) ([]UserIdentity, error)
+	GetLinks(ctx context.Context, userId int64) ([]GeneratedUrl, error)
+	GetClickEvents(ctx context.Context, userId int64) ([]ClickEvent, error)
+	GetClickEventsToAnonymize(ctx context.Context, before time.Time, limit int) ([]ClickEvent, error)
+	AnonymizeClickEvent(ctx context.Context, id int64, ip string) error
+	DeleteOrphanedData(ctx context.Context) (deletedClicks, deletedRecords int64, err error)
+}
@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Session represent a single login of a user. It outlives the access/refresh
+// token pair it currently holds, so it can be listed and revoked as a whole.
+type Session struct {
+	ID          string `json:"id"`
+	UserId      int64  `json:"user_id"`
+	AccessUUID  string `json:"-"`
+	RefreshUUID string `json:"-"`
+	Device      string `json:"device"`
+	IP          string `json:"ip"`
+	UserAgent   string `json:"user_agent"`
+	Current     bool   `json:"current"`
+	// Generation count how many times the refresh token of this session
+	// (the refresh token family) has been rotated
+	Generation int64     `json:"generation"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+}
+
+// ClientInfo describe the client that sent the current request
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+	Device    string
+	Referer   string
+	RequestID string
+}
+
+type clientInfoKey struct{}
+
+// NewContextWithClientInfo return a copy of ctx carrying the client info
+func NewContextWithClientInfo(ctx context.Context, info ClientInfo) context.Context {
+	return context.WithValue(ctx, clientInfoKey{}, info)
+}
+
+// ClientInfoFromContext return the client info stored in ctx, if any
+func ClientInfoFromContext(ctx context.Context) ClientInfo {
+	info, _ := ctx.Value(clientInfoKey{}).(ClientInfo)
+	return info
+}
+
+// SessionUsecase represent the session's usecases
+type SessionUsecase interface {
+	Fetch(ctx context.Context, userId int64) ([]Session, error)
+	Revoke(ctx context.Context, userId int64, sessionId string) error
+	RevokeAll(ctx context.Context, userId int64) error
+}
@@ -6,17 +6,30 @@ import (
 )
 
 // User ...
+// A suspended user can't log in and its links don't redirect.
+// ResetRequired is "Y" when the password must be reset before the next login.
 type User struct {
-	ID            int64     `json:"id" gorm:"primary_key;auto_increment"`
-	Username      string    `json:"username" validate:"required" gorm:"size:12;not null;unique"`
-	Email         string    `json:"email" validate:"required" gorm:"size:165;not null;unique"`
-	Password      string    `json:"password" validate:"required" gorm:"size:125;not null;"`
-	Name          string    `json:"name" validate:"required" gorm:"size:125;not null;"`
-	EmailVerified string    `json:"email_verified" gorm:"size:1;not null;"`
-	UpdatedAt     time.Time `json:"updated_at"`
-	CreatedAt     time.Time `json:"created_at"`
+	ID            int64      `json:"id" gorm:"primary_key;auto_increment"`
+	Username      string     `json:"username" validate:"required" gorm:"size:12;not null;unique"`
+	Email         string     `json:"email" validate:"required" gorm:"size:165;not null;unique"`
+	Password      string     `json:"password" validate:"required" gorm:"size:125;not null;"`
+	Name          string     `json:"name" validate:"required" gorm:"size:125;not null;"`
+	EmailVerified string     `json:"email_verified" gorm:"size:1;not null;"`
+	Role          string     `json:"role" gorm:"size:16;not null;default:'user'"`
+	SuspendedAt   *time.Time `json:"suspended_at"`
+	SuspendReason string     `json:"suspend_reason" gorm:"size:255"`
+	ResetRequired string     `json:"reset_required" gorm:"size:1;not null;default:'N'"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	CreatedAt     time.Time  `json:"created_at"`
 }
 
+// roles of the users, only admins reach the admin console. Not to be
+// confused with the organization member roles.
+const (
+	UserRoleUser  = "user"
+	UserRoleAdmin = "admin"
+)
+
 // UserUsecase represent the article's usecases
 type UserUsecase interface {
 	Fetch(ctx context.Context) ([]User, error)
@@ -0,0 +1,127 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// webhook events
+const (
+	WebhookLinkCreated = "link.created"
+	WebhookLinkUpdated = "link.updated"
+	WebhookLinkExpired = "link.expired"
+	WebhookLinkClicked = "link.clicked"
+)
+
+// ValidWebhookEvent tell if event can be subscribed to
+func ValidWebhookEvent(event string) bool {
+	switch event {
+	case WebhookLinkCreated, WebhookLinkUpdated, WebhookLinkExpired, WebhookLinkClicked:
+		return true
+	}
+	return false
+}
+
+// status of a webhook delivery
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+)
+
+// Webhook is an endpoint of a user notified of the events of its links.
+// Events is stored comma separated, EventList is its decoded form. Secret
+// signs the payloads, it is only shown when the webhook is created.
+type Webhook struct {
+	ID        int64     `json:"id" gorm:"primary_key;auto_increment"`
+	UserId    int64     `json:"user_id" gorm:"not null;index"`
+	Url       string    `json:"url" gorm:"size:2048;not null"`
+	Events    string    `json:"-" gorm:"size:255;not null"`
+	EventList []string  `json:"events" gorm:"-"`
+	Secret    string    `json:"-" gorm:"size:64;not null"`
+	Active    string    `json:"active" gorm:"size:1;not null;default:'Y'"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// AfterFind decode the subscribed events
+func (w *Webhook) AfterFind() error {
+	w.EventList = nil
+	if w.Events != "" {
+		w.EventList = strings.Split(w.Events, ",")
+	}
+	return nil
+}
+
+// Subscribed tell if the webhook is notified of event
+func (w Webhook) Subscribed(event string) bool {
+	for _, subscribed := range strings.Split(w.Events, ",") {
+		if subscribed == event {
+			return true
+		}
+	}
+	return false
+}
+
+type CreateWebhook struct {
+	Url    string   `json:"url" validate:"required,url,max=2048"`
+	Events []string `json:"events" validate:"required,min=1,dive,required"`
+}
+
+// WebhookDelivery is a single event sent to a webhook, retried with an
+// exponential backoff until it succeeds or runs out of attempts. EventId is
+// kept by the redeliveries, so receivers can drop the duplicates.
+type WebhookDelivery struct {
+	ID            int64           `json:"id" gorm:"primary_key;auto_increment"`
+	WebhookId     int64           `json:"webhook_id" gorm:"not null;index"`
+	EventId       string          `json:"event_id" gorm:"size:64;not null"`
+	Event         string          `json:"event" gorm:"size:32;not null"`
+	Payload       json.RawMessage `json:"payload" gorm:"type:text"`
+	Status        string          `json:"status" gorm:"size:16;not null;index:idx_delivery_due"`
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt time.Time       `json:"next_attempt_at" gorm:"index:idx_delivery_due"`
+	ResponseCode  int             `json:"response_code"`
+	ResponseBody  string          `json:"response_body" gorm:"size:1024"`
+	LastError     string          `json:"last_error" gorm:"size:255"`
+	DeliveredAt   *time.Time      `json:"delivered_at"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+}
+
+// WebhookEvent is the body posted to the webhooks
+type WebhookEvent struct {
+	ID        string      `json:"id"`
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+// WebhookUsecase represent the webhooks usecases
+type WebhookUsecase interface {
+	Create(ctx context.Context, userId int64, param CreateWebhook) (webhook Webhook, secret string, err error)
+	Fetch(ctx context.Context, userId int64) ([]Webhook, error)
+	Delete(ctx context.Context, userId, webhookId int64) error
+	Deliveries(ctx context.Context, userId, webhookId int64, limit int) ([]WebhookDelivery, error)
+	Redeliver(ctx context.Context, userId, webhookId, deliveryId int64) (WebhookDelivery, error)
+	// ProcessQueue send the deliveries that are due, it returns how many were attempted
+	ProcessQueue(ctx context.Context) (int, error)
+}
+
+// WebhookRepository represent the webhooks repository contract
+type WebhookRepository interface {
+	Create(ctx context.Context, webhook *Webhook) error
+	FetchByUserId(ctx context.Context, userId int64) ([]Webhook, error)
+	GetById(ctx context.Context, webhookId int64) (Webhook, error)
+	Delete(ctx context.Context, webhookId int64) error
+	FetchSubscribed(ctx context.Context, userId int64, event string) ([]Webhook, error)
+	CreateDelivery(ctx context.Context, delivery *WebhookDelivery) error
+	GetDelivery(ctx context.Context, deliveryId int64) (WebhookDelivery, error)
+	FetchDeliveries(ctx context.Context, webhookId int64, limit int) ([]WebhookDelivery, error)
+	FetchDueDeliveries(ctx context.Context, now time.Time, limit int) ([]WebhookDelivery, error)
+	// ClaimDelivery push the next attempt of the delivery to leaseUntil, it
+	// fails when another worker claimed it first
+	ClaimDelivery(ctx context.Context, delivery WebhookDelivery, leaseUntil time.Time) (bool, error)
+	UpdateDelivery(ctx context.Context, delivery *WebhookDelivery) error
+}